@@ -1,26 +1,29 @@
 package main
 
 import (
+	"context"
 	"encoding/csv"
+	"encoding/json"
 	"flag"
 	"fmt"
+	"io"
+	"net/http"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
+
+	"github.com/ahmadzakiakmal/thesis-extension/layer-2/l2client"
 )
 
-type SessionResponse struct {
-	SessionID string `json:"session_id"`
-}
-
-type CommitResponse struct {
-	TxHash      string `json:"tx_hash"`
-	BlockHeight int64  `json:"block_height"`
-}
+// operatorID is the operator the benchmark acts as. L2 authorizes every
+// session step against the X-Operator-ID header.
+const operatorID = "OPR-001"
 
 type WorkflowResult struct {
+	Type     string
 	Success  bool
 	Latency  time.Duration
 	ErrorMsg string
@@ -44,8 +47,26 @@ func main() {
 	duration := flag.Int("duration", 30, "Test duration in seconds")
 	l2Port := flag.String("port", "7000", "L2 port")
 	packageID := flag.String("pkg", "PKG-001", "Package ID to use")
+	maxConns := flag.Int("maxconns", 0, "Maximum connections to the L2 node (0 is unlimited)")
+	keepAlive := flag.Bool("keepalive", true, "Reuse connections between requests")
+	mixFlag := flag.String("mix", "commit=100", "Workflow percentages: commit (full workflow), read (L1 session lookup), forward (workflow for another shard's group)")
+	forwardGroup := flag.String("forward-group", "group-b", "Client group used by forward workflows")
+	l1URL := flag.String("l1url", "http://127.0.0.1:5000", "L1 URL used by read workflows")
+	rate := flag.Float64("rate", 0, "Open loop: workflows started per second regardless of completion (0 runs the closed loop of -workers)")
+	think := flag.Duration("thinktime", 0, "Closed loop: pause between a worker's iterations")
 	flag.Parse()
 
+	if *rate < 0 {
+		fmt.Println("Invalid -rate: must not be negative")
+		os.Exit(1)
+	}
+
+	mix, err := ParseMix(*mixFlag)
+	if err != nil {
+		fmt.Printf("Invalid -mix: %v\n", err)
+		os.Exit(1)
+	}
+
 	recordsDir := "./records"
 	os.MkdirAll(recordsDir, 0755)
 
@@ -60,16 +81,28 @@ func main() {
 	fmt.Println("========================================")
 	fmt.Printf("L1 Nodes:   %d\n", *l1Nodes)
 	fmt.Printf("L2 Nodes:   %d\n", *l2Nodes)
-	fmt.Printf("Workers:    %d\n", *workers)
+	if *rate > 0 {
+		fmt.Printf("Rate:       %.2f workflows/s (open loop)\n", *rate)
+	} else {
+		fmt.Printf("Workers:    %d\n", *workers)
+		fmt.Printf("Think Time: %v\n", *think)
+	}
 	fmt.Printf("Duration:   %ds\n", *duration)
 	fmt.Printf("L2 URL:     http://127.0.0.1:%s\n", *l2Port)
 	fmt.Printf("Package ID: %s\n", *packageID)
+	fmt.Printf("Max Conns:  %d\n", *maxConns)
+	fmt.Printf("Keep-Alive: %t\n", *keepAlive)
+	fmt.Printf("Mix:        %s\n", mix)
 	fmt.Printf("Output:     %s\n", filename)
 	fmt.Println("========================================")
 	fmt.Println("")
 
 	baseURL := fmt.Sprintf("http://127.0.0.1:%s", *l2Port)
 
+	// All workers share one transport so connection reuse can be measured
+	transport := NewTransport(*maxConns, *workers, *keepAlive)
+	connStats := &ConnStats{}
+
 	// Channels for communication
 	stopChan := make(chan struct{})
 	resultsChan := make(chan WorkflowResult, *workers*10)
@@ -81,15 +114,47 @@ func main() {
 	var totalLatency int64
 	var minLatency int64 = 1<<63 - 1
 	var maxLatency int64 = 0
+	byWorkflow := map[string]*WorkflowStats{}
+	errorCounts := ErrorCounts{}
 
 	// WaitGroup for workers
 	var wg sync.WaitGroup
 
-	// Start worker goroutines
-	fmt.Println("Starting workers...")
-	for i := 0; i < *workers; i++ {
+	// Sessions committed during the run, looked up by read workflows
+	sessions := &sessionPool{}
+
+	newRunner := func() *workflowRunner {
+		httpClient := NewHTTPClient(transport, connStats)
+		l2Client := l2client.NewL2Client(baseURL)
+		l2Client.SetHTTPClient(httpClient)
+		l2Client.SetOperator(operatorID)
+		forwardClient := l2client.NewL2Client(baseURL)
+		forwardClient.SetHTTPClient(httpClient)
+		forwardClient.SetOperator(operatorID)
+		forwardClient.SetClientGroup(*forwardGroup)
+		return &workflowRunner{
+			l2:        l2Client,
+			forward:   forwardClient,
+			l1:        httpClient,
+			l1URL:     *l1URL,
+			mix:       mix,
+			sessions:  sessions,
+			packageID: *packageID,
+		}
+	}
+
+	if *rate > 0 {
+		// Open loop: workflows are started on a schedule and may overlap
+		fmt.Println("Starting open-loop dispatcher...")
 		wg.Add(1)
-		go worker(i, baseURL, *packageID, stopChan, resultsChan, &wg)
+		go openLoop(*rate, newRunner(), stopChan, resultsChan, &wg)
+	} else {
+		// Start worker goroutines
+		fmt.Println("Starting workers...")
+		for i := 0; i < *workers; i++ {
+			wg.Add(1)
+			go worker(i, newRunner(), *think, stopChan, resultsChan, &wg)
+		}
 	}
 
 	// Start result collector
@@ -99,6 +164,13 @@ func main() {
 		defer collectorWg.Done()
 		for result := range resultsChan {
 			atomic.AddInt64(&totalReqs, 1)
+			stats := byWorkflow[result.Type]
+			if stats == nil {
+				stats = &WorkflowStats{}
+				byWorkflow[result.Type] = stats
+			}
+			stats.Record(result)
+			errorCounts.Record(result)
 
 			if result.Success {
 				atomic.AddInt64(&successReqs, 1)
@@ -153,6 +225,15 @@ func main() {
 		avgLatency = time.Duration(totalLatency / successReqs)
 	}
 
+	// Connections opened per HTTP request: near 0% means the client reuses
+	// connections, near 100% means every request pays for a new one
+	httpRequests := connStats.Requests.Load()
+	connsOpened := connStats.ConnsOpened.Load()
+	connShare := 0.0
+	if httpRequests > 0 {
+		connShare = float64(connsOpened) / float64(httpRequests) * 100
+	}
+
 	// Print results
 	fmt.Println("\n\n========================================")
 	fmt.Println("   BENCHMARK RESULTS")
@@ -165,7 +246,13 @@ func main() {
 	fmt.Printf("Avg Latency:       %v\n", avgLatency)
 	fmt.Printf("Min Latency:       %v\n", time.Duration(minLatency))
 	fmt.Printf("Max Latency:       %v\n", time.Duration(maxLatency))
+	fmt.Printf("HTTP Requests:     %d\n", httpRequests)
+	fmt.Printf("Connections:       %d (%.2f%% of requests)\n", connsOpened, connShare)
 	fmt.Println("========================================")
+	printWorkflowBreakdown(byWorkflow)
+	if failedReqs > 0 {
+		errorCounts.Print(failedReqs)
+	}
 
 	// Save to CSV
 	file, err := os.Create(filename)
@@ -178,13 +265,16 @@ func main() {
 	writer := csv.NewWriter(file)
 	defer writer.Flush()
 
-	writer.Write([]string{
+	errorHeader, errorValues := errorCounts.CSVColumns()
+
+	writer.Write(append([]string{
 		"L1_Nodes", "L2_Nodes", "Workers", "Duration_s",
 		"Total_Requests", "Successful", "Failed",
 		"TPS", "Avg_Latency_ms", "Min_Latency_ms", "Max_Latency_ms",
-	})
+		"HTTP_Requests", "Connections_Opened",
+	}, errorHeader...))
 
-	writer.Write([]string{
+	writer.Write(append([]string{
 		fmt.Sprintf("%d", *l1Nodes),
 		fmt.Sprintf("%d", *l2Nodes),
 		fmt.Sprintf("%d", *workers),
@@ -196,89 +286,197 @@ func main() {
 		fmt.Sprintf("%.2f", float64(avgLatency.Milliseconds())),
 		fmt.Sprintf("%.2f", float64(time.Duration(minLatency).Milliseconds())),
 		fmt.Sprintf("%.2f", float64(time.Duration(maxLatency).Milliseconds())),
-	})
+		fmt.Sprintf("%d", httpRequests),
+		fmt.Sprintf("%d", connsOpened),
+	}, errorValues...))
 
 	fmt.Printf("\nResults saved to: %s\n", filename)
+
+	if len(byWorkflow) > 1 {
+		breakdownFile := strings.TrimSuffix(filename, ".csv") + "_by_workflow.csv"
+		if err := writeWorkflowBreakdown(breakdownFile, byWorkflow); err != nil {
+			fmt.Printf("Error writing workflow breakdown: %v\n", err)
+			return
+		}
+		fmt.Printf("Workflow breakdown saved to: %s\n", breakdownFile)
+	}
 }
 
-func worker(id int, baseURL, packageID string, stopChan chan struct{}, resultsChan chan WorkflowResult, wg *sync.WaitGroup) {
-	defer wg.Done()
+// printWorkflowBreakdown prints per workflow type results
+func printWorkflowBreakdown(byWorkflow map[string]*WorkflowStats) {
+	fmt.Println("   BY WORKFLOW")
+	fmt.Println("========================================")
+	fmt.Printf("%-8s %8s %8s %8s %10s %10s %10s\n", "Type", "Total", "Success", "Failed", "Avg", "P95", "Max")
+	for _, name := range workflowOrder {
+		stats, ok := byWorkflow[name]
+		if !ok {
+			continue
+		}
+		fmt.Printf("%-8s %8d %8d %8d %10v %10v %10v\n", name, stats.Total, stats.Successful, stats.Failed,
+			stats.AvgLatency().Round(time.Millisecond), stats.P95Latency().Round(time.Millisecond), stats.MaxLatency.Round(time.Millisecond))
+	}
+	fmt.Println("========================================")
+}
 
-	client := NewHTTPClient(baseURL)
+// writeWorkflowBreakdown saves per workflow type results as CSV
+func writeWorkflowBreakdown(filename string, byWorkflow map[string]*WorkflowStats) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	writer.Write([]string{
+		"Workflow", "Total", "Successful", "Failed",
+		"Avg_Latency_ms", "P95_Latency_ms", "Min_Latency_ms", "Max_Latency_ms",
+	})
+	for _, name := range workflowOrder {
+		stats, ok := byWorkflow[name]
+		if !ok {
+			continue
+		}
+		writer.Write([]string{
+			name,
+			fmt.Sprintf("%d", stats.Total),
+			fmt.Sprintf("%d", stats.Successful),
+			fmt.Sprintf("%d", stats.Failed),
+			fmt.Sprintf("%.2f", float64(stats.AvgLatency().Microseconds())/1000),
+			fmt.Sprintf("%.2f", float64(stats.P95Latency().Microseconds())/1000),
+			fmt.Sprintf("%.2f", float64(stats.MinLatency.Microseconds())/1000),
+			fmt.Sprintf("%.2f", float64(stats.MaxLatency.Microseconds())/1000),
+		})
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// workflowRunner runs the workflows of one worker
+type workflowRunner struct {
+	l2        *l2client.L2Client
+	forward   *l2client.L2Client
+	l1        *http.Client
+	l1URL     string
+	mix       Mix
+	sessions  *sessionPool
+	packageID string
+}
+
+// run picks a workflow type from the mix and runs it. Reads before any
+// session has been committed run a commit workflow instead.
+func (r *workflowRunner) run() WorkflowResult {
+	workflow := r.mix.Pick()
+	sessionID, haveSession := "", false
+	if workflow == WorkflowRead {
+		sessionID, haveSession = r.sessions.Random()
+		if !haveSession {
+			workflow = WorkflowCommit
+		}
+	}
+
+	ctx := context.Background()
+	start := time.Now()
+	var err error
+	switch workflow {
+	case WorkflowRead:
+		err = readSession(ctx, r.l1, r.l1URL, sessionID)
+	case WorkflowForward:
+		_, err = runWorkflow(ctx, r.forward, r.packageID)
+	default:
+		sessionID, err = runWorkflow(ctx, r.l2, r.packageID)
+		if err == nil {
+			r.sessions.Add(sessionID)
+		}
+	}
+
+	result := WorkflowResult{
+		Type:    workflow,
+		Success: err == nil,
+		Latency: time.Since(start),
+	}
+	if err != nil {
+		result.ErrorMsg = err.Error()
+	}
+	return result
+}
+
+func worker(id int, runner *workflowRunner, think time.Duration, stopChan chan struct{}, resultsChan chan WorkflowResult, wg *sync.WaitGroup) {
+	defer wg.Done()
 
 	for {
 		select {
 		case <-stopChan:
 			return
 		default:
-			start := time.Now()
-			err := runWorkflow(client, packageID)
-			latency := time.Since(start)
-
-			result := WorkflowResult{
-				Success: err == nil,
-				Latency: latency,
-			}
-			if err != nil {
-				result.ErrorMsg = err.Error()
+			resultsChan <- runner.run()
+			if !thinkTime(think, stopChan) {
+				return
 			}
-
-			resultsChan <- result
 		}
 	}
 }
 
-func runWorkflow(client *HTTPClient, packageID string) error {
-	// 1. Start Session
-	resp, err := client.POST("/session/start", map[string]interface{}{
-		"operator_id": "OPR-001",
-	})
+// readSession looks up a committed session on L1
+func readSession(ctx context.Context, client *http.Client, l1URL, sessionID string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, l1URL+"/l1/sessions/"+sessionID, nil)
 	if err != nil {
-		return fmt.Errorf("start session: %v", err)
+		return fmt.Errorf("read session: %w", err)
 	}
-	var sessResp SessionResponse
-	if err := UnmarshalBody(resp, &sessResp); err != nil {
-		return fmt.Errorf("start session unmarshal: %v", err)
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("read session: %w", err)
 	}
-	sessionID := sessResp.SessionID
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("read session: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("read session: L1 returned status %d: %s", resp.StatusCode, body)
+	}
+	var session map[string]interface{}
+	if err := json.Unmarshal(body, &session); err != nil {
+		return fmt.Errorf("read session: failed to parse L1 response: %w", err)
+	}
+	return nil
+}
+
+// runWorkflow runs the full six-step session workflow and returns the
+// committed session ID
+func runWorkflow(ctx context.Context, client *l2client.L2Client, packageID string) (string, error) {
+	// 1. Start Session
+	session, err := client.StartSession(ctx, operatorID)
+	if err != nil {
+		return "", fmt.Errorf("start session: %w", err)
+	}
+	sessionID := session.SessionID
 
 	// 2. Scan Package
-	endpoint := fmt.Sprintf("/session/%s/scan", sessionID)
-	if _, err := client.GET(endpoint); err != nil {
-		return fmt.Errorf("scan package: %v", err)
+	scan, err := client.ScanPackage(ctx, sessionID, packageID)
+	if err != nil {
+		return "", fmt.Errorf("scan package: %w", err)
 	}
 
 	// 3. Validate Package
-	endpoint = fmt.Sprintf("/session/%s/validate", sessionID)
-	if _, err := client.POST(endpoint, map[string]interface{}{
-		"package_id": packageID,
-		"signature":  "sig_test_001",
-	}); err != nil {
-		return fmt.Errorf("validate package: %v", err)
+	if _, err := client.ValidatePackage(ctx, sessionID, packageID, scan.SupplierSignature); err != nil {
+		return "", fmt.Errorf("validate package: %w", err)
 	}
 
 	// 4. Quality Check
-	endpoint = fmt.Sprintf("/session/%s/qc", sessionID)
-	if _, err := client.POST(endpoint, map[string]interface{}{
-		"passed": true,
-		"issues": []string{},
-	}); err != nil {
-		return fmt.Errorf("quality check: %v", err)
+	if _, err := client.QualityCheck(ctx, sessionID, true, nil); err != nil {
+		return "", fmt.Errorf("quality check: %w", err)
 	}
 
 	// 5. Label Package
-	endpoint = fmt.Sprintf("/session/%s/label", sessionID)
-	if _, err := client.POST(endpoint, map[string]interface{}{
-		"courier_id": "CUR-001",
-	}); err != nil {
-		return fmt.Errorf("label package: %v", err)
+	if _, err := client.LabelPackage(ctx, sessionID, "CUR-001"); err != nil {
+		return "", fmt.Errorf("label package: %w", err)
 	}
 
 	// 6. Commit Session
-	endpoint = fmt.Sprintf("/session/%s/commit", sessionID)
-	if _, err := client.POST(endpoint, nil); err != nil {
-		return fmt.Errorf("commit session: %v", err)
+	if _, err := client.CommitSession(ctx, sessionID, ""); err != nil {
+		return "", fmt.Errorf("commit session: %w", err)
 	}
 
-	return nil
+	return sessionID, nil
 }
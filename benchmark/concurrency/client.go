@@ -1,75 +1,69 @@
 package main
 
 import (
-	"bytes"
-	"encoding/json"
-	"fmt"
-	"io"
 	"net/http"
+	"net/http/httptrace"
+	"sync/atomic"
 	"time"
 )
 
-type HTTPClient struct {
-	baseURL string
-	client  *http.Client
+// ConnStats counts requests and the connections opened to serve them. Many
+// more connections than the number of workers means connections are churned
+// instead of reused.
+type ConnStats struct {
+	Requests    atomic.Int64
+	ConnsOpened atomic.Int64
 }
 
-func NewHTTPClient(baseURL string) *HTTPClient {
-	return &HTTPClient{
-		baseURL: baseURL,
-		client: &http.Client{
-			Timeout: 30 * time.Second,
+// trace returns a client trace counting the request and, unless an idle
+// connection was reused, the new connection
+func (s *ConnStats) trace() *httptrace.ClientTrace {
+	s.Requests.Add(1)
+	return &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			if !info.Reused {
+				s.ConnsOpened.Add(1)
+			}
 		},
 	}
 }
 
-func (c *HTTPClient) GET(endpoint string) (*http.Response, error) {
-	url := c.baseURL + endpoint
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return nil, err
+// NewTransport builds the transport shared by all workers. maxConns caps the
+// connections to the L2 node (0 is unlimited) and idle connections are kept
+// for up to idleConns workers; keepAlive false opens a connection per request.
+func NewTransport(maxConns, idleConns int, keepAlive bool) *http.Transport {
+	if maxConns > 0 && idleConns > maxConns {
+		idleConns = maxConns
 	}
-
-	req.Header.Set("Accept", "*/*")
-	req.Header.Set("Cache-Control", "no-cache")
-
-	return c.client.Do(req)
-}
-
-func (c *HTTPClient) POST(endpoint string, body interface{}) (*http.Response, error) {
-	url := c.baseURL + endpoint
-	
-	var bodyReader io.Reader
-	if body != nil {
-		jsonData, err := json.Marshal(body)
-		if err != nil {
-			return nil, err
-		}
-		bodyReader = bytes.NewBuffer(jsonData)
-	}
-
-	req, err := http.NewRequest("POST", url, bodyReader)
-	if err != nil {
-		return nil, err
+	return &http.Transport{
+		Proxy:               http.ProxyFromEnvironment,
+		MaxConnsPerHost:     maxConns,
+		MaxIdleConns:        idleConns,
+		MaxIdleConnsPerHost: idleConns,
+		IdleConnTimeout:     90 * time.Second,
+		DisableKeepAlives:   !keepAlive,
 	}
+}
 
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Accept", "*/*")
-	req.Header.Set("Cache-Control", "no-cache")
+// countingTransport records every request it sends in ConnStats
+type countingTransport struct {
+	next  http.RoundTripper
+	stats *ConnStats
+}
 
-	return c.client.Do(req)
+func (t *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.WithContext(httptrace.WithClientTrace(req.Context(), t.stats.trace()))
+	return t.next.RoundTrip(req)
 }
 
-func UnmarshalBody(resp *http.Response, v interface{}) error {
-	defer resp.Body.Close()
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return err
+// NewHTTPClient returns a client sending requests over transport, recorded in
+// stats unless it is nil
+func NewHTTPClient(transport http.RoundTripper, stats *ConnStats) *http.Client {
+	if stats != nil {
+		transport = &countingTransport{next: transport, stats: stats}
 	}
-	
-	if resp.StatusCode >= 400 {
-		return fmt.Errorf("HTTP %d: %s", resp.StatusCode, string(body))
+	return &http.Client{
+		Timeout:   30 * time.Second,
+		Transport: transport,
 	}
-	
-	return json.Unmarshal(body, v)
 }
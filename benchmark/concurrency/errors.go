@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Failure categories for failed workflows
+const (
+	ErrorTimeout    = "timeout"
+	ErrorClient     = "4xx"
+	ErrorServer     = "5xx"
+	ErrorConnection = "connection"
+	ErrorUnmarshal  = "unmarshal"
+	ErrorOther      = "other"
+)
+
+// errorCategories is the order failure categories are reported in
+var errorCategories = []string{ErrorTimeout, ErrorClient, ErrorServer, ErrorConnection, ErrorUnmarshal, ErrorOther}
+
+// httpStatusPattern matches the status l2client.APIError and readSession
+// put in their errors
+var httpStatusPattern = regexp.MustCompile(`status ([1-5])\d\d`)
+
+// connectionErrors are substrings of errors from failing to reach the node
+var connectionErrors = []string{
+	"connection refused",
+	"connection reset",
+	"broken pipe",
+	"no such host",
+	"dial tcp",
+	"EOF",
+}
+
+// CategorizeError sorts a failed workflow's ErrorMsg into a failure category.
+// Timeouts are checked first since they can also mention the connection, and
+// HTTP statuses before unmarshal since error bodies can fail to parse too.
+func CategorizeError(msg string) string {
+	if strings.Contains(msg, "Timeout exceeded") ||
+		strings.Contains(msg, "deadline exceeded") ||
+		strings.Contains(msg, "i/o timeout") {
+		return ErrorTimeout
+	}
+	if match := httpStatusPattern.FindStringSubmatch(msg); match != nil {
+		if match[1] == "5" {
+			return ErrorServer
+		}
+		return ErrorClient
+	}
+	for _, connErr := range connectionErrors {
+		if strings.Contains(msg, connErr) {
+			return ErrorConnection
+		}
+	}
+	if strings.Contains(msg, "failed to parse") {
+		return ErrorUnmarshal
+	}
+	return ErrorOther
+}
+
+// ErrorCounts counts failed workflows per failure category
+type ErrorCounts map[string]int64
+
+// Record counts a result if it failed
+func (c ErrorCounts) Record(result WorkflowResult) {
+	if result.Success {
+		return
+	}
+	c[CategorizeError(result.ErrorMsg)]++
+}
+
+// CSVColumns returns the header and value of one column per category
+func (c ErrorCounts) CSVColumns() (header, values []string) {
+	for _, category := range errorCategories {
+		header = append(header, "Errors_"+category)
+		values = append(values, fmt.Sprintf("%d", c[category]))
+	}
+	return header, values
+}
+
+// Print prints the failure breakdown
+func (c ErrorCounts) Print(failed int64) {
+	fmt.Println("   FAILURES BY CAUSE")
+	fmt.Println("========================================")
+	for _, category := range errorCategories {
+		count := c[category]
+		if count == 0 {
+			continue
+		}
+		fmt.Printf("%-12s %8d (%.2f%%)\n", category, count, float64(count)/float64(failed)*100)
+	}
+	fmt.Println("========================================")
+}
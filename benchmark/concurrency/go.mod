@@ -1,3 +1,7 @@
 module github.com/ahmadzakiakmal/thesis-extension/benchmark/concurrency
 
 go 1.24.0
+
+require github.com/ahmadzakiakmal/thesis-extension/layer-2 v0.0.0
+
+replace github.com/ahmadzakiakmal/thesis-extension/layer-2 => ../../layer-2
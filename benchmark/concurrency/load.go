@@ -0,0 +1,44 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// openLoop starts workflows at rate per second until stopChan is closed,
+// whether or not earlier ones have completed. Start times follow a fixed
+// schedule so slow iterations of the dispatcher don't lower the offered rate.
+func openLoop(rate float64, runner *workflowRunner, stopChan chan struct{}, resultsChan chan WorkflowResult, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	interval := time.Duration(float64(time.Second) / rate)
+	start := time.Now()
+	for i := 0; ; i++ {
+		next := start.Add(time.Duration(i) * interval)
+		select {
+		case <-stopChan:
+			return
+		case <-time.After(time.Until(next)):
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resultsChan <- runner.run()
+		}()
+	}
+}
+
+// thinkTime pauses a closed-loop worker between iterations. It returns false
+// if the benchmark was stopped while waiting.
+func thinkTime(pause time.Duration, stopChan chan struct{}) bool {
+	if pause <= 0 {
+		return true
+	}
+	select {
+	case <-stopChan:
+		return false
+	case <-time.After(pause):
+		return true
+	}
+}
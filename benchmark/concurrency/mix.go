@@ -0,0 +1,153 @@
+package main
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Workflow types a worker can run
+const (
+	WorkflowCommit  = "commit"  // full session workflow committed to L1
+	WorkflowRead    = "read"    // lookup of a committed session on L1
+	WorkflowForward = "forward" // full workflow for another shard's client group
+)
+
+// workflowOrder is the order workflow types are reported in
+var workflowOrder = []string{WorkflowCommit, WorkflowRead, WorkflowForward}
+
+// Mix is the percentage of iterations given to each workflow type
+type Mix map[string]int
+
+// ParseMix parses a mix such as "commit=80,read=15,forward=5". The
+// percentages must add up to 100.
+func ParseMix(raw string) (Mix, error) {
+	mix := Mix{}
+	total := 0
+	for _, part := range strings.Split(raw, ",") {
+		name, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			return nil, fmt.Errorf("mix entry %q must be workflow=percent", part)
+		}
+		switch name {
+		case WorkflowCommit, WorkflowRead, WorkflowForward:
+		default:
+			return nil, fmt.Errorf("unknown workflow %q (want %s)", name, strings.Join(workflowOrder, ", "))
+		}
+		percent, err := strconv.Atoi(value)
+		if err != nil || percent < 0 {
+			return nil, fmt.Errorf("percentage for %s must be a non-negative integer", name)
+		}
+		mix[name] += percent
+		total += percent
+	}
+	if total != 100 {
+		return nil, fmt.Errorf("mix percentages add up to %d, not 100", total)
+	}
+	return mix, nil
+}
+
+// Pick chooses a workflow type at random according to the mix
+func (m Mix) Pick() string {
+	n := rand.IntN(100)
+	for _, name := range workflowOrder {
+		if n < m[name] {
+			return name
+		}
+		n -= m[name]
+	}
+	return WorkflowCommit
+}
+
+// String formats the mix in the -mix flag syntax
+func (m Mix) String() string {
+	parts := make([]string, 0, len(m))
+	for _, name := range workflowOrder {
+		if m[name] > 0 {
+			parts = append(parts, fmt.Sprintf("%s=%d", name, m[name]))
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+// sessionPool keeps recently committed session IDs for read workflows
+type sessionPool struct {
+	mu       sync.Mutex
+	sessions []string
+	next     int
+}
+
+// sessionPoolSize bounds the committed sessions remembered for reads
+const sessionPoolSize = 1000
+
+func (p *sessionPool) Add(sessionID string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.sessions) < sessionPoolSize {
+		p.sessions = append(p.sessions, sessionID)
+		return
+	}
+	p.sessions[p.next] = sessionID
+	p.next = (p.next + 1) % sessionPoolSize
+}
+
+// Random returns a committed session ID, or false if none is known yet
+func (p *sessionPool) Random() (string, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.sessions) == 0 {
+		return "", false
+	}
+	return p.sessions[rand.IntN(len(p.sessions))], true
+}
+
+// WorkflowStats aggregates results for one workflow type
+type WorkflowStats struct {
+	Total        int64
+	Successful   int64
+	Failed       int64
+	TotalLatency time.Duration
+	MinLatency   time.Duration
+	MaxLatency   time.Duration
+	latencies    []time.Duration
+}
+
+// Record adds one workflow result
+func (s *WorkflowStats) Record(result WorkflowResult) {
+	s.Total++
+	if !result.Success {
+		s.Failed++
+		return
+	}
+	s.Successful++
+	s.TotalLatency += result.Latency
+	if s.MinLatency == 0 || result.Latency < s.MinLatency {
+		s.MinLatency = result.Latency
+	}
+	if result.Latency > s.MaxLatency {
+		s.MaxLatency = result.Latency
+	}
+	s.latencies = append(s.latencies, result.Latency)
+}
+
+// AvgLatency is the mean latency of successful workflows
+func (s *WorkflowStats) AvgLatency() time.Duration {
+	if s.Successful == 0 {
+		return 0
+	}
+	return s.TotalLatency / time.Duration(s.Successful)
+}
+
+// P95Latency is the 95th percentile latency of successful workflows
+func (s *WorkflowStats) P95Latency() time.Duration {
+	if len(s.latencies) == 0 {
+		return 0
+	}
+	sorted := append([]time.Duration(nil), s.latencies...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return sorted[(len(sorted)-1)*95/100]
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"encoding/csv"
 	"flag"
 	"fmt"
@@ -8,16 +9,13 @@ import (
 	"path/filepath"
 	"strconv"
 	"time"
-)
 
-type SessionResponse struct {
-	SessionID string `json:"session_id"`
-}
+	"github.com/ahmadzakiakmal/thesis-extension/layer-2/l2client"
+)
 
-type CommitResponse struct {
-	TxHash      string `json:"tx_hash"`
-	BlockHeight int64  `json:"block_height"`
-}
+// operatorID is the operator the benchmark acts as. L2 authorizes every
+// session step against the X-Operator-ID header.
+const operatorID = "OPR-001"
 
 type Result struct {
 	Step        string
@@ -55,7 +53,11 @@ func main() {
 	writer.Write([]string{"Iteration", "Step", "Latency_ms", "BlockHeight"})
 
 	baseURL := fmt.Sprintf("http://127.0.0.1:%s", *l2Port)
-	client := NewHTTPClient(baseURL)
+	// WRONG CLIENT GROUP - This will trigger cross-shard forwarding!
+	// Sending to shard A (localhost:7000) but with group-b header
+	client := l2client.NewL2Client(baseURL)
+	client.SetClientGroup("group-b")
+	client.SetOperator(operatorID)
 
 	fmt.Println("========================================")
 	fmt.Println("   LATENCY BENCHMARK")
@@ -104,92 +106,61 @@ func main() {
 	fmt.Println("========================================")
 }
 
-func runWorkflow(client *HTTPClient, packageID string) ([]Result, string) {
+func runWorkflow(client *l2client.L2Client, packageID string) ([]Result, string) {
 	var results []Result
+	ctx := context.Background()
 	totalStart := time.Now()
 
-	// WRONG CLIENT GROUP - This will trigger cross-shard forwarding!
-	// Sending to shard A (localhost:7000) but with group-b header
-	headers := map[string]string{
-		"X-Client-Group": "group-b",
-	}
-
 	// 1. Start Session
 	start := time.Now()
-	resp, err := client.POST("/session/start", map[string]interface{}{
-		"operator_id": "OPR-001",
-	}, headers)
+	session, err := client.StartSession(ctx, operatorID)
 	if err != nil {
-		return results, fmt.Sprintf("Start Session: %v", err)
+		return nil, fmt.Sprintf("Start Session: %v", err)
 	}
-	var sessResp SessionResponse
-	if err := UnmarshalBody(resp, &sessResp); err != nil {
-		return results, fmt.Sprintf("Start Session (unmarshal): %v", err)
-	}
-	sessionID := sessResp.SessionID
+	sessionID := session.SessionID
 	results = append(results, Result{"Start Session", time.Since(start), 0})
 	time.Sleep(100 * time.Millisecond)
 
 	// 2. Scan Package
 	start = time.Now()
-	endpoint := fmt.Sprintf("/session/%s/scan", sessionID)
-	_, err = client.GET(endpoint, headers)
+	scan, err := client.ScanPackage(ctx, sessionID, packageID)
 	if err != nil {
-		return results, fmt.Sprintf("Scan Package: %v", err)
+		return nil, fmt.Sprintf("Scan Package: %v", err)
 	}
 	results = append(results, Result{"Scan Package", time.Since(start), 0})
 	time.Sleep(100 * time.Millisecond)
 
 	// 3. Validate Package
 	start = time.Now()
-	endpoint = fmt.Sprintf("/session/%s/validate", sessionID)
-	_, err = client.POST(endpoint, map[string]interface{}{
-		"package_id": packageID,
-		"signature":  "sig_test_001",
-	}, headers)
-	if err != nil {
-		return results, fmt.Sprintf("Validate Package: %v", err)
+	if _, err := client.ValidatePackage(ctx, sessionID, packageID, scan.SupplierSignature); err != nil {
+		return nil, fmt.Sprintf("Validate Package: %v", err)
 	}
 	results = append(results, Result{"Validate Package", time.Since(start), 0})
 	time.Sleep(100 * time.Millisecond)
 
 	// 4. Quality Check
 	start = time.Now()
-	endpoint = fmt.Sprintf("/session/%s/qc", sessionID)
-	_, err = client.POST(endpoint, map[string]interface{}{
-		"passed": true,
-		"issues": []string{},
-	}, headers)
-	if err != nil {
-		return results, fmt.Sprintf("Quality Check: %v", err)
+	if _, err := client.QualityCheck(ctx, sessionID, true, nil); err != nil {
+		return nil, fmt.Sprintf("Quality Check: %v", err)
 	}
 	results = append(results, Result{"Quality Check", time.Since(start), 0})
 	time.Sleep(100 * time.Millisecond)
 
 	// 5. Label Package
 	start = time.Now()
-	endpoint = fmt.Sprintf("/session/%s/label", sessionID)
-	_, err = client.POST(endpoint, map[string]interface{}{
-		"courier_id": "CUR-001",
-	}, headers)
-	if err != nil {
-		return results, fmt.Sprintf("Label Package: %v", err)
+	if _, err := client.LabelPackage(ctx, sessionID, "CUR-001"); err != nil {
+		return nil, fmt.Sprintf("Label Package: %v", err)
 	}
 	results = append(results, Result{"Label Package", time.Since(start), 0})
 	time.Sleep(100 * time.Millisecond)
 
 	// 6. Commit Session
 	start = time.Now()
-	endpoint = fmt.Sprintf("/session/%s/commit", sessionID)
-	resp, err = client.POST(endpoint, nil, headers)
+	commit, err := client.CommitSession(ctx, sessionID, "")
 	if err != nil {
-		return results, fmt.Sprintf("Commit Session: %v", err)
-	}
-	var commitResp CommitResponse
-	if err := UnmarshalBody(resp, &commitResp); err != nil {
-		return results, fmt.Sprintf("Commit Session (unmarshal): %v", err)
+		return nil, fmt.Sprintf("Commit Session: %v", err)
 	}
-	results = append(results, Result{"Commit Session", time.Since(start), commitResp.BlockHeight})
+	results = append(results, Result{"Commit Session", time.Since(start), commit.BlockHeight})
 
 	// Total
 	results = append(results, Result{"Complete Workflow", time.Since(totalStart), 0})
@@ -1,6 +1,7 @@
 package app
 
 import (
+	"bytes"
 	"context"
 	"crypto/sha256"
 	"encoding/hex"
@@ -8,25 +9,29 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"sort"
 	"sync"
+	"time"
 
 	"github.com/ahmadzakiakmal/thesis-extension/layer-1/repository"
 	"github.com/ahmadzakiakmal/thesis-extension/layer-1/srvreg"
 	abcitypes "github.com/cometbft/cometbft/abci/types"
 	cmtlog "github.com/cometbft/cometbft/libs/log"
+	cmttypes "github.com/cometbft/cometbft/types"
 	"github.com/dgraph-io/badger/v4"
 )
 
 // Application implements the ABCI interface for L1 BFT consensus
 type Application struct {
 	badgerDB        *badger.DB
-	onGoingBlock    *badger.Txn
+	onGoingBlock    *blockTxn
 	serviceRegistry *srvreg.ServiceRegistry
 	nodeID          string
 	mu              sync.Mutex
 	config          *AppConfig
 	logger          cmtlog.Logger
 	repository      *repository.Repository
+	shards          *shardSet
 }
 
 // AppConfig contains configuration for the L1 application
@@ -34,6 +39,59 @@ type AppConfig struct {
 	NodeID        string
 	RequiredVotes int
 	LogAllTxs     bool
+
+	// CheckTxMode selects how thoroughly CheckTx vets commits before they
+	// enter the mempool: CheckTxLenient, CheckTxStrict or CheckTxParanoid.
+	// Empty means DefaultCheckTxMode.
+	CheckTxMode string
+
+	// TimestampSkew is how far a commit's timestamp may be from the block
+	// time (or local time in CheckTx). Zero disables the check.
+	TimestampSkew time.Duration
+
+	// MaxSessionDataBytes caps a commit's serialized session_data. Zero
+	// disables the check.
+	MaxSessionDataBytes int
+
+	// BadgerSyncWrites fsyncs every Badger write before it returns. Turning
+	// it on survives power loss and kernel crashes at the cost of commit
+	// throughput; with it off a process crash is still safe, but the last
+	// writes can be lost if the machine goes down.
+	BadgerSyncWrites bool
+
+	// BadgerValueLogFileSize is the size of each value log file in bytes.
+	// Smaller files are garbage collected sooner but rotate more often. Zero
+	// keeps Badger's default of 1GB.
+	BadgerValueLogFileSize int64
+
+	// BadgerNumVersionsToKeep is how many versions of a key Badger retains.
+	// Only the latest is ever read, so more versions only cost disk. Zero
+	// keeps Badger's default of 1.
+	BadgerNumVersionsToKeep int
+}
+
+// BadgerOptions returns the options for opening the Badger store at path
+func (c *AppConfig) BadgerOptions(path string) badger.Options {
+	opts := badger.DefaultOptions(path).WithSyncWrites(c.BadgerSyncWrites)
+	if c.BadgerValueLogFileSize > 0 {
+		opts = opts.WithValueLogFileSize(c.BadgerValueLogFileSize)
+	}
+	if c.BadgerNumVersionsToKeep > 0 {
+		opts = opts.WithNumVersionsToKeep(c.BadgerNumVersionsToKeep)
+	}
+	return opts
+}
+
+// DefaultTimestampSkew is the accepted commit timestamp window
+const DefaultTimestampSkew = 5 * time.Minute
+
+// timestampInWindow reports whether ts lies within the configured skew of ref
+func (app *Application) timestampInWindow(ts, ref time.Time) bool {
+	if app.config.TimestampSkew <= 0 {
+		return true
+	}
+	skew := ts.Sub(ref)
+	return skew <= app.config.TimestampSkew && skew >= -app.config.TimestampSkew
 }
 
 // NewABCIApplication creates a new L1 ABCI application
@@ -45,6 +103,7 @@ func NewABCIApplication(badgerDB *badger.DB, serviceRegistry *srvreg.ServiceRegi
 		config:          config,
 		logger:          logger,
 		repository:      repository,
+		shards:          newShardSet(repository),
 	}
 }
 
@@ -136,11 +195,18 @@ func (app *Application) Query(_ context.Context, req *abcitypes.QueryRequest) (*
 			return nil
 		}
 
-		return item.Value(func(val []byte) error {
-			resp.Log = "exists"
-			resp.Value = val
-			return nil
-		})
+		val, err := item.ValueCopy(nil)
+		if err != nil {
+			return err
+		}
+		if bytes.HasPrefix(req.Data, []byte("tx:")) {
+			if val, err = decompressTx(val); err != nil {
+				return err
+			}
+		}
+		resp.Log = "exists"
+		resp.Value = val
+		return nil
 	})
 
 	if dbErr != nil {
@@ -159,8 +225,7 @@ func (app *Application) verifyTransaction(txID []byte) (*abcitypes.QueryResponse
 	var resp abcitypes.QueryResponse
 
 	err := app.badgerDB.View(func(txn *badger.Txn) error {
-		txKey := append([]byte("tx:"), txID...)
-		item, err := txn.Get(txKey)
+		txData, err := readTx(txn, txID)
 		if err != nil {
 			if errors.Is(err, badger.ErrKeyNotFound) {
 				resp.Log = "Transaction not found"
@@ -170,18 +235,9 @@ func (app *Application) verifyTransaction(txID []byte) (*abcitypes.QueryResponse
 			return err
 		}
 
-		var txData []byte
-		err = item.Value(func(val []byte) error {
-			txData = append([]byte{}, val...)
-			return nil
-		})
-		if err != nil {
-			return err
-		}
-
 		// Get status
 		statusKey := append([]byte("status:"), txID...)
-		item, err = txn.Get(statusKey)
+		item, err := txn.Get(statusKey)
 		status := "confirmed"
 		if err == nil {
 			err = item.Value(func(val []byte) error {
@@ -223,12 +279,23 @@ func (app *Application) queryShardData(shardID string) (*abcitypes.QueryResponse
 			return err
 		}
 
-		return item.Value(func(val []byte) error {
-			resp.Value = val
-			resp.Log = "found"
-			resp.Code = 0
-			return nil
-		})
+		val, err := item.ValueCopy(nil)
+		if err != nil {
+			return err
+		}
+		txData, err := resolveShardValue(txn, val)
+		if err != nil {
+			if errors.Is(err, badger.ErrKeyNotFound) {
+				resp.Log = "Transaction not found"
+				resp.Code = 1
+				return nil
+			}
+			return err
+		}
+		resp.Value = txData
+		resp.Log = "found"
+		resp.Code = 0
+		return nil
 	})
 
 	if err != nil {
@@ -257,6 +324,66 @@ func (app *Application) CheckTx(_ context.Context, check *abcitypes.CheckTxReque
 			fmt.Errorf("missing required fields in shard commit")
 	}
 
+	// A sequence the shard already used can never be committed
+	if shardCommit.Sequence < 0 {
+		return &abcitypes.CheckTxResponse{Code: 1, Log: "sequence must not be negative"}, nil
+	}
+	if shardCommit.Sequence > 0 {
+		last, err := app.lastSequence(shardCommit.ShardID)
+		if err != nil {
+			return &abcitypes.CheckTxResponse{Code: 1, Log: err.Error()}, nil
+		}
+		if shardCommit.Sequence <= last {
+			return &abcitypes.CheckTxResponse{
+				Code: 1,
+				Log:  fmt.Sprintf("sequence %d of shard %s was already committed (last is %d)", shardCommit.Sequence, shardCommit.ShardID, last),
+			}, nil
+		}
+	}
+
+	mode := app.config.CheckTxMode
+	if mode == "" {
+		mode = DefaultCheckTxMode
+	}
+	if mode == CheckTxLenient {
+		return &abcitypes.CheckTxResponse{Code: 0}, nil
+	}
+
+	// Unknown shards would be rejected by the handler anyway; drop them
+	// before they cost a consensus round
+	known, err := app.shards.contains(shardCommit.ShardID)
+	if err != nil {
+		app.logger.Error("Failed to load registered shards", "err", err)
+		return &abcitypes.CheckTxResponse{Code: 1, Log: err.Error()}, nil
+	}
+	if !known {
+		return &abcitypes.CheckTxResponse{
+			Code: 1,
+			Log:  fmt.Sprintf("shard %s is not registered", shardCommit.ShardID),
+		}, nil
+	}
+	if mode == CheckTxStrict {
+		return &abcitypes.CheckTxResponse{Code: 0}, nil
+	}
+
+	if app.config.MaxSessionDataBytes > 0 {
+		size, err := shardCommit.SessionDataSize()
+		if err != nil || size > app.config.MaxSessionDataBytes {
+			return &abcitypes.CheckTxResponse{
+				Code: 1,
+				Log:  fmt.Sprintf("session_data is %d bytes, exceeding the limit of %d bytes", size, app.config.MaxSessionDataBytes),
+			}, nil
+		}
+	}
+
+	// Keep skewed commits out of the mempool so proposals aren't rejected for them
+	if !app.timestampInWindow(shardCommit.Timestamp, time.Now()) {
+		return &abcitypes.CheckTxResponse{
+			Code: 1,
+			Log:  fmt.Sprintf("commit timestamp %s is outside the allowed skew of %s", shardCommit.Timestamp.Format(time.RFC3339), app.config.TimestampSkew),
+		}, nil
+	}
+
 	return &abcitypes.CheckTxResponse{Code: 0}, nil
 }
 
@@ -265,15 +392,62 @@ func (app *Application) InitChain(_ context.Context, chain *abcitypes.InitChainR
 	return &abcitypes.InitChainResponse{}, nil
 }
 
-// PrepareProposal implements the ABCI PrepareProposal method
+// PrepareProposal implements the ABCI PrepareProposal method. Commits that
+// aged out of the timestamp window while in the mempool are left out, since
+// ProcessProposal would reject the whole block for them. Commits are taken in
+// mempool order up to MaxTxBytes, then sorted by hash so the same set of
+// commits always yields the same block regardless of arrival order. Sequenced
+// commits are then put in sequence order per shard, leaving out any that
+// don't continue the shard's sequence.
 func (app *Application) PrepareProposal(_ context.Context, proposal *abcitypes.PrepareProposalRequest) (*abcitypes.PrepareProposalResponse, error) {
-	return &abcitypes.PrepareProposalResponse{Txs: proposal.Txs}, nil
+	txs := make([][]byte, 0, len(proposal.Txs))
+	var totalBytes int64
+	for _, txBytes := range proposal.Txs {
+		var shardCommit repository.ShardedCommitRequest
+		if err := json.Unmarshal(txBytes, &shardCommit); err == nil &&
+			!app.timestampInWindow(shardCommit.Timestamp, proposal.Time) {
+			app.logger.Info("Dropping commit outside timestamp window", "session_id", shardCommit.SessionID, "timestamp", shardCommit.Timestamp)
+			continue
+		}
+
+		// Leave commits that don't fit for the next block
+		txSize := cmttypes.ComputeProtoSizeForTxs([]cmttypes.Tx{txBytes})
+		if proposal.MaxTxBytes > 0 && totalBytes+txSize > proposal.MaxTxBytes {
+			continue
+		}
+		totalBytes += txSize
+		txs = append(txs, txBytes)
+	}
+	sortTxsByHash(txs)
+	txs = app.orderSequenced(txs)
+	return &abcitypes.PrepareProposalResponse{Txs: txs}, nil
+}
+
+// sortTxsByHash orders txs by their SHA-256 hash, the same hash CometBFT
+// reports as the tx hash
+func sortTxsByHash(txs [][]byte) {
+	type hashedTx struct {
+		hash [32]byte
+		tx   []byte
+	}
+	hashed := make([]hashedTx, len(txs))
+	for i, tx := range txs {
+		hashed[i] = hashedTx{hash: sha256.Sum256(tx), tx: tx}
+	}
+
+	sort.Slice(hashed, func(i, j int) bool {
+		return bytes.Compare(hashed[i].hash[:], hashed[j].hash[:]) < 0
+	})
+	for i := range hashed {
+		txs[i] = hashed[i].tx
+	}
 }
 
 // ProcessProposal implements the ABCI ProcessProposal method
 func (app *Application) ProcessProposal(_ context.Context, proposal *abcitypes.ProcessProposalRequest) (*abcitypes.ProcessProposalResponse, error) {
 	app.logger.Info("Processing proposal with transactions", "count", len(proposal.Txs))
 
+	sequences := app.newSequenceTracker()
 	for i, txBytes := range proposal.Txs {
 		var shardCommit repository.ShardedCommitRequest
 		err := json.Unmarshal(txBytes, &shardCommit)
@@ -292,6 +466,25 @@ func (app *Application) ProcessProposal(_ context.Context, proposal *abcitypes.P
 			}, fmt.Errorf("invalid shard commit at index %d", i)
 		}
 
+		// Reject replayed or clock-skewed commits. This is a normal rejection,
+		// not an error, so a bad proposer can't halt the node.
+		if !app.timestampInWindow(shardCommit.Timestamp, proposal.Time) {
+			app.logger.Error("Shard commit timestamp outside allowed skew", "index", i,
+				"session_id", shardCommit.SessionID, "timestamp", shardCommit.Timestamp, "block_time", proposal.Time)
+			return &abcitypes.ProcessProposalResponse{
+				Status: abcitypes.PROCESS_PROPOSAL_STATUS_REJECT,
+			}, nil
+		}
+
+		// Reject dropped or reordered commits of sequenced shards
+		if err := sequences.accept(&shardCommit); err != nil {
+			app.logger.Error("Shard commit out of sequence", "index", i,
+				"shard_id", shardCommit.ShardID, "session_id", shardCommit.SessionID, "err", err)
+			return &abcitypes.ProcessProposalResponse{
+				Status: abcitypes.PROCESS_PROPOSAL_STATUS_REJECT,
+			}, nil
+		}
+
 		app.logger.Info("Validating shard commit", "index", i, "shard_id", shardCommit.ShardID, "session_id", shardCommit.SessionID)
 	}
 
@@ -307,7 +500,7 @@ func (app *Application) FinalizeBlock(_ context.Context, req *abcitypes.Finalize
 	app.mu.Lock()
 	defer app.mu.Unlock()
 
-	app.onGoingBlock = app.badgerDB.NewTransaction(true)
+	app.onGoingBlock = newBlockTxn(app.badgerDB)
 
 	for i, txBytes := range req.Txs {
 		var shardCommit repository.ShardedCommitRequest
@@ -319,23 +512,22 @@ func (app *Application) FinalizeBlock(_ context.Context, req *abcitypes.Finalize
 			continue
 		}
 
-		txID := generateTxID(shardCommit.SessionID, shardCommit.ShardID)
-		txResults[i] = app.storeShardCommit(txID, &shardCommit, "accepted", txBytes)
+		txID := generateTxID(shardCommit.SessionID, shardCommit.ShardID, req.Height, i)
+		result := app.storeShardCommit(txID, &shardCommit, "accepted", txBytes)
+		txResults[i] = result
+		if result.Code == 0 {
+			app.storeSequence(&shardCommit)
+		}
 	}
 
 	// Store block info
 	blockHeight := req.Height
-	appHash := calculateAppHash(txResults)
+	appHash := repository.AppHash(txResults)
 
-	err := app.onGoingBlock.Set([]byte("last_block_height"), int64ToBytes(blockHeight))
-	if err != nil {
-		log.Printf("Error storing block height: %v", err)
-	}
-
-	err = app.onGoingBlock.Set([]byte("last_block_app_hash"), appHash)
-	if err != nil {
-		log.Printf("Error storing app hash: %v", err)
-	}
+	app.onGoingBlock.set(badger.NewEntry([]byte("last_block_app_hash"), appHash))
+	// Written last, so a block committed in several Badger transactions only
+	// counts as stored once all of it is
+	app.onGoingBlock.set(badger.NewEntry([]byte("last_block_height"), int64ToBytes(blockHeight)))
 
 	return &abcitypes.FinalizeBlockResponse{
 		TxResults: txResults,
@@ -343,32 +535,25 @@ func (app *Application) FinalizeBlock(_ context.Context, req *abcitypes.Finalize
 	}, nil
 }
 
-// storeShardCommit stores the shard commit in the database
+// storeShardCommit stores the shard commit in the block. The tx, shard and
+// status keys only reach disk together when the block is committed, so a
+// commit is never stored in part.
 func (app *Application) storeShardCommit(txID string, shardCommit *repository.ShardedCommitRequest, status string, rawTx []byte) *abcitypes.ExecTxResult {
-	// Store the transaction
-	txKey := append([]byte("tx:"), []byte(txID)...)
-	err := app.onGoingBlock.Set(txKey, rawTx)
+	// Store the only copy of the transaction, compressed
+	storedTx, err := compressTx(rawTx)
 	if err != nil {
-		log.Printf("Error storing transaction: %v", err)
+		log.Printf("Error compressing transaction: %v", err)
 		return &abcitypes.ExecTxResult{
 			Code: 3,
-			Log:  fmt.Sprintf("Database error: %v", err),
+			Log:  fmt.Sprintf("Compression error: %v", err),
 		}
 	}
 
-	// Store by shard
+	// The shard index references the transaction instead of copying it
 	shardKey := fmt.Sprintf("shard:%s:session:%s", shardCommit.ShardID, shardCommit.SessionID)
-	err = app.onGoingBlock.Set([]byte(shardKey), rawTx)
-	if err != nil {
-		log.Printf("Error storing shard data: %v", err)
-	}
-
-	// Store status
-	statusKey := append([]byte("status:"), []byte(txID)...)
-	err = app.onGoingBlock.Set(statusKey, []byte(status))
-	if err != nil {
-		log.Printf("Error storing transaction status: %v", err)
-	}
+	app.onGoingBlock.set(badger.NewEntry(append([]byte("tx:"), []byte(txID)...), storedTx))
+	app.onGoingBlock.set(badger.NewEntry([]byte(shardKey), []byte(txID)))
+	app.onGoingBlock.set(badger.NewEntry(append([]byte("status:"), []byte(txID)...), []byte(status)))
 
 	// Create events
 	events := []abcitypes.Event{
@@ -394,9 +579,10 @@ func (app *Application) storeShardCommit(txID string, shardCommit *repository.Sh
 
 // Commit implements the ABCI Commit method
 func (app *Application) Commit(_ context.Context, commit *abcitypes.CommitRequest) (*abcitypes.CommitResponse, error) {
-	err := app.onGoingBlock.Commit()
-	if err != nil {
-		log.Printf("Error committing block: %v", err)
+	// Storage failures are local to this node, so carrying on would leave it
+	// with a state the other validators don't share; halt instead
+	if err := app.onGoingBlock.commit(); err != nil {
+		return nil, fmt.Errorf("committing block: %w", err)
 	}
 	return &abcitypes.CommitResponse{}, nil
 }
@@ -430,22 +616,16 @@ func (app *Application) VerifyVoteExtension(_ context.Context, verify *abcitypes
 
 // Helper functions
 
-// generateTxID generates a unique ID for a shard commit transaction
-func generateTxID(sessionID, shardID string) string {
-	hash := sha256.Sum256([]byte(sessionID + shardID))
+// generateTxID generates a unique ID for a shard commit transaction. The block
+// height and position in the block are hashed in so a session ID reused by a
+// shard (e.g. after an L2 database reset) gets a new ID instead of overwriting
+// the earlier commit in Badger. Fields are NUL-separated so different splits of
+// the same characters cannot collide.
+func generateTxID(sessionID, shardID string, height int64, index int) string {
+	hash := sha256.Sum256([]byte(fmt.Sprintf("%s\x00%s\x00%d\x00%d", sessionID, shardID, height, index)))
 	return hex.EncodeToString(hash[:])
 }
 
-// calculateAppHash calculates the application hash for the current block
-func calculateAppHash(txResults []*abcitypes.ExecTxResult) []byte {
-	allData := make([]byte, 0)
-	for _, result := range txResults {
-		allData = append(allData, result.Data...)
-	}
-	hash := sha256.Sum256(allData)
-	return hash[:]
-}
-
 // int64ToBytes converts an int64 to bytes
 func int64ToBytes(i int64) []byte {
 	buf := make([]byte, 8)
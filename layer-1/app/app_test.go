@@ -0,0 +1,143 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"math/rand"
+	"testing"
+	"time"
+
+	abcitypes "github.com/cometbft/cometbft/abci/types"
+	cmtlog "github.com/cometbft/cometbft/libs/log"
+)
+
+func TestFinalizeBlockTxIDsDifferAcrossHeights(t *testing.T) {
+	db := openTestDB(t, 0)
+	app := &Application{badgerDB: db}
+	commit := []byte(`{"shard_id":"shard-a","session_id":"SES-1","operator_id":"OPR-001"}`)
+
+	var txIDs []string
+	for _, height := range []int64{7, 8} {
+		resp, err := app.FinalizeBlock(context.Background(), &abcitypes.FinalizeBlockRequest{
+			Height: height,
+			Txs:    [][]byte{commit},
+		})
+		if err != nil {
+			t.Fatalf("height %d: FinalizeBlock: %v", height, err)
+		}
+		if _, err := app.Commit(context.Background(), &abcitypes.CommitRequest{}); err != nil {
+			t.Fatalf("height %d: Commit: %v", height, err)
+		}
+
+		result := resp.TxResults[0]
+		if result.Code != 0 {
+			t.Fatalf("height %d: code = %d (%s), want 0", height, result.Code, result.Log)
+		}
+		txID := string(result.Data)
+		if txID != generateTxID("SES-1", "shard-a", height, 0) {
+			t.Errorf("height %d: Data = %q, want the generated tx ID", height, txID)
+		}
+		txIDs = append(txIDs, txID)
+	}
+
+	if txIDs[0] == txIDs[1] {
+		t.Fatalf("both heights got tx ID %s", txIDs[0])
+	}
+	for _, txID := range txIDs {
+		if readKey(t, db, "tx:"+txID) == nil {
+			t.Errorf("tx:%s missing; the later commit overwrote the earlier one", txID)
+		}
+	}
+}
+
+func TestPrepareProposalIgnoresArrivalOrder(t *testing.T) {
+	app := &Application{config: &AppConfig{}, logger: cmtlog.NewNopLogger()}
+	now := time.Now()
+	var txs [][]byte
+	for i := 0; i < 8; i++ {
+		txs = append(txs, commitTx("shard-a", now, fmt.Sprintf("commit-%d", i)))
+	}
+
+	var want [][]byte
+	rng := rand.New(rand.NewSource(1))
+	for round := 0; round < 5; round++ {
+		shuffled := append([][]byte(nil), txs...)
+		rng.Shuffle(len(shuffled), func(i, j int) { shuffled[i], shuffled[j] = shuffled[j], shuffled[i] })
+
+		resp, err := app.PrepareProposal(context.Background(), &abcitypes.PrepareProposalRequest{Txs: shuffled, Time: now})
+		if err != nil {
+			t.Fatalf("round %d: PrepareProposal: %v", round, err)
+		}
+		if len(resp.Txs) != len(txs) {
+			t.Fatalf("round %d: %d txs proposed, want %d", round, len(resp.Txs), len(txs))
+		}
+		if want == nil {
+			want = resp.Txs
+			for i := 1; i < len(want); i++ {
+				prev, cur := sha256.Sum256(want[i-1]), sha256.Sum256(want[i])
+				if bytes.Compare(prev[:], cur[:]) > 0 {
+					t.Fatalf("tx %d is out of hash order", i)
+				}
+			}
+			continue
+		}
+		for i := range want {
+			if !bytes.Equal(resp.Txs[i], want[i]) {
+				t.Fatalf("round %d: tx %d differs from the first proposal", round, i)
+			}
+		}
+	}
+}
+
+func TestProcessProposalTimestampSkew(t *testing.T) {
+	blockTime := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		skew   time.Duration
+		offset time.Duration
+		accept bool
+	}{
+		{"in window", 5 * time.Minute, -time.Minute, true},
+		{"at the window edge", 5 * time.Minute, 5 * time.Minute, true},
+		{"future skew", 5 * time.Minute, 5*time.Minute + time.Second, false},
+		{"past skew", 5 * time.Minute, -time.Hour, false},
+		{"check disabled", 0, -time.Hour, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := &Application{config: &AppConfig{TimestampSkew: tt.skew}, logger: cmtlog.NewNopLogger()}
+			resp, err := app.ProcessProposal(context.Background(), &abcitypes.ProcessProposalRequest{
+				Time: blockTime,
+				Txs:  [][]byte{commitTx("shard-a", blockTime.Add(tt.offset), "ok")},
+			})
+			if err != nil {
+				t.Fatalf("ProcessProposal: %v", err)
+			}
+			if got := resp.Status == abcitypes.PROCESS_PROPOSAL_STATUS_ACCEPT; got != tt.accept {
+				t.Errorf("accepted = %v (%s), want %v", got, resp.Status, tt.accept)
+			}
+		})
+	}
+}
+
+func TestProcessProposalRejectsBlockWithOneSkewedCommit(t *testing.T) {
+	blockTime := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	app := &Application{config: &AppConfig{TimestampSkew: DefaultTimestampSkew}, logger: cmtlog.NewNopLogger()}
+
+	resp, err := app.ProcessProposal(context.Background(), &abcitypes.ProcessProposalRequest{
+		Time: blockTime,
+		Txs: [][]byte{
+			commitTx("shard-a", blockTime, "ok"),
+			commitTx("shard-b", blockTime.Add(24*time.Hour), "replayed"),
+		},
+	})
+	if err != nil {
+		t.Fatalf("ProcessProposal: %v", err)
+	}
+	if resp.Status != abcitypes.PROCESS_PROPOSAL_STATUS_REJECT {
+		t.Errorf("status = %s, want REJECT", resp.Status)
+	}
+}
@@ -0,0 +1,95 @@
+package app
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ahmadzakiakmal/thesis-extension/layer-1/repository"
+)
+
+// CheckTx strictness modes. Each mode adds to the checks of the one before it.
+const (
+	// CheckTxLenient only checks that a commit parses and has its required fields
+	CheckTxLenient = "lenient"
+	// CheckTxStrict also rejects commits from shards that are not registered
+	CheckTxStrict = "strict"
+	// CheckTxParanoid also enforces the timestamp skew and session_data size limits
+	CheckTxParanoid = "paranoid"
+)
+
+// DefaultCheckTxMode runs every check, so the timestamp skew and session_data
+// size limits apply unless an operator opts out of them
+const DefaultCheckTxMode = CheckTxParanoid
+
+// ValidateCheckTxMode reports whether mode is a known CheckTx strictness mode
+func ValidateCheckTxMode(mode string) error {
+	switch mode {
+	case CheckTxLenient, CheckTxStrict, CheckTxParanoid:
+		return nil
+	}
+	return fmt.Errorf("unknown CheckTx mode %q (want %q, %q or %q)", mode, CheckTxLenient, CheckTxStrict, CheckTxParanoid)
+}
+
+// Shard set refresh intervals. A shard missing from the set triggers an early
+// reload, so newly registered shards are accepted without waiting a full TTL.
+const (
+	shardSetTTL         = 30 * time.Second
+	shardSetMissRefresh = time.Second
+)
+
+// shardSet caches the IDs of the active shards registered in the database so
+// CheckTx doesn't query PostgreSQL for every transaction
+type shardSet struct {
+	repository *repository.Repository
+
+	mu       sync.Mutex
+	shards   map[string]struct{}
+	loadedAt time.Time
+}
+
+func newShardSet(repository *repository.Repository) *shardSet {
+	return &shardSet{repository: repository}
+}
+
+// contains reports whether shardID is a registered shard, reloading the set
+// when it is stale
+func (s *shardSet) contains(shardID string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	age := time.Since(s.loadedAt)
+	if s.shards == nil || age > shardSetTTL {
+		if err := s.reload(); err != nil {
+			return false, err
+		}
+		age = 0
+	}
+	if _, ok := s.shards[shardID]; ok {
+		return true, nil
+	}
+	if age < shardSetMissRefresh {
+		return false, nil
+	}
+
+	if err := s.reload(); err != nil {
+		return false, err
+	}
+	_, ok := s.shards[shardID]
+	return ok, nil
+}
+
+// reload replaces the set with the shards currently in the database
+func (s *shardSet) reload() error {
+	shards, repoErr := s.repository.GetAllShards()
+	if repoErr != nil {
+		return fmt.Errorf("loading shards: %s", repoErr.Detail)
+	}
+
+	s.shards = make(map[string]struct{}, len(shards))
+	for _, shard := range shards {
+		s.shards[shard.ShardID] = struct{}{}
+	}
+	s.loadedAt = time.Now()
+	return nil
+}
@@ -0,0 +1,62 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	abcitypes "github.com/cometbft/cometbft/abci/types"
+)
+
+// checkTxApp returns an application in mode whose shard set holds only shard-a
+func checkTxApp(mode string) *Application {
+	return &Application{
+		config: &AppConfig{
+			CheckTxMode:         mode,
+			TimestampSkew:       5 * time.Minute,
+			MaxSessionDataBytes: 64,
+		},
+		shards: &shardSet{
+			shards:   map[string]struct{}{"shard-a": {}},
+			loadedAt: time.Now(),
+		},
+	}
+}
+
+// commitTx builds a shard commit transaction
+func commitTx(shardID string, timestamp time.Time, note string) []byte {
+	return []byte(fmt.Sprintf(
+		`{"shard_id":%q,"client_group":"group-a","session_id":"SES-1","timestamp":%q,"session_data":{"note":%q}}`,
+		shardID, timestamp.Format(time.RFC3339Nano), note))
+}
+
+func TestCheckTxModes(t *testing.T) {
+	now := time.Now()
+	txs := map[string][]byte{
+		"valid":         commitTx("shard-a", now, "ok"),
+		"unknown shard": commitTx("shard-x", now, "ok"),
+		"skewed":        commitTx("shard-a", now.Add(-time.Hour), "ok"),
+		"oversized":     commitTx("shard-a", now, strings.Repeat("x", 100)),
+	}
+	accepted := map[string]map[string]bool{
+		CheckTxLenient:  {"valid": true, "unknown shard": true, "skewed": true, "oversized": true},
+		CheckTxStrict:   {"valid": true, "unknown shard": false, "skewed": true, "oversized": true},
+		CheckTxParanoid: {"valid": true, "unknown shard": false, "skewed": false, "oversized": false},
+		"":              {"valid": true, "unknown shard": false, "skewed": false, "oversized": false},
+	}
+
+	for mode, want := range accepted {
+		app := checkTxApp(mode)
+		for name, tx := range txs {
+			resp, err := app.CheckTx(context.Background(), &abcitypes.CheckTxRequest{Tx: tx})
+			if err != nil {
+				t.Fatalf("mode %q, %s: %v", mode, name, err)
+			}
+			if got := resp.Code == 0; got != want[name] {
+				t.Errorf("mode %q, %s: accepted = %v (%s), want %v", mode, name, got, resp.Log, want[name])
+			}
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/ahmadzakiakmal/thesis-extension/layer-1/repository"
+	"github.com/dgraph-io/badger/v4"
+)
+
+// Commits may carry a per-shard sequence number. Sequenced commits of a shard
+// must be committed as 1, 2, 3, ... with no gaps or repeats; commits without
+// a sequence (0) are not ordered. The last committed sequence of each shard is
+// kept under seq:<shard>.
+
+// sequenceKey is the Badger key holding a shard's last committed sequence
+func sequenceKey(shardID string) []byte {
+	return []byte("seq:" + shardID)
+}
+
+// lastSequence returns the last committed sequence of a shard, 0 if none
+func (app *Application) lastSequence(shardID string) (int64, error) {
+	var last int64
+	err := app.badgerDB.View(func(txn *badger.Txn) error {
+		item, err := txn.Get(sequenceKey(shardID))
+		if err == badger.ErrKeyNotFound {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		return item.Value(func(val []byte) error {
+			last = bytesToInt64(val)
+			return nil
+		})
+	})
+	return last, err
+}
+
+// sequenceTracker checks the sequences in a block against what each shard
+// has committed so far
+type sequenceTracker struct {
+	app  *Application
+	last map[string]int64
+}
+
+func (app *Application) newSequenceTracker() *sequenceTracker {
+	return &sequenceTracker{app: app, last: make(map[string]int64)}
+}
+
+// next returns the sequence the shard's next sequenced commit must carry
+func (t *sequenceTracker) next(shardID string) (int64, error) {
+	last, ok := t.last[shardID]
+	if !ok {
+		var err error
+		if last, err = t.app.lastSequence(shardID); err != nil {
+			return 0, err
+		}
+		t.last[shardID] = last
+	}
+	return last + 1, nil
+}
+
+// accept checks that commit is in order and records it. Unsequenced commits
+// are always accepted.
+func (t *sequenceTracker) accept(commit *repository.ShardedCommitRequest) error {
+	if commit.Sequence == 0 {
+		return nil
+	}
+	next, err := t.next(commit.ShardID)
+	if err != nil {
+		return fmt.Errorf("reading last sequence of shard %s: %w", commit.ShardID, err)
+	}
+	switch {
+	case commit.Sequence < next:
+		return fmt.Errorf("sequence %d of shard %s regresses, expected %d", commit.Sequence, commit.ShardID, next)
+	case commit.Sequence > next:
+		return fmt.Errorf("sequence %d of shard %s leaves a gap, expected %d", commit.Sequence, commit.ShardID, next)
+	}
+	t.last[commit.ShardID] = commit.Sequence
+	return nil
+}
+
+// orderSequenced keeps the sequenced commits of each shard that continue its
+// committed sequence and puts them in sequence order, reusing the positions
+// they held in txs so the hash order of everything else is untouched.
+// Commits that would leave a gap stay in the mempool for a later block;
+// regressions are dropped from the proposal and refused by CheckTx.
+func (app *Application) orderSequenced(txs [][]byte) [][]byte {
+	type sequenced struct {
+		position int
+		sequence int64
+	}
+	byShard := make(map[string][]sequenced)
+	shardOrder := []string{}
+	for i, tx := range txs {
+		var commit repository.ShardedCommitRequest
+		if err := json.Unmarshal(tx, &commit); err != nil || commit.Sequence == 0 {
+			continue
+		}
+		if _, ok := byShard[commit.ShardID]; !ok {
+			shardOrder = append(shardOrder, commit.ShardID)
+		}
+		byShard[commit.ShardID] = append(byShard[commit.ShardID], sequenced{position: i, sequence: commit.Sequence})
+	}
+	if len(byShard) == 0 {
+		return txs
+	}
+
+	ordered := make([][]byte, len(txs))
+	copy(ordered, txs)
+	dropped := make(map[int]bool)
+	for _, shardID := range shardOrder {
+		commits := byShard[shardID]
+		last, err := app.lastSequence(shardID)
+		if err != nil {
+			app.logger.Error("Failed to read last sequence, leaving shard's sequenced commits out", "shard_id", shardID, "err", err)
+			for _, c := range commits {
+				dropped[c.position] = true
+			}
+			continue
+		}
+
+		positions := make([]int, len(commits))
+		for i, c := range commits {
+			positions[i] = c.position
+		}
+		sort.Ints(positions)
+		sort.Slice(commits, func(i, j int) bool { return commits[i].sequence < commits[j].sequence })
+
+		kept := 0
+		for _, c := range commits {
+			if c.sequence == last+1 {
+				ordered[positions[kept]] = txs[c.position]
+				last = c.sequence
+				kept++
+				continue
+			}
+			app.logger.Info("Leaving out-of-order commit for a later block", "shard_id", shardID, "sequence", c.sequence, "expected", last+1)
+		}
+		for _, position := range positions[kept:] {
+			dropped[position] = true
+		}
+	}
+
+	result := ordered[:0:0]
+	for i, tx := range ordered {
+		if !dropped[i] {
+			result = append(result, tx)
+		}
+	}
+	return result
+}
+
+// storeSequence records the last sequence committed by a shard
+func (app *Application) storeSequence(commit *repository.ShardedCommitRequest) {
+	if commit.Sequence == 0 {
+		return
+	}
+	app.onGoingBlock.set(badger.NewEntry(sequenceKey(commit.ShardID), int64ToBytes(commit.Sequence)))
+}
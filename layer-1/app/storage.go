@@ -0,0 +1,125 @@
+package app
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/dgraph-io/badger/v4"
+)
+
+// gzipMagic starts every gzip stream. Commit JSON never does, so values
+// written before compression was introduced are still read as-is.
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// compressTx gzips a raw commit for storage under its tx: key
+func compressTx(rawTx []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	if _, err := writer.Write(rawTx); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// decompressTx returns the raw commit stored under a tx: key, accepting both
+// compressed and legacy uncompressed values
+func decompressTx(stored []byte) ([]byte, error) {
+	if !bytes.HasPrefix(stored, gzipMagic) {
+		return stored, nil
+	}
+	reader, err := gzip.NewReader(bytes.NewReader(stored))
+	if err != nil {
+		return nil, fmt.Errorf("decompressing transaction: %w", err)
+	}
+	defer reader.Close()
+
+	rawTx, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("decompressing transaction: %w", err)
+	}
+	return rawTx, nil
+}
+
+// readTx loads and decompresses the commit stored for txID
+func readTx(txn *badger.Txn, txID []byte) ([]byte, error) {
+	item, err := txn.Get(append([]byte("tx:"), txID...))
+	if err != nil {
+		return nil, err
+	}
+	stored, err := item.ValueCopy(nil)
+	if err != nil {
+		return nil, err
+	}
+	return decompressTx(stored)
+}
+
+// resolveShardValue returns the commit behind a shard:<shard>:session:<id>
+// value. New values hold the tx ID of the commit; legacy values hold the
+// commit JSON itself.
+func resolveShardValue(txn *badger.Txn, value []byte) ([]byte, error) {
+	if len(value) > 0 && value[0] == '{' {
+		return value, nil
+	}
+	return readTx(txn, value)
+}
+
+// blockTxn holds the writes of the block being finalized in memory until
+// ABCI Commit, so nothing a block writes reaches disk before the block is
+// committed and finalizing a block never depends on local storage. A block
+// too large for one Badger transaction is committed in several; its writes
+// are applied in order and last_block_height is written last, so a crash in
+// between leaves the previous height in place and CometBFT replays the block,
+// repeating the same writes.
+type blockTxn struct {
+	db      *badger.DB
+	entries []*badger.Entry
+
+	// setEntry writes one entry into txn; tests replace it to inject
+	// storage failures
+	setEntry func(txn *badger.Txn, entry *badger.Entry) error
+}
+
+func newBlockTxn(db *badger.DB) *blockTxn {
+	return &blockTxn{
+		db:       db,
+		setEntry: (*badger.Txn).SetEntry,
+	}
+}
+
+// set adds entry to the block's writes
+func (b *blockTxn) set(entry *badger.Entry) {
+	b.entries = append(b.entries, entry)
+}
+
+// commit writes the block to Badger, starting a new transaction whenever
+// one is full. An error leaves the block partly written and must halt the
+// node rather than let it carry on from a state the other validators don't
+// share.
+func (b *blockTxn) commit() error {
+	txn := b.db.NewTransaction(true)
+	defer func() { txn.Discard() }()
+
+	for _, entry := range b.entries {
+		err := b.setEntry(txn, entry)
+		if errors.Is(err, badger.ErrTxnTooBig) {
+			if err := txn.Commit(); err != nil {
+				return fmt.Errorf("committing block writes: %w", err)
+			}
+			txn = b.db.NewTransaction(true)
+			err = b.setEntry(txn, entry)
+		}
+		if err != nil {
+			return fmt.Errorf("writing %s: %w", entry.Key, err)
+		}
+	}
+	if err := txn.Commit(); err != nil {
+		return fmt.Errorf("committing block writes: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/ahmadzakiakmal/thesis-extension/layer-1/repository"
+	abcitypes "github.com/cometbft/cometbft/abci/types"
+	cmtlog "github.com/cometbft/cometbft/libs/log"
+	"github.com/dgraph-io/badger/v4"
+)
+
+// openTestDB opens an in-memory Badger closed with the test
+func openTestDB(t *testing.T, memTableSize int64) *badger.DB {
+	t.Helper()
+	opts := badger.DefaultOptions("").WithInMemory(true).WithLogger(nil)
+	if memTableSize > 0 {
+		opts = opts.WithMemTableSize(memTableSize).WithValueThreshold(memTableSize / 16)
+	}
+	db, err := badger.Open(opts)
+	if err != nil {
+		t.Fatalf("opening badger: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// failKeys makes committing block writes of keys with prefix fail with err
+func failKeys(block *blockTxn, prefix string, err error) {
+	block.setEntry = func(txn *badger.Txn, entry *badger.Entry) error {
+		if bytes.HasPrefix(entry.Key, []byte(prefix)) {
+			return err
+		}
+		return txn.SetEntry(entry)
+	}
+}
+
+// readKey returns the committed value of key, or nil if it doesn't exist
+func readKey(t *testing.T, db *badger.DB, key string) []byte {
+	t.Helper()
+	var value []byte
+	err := db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get([]byte(key))
+		if errors.Is(err, badger.ErrKeyNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		value, err = item.ValueCopy(nil)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("reading %s: %v", key, err)
+	}
+	return value
+}
+
+func TestStoreShardCommitWritesNothingUntilCommit(t *testing.T) {
+	db := openTestDB(t, 0)
+	app := &Application{badgerDB: db, onGoingBlock: newBlockTxn(db)}
+
+	commit := &repository.ShardedCommitRequest{SessionID: "SES-1", ShardID: "shard-a"}
+	if result := app.storeShardCommit("TX-1", commit, "accepted", []byte(`{"session_id":"SES-1"}`)); result.Code != 0 {
+		t.Fatalf("code = %d (%s), want 0", result.Code, result.Log)
+	}
+	keys := []string{"tx:TX-1", "shard:shard-a:session:SES-1", "status:TX-1"}
+	for _, key := range keys {
+		if value := readKey(t, db, key); value != nil {
+			t.Errorf("%s = %q before the block was committed", key, value)
+		}
+	}
+
+	if _, err := app.Commit(context.Background(), &abcitypes.CommitRequest{}); err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+	for _, key := range keys {
+		if readKey(t, db, key) == nil {
+			t.Errorf("%s missing after the block was committed", key)
+		}
+	}
+}
+
+func TestCommitHaltsOnStorageFailure(t *testing.T) {
+	db := openTestDB(t, 0)
+	app := &Application{badgerDB: db, config: &AppConfig{}, logger: cmtlog.NewNopLogger()}
+
+	_, err := app.FinalizeBlock(context.Background(), &abcitypes.FinalizeBlockRequest{
+		Height: 5,
+		Txs:    [][]byte{[]byte(`{"shard_id":"shard-a","session_id":"SES-1"}`)},
+	})
+	if err != nil {
+		t.Fatalf("FinalizeBlock: %v", err)
+	}
+	failKeys(app.onGoingBlock, "status:", errors.New("injected status failure"))
+
+	if _, err := app.Commit(context.Background(), &abcitypes.CommitRequest{}); err == nil {
+		t.Fatal("Commit succeeded despite the failed write, want an error halting the node")
+	}
+	if value := readKey(t, db, "last_block_height"); value != nil {
+		t.Errorf("last_block_height = %d after the failed commit, want it unset so the block is replayed", bytesToInt64(value))
+	}
+}
+
+func TestBlockTxnSplitsLargeBlocksAtCommit(t *testing.T) {
+	// A 1 MiB memtable caps a transaction at roughly 150 KiB
+	db := openTestDB(t, 1<<20)
+	block := newBlockTxn(db)
+
+	value := bytes.Repeat([]byte("x"), 1024)
+	const entries = 1000
+	for i := 0; i < entries; i++ {
+		block.set(badger.NewEntry([]byte(fmt.Sprintf("tx:%04d", i)), value))
+	}
+	if got := readKey(t, db, "tx:0000"); got != nil {
+		t.Fatal("block writes reached disk before commit")
+	}
+	if err := block.commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, i := range []int{0, entries / 2, entries - 1} {
+		if got := readKey(t, db, fmt.Sprintf("tx:%04d", i)); !bytes.Equal(got, value) {
+			t.Errorf("entry %d not stored", i)
+		}
+	}
+}
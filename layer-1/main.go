@@ -8,6 +8,9 @@ import (
 	"os"
 	"os/signal"
 	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
@@ -15,6 +18,7 @@ import (
 	"github.com/ahmadzakiakmal/thesis-extension/layer-1/repository"
 	"github.com/ahmadzakiakmal/thesis-extension/layer-1/server"
 	"github.com/ahmadzakiakmal/thesis-extension/layer-1/srvreg"
+	"github.com/ahmadzakiakmal/thesis-extension/layer-1/tracing"
 
 	cfg "github.com/cometbft/cometbft/config"
 	cmtflags "github.com/cometbft/cometbft/libs/cli/flags"
@@ -31,13 +35,122 @@ import (
 var (
 	homeDir      string
 	httpPort     string
+	basePath     string
+	bindAddress  string
 	postgresHost string
+	commitRate   float64
+	commitBurst  int
+	maxBodyBytes int64
+	rpcTimeout   time.Duration
+	rpcAddress   string
+
+	readHeaderTimeout time.Duration
+	readTimeout       time.Duration
+	writeTimeout      time.Duration
+	idleTimeout       time.Duration
+	maxInflight       int
+
+	reconcileOnStart bool
+	reconcileDepth   int64
+
+	orphanAction string
+	orphanAge    time.Duration
+
+	seedData              bool
+	seedFile              string
+	seedShards            int
+	seedOperatorsPerShard int
+
+	broadcastMode         string
+	broadcastPollInterval time.Duration
+	broadcastPollTimeout  time.Duration
+
+	consensusWorkers int
+
+	enableMempoolFlush bool
+
+	exportDir      string
+	exportInterval time.Duration
+	exportPrune    bool
+
+	timestampSkew time.Duration
+
+	maxSessionDataBytes int
+	sessionSchemaFile   string
+
+	checkTxMode string
+
+	dedupeTTL time.Duration
+
+	heartbeatTimeout time.Duration
+
+	otlpEndpoint string
+
+	dbMaxOpenConns    int
+	dbMaxIdleConns    int
+	dbConnMaxLifetime time.Duration
+	readReplicaDSN    string
+
+	enforceOperatorShard bool
+
+	shardAllowlist     string
+	shardAllowlistFile string
+
+	badgerSyncWrites       bool
+	badgerValueLogFileSize int64
+	badgerNumVersions      int
 )
 
 func init() {
 	flag.StringVar(&homeDir, "cmt-home", "./node-config/l1-node", "Path to the CometBFT config directory")
 	flag.StringVar(&httpPort, "http-port", "5000", "HTTP web server port")
+	flag.StringVar(&basePath, "base-path", os.Getenv("L1_BASE_PATH"), "Serve every HTTP route under this path prefix, e.g. /tenant-x")
+	flag.StringVar(&bindAddress, "bind-address", server.DefaultBindAddress, "Interface the HTTP web server listens on")
 	flag.StringVar(&postgresHost, "postgres-host", "l1-postgres0:5432", "DB host address")
+	flag.Float64Var(&commitRate, "commit-rate", 0, "Allowed commits per second per client group (0 disables rate limiting)")
+	flag.IntVar(&commitBurst, "commit-burst", 10, "Maximum burst of commits per client group")
+	flag.StringVar(&rpcAddress, "rpc-address", os.Getenv("L1_RPC_ADDRESS"), "CometBFT RPC URL used by the web server (empty derives it from the RPC listen address)")
+	flag.DurationVar(&rpcTimeout, "rpc-timeout", server.DefaultRPCTimeout, "Timeout for CometBFT RPC calls made by the web server")
+	flag.DurationVar(&readHeaderTimeout, "http-read-header-timeout", server.DefaultReadHeaderTimeout, "Time allowed to read request headers")
+	flag.DurationVar(&readTimeout, "http-read-timeout", server.DefaultReadTimeout, "Time allowed to read a whole request")
+	flag.DurationVar(&writeTimeout, "http-write-timeout", server.DefaultWriteTimeout, "Time allowed to write a response, including waiting for consensus")
+	flag.DurationVar(&idleTimeout, "http-idle-timeout", server.DefaultIdleTimeout, "How long idle keep-alive connections stay open")
+	flag.IntVar(&maxInflight, "max-inflight-requests", envInt("MAX_INFLIGHT_REQUESTS", 0), "Requests served at once before answering 503 (0 is unlimited)")
+	flag.BoolVar(&reconcileOnStart, "reconcile-on-start", false, "Repair the PostgreSQL mirror from recent blocks at startup")
+	flag.Int64Var(&reconcileDepth, "reconcile-depth", repository.DefaultReconcileDepth, "Number of recent blocks checked by reconciliation")
+	flag.StringVar(&orphanAction, "recover-orphans", os.Getenv("RECOVER_ORPHANS"), "At startup, retry or fail sessions left without a transaction by a crash: retry, fail or empty to skip")
+	flag.DurationVar(&orphanAge, "orphan-age", repository.DefaultOrphanAge, "How old a session without a transaction must be to count as orphaned")
+	flag.BoolVar(&seedData, "seed", os.Getenv("SEED_DATA") != "false", "Upsert demo shards and operators on startup (env SEED_DATA=false disables)")
+	flag.StringVar(&seedFile, "seed-file", os.Getenv("SEED_FILE"), "JSON file replacing the built-in seed data")
+	flag.IntVar(&seedShards, "seed-shards", envInt("SEED_SHARDS", 0), "Generate this many seed shards instead of the built-in 4 (0 keeps the built-in data)")
+	flag.IntVar(&seedOperatorsPerShard, "seed-operators-per-shard", envInt("SEED_OPERATORS_PER_SHARD", repository.DefaultOperatorsPerShard), "Operators generated per seed shard")
+	defaultBroadcast := repository.DefaultBroadcastConfig()
+	flag.StringVar(&broadcastMode, "broadcast-mode", defaultBroadcast.Mode, "How commits wait for consensus: commit (BroadcastTxCommit) or poll (BroadcastTxSync + Tx polling)")
+	flag.DurationVar(&broadcastPollInterval, "broadcast-poll-interval", defaultBroadcast.PollInterval, "Interval between Tx polls in poll mode")
+	flag.DurationVar(&broadcastPollTimeout, "broadcast-poll-timeout", defaultBroadcast.PollTimeout, "How long to poll for a committed tx in poll mode")
+	flag.IntVar(&consensusWorkers, "consensus-workers", runtime.NumCPU(), "Maximum concurrent consensus submissions; further commits queue")
+	flag.BoolVar(&enableMempoolFlush, "enable-mempool-flush", false, "Allow POST /l1/mempool/flush (development only)")
+	flag.StringVar(&exportDir, "export-dir", os.Getenv("EXPORT_DIR"), "Directory for periodic NDJSON transaction exports (empty disables)")
+	flag.DurationVar(&exportInterval, "export-interval", time.Hour, "How often transactions are exported to --export-dir")
+	flag.BoolVar(&exportPrune, "export-prune", false, "Delete transactions and sessions from PostgreSQL once exported")
+	flag.DurationVar(&timestampSkew, "timestamp-skew", app.DefaultTimestampSkew, "Allowed difference between a commit timestamp and block time (0 disables)")
+	flag.IntVar(&maxSessionDataBytes, "max-session-data-bytes", repository.DefaultMaxSessionDataBytes, "Maximum serialized session_data size per commit in bytes (0 disables)")
+	flag.StringVar(&sessionSchemaFile, "session-schema", os.Getenv("SESSION_SCHEMA"), "JSON schema file that commit session_data must match (empty disables)")
+	flag.StringVar(&checkTxMode, "checktx-mode", envString("CHECKTX_MODE", app.DefaultCheckTxMode), "How thoroughly CheckTx vets commits: lenient, strict (also rejects unknown shards) or paranoid (also checks timestamp skew and session_data size)")
+	flag.DurationVar(&dedupeTTL, "dedupe-ttl", srvreg.DefaultDedupeTTL, "How long identical write requests replay the first response (0 disables)")
+	flag.BoolVar(&badgerSyncWrites, "badger-sync-writes", os.Getenv("BADGER_SYNC_WRITES") == "true", "Fsync every Badger write for crash durability at the cost of throughput")
+	flag.Int64Var(&badgerValueLogFileSize, "badger-value-log-file-size", int64(envInt("BADGER_VALUE_LOG_FILE_SIZE", 0)), "Badger value log file size in bytes (0 keeps Badger's 1GB default)")
+	flag.IntVar(&badgerNumVersions, "badger-num-versions", envInt("BADGER_NUM_VERSIONS", 0), "Versions of each key Badger keeps (0 keeps Badger's default of 1)")
+	flag.DurationVar(&heartbeatTimeout, "shard-heartbeat-timeout", srvreg.DefaultHeartbeatTimeout, "How long a shard is reported healthy after its last heartbeat")
+	flag.StringVar(&otlpEndpoint, "otlp-endpoint", os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT"), "OTLP/HTTP collector URL traces are exported to, e.g. http://localhost:4318 (empty disables export)")
+	flag.IntVar(&dbMaxOpenConns, "db-max-open-conns", envInt("DB_MAX_OPEN_CONNS", 0), "Maximum open PostgreSQL connections (0 is unlimited)")
+	flag.IntVar(&dbMaxIdleConns, "db-max-idle-conns", envInt("DB_MAX_IDLE_CONNS", 0), "Maximum idle PostgreSQL connections kept for reuse (0 keeps the default of 2)")
+	flag.DurationVar(&dbConnMaxLifetime, "db-conn-max-lifetime", 0, "How long a PostgreSQL connection may be reused (0 is forever)")
+	flag.StringVar(&readReplicaDSN, "read-replica-dsn", os.Getenv("READ_REPLICA_DSN"), "PostgreSQL DSN of a read replica serving the session and transaction queries (empty uses the primary)")
+	flag.StringVar(&shardAllowlist, "shard-allowlist", os.Getenv("SHARD_ALLOWLIST"), "Comma-separated shard IDs allowed to commit (empty allows all)")
+	flag.StringVar(&shardAllowlistFile, "shard-allowlist-file", os.Getenv("SHARD_ALLOWLIST_FILE"), "File of shard IDs allowed to commit, one per line, added to --shard-allowlist")
+	flag.BoolVar(&enforceOperatorShard, "enforce-operator-shard", os.Getenv("ENFORCE_OPERATOR_SHARD") == "true", "Reject commits whose operator is unknown to L1 or registered to another shard")
+	flag.Int64Var(&maxBodyBytes, "max-body-bytes", server.DefaultMaxBodyBytes, "Maximum HTTP request body size in bytes")
 }
 
 func main() {
@@ -47,8 +160,16 @@ func main() {
 	log.Println("=== Starting Layer 1 - Byzantine Fault Tolerant Consensus Node ===")
 	log.Printf("Home Directory: %s", homeDir)
 	log.Printf("HTTP Port: %s", httpPort)
+	log.Printf("Bind Address: %s", bindAddress)
 	log.Printf("PostgreSQL Host: %s", postgresHost)
 
+	if orphanAction != "" && orphanAction != repository.OrphanRetry && orphanAction != repository.OrphanFail {
+		log.Fatalf("Invalid --recover-orphans %q: must be %s or %s", orphanAction, repository.OrphanRetry, repository.OrphanFail)
+	}
+	if err := app.ValidateCheckTxMode(checkTxMode); err != nil {
+		log.Fatalf("Invalid --checktx-mode: %v", err)
+	}
+
 	// Load CometBFT configuration
 	if homeDir == "" {
 		homeDir = os.ExpandEnv("$HOME/.cometbft")
@@ -68,13 +189,69 @@ func main() {
 
 	// Connect to PostgreSQL Database
 	dsn := fmt.Sprintf("postgresql://postgres:postgres@%s/l1db?sslmode=disable", postgresHost)
+	seedConfig := repository.SeedConfig{
+		Enabled:           seedData,
+		File:              seedFile,
+		Shards:            seedShards,
+		OperatorsPerShard: seedOperatorsPerShard,
+	}
+	broadcastConfig := repository.BroadcastConfig{
+		Mode:         broadcastMode,
+		PollInterval: broadcastPollInterval,
+		PollTimeout:  broadcastPollTimeout,
+	}
+	poolConfig := repository.PoolConfig{
+		MaxOpenConns:    dbMaxOpenConns,
+		MaxIdleConns:    dbMaxIdleConns,
+		ConnMaxLifetime: dbConnMaxLifetime,
+	}
+	allowedShards := repository.ParseShardAllowlist(shardAllowlist)
+	if shardAllowlistFile != "" {
+		fileShards, err := repository.LoadShardAllowlist(shardAllowlistFile)
+		if err != nil {
+			log.Fatalf("Loading shard allowlist: %v", err)
+		}
+		allowedShards = append(allowedShards, fileShards...)
+	}
 	repository := repository.NewRepository()
+	repository.SetSeedConfig(seedConfig)
+	repository.SetConsensusConcurrency(consensusWorkers)
+	if err := repository.SetBroadcastConfig(broadcastConfig); err != nil {
+		log.Fatalf("Invalid broadcast configuration: %v", err)
+	}
+	repository.SetPoolConfig(poolConfig)
+	repository.SetOperatorAttribution(enforceOperatorShard)
+	repository.SetShardAllowlist(allowedShards)
+	if len(allowedShards) > 0 {
+		log.Printf("Shard allowlist: %s", strings.Join(allowedShards, ", "))
+	}
 	log.Printf("Connecting to PostgreSQL: %s", dsn)
 	repository.ConnectDB(dsn)
+	if readReplicaDSN != "" {
+		if err := repository.ConnectReadReplica(readReplicaDSN); err != nil {
+			log.Printf("Read replica unavailable, queries use the primary: %v", err)
+		} else {
+			log.Println("Session and transaction queries use the read replica")
+		}
+	}
+
+	appConfig := &app.AppConfig{
+		NodeID:              filepath.Base(homeDir),
+		RequiredVotes:       1,
+		LogAllTxs:           true,
+		TimestampSkew:       timestampSkew,
+		MaxSessionDataBytes: maxSessionDataBytes,
+		CheckTxMode:         checkTxMode,
+
+		BadgerSyncWrites:        badgerSyncWrites,
+		BadgerValueLogFileSize:  badgerValueLogFileSize,
+		BadgerNumVersionsToKeep: badgerNumVersions,
+	}
 
 	// Initialize Badger DB for blockchain storage
 	badgerPath := filepath.Join(homeDir, "badger")
-	db, err := badger.Open(badger.DefaultOptions(badgerPath))
+	log.Printf("Badger: sync writes %t", badgerSyncWrites)
+	db, err := badger.Open(appConfig.BadgerOptions(badgerPath))
 	if err != nil {
 		log.Fatalf("Opening badger database: %v", err)
 	}
@@ -91,16 +268,35 @@ func main() {
 		log.Fatalf("Failed to parse log level: %v", err)
 	}
 
+	shutdownTracing, err := tracing.Setup(context.Background(), "l1-"+appConfig.NodeID, otlpEndpoint)
+	if err != nil {
+		log.Fatalf("Setting up tracing: %v", err)
+	}
+	if otlpEndpoint != "" {
+		logger.Info("Exporting traces", "endpoint", otlpEndpoint)
+	}
+
 	// Initialize Service Registry with L1-specific endpoints
 	serviceRegistry := srvreg.NewServiceRegistry(repository, logger)
 	serviceRegistry.RegisterDefaultServices()
+	serviceRegistry.SetCommitRateLimit(commitRate, commitBurst)
+	serviceRegistry.SetMempoolFlush(enableMempoolFlush)
+	serviceRegistry.SetMaxSessionDataBytes(maxSessionDataBytes)
+	if sessionSchemaFile != "" {
+		sessionSchema, err := srvreg.LoadSessionSchema(sessionSchemaFile)
+		if err != nil {
+			log.Fatalf("Loading session schema: %v", err)
+		}
+		serviceRegistry.SetSessionSchema(sessionSchema)
+		logger.Info("Validating session_data", "schema", sessionSchemaFile)
+	}
+	serviceRegistry.SetDedupeTTL(dedupeTTL)
+	serviceRegistry.SetHeartbeatTimeout(heartbeatTimeout)
+	if commitRate > 0 {
+		logger.Info("Commit rate limiting enabled", "rate", commitRate, "burst", commitBurst)
+	}
 
 	// Create ABCI Application
-	appConfig := &app.AppConfig{
-		NodeID:        filepath.Base(homeDir),
-		RequiredVotes: 1,
-		LogAllTxs:     true,
-	}
 	abciApp := app.NewABCIApplication(db, serviceRegistry, appConfig, logger, repository)
 
 	// Load private validator
@@ -108,6 +304,7 @@ func main() {
 		config.PrivValidatorKeyFile(),
 		config.PrivValidatorStateFile(),
 	)
+	serviceRegistry.SetReceiptKey(pv.Key.PrivKey)
 
 	// Load node key for P2P networking
 	nodeKey, err := p2p.LoadNodeKey(config.NodeKeyFile())
@@ -138,6 +335,7 @@ func main() {
 	// Create RPC client and set up repository
 	rpcClient := cmtrpc.New(node)
 	repository.SetupRpcClient(rpcClient)
+	repository.SetMempool(node.Mempool())
 
 	// Start CometBFT node
 	logger.Info("Starting CometBFT node...")
@@ -151,9 +349,47 @@ func main() {
 		node.Wait()
 	}()
 
+	// Restore PostgreSQL rows that consensus state has but the mirror lost,
+	// then settle sessions a crash left without a transaction
+	if reconcileOnStart || orphanAction != "" {
+		go func() {
+			if reconcileOnStart {
+				reconcileOnStartup(repository, logger)
+			}
+			if orphanAction != "" {
+				recoverOrphansOnStartup(repository, logger)
+			}
+		}()
+	}
+
+	// Periodically archive committed transactions
+	exportCtx, stopExport := context.WithCancel(context.Background())
+	defer stopExport()
+	if exportDir != "" {
+		if err := os.MkdirAll(exportDir, 0o755); err != nil {
+			log.Fatalf("Creating export directory: %v", err)
+		}
+		go runTransactionExport(exportCtx, repository, logger)
+	}
+
 	// Start Web Server
 	logger.Info("Starting L1 web server...")
-	webserver, err := server.NewWebServer(abciApp, httpPort, logger, node, serviceRegistry, repository)
+	serverConfig := &server.ServerConfig{
+		APIKeys:      parseAPIKeys(os.Getenv("L1_API_KEYS")),
+		MaxBodyBytes: maxBodyBytes,
+		BindAddress:  bindAddress,
+		RPCTimeout:   rpcTimeout,
+		RPCAddress:   rpcAddress,
+		BasePath:     basePath,
+
+		ReadHeaderTimeout: readHeaderTimeout,
+		ReadTimeout:       readTimeout,
+		WriteTimeout:      writeTimeout,
+		IdleTimeout:       idleTimeout,
+
+		MaxInflight: maxInflight,
+	}
+	webserver, err := server.NewWebServer(abciApp, httpPort, logger, node, serviceRegistry, repository, serverConfig)
 	if err != nil {
 		log.Fatalf("Creating web server: %v", err)
 	}
@@ -165,20 +401,40 @@ func main() {
 
 	// Display startup information
 	logger.Info("=== L1 Node Successfully Started ===")
-	logger.Info("Layer 1 HTTP API", "url", fmt.Sprintf("http://localhost:%s", httpPort))
-	logger.Info("CometBFT RPC", "url", fmt.Sprintf("http://localhost:%s", extractPortFromAddress(config.RPC.ListenAddress)))
+	logger.Info("Layer 1 HTTP API", "url", fmt.Sprintf("http://localhost:%s%s", httpPort, server.NormalizeBasePath(basePath)))
+	logger.Info("CometBFT RPC", "url", serverConfig.RPCAddress)
 	logger.Info("Node ID", "id", string(node.NodeInfo().ID()))
 	logger.Info("Architecture", "type", "Unified L1 for Sharded L2")
 
 	// Display available endpoints
 	logger.Info("Available L1 Endpoints:")
 	logger.Info("  POST /l1/commit - Receive commits from L2 shards")
+	logger.Info("  GET  /l1/sessions?group=&shard=&operator=&status=&from=&to=&limit=&offset= - Search sessions")
+	logger.Info("  GET  /l1/sessions/{id} - Get a single session")
 	logger.Info("  GET  /l1/sessions/group/{group} - Query sessions by client group")
-	logger.Info("  GET  /l1/sessions/shard/{shard} - Query sessions by shard")
+	logger.Info("  GET  /l1/sessions/group/{group}/count?status= - Count sessions by client group")
+	logger.Info("  GET  /l1/sessions/shard/{shard}?status=&limit=&offset= - Query sessions by shard")
 	logger.Info("  GET  /l1/transaction/{hash} - Get transaction details")
+	logger.Info("  POST /l1/verify-batch - Look up many transactions by hash")
+	logger.Info("  GET  /l1/transactions?since={height}&limit={n} - Incremental transaction listing")
+	logger.Info("  GET  /l1/verify/{txid} - Verify transaction in consensus state")
+	logger.Info("  GET  /l1/block/{height} - List the shard commits in a block")
+	logger.Info("  GET  /l1/audit/{session_id} - Prove a session's commit end-to-end")
 	logger.Info("  GET  /l1/status - Get L1 status")
+	logger.Info("  GET  /l1/mempool?limit={n} - Pending transactions in the mempool")
+	logger.Info("  POST /l1/mempool/flush - Drop pending transactions (dev only)")
+	logger.Info("  GET  /l1/stats - Consensus latency statistics")
 	logger.Info("  GET  /l1/shards - Get registered shards")
+	logger.Info("  DELETE /l1/shards/{shard}?purge=&confirm= - Deregister a shard or purge its data")
+	logger.Info("  POST /l1/shards/{shard}/heartbeat - Report that a shard's L2 node is alive")
+	logger.Info("  GET  /l1/shards/{shard}/sessions?status=&limit=&offset= - Query sessions by shard")
+	logger.Info("  GET  /l1/operators?shard=&limit=&offset= - List operators")
+	logger.Info("  POST /l1/operators - Register an operator")
+	logger.Info("  GET  /l1/operators/{id}/sessions?status=&limit=&offset= - Query sessions by operator")
+	logger.Info("  GET  /l1/events/ws - Stream commit events (WebSocket)")
+	logger.Info("  GET  /l1/reconcile - Compare recent blocks with the PostgreSQL mirror")
 	logger.Info("  GET  /debug - Debug information")
+	logger.Info("  GET  /openapi.json - OpenAPI 3 document")
 
 	// Wait for interrupt signal to gracefully shut down
 	c := make(chan os.Signal, 1)
@@ -186,6 +442,7 @@ func main() {
 	<-c
 
 	logger.Info("Received shutdown signal, shutting down gracefully...")
+	stopExport()
 
 	// Create deadline for shutdown
 	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
@@ -196,15 +453,110 @@ func main() {
 	if err != nil {
 		logger.Error("Error shutting down HTTP web server", "err", err)
 	}
+
+	// Let in-flight commits reach a block before the node is stopped
+	logger.Info("Draining in-flight commits...")
+	err = repository.Drain(ctx)
+	if err != nil {
+		logger.Error("Error draining in-flight commits", "err", err)
+	}
+
+	// Flush spans of the drained commits
+	if err := shutdownTracing(ctx); err != nil {
+		logger.Error("Error flushing traces", "err", err)
+	}
 	logger.Info("L1 Node gracefully stopped")
 }
 
-// extractPortFromAddress extracts the port from an address string
-func extractPortFromAddress(address string) string {
-	for i := len(address) - 1; i >= 0; i-- {
-		if address[i] == ':' {
-			return address[i+1:]
+// runTransactionExport exports new transactions to exportDir every
+// exportInterval until ctx is canceled
+func runTransactionExport(ctx context.Context, repo *repository.Repository, logger cmtlog.Logger) {
+	ticker := time.NewTicker(exportInterval)
+	defer ticker.Stop()
+
+	for {
+		result, repoErr := repo.ExportToDir(ctx, exportDir, exportPrune)
+		if repoErr != nil {
+			logger.Error("Transaction export failed", "err", repoErr.Detail)
+		} else if result.Exported > 0 {
+			logger.Info("Exported transactions",
+				"from_height", result.FromHeight, "to_height", result.ToHeight,
+				"exported", result.Exported, "pruned", result.Pruned, "file", result.File)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// reconcileOnStartup repairs the mirror from the last --reconcile-depth blocks
+func reconcileOnStartup(repo *repository.Repository, logger cmtlog.Logger) {
+	report, repoErr := repo.Reconcile(context.Background(), reconcileDepth, true)
+	if repoErr != nil {
+		logger.Error("Startup reconciliation failed", "err", repoErr.Detail)
+		return
+	}
+	repaired := 0
+	for _, discrepancy := range report.Discrepancies {
+		if discrepancy.Repaired {
+			repaired++
+		}
+	}
+	logger.Info("Startup reconciliation finished",
+		"from_height", report.FromHeight, "to_height", report.ToHeight,
+		"checked", report.Checked, "discrepancies", len(report.Discrepancies), "repaired", repaired)
+}
+
+// recoverOrphansOnStartup settles sessions older than --orphan-age that never
+// got a transaction, logging the outcome for each
+func recoverOrphansOnStartup(repo *repository.Repository, logger cmtlog.Logger) {
+	recovered, repoErr := repo.RecoverOrphanedSessions(context.Background(), orphanAge, orphanAction)
+	for _, orphan := range recovered {
+		if orphan.Error != "" {
+			logger.Error("Orphaned session recovery failed", "session_id", orphan.SessionID, "action", orphan.Action, "err", orphan.Error)
+			continue
+		}
+		logger.Info("Orphaned session recovered", "session_id", orphan.SessionID, "action", orphan.Action, "tx_hash", orphan.TxHash)
+	}
+	if repoErr != nil {
+		logger.Error("Orphan recovery failed", "err", repoErr.Detail)
+		return
+	}
+	logger.Info("Orphan recovery finished", "sessions", len(recovered))
+}
+
+// parseAPIKeys splits a comma-separated list of API keys, dropping empty entries
+func parseAPIKeys(raw string) []string {
+	keys := []string{}
+	for _, key := range strings.Split(raw, ",") {
+		key = strings.TrimSpace(key)
+		if key != "" {
+			keys = append(keys, key)
 		}
 	}
-	return ""
+	return keys
+}
+
+// envInt reads an integer environment variable, falling back to def when unset
+func envInt(key string, def int) int {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return def
+	}
+	value, err := strconv.Atoi(raw)
+	if err != nil || value < 0 {
+		log.Fatalf("Invalid %s %q: must be a non-negative integer", key, raw)
+	}
+	return value
+}
+
+// envString reads a string environment variable, falling back to def when unset
+func envString(key, def string) string {
+	if raw := os.Getenv(key); raw != "" {
+		return raw
+	}
+	return def
 }
@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// ParseShardAllowlist splits a comma-separated list of shard IDs, skipping
+// blanks
+func ParseShardAllowlist(raw string) []string {
+	var shards []string
+	for _, shard := range strings.Split(raw, ",") {
+		if shard = strings.TrimSpace(shard); shard != "" {
+			shards = append(shards, shard)
+		}
+	}
+	return shards
+}
+
+// LoadShardAllowlist reads shard IDs from a file, one per line. Blank lines
+// and lines starting with # are skipped.
+func LoadShardAllowlist(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading shard allowlist: %w", err)
+	}
+	defer file.Close()
+
+	var shards []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		shards = append(shards, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading shard allowlist %s: %w", path, err)
+	}
+	return shards, nil
+}
+
+// checkShardAllowed rejects shards missing from a non-empty allowlist
+func (r *Repository) checkShardAllowed(shardID string) *RepositoryError {
+	if len(r.shardAllowlist) == 0 {
+		return nil
+	}
+	if _, ok := r.shardAllowlist[shardID]; ok {
+		return nil
+	}
+	return &RepositoryError{
+		Code:    "SHARD_NOT_ALLOWED",
+		Message: "Shard not allowed",
+		Detail:  fmt.Sprintf("Shard %s is not on this deployment's shard allowlist", shardID),
+	}
+}
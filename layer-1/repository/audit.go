@@ -0,0 +1,180 @@
+package repository
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	abcitypes "github.com/cometbft/cometbft/abci/types"
+)
+
+// AuditReport is the end-to-end check of one committed session: the
+// transaction's Merkle inclusion proof against its block, the block's app hash
+// recomputed from the execution results, the Badger state and the PostgreSQL
+// mirror. Verified is true only when every check passes.
+type AuditReport struct {
+	SessionID       string   `json:"session_id"`
+	TxHash          string   `json:"tx_hash"`
+	TxID            string   `json:"tx_id,omitempty"`
+	BlockHeight     int64    `json:"block_height"`
+	DataHash        string   `json:"data_hash,omitempty"`
+	ProofRoot       string   `json:"proof_root,omitempty"`
+	TxIncluded      bool     `json:"tx_included"`
+	AppHash         string   `json:"app_hash,omitempty"`
+	ComputedAppHash string   `json:"computed_app_hash,omitempty"`
+	AppHashMatches  bool     `json:"app_hash_matches"`
+	InConsensus     bool     `json:"in_consensus_state"`
+	MirrorMatches   bool     `json:"mirror_matches"`
+	Verified        bool     `json:"verified"`
+	Failures        []string `json:"failures"`
+}
+
+// AppHash is the application hash committed for a block: the SHA-256 of the
+// concatenated Data of its transaction results
+func AppHash(txResults []*abcitypes.ExecTxResult) []byte {
+	allData := make([]byte, 0)
+	for _, result := range txResults {
+		allData = append(allData, result.Data...)
+	}
+	hash := sha256.Sum256(allData)
+	return hash[:]
+}
+
+// AuditSession proves a session's commit end-to-end. Lookup failures of the
+// session itself are errors; anything that does not check out on chain is
+// reported in Failures with Verified false.
+func (r *Repository) AuditSession(ctx context.Context, sessionID string) (*AuditReport, *RepositoryError) {
+	session, repoErr := r.GetSessionByID(sessionID)
+	if repoErr != nil {
+		return nil, repoErr
+	}
+	if session.Transaction == nil || session.Transaction.TxHash == "" {
+		return nil, &RepositoryError{
+			Code:    "SESSION_NOT_COMMITTED",
+			Message: "Session has no committed transaction",
+			Detail:  fmt.Sprintf("Session %s has not been committed to L1", sessionID),
+		}
+	}
+
+	report := &AuditReport{
+		SessionID:   sessionID,
+		TxHash:      session.Transaction.TxHash,
+		BlockHeight: session.Transaction.BlockHeight,
+		Failures:    []string{},
+	}
+	fail := func(format string, args ...interface{}) {
+		report.Failures = append(report.Failures, fmt.Sprintf(format, args...))
+	}
+
+	hash, err := hex.DecodeString(report.TxHash)
+	if err != nil {
+		fail("recorded tx hash is not valid hex: %v", err)
+		return report, nil
+	}
+
+	txResult, err := r.rpcClient.Tx(ctx, hash, true)
+	if err != nil {
+		fail("transaction not found on chain: %v", err)
+		return report, nil
+	}
+	if txResult.Height != report.BlockHeight {
+		fail("mirror records height %d but the transaction is in block %d", report.BlockHeight, txResult.Height)
+		report.BlockHeight = txResult.Height
+	}
+	if txResult.TxResult.Code != 0 {
+		fail("transaction was rejected with code %d", txResult.TxResult.Code)
+	}
+	report.TxID = string(txResult.TxResult.Data)
+
+	// Merkle inclusion of the transaction in the block's data hash
+	height := txResult.Height
+	block, err := r.rpcClient.Block(ctx, &height)
+	if err != nil {
+		return nil, &RepositoryError{
+			Code:    "QUERY_ERROR",
+			Message: fmt.Sprintf("Failed to load block %d", height),
+			Detail:  err.Error(),
+		}
+	}
+	report.DataHash = hex.EncodeToString(block.Block.DataHash)
+	report.ProofRoot = hex.EncodeToString(txResult.Proof.RootHash)
+	if err := txResult.Proof.Validate(block.Block.DataHash); err != nil {
+		fail("inclusion proof does not verify: %v", err)
+	} else {
+		report.TxIncluded = true
+	}
+
+	// The app hash for block H is committed in the header of block H+1
+	results, err := r.rpcClient.BlockResults(ctx, &height)
+	if err != nil {
+		return nil, &RepositoryError{
+			Code:    "QUERY_ERROR",
+			Message: fmt.Sprintf("Failed to load block results %d", height),
+			Detail:  err.Error(),
+		}
+	}
+	report.ComputedAppHash = hex.EncodeToString(AppHash(results.TxResults))
+
+	nextHeight := height + 1
+	header, err := r.rpcClient.Header(ctx, &nextHeight)
+	if err != nil {
+		fail("block %d with the committed app hash is not available yet: %v", nextHeight, err)
+	} else {
+		report.AppHash = hex.EncodeToString(header.Header.AppHash)
+		if report.AppHash == report.ComputedAppHash {
+			report.AppHashMatches = true
+		} else {
+			fail("app hash in block %d does not match the execution results of block %d", nextHeight, height)
+		}
+	}
+
+	// Badger holds the state written when the block was executed
+	if _, repoErr := r.VerifyTransaction(ctx, report.TxID); repoErr != nil {
+		fail("transaction %s not found in consensus state: %s", report.TxID, repoErr.Detail)
+	} else {
+		report.InConsensus = true
+	}
+
+	// The PostgreSQL mirror must agree with the transaction in the block
+	var commit ShardedCommitRequest
+	if err := json.Unmarshal(txResult.Tx, &commit); err != nil {
+		fail("transaction in block is not a shard commit: %v", err)
+	} else if matches, reason := mirrorMatchesCommit(session.SessionData, &commit, sessionID); matches {
+		report.MirrorMatches = true
+	} else {
+		fail("%s", reason)
+	}
+
+	report.Verified = len(report.Failures) == 0
+	return report, nil
+}
+
+// mirrorMatchesCommit compares the mirrored session data with the session
+// data carried in the block, ignoring key order
+func mirrorMatchesCommit(sessionData string, commit *ShardedCommitRequest, sessionID string) (bool, string) {
+	if commit.SessionID != sessionID {
+		return false, fmt.Sprintf("transaction in block belongs to session %s", commit.SessionID)
+	}
+
+	var mirrored interface{}
+	if err := json.Unmarshal([]byte(sessionData), &mirrored); err != nil {
+		return false, fmt.Sprintf("mirrored session data is not valid JSON: %v", err)
+	}
+
+	onChainBytes, err := json.Marshal(commit.SessionData)
+	if err != nil {
+		return false, fmt.Sprintf("failed to encode on-chain session data: %v", err)
+	}
+	var onChain interface{}
+	if err := json.Unmarshal(onChainBytes, &onChain); err != nil {
+		return false, fmt.Sprintf("failed to decode on-chain session data: %v", err)
+	}
+
+	if !reflect.DeepEqual(mirrored, onChain) {
+		return false, "mirrored session data differs from the transaction in the block"
+	}
+	return true, ""
+}
@@ -0,0 +1,131 @@
+package repository
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// BlockCommit is one shard commit in a block. Status is "accepted" or
+// "rejected"; shard fields are empty for transactions that are not commits.
+type BlockCommit struct {
+	Index       int    `json:"index"`
+	TxHash      string `json:"tx_hash"`
+	TxID        string `json:"tx_id,omitempty"`
+	SessionID   string `json:"session_id,omitempty"`
+	ShardID     string `json:"shard_id,omitempty"`
+	ClientGroup string `json:"client_group,omitempty"`
+	Status      string `json:"status"`
+	Code        uint32 `json:"code"`
+	Log         string `json:"log,omitempty"`
+}
+
+// BlockSummary lists the shard commits that landed in a block
+type BlockSummary struct {
+	Height   int64         `json:"height"`
+	Hash     string        `json:"hash"`
+	Time     time.Time     `json:"time"`
+	Proposer string        `json:"proposer"`
+	AppHash  string        `json:"app_hash"`
+	NumTxs   int           `json:"num_txs"`
+	Commits  []BlockCommit `json:"commits"`
+}
+
+// GetBlockSummary describes the commits in the block at height using the
+// block data and the events of its execution results
+func (r *Repository) GetBlockSummary(ctx context.Context, height int64) (*BlockSummary, *RepositoryError) {
+	status, err := r.rpcClient.Status(ctx)
+	if err != nil {
+		return nil, &RepositoryError{
+			Code:    "QUERY_ERROR",
+			Message: "Failed to query node status",
+			Detail:  err.Error(),
+		}
+	}
+	if height < status.SyncInfo.EarliestBlockHeight || height > status.SyncInfo.LatestBlockHeight {
+		return nil, &RepositoryError{
+			Code:    "BLOCK_NOT_FOUND",
+			Message: "Block not found",
+			Detail: fmt.Sprintf("Block %d is outside the stored range %d-%d",
+				height, status.SyncInfo.EarliestBlockHeight, status.SyncInfo.LatestBlockHeight),
+		}
+	}
+
+	block, err := r.rpcClient.Block(ctx, &height)
+	if err != nil {
+		return nil, &RepositoryError{
+			Code:    "QUERY_ERROR",
+			Message: fmt.Sprintf("Failed to load block %d", height),
+			Detail:  err.Error(),
+		}
+	}
+	results, err := r.rpcClient.BlockResults(ctx, &height)
+	if err != nil {
+		return nil, &RepositoryError{
+			Code:    "QUERY_ERROR",
+			Message: fmt.Sprintf("Failed to load block results %d", height),
+			Detail:  err.Error(),
+		}
+	}
+
+	summary := &BlockSummary{
+		Height:   height,
+		Hash:     hex.EncodeToString(block.BlockID.Hash),
+		Time:     block.Block.Time,
+		Proposer: block.Block.ProposerAddress.String(),
+		AppHash:  hex.EncodeToString(block.Block.AppHash),
+		NumTxs:   len(block.Block.Txs),
+		Commits:  make([]BlockCommit, 0, len(block.Block.Txs)),
+	}
+
+	for i, tx := range block.Block.Txs {
+		commit := BlockCommit{
+			Index:  i,
+			TxHash: hex.EncodeToString(tx.Hash()),
+			Status: "rejected",
+		}
+		if i < len(results.TxResults) {
+			result := results.TxResults[i]
+			commit.Code = result.Code
+			if result.Code == 0 {
+				commit.Status = "accepted"
+				commit.TxID = string(result.Data)
+			} else {
+				commit.Log = result.Log
+			}
+			for _, event := range result.Events {
+				if event.Type != "l1_shard_commit" {
+					continue
+				}
+				for _, attr := range event.Attributes {
+					switch attr.Key {
+					case "session_id":
+						commit.SessionID = attr.Value
+					case "shard_id":
+						commit.ShardID = attr.Value
+					case "client_group":
+						commit.ClientGroup = attr.Value
+					case "status":
+						commit.Status = attr.Value
+					}
+				}
+			}
+		}
+
+		// Rejected commits emit no events; fall back to the transaction body
+		if commit.SessionID == "" {
+			var shardCommit ShardedCommitRequest
+			if err := json.Unmarshal(tx, &shardCommit); err == nil {
+				commit.SessionID = shardCommit.SessionID
+				commit.ShardID = shardCommit.ShardID
+				commit.ClientGroup = shardCommit.ClientGroup
+			}
+		}
+
+		summary.Commits = append(summary.Commits, commit)
+	}
+
+	return summary, nil
+}
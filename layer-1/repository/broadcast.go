@@ -0,0 +1,128 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	coretypes "github.com/cometbft/cometbft/rpc/core/types"
+	cmttypes "github.com/cometbft/cometbft/types"
+)
+
+// Broadcast modes for consensus transactions
+const (
+	// BroadcastModeCommit blocks in BroadcastTxCommit until the tx is in a block
+	BroadcastModeCommit = "commit"
+	// BroadcastModePoll submits with BroadcastTxSync and polls Tx(hash) until
+	// the tx is committed, so the wait isn't bound by the RPC commit timeout
+	BroadcastModePoll = "poll"
+)
+
+// BroadcastConfig selects how RunConsensus submits transactions
+type BroadcastConfig struct {
+	Mode         string
+	PollInterval time.Duration
+	PollTimeout  time.Duration
+}
+
+// DefaultBroadcastConfig keeps the original BroadcastTxCommit behavior
+func DefaultBroadcastConfig() BroadcastConfig {
+	return BroadcastConfig{
+		Mode:         BroadcastModeCommit,
+		PollInterval: 250 * time.Millisecond,
+		PollTimeout:  60 * time.Second,
+	}
+}
+
+// SetBroadcastConfig configures how consensus transactions are submitted
+func (r *Repository) SetBroadcastConfig(config BroadcastConfig) error {
+	switch config.Mode {
+	case BroadcastModeCommit:
+	case BroadcastModePoll:
+		if config.PollInterval <= 0 || config.PollTimeout <= 0 {
+			return fmt.Errorf("poll interval and timeout must be positive")
+		}
+	default:
+		return fmt.Errorf("unknown broadcast mode %q (want %q or %q)", config.Mode, BroadcastModeCommit, BroadcastModePoll)
+	}
+
+	r.broadcastConfig = config
+	return nil
+}
+
+// txClient is the part of the RPC client used to submit transactions,
+// count the votes behind them and find them again during recovery
+type txClient interface {
+	BroadcastTxCommit(ctx context.Context, tx cmttypes.Tx) (*coretypes.ResultBroadcastTxCommit, error)
+	BroadcastTxSync(ctx context.Context, tx cmttypes.Tx) (*coretypes.ResultBroadcastTx, error)
+	Tx(ctx context.Context, hash []byte, prove bool) (*coretypes.ResultTx, error)
+	Commit(ctx context.Context, height *int64) (*coretypes.ResultCommit, error)
+	TxSearch(ctx context.Context, query string, prove bool, page, perPage *int, orderBy string) (*coretypes.ResultTxSearch, error)
+	Header(ctx context.Context, height *int64) (*coretypes.ResultHeader, error)
+}
+
+// broadcastResult is the outcome of a broadcast in either mode
+type broadcastResult struct {
+	Hash        []byte
+	Height      int64
+	CheckTxCode uint32
+	TxCode      uint32 // FinalizeBlock result code, 0 when the tx was stored
+	TxLog       string
+	TxData      []byte // FinalizeBlock result data, the commit's tx ID
+}
+
+// broadcast submits tx using the configured mode and waits for it to commit
+func (r *Repository) broadcast(ctx context.Context, tx cmttypes.Tx) (*broadcastResult, error) {
+	if r.broadcastConfig.Mode != BroadcastModePoll {
+		result, err := r.txClient.BroadcastTxCommit(ctx, tx)
+		if err != nil {
+			return nil, err
+		}
+		return &broadcastResult{
+			Hash:        result.Hash,
+			Height:      result.Height,
+			CheckTxCode: result.CheckTx.Code,
+			TxCode:      result.TxResult.Code,
+			TxLog:       result.TxResult.Log,
+			TxData:      result.TxResult.Data,
+		}, nil
+	}
+
+	result, err := r.txClient.BroadcastTxSync(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+	if result.Code != 0 {
+		return &broadcastResult{Hash: result.Hash, CheckTxCode: result.Code}, nil
+	}
+
+	pollCtx, cancel := context.WithTimeout(ctx, r.broadcastConfig.PollTimeout)
+	defer cancel()
+
+	ticker := time.NewTicker(r.broadcastConfig.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		// Tx errors until the tx is indexed, so errors just mean "not yet"
+		committed, err := r.txClient.Tx(pollCtx, result.Hash, false)
+		if err == nil {
+			return &broadcastResult{
+				Hash:        result.Hash,
+				Height:      committed.Height,
+				CheckTxCode: result.Code,
+				TxCode:      committed.TxResult.Code,
+				TxLog:       committed.TxResult.Log,
+				TxData:      committed.TxResult.Data,
+			}, nil
+		}
+
+		select {
+		case <-pollCtx.Done():
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			return nil, fmt.Errorf("tx %X not committed within %s: %w", []byte(result.Hash), r.broadcastConfig.PollTimeout, err)
+		case <-ticker.C:
+		}
+	}
+}
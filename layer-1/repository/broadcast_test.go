@@ -0,0 +1,168 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	abcitypes "github.com/cometbft/cometbft/abci/types"
+	coretypes "github.com/cometbft/cometbft/rpc/core/types"
+	cmttypes "github.com/cometbft/cometbft/types"
+)
+
+// fakeBroadcaster accepts every tx into the mempool and reports it committed
+// once Tx has been polled commitOnPoll times. In commit mode the tx is
+// committed once commitGate is closed; without a gate commit mode fails.
+// TxSearch records its queries and answers with found.
+type fakeBroadcaster struct {
+	commitOnPoll int
+	polls        int
+	commitGate   chan struct{}
+	found        []*coretypes.ResultTx
+	queries      []string
+}
+
+func (f *fakeBroadcaster) BroadcastTxCommit(_ context.Context, tx cmttypes.Tx) (*coretypes.ResultBroadcastTxCommit, error) {
+	if f.commitGate == nil {
+		return nil, errors.New("BroadcastTxCommit used in poll mode")
+	}
+	<-f.commitGate
+	return &coretypes.ResultBroadcastTxCommit{
+		Hash:     tx.Hash(),
+		Height:   12,
+		TxResult: abcitypes.ExecTxResult{Data: []byte("tx-id")},
+	}, nil
+}
+
+func (f *fakeBroadcaster) Commit(context.Context, *int64) (*coretypes.ResultCommit, error) {
+	return &coretypes.ResultCommit{SignedHeader: cmttypes.SignedHeader{Commit: &cmttypes.Commit{
+		Signatures: []cmttypes.CommitSig{{BlockIDFlag: cmttypes.BlockIDFlagCommit}},
+	}}}, nil
+}
+
+func (f *fakeBroadcaster) TxSearch(_ context.Context, query string, _ bool, _, _ *int, _ string) (*coretypes.ResultTxSearch, error) {
+	f.queries = append(f.queries, query)
+	return &coretypes.ResultTxSearch{Txs: f.found, TotalCount: len(f.found)}, nil
+}
+
+func (f *fakeBroadcaster) Header(_ context.Context, height *int64) (*coretypes.ResultHeader, error) {
+	return &coretypes.ResultHeader{Header: &cmttypes.Header{
+		Height: *height,
+		Time:   time.Unix(1_700_000_000, 0).UTC(),
+	}}, nil
+}
+
+func (f *fakeBroadcaster) BroadcastTxSync(_ context.Context, tx cmttypes.Tx) (*coretypes.ResultBroadcastTx, error) {
+	return &coretypes.ResultBroadcastTx{Hash: tx.Hash()}, nil
+}
+
+func (f *fakeBroadcaster) Tx(_ context.Context, hash []byte, _ bool) (*coretypes.ResultTx, error) {
+	f.polls++
+	if f.polls < f.commitOnPoll {
+		return nil, errors.New("tx not found")
+	}
+	return &coretypes.ResultTx{
+		Hash:     hash,
+		Height:   12,
+		TxResult: abcitypes.ExecTxResult{Data: []byte("tx-id")},
+	}, nil
+}
+
+// pollRepository returns a repository broadcasting to fake in poll mode
+func pollRepository(t *testing.T, fake *fakeBroadcaster, timeout time.Duration) *Repository {
+	t.Helper()
+	r := NewRepository()
+	err := r.SetBroadcastConfig(BroadcastConfig{
+		Mode:         BroadcastModePoll,
+		PollInterval: time.Millisecond,
+		PollTimeout:  timeout,
+	})
+	if err != nil {
+		t.Fatalf("SetBroadcastConfig: %v", err)
+	}
+	r.txClient = fake
+	return r
+}
+
+func TestBroadcastPollsUntilCommitted(t *testing.T) {
+	fake := &fakeBroadcaster{commitOnPoll: 3}
+	r := pollRepository(t, fake, time.Second)
+
+	result, err := r.broadcast(context.Background(), cmttypes.Tx("commit"))
+	if err != nil {
+		t.Fatalf("broadcast: %v", err)
+	}
+	if fake.polls != 3 {
+		t.Errorf("polled %d times, want 3", fake.polls)
+	}
+	if result.Height != 12 || string(result.TxData) != "tx-id" {
+		t.Errorf("result = %+v, want height 12 and tx ID tx-id", result)
+	}
+}
+
+func TestBroadcastPollTimeout(t *testing.T) {
+	fake := &fakeBroadcaster{commitOnPoll: 1 << 30}
+	r := pollRepository(t, fake, 20*time.Millisecond)
+
+	if _, err := r.broadcast(context.Background(), cmttypes.Tx("commit")); err == nil {
+		t.Fatal("broadcast succeeded for a tx that never committed")
+	}
+}
+
+func TestRunConsensusCanceledMidCommit(t *testing.T) {
+	fake := &fakeBroadcaster{commitGate: make(chan struct{})}
+	r := NewRepository()
+	r.txClient = fake
+
+	late := make(chan *ConsensusResult, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+
+	start := time.Now()
+	_, repoErr, pending := r.runConsensusSettled(ctx, map[string]string{"session_id": "SES-1"},
+		func(result *ConsensusResult, repoErr *RepositoryError) {
+			if repoErr != nil {
+				t.Errorf("late outcome: %v", repoErr)
+			}
+			late <- result
+		})
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("consensus returned after %s, want promptly after the cancel", elapsed)
+	}
+	if repoErr == nil || repoErr.Code != "CONSENSUS_CANCELED" || !pending {
+		t.Fatalf("error = %v, pending = %v; want CONSENSUS_CANCELED with the broadcast pending", repoErr, pending)
+	}
+
+	// The broadcast carries on and its outcome is still delivered
+	close(fake.commitGate)
+	select {
+	case result := <-late:
+		if result == nil || result.BlockHeight != 12 || result.TxID != "tx-id" || result.Votes != 1 {
+			t.Fatalf("late result = %+v, want the committed tx", result)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("late outcome never delivered")
+	}
+	if err := r.Drain(context.Background()); err != nil {
+		t.Fatalf("Drain: %v", err)
+	}
+}
+
+func TestRunConsensusCanceledBeforeBroadcast(t *testing.T) {
+	fake := &fakeBroadcaster{commitGate: make(chan struct{})}
+	r := NewRepository()
+	r.txClient = fake
+	r.SetConsensusConcurrency(1)
+	if err := r.consensusSlots.acquire(context.Background(), 0); err != nil {
+		t.Fatalf("acquire: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, repoErr, pending := r.runConsensusSettled(ctx, map[string]string{"session_id": "SES-1"},
+		func(*ConsensusResult, *RepositoryError) { t.Error("late called for a tx never broadcast") })
+	if repoErr == nil || repoErr.Code != "CONSENSUS_TIMEOUT" || pending {
+		t.Fatalf("error = %v, pending = %v; want CONSENSUS_TIMEOUT with nothing pending", repoErr, pending)
+	}
+}
@@ -0,0 +1,42 @@
+package repository
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestCanonicalJSONIgnoresInsertionOrder(t *testing.T) {
+	first := map[string]interface{}{}
+	first["zeta"] = 1
+	first["alpha"] = map[string]interface{}{"b": true, "a": []interface{}{"x", map[string]interface{}{"d": 2, "c": 1}}}
+	first["mid"] = "value"
+
+	second := map[string]interface{}{}
+	second["mid"] = "value"
+	second["alpha"] = map[string]interface{}{"a": []interface{}{"x", map[string]interface{}{"c": 1, "d": 2}}, "b": true}
+	second["zeta"] = 1
+
+	// The same payload arriving as raw JSON with its keys in yet another order
+	third := json.RawMessage(`{"mid": "value", "zeta": 1, "alpha": {"b": true, "a": ["x", {"d": 2, "c": 1}]}}`)
+
+	want := `{"alpha":{"a":["x",{"c":1,"d":2}],"b":true},"mid":"value","zeta":1}`
+	for name, payload := range map[string]interface{}{"first": first, "second": second, "third": third} {
+		got, err := CanonicalJSON(payload)
+		if err != nil {
+			t.Fatalf("%s: CanonicalJSON: %v", name, err)
+		}
+		if string(got) != want {
+			t.Errorf("%s: got %s, want %s", name, got, want)
+		}
+	}
+}
+
+func TestCanonicalJSONKeepsLargeIntegers(t *testing.T) {
+	got, err := CanonicalJSON(json.RawMessage(`{"n":9007199254740993}`))
+	if err != nil {
+		t.Fatalf("CanonicalJSON: %v", err)
+	}
+	if string(got) != `{"n":9007199254740993}` {
+		t.Fatalf("got %s, want the integer unchanged", got)
+	}
+}
@@ -0,0 +1,128 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/ahmadzakiakmal/thesis-extension/layer-1/repository/models"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// testRepository connects to the PostgreSQL database named by L1_TEST_DSN and
+// migrates it, skipping the test when the variable is unset. No RPC client is
+// set up, so a test that reaches consensus panics.
+func testRepository(t *testing.T) *Repository {
+	t.Helper()
+	dsn := os.Getenv("L1_TEST_DSN")
+	if dsn == "" {
+		t.Skip("L1_TEST_DSN is not set")
+	}
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("connecting to %s: %v", dsn, err)
+	}
+	r := NewRepository()
+	r.db = db
+	r.Migrate()
+	return r
+}
+
+// testShard registers a shard removed with its sessions when the test ends
+func testShard(t *testing.T, r *Repository) *models.ShardInfo {
+	t.Helper()
+	shard := &models.ShardInfo{
+		ShardID:     fmt.Sprintf("test-%d", time.Now().UnixNano()),
+		ClientGroup: "test-group",
+		L2NodeID:    "test-node",
+		L2Endpoint:  "http://localhost:0",
+	}
+	if err := r.db.Create(shard).Error; err != nil {
+		t.Fatalf("creating shard: %v", err)
+	}
+	t.Cleanup(func() {
+		r.db.Where("shard_id = ?", shard.ShardID).Delete(&models.Transaction{})
+		r.db.Where("shard_id = ?", shard.ShardID).Delete(&models.Session{})
+		r.db.Delete(shard)
+	})
+	return shard
+}
+
+func TestReceiveShardCommitExistingTransaction(t *testing.T) {
+	r := testRepository(t)
+	shard := testShard(t, r)
+
+	sessionID := shard.ShardID + "-SES"
+	txHash := "AB12"
+	session := models.Session{
+		ID:          sessionID,
+		ShardID:     shard.ShardID,
+		ClientGroup: shard.ClientGroup,
+		Status:      "committed",
+		IsCommitted: true,
+		TxHash:      &txHash,
+		SessionData: "{}",
+	}
+	if err := r.db.Create(&session).Error; err != nil {
+		t.Fatalf("creating session: %v", err)
+	}
+	stored := models.Transaction{
+		TxHash:      txHash,
+		TxID:        "stored-tx-id",
+		SessionID:   sessionID,
+		ShardID:     shard.ShardID,
+		ClientGroup: shard.ClientGroup,
+		BlockHeight: 42,
+		Timestamp:   time.Now(),
+	}
+	if err := r.db.Create(&stored).Error; err != nil {
+		t.Fatalf("creating transaction: %v", err)
+	}
+
+	// Broadcasting would panic on the missing RPC client
+	transaction, _, repoErr := r.ReceiveShardCommit(context.Background(), &ShardedCommitRequest{
+		ShardID:     shard.ShardID,
+		ClientGroup: shard.ClientGroup,
+		SessionID:   sessionID,
+		Timestamp:   time.Now(),
+	})
+	if repoErr == nil || repoErr.Code != "TRANSACTION_EXISTS" {
+		t.Fatalf("error = %v, want TRANSACTION_EXISTS", repoErr)
+	}
+	if transaction == nil || transaction.TxHash != txHash || transaction.TxID != stored.TxID || transaction.BlockHeight != 42 {
+		t.Fatalf("transaction = %+v, want the stored record", transaction)
+	}
+}
+
+func TestReceiveShardCommitExistingSession(t *testing.T) {
+	r := testRepository(t)
+	shard := testShard(t, r)
+
+	// A session mirrored without a transaction, e.g. one whose commit is
+	// still in consensus
+	session := models.Session{
+		ID:          shard.ShardID + "-SES",
+		ShardID:     shard.ShardID,
+		ClientGroup: shard.ClientGroup,
+		Status:      "committed",
+		IsCommitted: true,
+		SessionData: "{}",
+	}
+	if err := r.db.Create(&session).Error; err != nil {
+		t.Fatalf("creating session: %v", err)
+	}
+
+	_, _, repoErr := r.ReceiveShardCommit(context.Background(), &ShardedCommitRequest{
+		ShardID:     shard.ShardID,
+		ClientGroup: shard.ClientGroup,
+		SessionID:   session.ID,
+		Timestamp:   time.Now(),
+	})
+	if repoErr == nil || repoErr.Code != "SESSION_EXISTS" {
+		t.Fatalf("error = %v, want SESSION_EXISTS", repoErr)
+	}
+}
@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ahmadzakiakmal/thesis-extension/layer-1/repository/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Shard statuses stored in shard_infos
+const (
+	ShardStatusActive   = "active"
+	ShardStatusInactive = "inactive"
+)
+
+// PurgeResult counts the rows removed when a shard's data is purged
+type PurgeResult struct {
+	SessionsDeleted     int64
+	TransactionsDeleted int64
+}
+
+// DeregisterShard marks a shard inactive. Its commits are refused from then
+// on, while its sessions and transactions are kept.
+func (r *Repository) DeregisterShard(shardID string) *RepositoryError {
+	result := r.db.Model(&models.ShardInfo{}).Where("shard_id = ?", shardID).Update("status", ShardStatusInactive)
+	if result.Error != nil {
+		return &RepositoryError{
+			Code:    "DATABASE_ERROR",
+			Message: "Failed to deregister shard",
+			Detail:  result.Error.Error(),
+		}
+	}
+	if result.RowsAffected == 0 {
+		return &RepositoryError{
+			Code:    "SHARD_NOT_FOUND",
+			Message: "Unknown shard",
+			Detail:  fmt.Sprintf("Shard %s not registered in L1", shardID),
+		}
+	}
+	return nil
+}
+
+// PurgeShardData deletes the sessions and transactions of an inactive shard
+// from PostgreSQL in one transaction. The shard row is locked so it can't be
+// reactivated halfway through. Blocks in Badger are not touched.
+func (r *Repository) PurgeShardData(shardID string) (*PurgeResult, *RepositoryError) {
+	dbTx := r.db.Begin()
+	if dbTx.Error != nil {
+		return nil, &RepositoryError{
+			Code:    "DATABASE_ERROR",
+			Message: "Failed to start transaction",
+			Detail:  dbTx.Error.Error(),
+		}
+	}
+
+	var shard models.ShardInfo
+	err := dbTx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("shard_id = ?", shardID).First(&shard).Error
+	if err != nil {
+		dbTx.Rollback()
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, &RepositoryError{
+				Code:    "SHARD_NOT_FOUND",
+				Message: "Unknown shard",
+				Detail:  fmt.Sprintf("Shard %s not registered in L1", shardID),
+			}
+		}
+		return nil, &RepositoryError{
+			Code:    "DATABASE_ERROR",
+			Message: "Database error",
+			Detail:  err.Error(),
+		}
+	}
+	if shard.Status != ShardStatusInactive {
+		dbTx.Rollback()
+		return nil, &RepositoryError{
+			Code:    "SHARD_ACTIVE",
+			Message: "Shard is still active",
+			Detail:  fmt.Sprintf("Shard %s must be deregistered before its data is purged", shardID),
+		}
+	}
+
+	result := &PurgeResult{}
+	deleted := dbTx.Where("shard_id = ?", shardID).Delete(&models.Transaction{})
+	if deleted.Error != nil {
+		dbTx.Rollback()
+		return nil, &RepositoryError{
+			Code:    "DATABASE_ERROR",
+			Message: "Failed to delete transactions",
+			Detail:  deleted.Error.Error(),
+		}
+	}
+	result.TransactionsDeleted = deleted.RowsAffected
+
+	deleted = dbTx.Where("shard_id = ?", shardID).Delete(&models.Session{})
+	if deleted.Error != nil {
+		dbTx.Rollback()
+		return nil, &RepositoryError{
+			Code:    "DATABASE_ERROR",
+			Message: "Failed to delete sessions",
+			Detail:  deleted.Error.Error(),
+		}
+	}
+	result.SessionsDeleted = deleted.RowsAffected
+
+	if err := dbTx.Commit().Error; err != nil {
+		return nil, &RepositoryError{
+			Code:    "DATABASE_ERROR",
+			Message: "Failed to commit purge",
+			Detail:  err.Error(),
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,259 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ahmadzakiakmal/thesis-extension/layer-1/repository/models"
+	"gorm.io/gorm"
+)
+
+// exportCursorFile records the last block height exported to a directory
+const exportCursorFile = "export.cursor"
+
+// ExportedTransaction is one NDJSON line of a transaction export
+type ExportedTransaction struct {
+	TxHash      string    `json:"tx_hash"`
+	SessionID   string    `json:"session_id"`
+	ShardID     string    `json:"shard_id"`
+	ClientGroup string    `json:"client_group"`
+	BlockHeight int64     `json:"block_height"`
+	Timestamp   time.Time `json:"timestamp"`
+	Status      string    `json:"status"`
+	ConsensusMs int64     `json:"consensus_ms"`
+}
+
+// ExportResult describes one export run
+type ExportResult struct {
+	FromHeight int64
+	ToHeight   int64
+	Exported   int
+	Pruned     int64
+	File       string
+}
+
+// ExportTransactions streams transactions with fromHeight <= block_height <=
+// toHeight to w as NDJSON, ordered by height then session, and returns how
+// many were written. The same range always yields the same output.
+func (r *Repository) ExportTransactions(ctx context.Context, fromHeight, toHeight int64, w io.Writer) (int, *RepositoryError) {
+	sessionIDs, repoErr := r.exportTransactions(ctx, fromHeight, toHeight, w)
+	return len(sessionIDs), repoErr
+}
+
+// exportTransactions implements ExportTransactions, returning the session IDs
+// of the transactions written
+func (r *Repository) exportTransactions(ctx context.Context, fromHeight, toHeight int64, w io.Writer) ([]string, *RepositoryError) {
+	rows, err := r.db.WithContext(ctx).Model(&models.Transaction{}).
+		Where("block_height >= ? AND block_height <= ?", fromHeight, toHeight).
+		Order("block_height ASC, session_id ASC").
+		Rows()
+	if err != nil {
+		return nil, &RepositoryError{
+			Code:    "DATABASE_ERROR",
+			Message: "Failed to query transactions for export",
+			Detail:  err.Error(),
+		}
+	}
+	defer rows.Close()
+
+	encoder := json.NewEncoder(w)
+	var exported []string
+	for rows.Next() {
+		var transaction models.Transaction
+		if err := r.db.ScanRows(rows, &transaction); err != nil {
+			return exported, &RepositoryError{
+				Code:    "DATABASE_ERROR",
+				Message: "Failed to read transaction for export",
+				Detail:  err.Error(),
+			}
+		}
+
+		if err := encoder.Encode(ExportedTransaction{
+			TxHash:      transaction.TxHash,
+			SessionID:   transaction.SessionID,
+			ShardID:     transaction.ShardID,
+			ClientGroup: transaction.ClientGroup,
+			BlockHeight: transaction.BlockHeight,
+			Timestamp:   transaction.Timestamp,
+			Status:      transaction.Status,
+			ConsensusMs: transaction.ConsensusMs,
+		}); err != nil {
+			return exported, &RepositoryError{
+				Code:    "EXPORT_ERROR",
+				Message: "Failed to write exported transaction",
+				Detail:  err.Error(),
+			}
+		}
+		exported = append(exported, transaction.SessionID)
+	}
+
+	if err := rows.Err(); err != nil {
+		return exported, &RepositoryError{
+			Code:    "DATABASE_ERROR",
+			Message: "Failed to iterate transactions for export",
+			Detail:  err.Error(),
+		}
+	}
+
+	return exported, nil
+}
+
+// PruneTransactions deletes the transactions of the given sessions together
+// with the sessions and returns how many transactions were removed. Pass the
+// sessions an export wrote, so rows mirrored after the export are kept.
+func (r *Repository) PruneTransactions(ctx context.Context, sessionIDs []string) (int64, *RepositoryError) {
+	if len(sessionIDs) == 0 {
+		return 0, nil
+	}
+
+	var pruned int64
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("session_id IN ?", sessionIDs).Delete(&models.Transaction{})
+		if result.Error != nil {
+			return result.Error
+		}
+		pruned = result.RowsAffected
+
+		return tx.Where("session_id IN ?", sessionIDs).Delete(&models.Session{}).Error
+	})
+	if err != nil {
+		return 0, &RepositoryError{
+			Code:    "DATABASE_ERROR",
+			Message: "Failed to prune exported transactions",
+			Detail:  err.Error(),
+		}
+	}
+
+	return pruned, nil
+}
+
+// ExportToDir exports every transaction above the directory's cursor into
+// transactions-<from>-<to>.ndjson and advances the cursor, pruning the
+// exported rows when prune is set. A run interrupted before the cursor moves
+// rewrites the same file on the next run, so runs are idempotent.
+//
+// A commit can be mirrored after a later height was already exported. When
+// pruning, everything still in the table was never exported, so the file
+// also takes the rows left below the cursor.
+func (r *Repository) ExportToDir(ctx context.Context, dir string, prune bool) (*ExportResult, *RepositoryError) {
+	cursor, err := readExportCursor(dir)
+	if err != nil {
+		return nil, &RepositoryError{
+			Code:    "EXPORT_ERROR",
+			Message: "Failed to read export cursor",
+			Detail:  err.Error(),
+		}
+	}
+
+	var toHeight int64
+	if err := r.db.WithContext(ctx).Model(&models.Transaction{}).
+		Select("COALESCE(MAX(block_height), 0)").Scan(&toHeight).Error; err != nil {
+		return nil, &RepositoryError{
+			Code:    "DATABASE_ERROR",
+			Message: "Failed to query latest transaction height",
+			Detail:  err.Error(),
+		}
+	}
+
+	result := &ExportResult{FromHeight: cursor + 1, ToHeight: toHeight}
+	if toHeight < result.FromHeight {
+		return result, nil
+	}
+
+	fromHeight := result.FromHeight
+	if prune {
+		fromHeight = 0
+	}
+	result.File = filepath.Join(dir, fmt.Sprintf("transactions-%012d-%012d.ndjson", result.FromHeight, toHeight))
+	exported, repoErr := r.writeExportFile(ctx, result.File, fromHeight, toHeight)
+	if repoErr != nil {
+		return nil, repoErr
+	}
+	result.Exported = len(exported)
+
+	if err := writeFileAtomic(filepath.Join(dir, exportCursorFile), []byte(strconv.FormatInt(toHeight, 10)+"\n")); err != nil {
+		return nil, &RepositoryError{
+			Code:    "EXPORT_ERROR",
+			Message: "Failed to advance export cursor",
+			Detail:  err.Error(),
+		}
+	}
+
+	if prune {
+		pruned, repoErr := r.PruneTransactions(ctx, exported)
+		if repoErr != nil {
+			return result, repoErr
+		}
+		result.Pruned = pruned
+	}
+
+	return result, nil
+}
+
+// writeExportFile exports a height range into path via a temporary file so a
+// partial export is never left under the final name, returning the session
+// IDs of the exported transactions
+func (r *Repository) writeExportFile(ctx context.Context, path string, fromHeight, toHeight int64) ([]string, *RepositoryError) {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".export-*")
+	if err != nil {
+		return nil, &RepositoryError{
+			Code:    "EXPORT_ERROR",
+			Message: "Failed to create export file",
+			Detail:  err.Error(),
+		}
+	}
+	defer os.Remove(tmp.Name())
+
+	exported, repoErr := r.exportTransactions(ctx, fromHeight, toHeight, tmp)
+	if repoErr != nil {
+		tmp.Close()
+		return nil, repoErr
+	}
+
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return nil, &RepositoryError{Code: "EXPORT_ERROR", Message: "Failed to sync export file", Detail: err.Error()}
+	}
+	if err := tmp.Close(); err != nil {
+		return nil, &RepositoryError{Code: "EXPORT_ERROR", Message: "Failed to close export file", Detail: err.Error()}
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return nil, &RepositoryError{Code: "EXPORT_ERROR", Message: "Failed to finalize export file", Detail: err.Error()}
+	}
+
+	return exported, nil
+}
+
+// readExportCursor returns the last exported height, 0 when nothing was exported
+func readExportCursor(dir string) (int64, error) {
+	raw, err := os.ReadFile(filepath.Join(dir, exportCursorFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	cursor, err := strconv.ParseInt(strings.TrimSpace(string(raw)), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid export cursor: %w", err)
+	}
+	return cursor, nil
+}
+
+// writeFileAtomic replaces path with data so readers never see a partial file
+func writeFileAtomic(path string, data []byte) error {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,176 @@
+package repository
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/ahmadzakiakmal/thesis-extension/layer-1/repository/models"
+)
+
+// testHeight returns a block height above anything a test DB holds
+func testHeight() int64 {
+	return 1_000_000_000 + time.Now().UnixNano()%1_000_000*100
+}
+
+// testCommit mirrors a committed session of shard at height
+func testCommit(t *testing.T, r *Repository, shard *models.ShardInfo, sessionID string, height int64) {
+	t.Helper()
+	session := models.Session{
+		ID:          sessionID,
+		ShardID:     shard.ShardID,
+		ClientGroup: shard.ClientGroup,
+		Status:      "committed",
+		IsCommitted: true,
+		SessionData: "{}",
+	}
+	if err := r.db.Create(&session).Error; err != nil {
+		t.Fatalf("creating session %s: %v", sessionID, err)
+	}
+	transaction := models.Transaction{
+		TxHash:      fmt.Sprintf("%X", height),
+		SessionID:   sessionID,
+		ShardID:     shard.ShardID,
+		ClientGroup: shard.ClientGroup,
+		BlockHeight: height,
+		Timestamp:   time.Unix(height, 0).UTC(),
+		Status:      "confirmed",
+	}
+	if err := r.db.Create(&transaction).Error; err != nil {
+		t.Fatalf("creating transaction %s: %v", sessionID, err)
+	}
+}
+
+// exportedSessions decodes NDJSON and returns the session IDs of shardID
+func exportedSessions(t *testing.T, data []byte, shardID string) []string {
+	t.Helper()
+	var sessionIDs []string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		var line ExportedTransaction
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			t.Fatalf("decoding %s: %v", scanner.Bytes(), err)
+		}
+		if line.ShardID == shardID {
+			sessionIDs = append(sessionIDs, line.SessionID)
+		}
+	}
+	return sessionIDs
+}
+
+func TestExportTransactionsRange(t *testing.T) {
+	r := testRepository(t)
+	shard := testShard(t, r)
+	base := testHeight()
+
+	testCommit(t, r, shard, shard.ShardID+"-B", base+1)
+	testCommit(t, r, shard, shard.ShardID+"-A", base+1)
+	testCommit(t, r, shard, shard.ShardID+"-C", base+2)
+	testCommit(t, r, shard, shard.ShardID+"-D", base+3)
+
+	var buf bytes.Buffer
+	exported, repoErr := r.ExportTransactions(context.Background(), base+1, base+2, &buf)
+	if repoErr != nil {
+		t.Fatalf("ExportTransactions: %v", repoErr)
+	}
+	if exported != 3 {
+		t.Errorf("exported %d transactions, want 3", exported)
+	}
+
+	got := fmt.Sprint(exportedSessions(t, buf.Bytes(), shard.ShardID))
+	want := fmt.Sprint([]string{shard.ShardID + "-A", shard.ShardID + "-B", shard.ShardID + "-C"})
+	if got != want {
+		t.Fatalf("exported %s, want %s in height then session order", got, want)
+	}
+
+	var first ExportedTransaction
+	if err := json.Unmarshal(bytes.SplitN(buf.Bytes(), []byte("\n"), 2)[0], &first); err != nil {
+		t.Fatalf("decoding first line: %v", err)
+	}
+	if first.BlockHeight != base+1 || first.ClientGroup != shard.ClientGroup || first.Status != "confirmed" ||
+		!first.Timestamp.Equal(time.Unix(base+1, 0)) {
+		t.Errorf("first line = %+v, want the stored transaction", first)
+	}
+
+	var again bytes.Buffer
+	if _, repoErr := r.ExportTransactions(context.Background(), base+1, base+2, &again); repoErr != nil {
+		t.Fatalf("ExportTransactions again: %v", repoErr)
+	}
+	if !bytes.Equal(buf.Bytes(), again.Bytes()) {
+		t.Error("exporting the same range twice gave different output")
+	}
+}
+
+func TestExportToDirPrunesOnlyExportedRows(t *testing.T) {
+	r := testRepository(t)
+	shard := testShard(t, r)
+	base := testHeight()
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	testCommit(t, r, shard, shard.ShardID+"-A", base+2)
+	first, repoErr := r.ExportToDir(ctx, dir, true)
+	if repoErr != nil {
+		t.Fatalf("first export: %v", repoErr)
+	}
+
+	// A commit mirrored late, below the cursor, and one above it
+	testCommit(t, r, shard, shard.ShardID+"-LATE", base+1)
+	testCommit(t, r, shard, shard.ShardID+"-B", base+3)
+
+	second, repoErr := r.ExportToDir(ctx, dir, true)
+	if repoErr != nil {
+		t.Fatalf("second export: %v", repoErr)
+	}
+	if second.FromHeight != first.ToHeight+1 {
+		t.Errorf("second run starts at %d, want %d", second.FromHeight, first.ToHeight+1)
+	}
+
+	for _, run := range []struct {
+		result *ExportResult
+		want   []string
+	}{
+		{first, []string{shard.ShardID + "-A"}},
+		{second, []string{shard.ShardID + "-LATE", shard.ShardID + "-B"}},
+	} {
+		data, err := os.ReadFile(run.result.File)
+		if err != nil {
+			t.Fatalf("reading %s: %v", run.result.File, err)
+		}
+		if got := exportedSessions(t, data, shard.ShardID); fmt.Sprint(got) != fmt.Sprint(run.want) {
+			t.Errorf("%s holds %v, want %v", run.result.File, got, run.want)
+		}
+	}
+
+	var left int64
+	r.db.Model(&models.Session{}).Where("shard_id = ?", shard.ShardID).Count(&left)
+	if left != 0 {
+		t.Errorf("%d exported sessions left after pruning", left)
+	}
+}
+
+func TestPruneTransactionsKeepsUnlistedSessions(t *testing.T) {
+	r := testRepository(t)
+	shard := testShard(t, r)
+	base := testHeight()
+
+	testCommit(t, r, shard, shard.ShardID+"-A", base+1)
+	testCommit(t, r, shard, shard.ShardID+"-B", base+1)
+
+	pruned, repoErr := r.PruneTransactions(context.Background(), []string{shard.ShardID + "-A"})
+	if repoErr != nil {
+		t.Fatalf("PruneTransactions: %v", repoErr)
+	}
+	if pruned != 1 {
+		t.Errorf("pruned %d transactions, want 1", pruned)
+	}
+	var kept models.Transaction
+	if err := r.db.Where("session_id = ?", shard.ShardID+"-B").First(&kept).Error; err != nil {
+		t.Fatalf("unexported transaction at the same height was pruned: %v", err)
+	}
+}
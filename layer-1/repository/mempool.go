@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+
+	mempl "github.com/cometbft/cometbft/mempool"
+)
+
+// PendingCommit summarizes a shard commit waiting in the mempool
+type PendingCommit struct {
+	TxHash      string `json:"tx_hash"`
+	SessionID   string `json:"session_id,omitempty"`
+	ShardID     string `json:"shard_id,omitempty"`
+	ClientGroup string `json:"client_group,omitempty"`
+	Size        int    `json:"size"`
+}
+
+// MempoolStatus is a snapshot of the mempool. Pending holds at most the
+// requested number of transactions while Total counts all of them.
+type MempoolStatus struct {
+	Count      int             `json:"count"`
+	Total      int             `json:"total"`
+	TotalBytes int64           `json:"total_bytes"`
+	Pending    []PendingCommit `json:"pending"`
+}
+
+// SetMempool gives the repository access to the node's mempool for flushing
+func (r *Repository) SetMempool(mempool mempl.Mempool) {
+	r.mempool = mempool
+}
+
+// GetMempool lists up to limit unconfirmed transactions. Transactions that
+// aren't shard commits are reported with their hash and size only.
+func (r *Repository) GetMempool(ctx context.Context, limit int) (*MempoolStatus, *RepositoryError) {
+	result, err := r.rpcClient.UnconfirmedTxs(ctx, &limit)
+	if err != nil {
+		return nil, &RepositoryError{
+			Code:    "QUERY_ERROR",
+			Message: "Failed to query mempool",
+			Detail:  err.Error(),
+		}
+	}
+
+	status := &MempoolStatus{
+		Count:      result.Count,
+		Total:      result.Total,
+		TotalBytes: result.TotalBytes,
+		Pending:    make([]PendingCommit, 0, len(result.Txs)),
+	}
+	for _, tx := range result.Txs {
+		pending := PendingCommit{
+			TxHash: hex.EncodeToString(tx.Hash()),
+			Size:   len(tx),
+		}
+		var commit ShardedCommitRequest
+		if err := json.Unmarshal(tx, &commit); err == nil {
+			pending.SessionID = commit.SessionID
+			pending.ShardID = commit.ShardID
+			pending.ClientGroup = commit.ClientGroup
+		}
+		status.Pending = append(status.Pending, pending)
+	}
+
+	return status, nil
+}
+
+// FlushMempool drops every unconfirmed transaction and returns how many were
+// removed. Commits waiting on those transactions fail or time out.
+func (r *Repository) FlushMempool() (int, *RepositoryError) {
+	if r.mempool == nil {
+		return 0, &RepositoryError{
+			Code:    "MEMPOOL_UNAVAILABLE",
+			Message: "Mempool is not available",
+			Detail:  "SetMempool was not called",
+		}
+	}
+
+	removed := r.mempool.Size()
+	r.mempool.Flush()
+	return removed, nil
+}
@@ -11,6 +11,13 @@ type ShardInfo struct {
 	Status      string    `gorm:"column:status;type:varchar(20);default:'active'"`
 	CreatedAt   time.Time `gorm:"column:created_at;autoCreateTime"`
 	UpdatedAt   time.Time `gorm:"column:updated_at;autoUpdateTime"`
+
+	// LastSeen is when the shard's L2 node last sent a heartbeat, nil if never
+	LastSeen *time.Time `gorm:"column:last_seen"`
+
+	// Priority orders this shard's commits when every consensus slot is
+	// taken: higher goes first. All shards default to 0.
+	Priority int `gorm:"column:priority;not null;default:0"`
 }
 
 // Session represents a session from any L2 shard
@@ -19,7 +26,7 @@ type Session struct {
 	ShardID     string     `gorm:"column:shard_id;type:varchar(50);index;not null"`
 	Shard       *ShardInfo `gorm:"foreignKey:ShardID;references:ShardID"`
 	ClientGroup string     `gorm:"column:client_group;type:varchar(100);not null"`
-	OperatorID  string     `gorm:"column:operator_id;type:varchar(50)"`
+	OperatorID  string     `gorm:"column:operator_id;type:varchar(50);index"`
 	Status      string     `gorm:"column:status;type:varchar(20);not null"`
 	IsCommitted bool       `gorm:"column:is_committed;default:false"`
 	TxHash      *string    `gorm:"column:tx_hash;type:varchar(66)"`
@@ -29,13 +36,18 @@ type Session struct {
 	// Session data as JSON (from L2)
 	SessionData string `gorm:"column:session_data;type:jsonb"`
 
+	// Sequence is the commit's position in its shard's ordered commits, 0
+	// when unordered. It is kept so an orphaned commit is retried in order.
+	Sequence int64 `gorm:"column:sequence;not null;default:0"`
+
 	// Relationships
 	Transaction *Transaction `gorm:"foreignKey:SessionID"`
 }
 
 // Transaction represents blockchain records with shard tracking
 type Transaction struct {
-	TxHash      string     `gorm:"column:tx_hash;type:varchar(66)"`
+	TxHash      string     `gorm:"column:tx_hash;type:varchar(66);index"`
+	TxID        string     `gorm:"column:tx_id;type:varchar(64)"` // Badger key, see /l1/verify/:txid
 	SessionID   string     `gorm:"column:session_id;type:varchar(50);uniqueIndex;not null;primaryKey"`
 	ShardID     string     `gorm:"column:shard_id;type:varchar(50);index;not null"`
 	Shard       *ShardInfo `gorm:"foreignKey:ShardID;references:ShardID"`
@@ -44,6 +56,9 @@ type Transaction struct {
 	Timestamp   time.Time  `gorm:"column:timestamp;not null"`
 	Status      string     `gorm:"column:status;type:varchar(20);default:'confirmed'"`
 
+	// ConsensusMs is how long consensus took for this commit, 0 when unknown
+	ConsensusMs int64 `gorm:"column:consensus_ms;default:0"`
+
 	// Relationships
 	Session *Session `gorm:"foreignKey:SessionID"`
 }
@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"context"
+	"time"
+)
+
+// ConsensusStatus is this node's view of consensus as reported by CometBFT
+type ConsensusStatus struct {
+	NodeID            string    `json:"node_id"`
+	LatestBlockHeight int64     `json:"latest_block_height"`
+	LatestBlockTime   time.Time `json:"latest_block_time"`
+	CatchingUp        bool      `json:"catching_up"`
+	PeersOut          int       `json:"num_peers_out"`
+	PeersIn           int       `json:"num_peers_in"`
+	Validator         bool      `json:"validator"`
+	VotingPower       int64     `json:"voting_power"`
+}
+
+// GetConsensusStatus reads block height, sync state, peers and validator
+// status from the node
+func (r *Repository) GetConsensusStatus(ctx context.Context) (*ConsensusStatus, *RepositoryError) {
+	if r.rpcClient == nil {
+		return nil, &RepositoryError{
+			Code:    "RPC_UNAVAILABLE",
+			Message: "RPC client is not available",
+			Detail:  "SetupRpcClient was not called",
+		}
+	}
+
+	status, err := r.rpcClient.Status(ctx)
+	if err != nil {
+		return nil, &RepositoryError{
+			Code:    "QUERY_ERROR",
+			Message: "Failed to query node status",
+			Detail:  err.Error(),
+		}
+	}
+	netInfo, err := r.rpcClient.NetInfo(ctx)
+	if err != nil {
+		return nil, &RepositoryError{
+			Code:    "QUERY_ERROR",
+			Message: "Failed to query peers",
+			Detail:  err.Error(),
+		}
+	}
+
+	consensus := &ConsensusStatus{
+		NodeID:            string(status.NodeInfo.ID()),
+		LatestBlockHeight: status.SyncInfo.LatestBlockHeight,
+		LatestBlockTime:   status.SyncInfo.LatestBlockTime,
+		CatchingUp:        status.SyncInfo.CatchingUp,
+		Validator:         status.ValidatorInfo.VotingPower > 0,
+		VotingPower:       status.ValidatorInfo.VotingPower,
+	}
+	for _, peer := range netInfo.Peers {
+		if peer.IsOutbound {
+			consensus.PeersOut++
+		} else {
+			consensus.PeersIn++
+		}
+	}
+	return consensus, nil
+}
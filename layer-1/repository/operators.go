@@ -0,0 +1,97 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ahmadzakiakmal/thesis-extension/layer-1/repository/models"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// OperatorFilter narrows an operator listing. An empty ShardID lists the
+// operators of every shard.
+type OperatorFilter struct {
+	ShardID string
+	Limit   int
+	Offset  int
+}
+
+// ListOperators retrieves a page of operators ordered by ID along with the
+// total number matching the filter
+func (r *Repository) ListOperators(filter OperatorFilter) ([]models.Operator, int64, *RepositoryError) {
+	query := r.reader().Model(&models.Operator{})
+	if filter.ShardID != "" {
+		query = query.Where("shard_id = ?", filter.ShardID)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, &RepositoryError{
+			Code:    "DATABASE_ERROR",
+			Message: "Failed to count operators",
+			Detail:  err.Error(),
+		}
+	}
+
+	var operators []models.Operator
+	err := query.Order("operator_id").
+		Limit(filter.Limit).Offset(filter.Offset).
+		Find(&operators).Error
+	if err != nil {
+		return nil, 0, &RepositoryError{
+			Code:    "DATABASE_ERROR",
+			Message: "Failed to query operators",
+			Detail:  err.Error(),
+		}
+	}
+
+	return operators, total, nil
+}
+
+// CreateOperator registers an operator with an existing shard. Unlike
+// seeding it never overwrites: an operator ID already in use is reported as
+// OPERATOR_EXISTS.
+func (r *Repository) CreateOperator(operator *models.Operator) *RepositoryError {
+	var shards int64
+	if err := r.db.Model(&models.ShardInfo{}).Where("shard_id = ?", operator.ShardID).Count(&shards).Error; err != nil {
+		return &RepositoryError{
+			Code:    "DATABASE_ERROR",
+			Message: "Failed to query shard",
+			Detail:  err.Error(),
+		}
+	}
+	if shards == 0 {
+		return &RepositoryError{
+			Code:    "SHARD_NOT_FOUND",
+			Message: "Unknown shard",
+			Detail:  fmt.Sprintf("Shard %s not registered in L1", operator.ShardID),
+		}
+	}
+
+	if err := r.db.Create(operator).Error; err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			switch pgErr.Code {
+			case PgErrUniqueViolation:
+				return &RepositoryError{
+					Code:    "OPERATOR_EXISTS",
+					Message: "Operator already exists",
+					Detail:  fmt.Sprintf("Operator %s already registered", operator.ID),
+				}
+			case PgErrForeignKeyViolation:
+				// The shard was removed after the check above
+				return &RepositoryError{
+					Code:    "SHARD_NOT_FOUND",
+					Message: "Unknown shard",
+					Detail:  fmt.Sprintf("Shard %s not registered in L1", operator.ShardID),
+				}
+			}
+		}
+		return &RepositoryError{
+			Code:    "DATABASE_ERROR",
+			Message: "Failed to create operator",
+			Detail:  err.Error(),
+		}
+	}
+	return nil
+}
@@ -0,0 +1,218 @@
+package repository
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ahmadzakiakmal/thesis-extension/layer-1/repository/models"
+	"gorm.io/gorm"
+)
+
+// Discrepancy kinds reported by Reconcile
+const (
+	DiscrepancyMissingInBadger     = "missing_in_badger"
+	DiscrepancyMissingTransaction  = "missing_transaction"
+	DiscrepancyTransactionMismatch = "transaction_mismatch"
+	DiscrepancyUndecodableTx       = "undecodable_tx"
+)
+
+// DefaultReconcileDepth is how many recent blocks Reconcile checks by default
+const DefaultReconcileDepth = 100
+
+// ReconcileDiscrepancy is one difference found between consensus state and
+// the PostgreSQL mirror
+type ReconcileDiscrepancy struct {
+	Height    int64  `json:"height"`
+	TxHash    string `json:"tx_hash"`
+	TxID      string `json:"tx_id,omitempty"`
+	SessionID string `json:"session_id,omitempty"`
+	ShardID   string `json:"shard_id,omitempty"`
+	Kind      string `json:"kind"`
+	Repaired  bool   `json:"repaired"`
+	Error     string `json:"error,omitempty"`
+}
+
+// ReconcileReport summarizes a reconciliation run
+type ReconcileReport struct {
+	FromHeight    int64                  `json:"from_height"`
+	ToHeight      int64                  `json:"to_height"`
+	Checked       int                    `json:"checked"`
+	Applied       bool                   `json:"applied"`
+	Discrepancies []ReconcileDiscrepancy `json:"discrepancies"`
+}
+
+// Reconcile walks the last depth blocks, checks every accepted shard commit
+// against Badger (through the ABCI verify query) and the PostgreSQL mirror,
+// and reports the differences. With apply set, missing or mismatched
+// PostgreSQL rows are restored from the block data. Badger is the source of
+// truth and is never modified.
+func (r *Repository) Reconcile(ctx context.Context, depth int64, apply bool) (*ReconcileReport, *RepositoryError) {
+	if depth <= 0 {
+		depth = DefaultReconcileDepth
+	}
+
+	status, err := r.rpcClient.Status(ctx)
+	if err != nil {
+		return nil, &RepositoryError{
+			Code:    "QUERY_ERROR",
+			Message: "Failed to query node status",
+			Detail:  err.Error(),
+		}
+	}
+
+	report := &ReconcileReport{
+		ToHeight:      status.SyncInfo.LatestBlockHeight,
+		Applied:       apply,
+		Discrepancies: []ReconcileDiscrepancy{},
+	}
+	report.FromHeight = max(report.ToHeight-depth+1, status.SyncInfo.EarliestBlockHeight, 1)
+
+	for height := report.FromHeight; height <= report.ToHeight; height++ {
+		if err := ctx.Err(); err != nil {
+			return nil, &RepositoryError{
+				Code:    "RECONCILE_CANCELED",
+				Message: "Reconciliation canceled",
+				Detail:  err.Error(),
+			}
+		}
+
+		block, err := r.rpcClient.Block(ctx, &height)
+		if err != nil {
+			return nil, &RepositoryError{
+				Code:    "QUERY_ERROR",
+				Message: fmt.Sprintf("Failed to load block %d", height),
+				Detail:  err.Error(),
+			}
+		}
+		results, err := r.rpcClient.BlockResults(ctx, &height)
+		if err != nil {
+			return nil, &RepositoryError{
+				Code:    "QUERY_ERROR",
+				Message: fmt.Sprintf("Failed to load block results %d", height),
+				Detail:  err.Error(),
+			}
+		}
+
+		for i, tx := range block.Block.Txs {
+			// Only accepted commits were written to Badger and the mirror
+			if i >= len(results.TxResults) || results.TxResults[i].Code != 0 {
+				continue
+			}
+			report.Checked++
+
+			discrepancy := ReconcileDiscrepancy{
+				Height: height,
+				TxHash: hex.EncodeToString(tx.Hash()),
+				TxID:   string(results.TxResults[i].Data),
+			}
+
+			var commit ShardedCommitRequest
+			if err := json.Unmarshal(tx, &commit); err != nil {
+				discrepancy.Kind = DiscrepancyUndecodableTx
+				discrepancy.Error = err.Error()
+				report.Discrepancies = append(report.Discrepancies, discrepancy)
+				continue
+			}
+			discrepancy.SessionID = commit.SessionID
+			discrepancy.ShardID = commit.ShardID
+
+			verified, repoErr := r.VerifyTransaction(ctx, discrepancy.TxID)
+			if repoErr != nil || verified == nil {
+				discrepancy.Kind = DiscrepancyMissingInBadger
+				if repoErr != nil {
+					discrepancy.Error = repoErr.Detail
+				}
+				report.Discrepancies = append(report.Discrepancies, discrepancy)
+				continue
+			}
+
+			var transaction models.Transaction
+			err := r.db.WithContext(ctx).Where("session_id = ?", commit.SessionID).First(&transaction).Error
+			switch {
+			case errors.Is(err, gorm.ErrRecordNotFound):
+				discrepancy.Kind = DiscrepancyMissingTransaction
+			case err != nil:
+				return nil, &RepositoryError{
+					Code:    "DATABASE_ERROR",
+					Message: "Failed to query transaction",
+					Detail:  err.Error(),
+				}
+			case transaction.TxHash != discrepancy.TxHash || transaction.BlockHeight != height:
+				discrepancy.Kind = DiscrepancyTransactionMismatch
+			default:
+				continue
+			}
+
+			if apply {
+				if err := r.restoreCommit(ctx, &commit, discrepancy.TxHash, discrepancy.TxID, height, block.Block.Time); err != nil {
+					discrepancy.Error = err.Error()
+				} else {
+					discrepancy.Repaired = true
+				}
+			}
+			report.Discrepancies = append(report.Discrepancies, discrepancy)
+		}
+	}
+
+	return report, nil
+}
+
+// restoreCommit rewrites the mirror rows for a commit found in a block
+func (r *Repository) restoreCommit(ctx context.Context, commit *ShardedCommitRequest, txHash, txID string, height int64, blockTime time.Time) error {
+	sessionDataBytes, err := json.Marshal(commit.SessionData)
+	if err != nil {
+		return fmt.Errorf("serializing session data: %w", err)
+	}
+
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var session models.Session
+		err := tx.Where("session_id = ?", commit.SessionID).First(&session).Error
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			session = models.Session{
+				ID:          commit.SessionID,
+				ShardID:     commit.ShardID,
+				ClientGroup: commit.ClientGroup,
+				OperatorID:  commit.OperatorID,
+				Status:      "committed",
+				IsCommitted: true,
+				TxHash:      &txHash,
+				SessionData: string(sessionDataBytes),
+				Sequence:    commit.Sequence,
+			}
+			if err := tx.Create(&session).Error; err != nil {
+				return fmt.Errorf("restoring session: %w", err)
+			}
+		case err != nil:
+			return fmt.Errorf("loading session: %w", err)
+		default:
+			err = tx.Model(&session).Updates(map[string]interface{}{
+				"status":       "committed",
+				"is_committed": true,
+				"tx_hash":      txHash,
+			}).Error
+			if err != nil {
+				return fmt.Errorf("updating session: %w", err)
+			}
+		}
+
+		transaction := models.Transaction{
+			TxHash:      txHash,
+			TxID:        txID,
+			SessionID:   commit.SessionID,
+			ShardID:     commit.ShardID,
+			ClientGroup: commit.ClientGroup,
+			BlockHeight: height,
+			Status:      "confirmed",
+			Timestamp:   blockTime,
+		}
+		if err := tx.Save(&transaction).Error; err != nil {
+			return fmt.Errorf("restoring transaction: %w", err)
+		}
+		return nil
+	})
+}
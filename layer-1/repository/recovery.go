@@ -0,0 +1,200 @@
+package repository
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/ahmadzakiakmal/thesis-extension/layer-1/repository/models"
+)
+
+// Orphan recovery actions
+const (
+	// OrphanRetry submits the orphaned commit to consensus again
+	OrphanRetry = "retry"
+	// OrphanFail marks the orphaned session failed
+	OrphanFail = "fail"
+)
+
+// SessionFailed is the status of an orphaned session that was given up on.
+// The next commit of the session from its shard re-drives it.
+const SessionFailed = "failed"
+
+// DefaultOrphanAge is how old a session without a transaction must be before
+// it is treated as orphaned. Consensus gives up well before this.
+const DefaultOrphanAge = 10 * time.Minute
+
+// OrphanRecovery is the outcome for one orphaned session. Action is
+// "restored" when the commit turned out to be on chain already.
+type OrphanRecovery struct {
+	SessionID string `json:"session_id"`
+	Action    string `json:"action"`
+	TxHash    string `json:"tx_hash,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// RecoverOrphanedSessions finds sessions mirrored before consensus that never
+// got a transaction, e.g. because the node crashed mid-commit, and are older
+// than olderThan. Sessions whose commit is found on chain are restored from
+// the block; the rest are retried or marked failed according to action.
+func (r *Repository) RecoverOrphanedSessions(ctx context.Context, olderThan time.Duration, action string) ([]OrphanRecovery, *RepositoryError) {
+	if action != OrphanRetry && action != OrphanFail {
+		return nil, &RepositoryError{
+			Code:    "INVALID_REQUEST",
+			Message: "Unknown orphan recovery action",
+			Detail:  fmt.Sprintf("action must be %q or %q, got %q", OrphanRetry, OrphanFail, action),
+		}
+	}
+
+	var sessions []models.Session
+	err := r.db.WithContext(ctx).Preload("Shard").
+		Where("tx_hash IS NULL AND status = ? AND created_at < ?", "committed", time.Now().Add(-olderThan)).
+		Order("created_at, session_id").
+		Find(&sessions).Error
+	if err != nil {
+		return nil, &RepositoryError{
+			Code:    "DATABASE_ERROR",
+			Message: "Failed to query orphaned sessions",
+			Detail:  err.Error(),
+		}
+	}
+
+	recovered := make([]OrphanRecovery, 0, len(sessions))
+	for i := range sessions {
+		if err := ctx.Err(); err != nil {
+			return recovered, &RepositoryError{
+				Code:    "RECOVERY_CANCELED",
+				Message: "Orphan recovery canceled",
+				Detail:  err.Error(),
+			}
+		}
+		recovered = append(recovered, r.recoverOrphan(ctx, &sessions[i], action))
+	}
+	return recovered, nil
+}
+
+// recoverOrphan restores, retries or fails a single orphaned session
+func (r *Repository) recoverOrphan(ctx context.Context, session *models.Session, action string) OrphanRecovery {
+	result := OrphanRecovery{SessionID: session.ID, Action: action}
+
+	commit, err := orphanCommit(session)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	// The commit may have reached a block before the crash
+	onChain, err := r.findAcceptedCommit(ctx, session.ID)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	if onChain != nil {
+		result.Action = "restored"
+		result.TxHash = onChain.txHash
+		if err := r.restoreCommit(ctx, commit, onChain.txHash, onChain.txID, onChain.height, onChain.blockTime); err != nil {
+			result.Error = err.Error()
+		}
+		return result
+	}
+
+	if action == OrphanRetry {
+		commit.Timestamp = time.Now()
+		priority := 0
+		if session.Shard != nil {
+			priority = session.Shard.Priority
+		}
+		consensusResult, repoErr := r.RunConsensus(withConsensusPriority(ctx, priority), commit)
+		if repoErr == nil {
+			result.TxHash = consensusResult.TxHash
+			if err := r.restoreCommit(ctx, commit, consensusResult.TxHash, consensusResult.TxID, consensusResult.BlockHeight, time.Now()); err != nil {
+				result.Error = err.Error()
+			}
+			return result
+		}
+		result.Error = repoErr.Detail
+	}
+
+	err = r.db.WithContext(ctx).Model(session).Updates(map[string]interface{}{
+		"status":       SessionFailed,
+		"is_committed": false,
+	}).Error
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.Action = OrphanFail
+	return result
+}
+
+// orphanCommit rebuilds the shard commit for a mirrored session
+func orphanCommit(session *models.Session) (*ShardedCommitRequest, error) {
+	commit := &ShardedCommitRequest{
+		ShardID:     session.ShardID,
+		ClientGroup: session.ClientGroup,
+		SessionID:   session.ID,
+		OperatorID:  session.OperatorID,
+		Sequence:    session.Sequence,
+	}
+	if session.Shard != nil {
+		commit.L2NodeID = session.Shard.L2NodeID
+	}
+	if err := json.Unmarshal([]byte(session.SessionData), &commit.SessionData); err != nil {
+		return nil, fmt.Errorf("decoding session data: %w", err)
+	}
+	return commit, nil
+}
+
+// acceptedCommit locates a shard commit accepted in a block
+type acceptedCommit struct {
+	txHash    string
+	txID      string
+	height    int64
+	blockTime time.Time
+}
+
+// searchableSessionID matches the session IDs that can be put in a tx index
+// query as-is. Quotes and spaces would change the query, so anything else is
+// refused rather than escaped.
+var searchableSessionID = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,50}$`)
+
+// sessionQuery builds the tx index query for the commits of sessionID
+func sessionQuery(sessionID string) (string, error) {
+	if !searchableSessionID.MatchString(sessionID) {
+		return "", fmt.Errorf("session ID %q cannot be searched in the tx index", sessionID)
+	}
+	return fmt.Sprintf("l1_shard_commit.session_id='%s'", sessionID), nil
+}
+
+// findAcceptedCommit searches the tx index for the latest accepted commit of
+// sessionID, returning nil when there is none
+func (r *Repository) findAcceptedCommit(ctx context.Context, sessionID string) (*acceptedCommit, error) {
+	query, err := sessionQuery(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	result, err := r.txClient.TxSearch(ctx, query, false, nil, nil, "desc")
+	if err != nil {
+		return nil, fmt.Errorf("searching transactions: %w", err)
+	}
+
+	for _, tx := range result.Txs {
+		if tx.TxResult.Code != 0 {
+			continue
+		}
+		header, err := r.txClient.Header(ctx, &tx.Height)
+		if err != nil {
+			return nil, fmt.Errorf("loading block %d: %w", tx.Height, err)
+		}
+		return &acceptedCommit{
+			txHash:    hex.EncodeToString(tx.Hash),
+			txID:      string(tx.TxResult.Data),
+			height:    tx.Height,
+			blockTime: header.Header.Time,
+		}, nil
+	}
+	return nil, nil
+}
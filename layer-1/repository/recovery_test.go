@@ -0,0 +1,152 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ahmadzakiakmal/thesis-extension/layer-1/repository/models"
+	abcitypes "github.com/cometbft/cometbft/abci/types"
+	coretypes "github.com/cometbft/cometbft/rpc/core/types"
+)
+
+func TestFindAcceptedCommitRejectsUnsafeSessionIDs(t *testing.T) {
+	fake := &fakeBroadcaster{}
+	r := NewRepository()
+	r.txClient = fake
+
+	for _, sessionID := range []string{
+		"",
+		"x' OR l1_shard_commit.shard_id='y",
+		"SES 1",
+		`SES"1`,
+		"SES-0123456789-0123456789-0123456789-0123456789-0123",
+	} {
+		if _, err := r.findAcceptedCommit(context.Background(), sessionID); err == nil {
+			t.Errorf("findAcceptedCommit(%q) succeeded, want the ID refused", sessionID)
+		}
+	}
+	if len(fake.queries) != 0 {
+		t.Fatalf("tx index searched with %q, want no search for refused IDs", fake.queries)
+	}
+}
+
+func TestFindAcceptedCommitSkipsRejectedTxs(t *testing.T) {
+	fake := &fakeBroadcaster{found: []*coretypes.ResultTx{
+		{Hash: []byte{0xAA}, Height: 7, TxResult: abcitypes.ExecTxResult{Code: 5}},
+		{Hash: []byte{0xBB}, Height: 6, TxResult: abcitypes.ExecTxResult{Data: []byte("tx-6")}},
+	}}
+	r := NewRepository()
+	r.txClient = fake
+
+	accepted, err := r.findAcceptedCommit(context.Background(), "shard-1:SES_01.a")
+	if err != nil {
+		t.Fatalf("findAcceptedCommit: %v", err)
+	}
+	if want := "l1_shard_commit.session_id='shard-1:SES_01.a'"; len(fake.queries) != 1 || fake.queries[0] != want {
+		t.Fatalf("queries = %q, want [%q]", fake.queries, want)
+	}
+	if accepted == nil || accepted.txHash != "bb" || accepted.txID != "tx-6" || accepted.height != 6 {
+		t.Fatalf("accepted = %+v, want the tx at height 6", accepted)
+	}
+}
+
+// testOrphan mirrors a session of shard that was committed long enough ago to
+// be orphaned but never got a transaction
+func testOrphan(t *testing.T, r *Repository, shard *models.ShardInfo) *models.Session {
+	t.Helper()
+	session := &models.Session{
+		ID:          shard.ShardID + "-ORPHAN",
+		ShardID:     shard.ShardID,
+		ClientGroup: shard.ClientGroup,
+		Status:      "committed",
+		IsCommitted: true,
+		SessionData: `{"status":"completed"}`,
+	}
+	if err := r.db.Create(session).Error; err != nil {
+		t.Fatalf("creating session: %v", err)
+	}
+	stale := time.Now().Add(-2 * DefaultOrphanAge)
+	if err := r.db.Model(session).UpdateColumn("created_at", stale).Error; err != nil {
+		t.Fatalf("backdating session: %v", err)
+	}
+	return session
+}
+
+// recoverTestOrphan runs recovery and returns the outcome for session
+func recoverTestOrphan(t *testing.T, r *Repository, session *models.Session, action string) OrphanRecovery {
+	t.Helper()
+	recovered, repoErr := r.RecoverOrphanedSessions(context.Background(), DefaultOrphanAge, action)
+	if repoErr != nil {
+		t.Fatalf("RecoverOrphanedSessions: %v", repoErr)
+	}
+	for _, outcome := range recovered {
+		if outcome.SessionID == session.ID {
+			return outcome
+		}
+	}
+	t.Fatalf("session %s was not recovered", session.ID)
+	return OrphanRecovery{}
+}
+
+func TestRecoverOrphanedSessionsFail(t *testing.T) {
+	r := testRepository(t)
+	fake := &fakeBroadcaster{}
+	r.txClient = fake
+	session := testOrphan(t, r, testShard(t, r))
+
+	outcome := recoverTestOrphan(t, r, session, OrphanFail)
+	if outcome.Action != OrphanFail || outcome.Error != "" {
+		t.Fatalf("outcome = %+v, want the session failed", outcome)
+	}
+	var stored models.Session
+	if err := r.db.First(&stored, "session_id = ?", session.ID).Error; err != nil {
+		t.Fatalf("loading session: %v", err)
+	}
+	if stored.Status != SessionFailed || stored.IsCommitted || stored.TxHash != nil {
+		t.Fatalf("session = %+v, want it failed without a transaction", stored)
+	}
+}
+
+func TestRecoverOrphanedSessionsRetry(t *testing.T) {
+	r := testRepository(t)
+	fake := &fakeBroadcaster{commitGate: make(chan struct{})}
+	close(fake.commitGate)
+	r.txClient = fake
+	session := testOrphan(t, r, testShard(t, r))
+
+	outcome := recoverTestOrphan(t, r, session, OrphanRetry)
+	if outcome.Action != OrphanRetry || outcome.Error != "" || outcome.TxHash == "" {
+		t.Fatalf("outcome = %+v, want the commit retried", outcome)
+	}
+	var transaction models.Transaction
+	if err := r.db.First(&transaction, "session_id = ?", session.ID).Error; err != nil {
+		t.Fatalf("loading transaction: %v", err)
+	}
+	if transaction.TxHash != outcome.TxHash || transaction.BlockHeight != 12 || transaction.TxID != "tx-id" {
+		t.Fatalf("transaction = %+v, want the retried commit", transaction)
+	}
+}
+
+func TestRecoverOrphanedSessionsRestoresFromChain(t *testing.T) {
+	r := testRepository(t)
+	fake := &fakeBroadcaster{found: []*coretypes.ResultTx{
+		{Hash: []byte{0xCD}, Height: 9, TxResult: abcitypes.ExecTxResult{Data: []byte("chain-tx")}},
+	}}
+	r.txClient = fake
+	session := testOrphan(t, r, testShard(t, r))
+
+	// Fail is requested, but the commit on chain wins
+	outcome := recoverTestOrphan(t, r, session, OrphanFail)
+	if outcome.Action != "restored" || outcome.TxHash != "cd" || outcome.Error != "" {
+		t.Fatalf("outcome = %+v, want the session restored from the chain", outcome)
+	}
+	var stored models.Session
+	if err := r.db.Preload("Transaction").First(&stored, "session_id = ?", session.ID).Error; err != nil {
+		t.Fatalf("loading session: %v", err)
+	}
+	if stored.Status != "committed" || stored.TxHash == nil || *stored.TxHash != "cd" ||
+		stored.Transaction == nil || stored.Transaction.BlockHeight != 9 {
+		t.Fatalf("session = %+v, want it restored with the block's transaction", stored)
+	}
+}
@@ -0,0 +1,41 @@
+package repository
+
+import (
+	"fmt"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// ConnectReadReplica opens a read-only connection used by the cross-shard
+// session and transaction queries. It uses the same pool settings as the
+// primary and runs no migrations or seeding; the replica is expected to
+// follow the primary's schema through replication.
+func (r *Repository) ConnectReadReplica(dsn string) error {
+	db, err := gorm.Open(postgres.Open(dsn))
+	if err != nil {
+		return fmt.Errorf("opening read replica: %w", err)
+	}
+	if err := r.applyPool(db); err != nil {
+		return err
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		return fmt.Errorf("getting read replica pool: %w", err)
+	}
+	if err := sqlDB.Ping(); err != nil {
+		sqlDB.Close()
+		return fmt.Errorf("pinging read replica: %w", err)
+	}
+	r.readDB = db
+	return nil
+}
+
+// reader returns the connection for read-only queries: the replica when one
+// is connected, otherwise the primary
+func (r *Repository) reader() *gorm.DB {
+	if r.readDB != nil {
+		return r.readDB
+	}
+	return r.db
+}
@@ -7,15 +7,21 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"runtime"
+	"sync"
 	"time"
 
 	"github.com/ahmadzakiakmal/thesis-extension/layer-1/repository/models"
+	"github.com/ahmadzakiakmal/thesis-extension/layer-1/tracing"
+	mempl "github.com/cometbft/cometbft/mempool"
 	cmtrpc "github.com/cometbft/cometbft/rpc/client/local"
-	cmtrpctypes "github.com/cometbft/cometbft/rpc/core/types"
 	cmttypes "github.com/cometbft/cometbft/types"
 	"github.com/jackc/pgx/v5/pgconn"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // PostgreSQL error codes
@@ -33,6 +39,16 @@ type ConsensusResult struct {
 	BlockHeight int64
 	Code        uint32
 	Error       error
+
+	// TxID is the key the commit is stored under in Badger, as returned by
+	// FinalizeBlock. It is what /l1/verify/:txid takes.
+	TxID string
+
+	// Votes is the number of validator precommits in the commit for BlockHeight
+	Votes int
+
+	// Duration is the consensus round trip, from broadcast until committed
+	Duration time.Duration
 }
 
 // RepositoryError represents repository layer errors
@@ -51,15 +67,89 @@ type ShardedCommitRequest struct {
 	SessionData map[string]interface{} `json:"session_data"`
 	L2NodeID    string                 `json:"l2_node_id"`
 	Timestamp   time.Time              `json:"timestamp"`
+
+	// Sequence is the commit's position in the shard's ordered commits,
+	// starting at 1. Zero leaves the commit unordered.
+	Sequence int64 `json:"sequence,omitempty"`
+}
+
+// DefaultMaxSessionDataBytes is the default limit on a commit's serialized
+// session_data, keeping oversized payloads out of blocks
+const DefaultMaxSessionDataBytes = 64 << 10
+
+// SessionDataSize returns the size of the commit's session_data as JSON
+func (c *ShardedCommitRequest) SessionDataSize() (int, error) {
+	data, err := json.Marshal(c.SessionData)
+	if err != nil {
+		return 0, err
+	}
+	return len(data), nil
 }
 
 type Repository struct {
 	db        *gorm.DB
 	rpcClient *cmtrpc.Local
+
+	// readDB serves the cross-shard queries when a read replica is
+	// connected; see reader
+	readDB *gorm.DB
+
+	// inflight tracks consensus broadcasts that have not returned yet
+	inflight sync.WaitGroup
+
+	seed            SeedConfig
+	pool            PoolConfig
+	broadcastConfig BroadcastConfig
+	// txClient submits consensus transactions and looks them up, the RPC
+	// client outside tests
+	txClient txClient
+
+	// consensusSlots bounds concurrent consensus submissions, serving
+	// higher-priority shards first when they are all taken
+	consensusSlots *slotQueue
+
+	// enforceOperatorShard rejects commits attributed to an operator of
+	// another shard
+	enforceOperatorShard bool
+
+	// shardAllowlist limits the shards that may commit, empty allows all
+	shardAllowlist map[string]struct{}
+
+	mempool mempl.Mempool
 }
 
 func NewRepository() *Repository {
-	return &Repository{}
+	return &Repository{
+		seed:            SeedConfig{Enabled: true},
+		broadcastConfig: DefaultBroadcastConfig(),
+		consensusSlots:  newSlotQueue(runtime.NumCPU()),
+	}
+}
+
+// SetConsensusConcurrency limits how many consensus submissions run at once;
+// the rest wait for a free slot, highest shard priority first. Defaults to the
+// number of CPUs. Call it before serving requests.
+func (r *Repository) SetConsensusConcurrency(limit int) {
+	if limit <= 0 {
+		limit = runtime.NumCPU()
+	}
+	r.consensusSlots = newSlotQueue(limit)
+}
+
+// SetOperatorAttribution makes ReceiveShardCommit reject commits whose
+// operator is unknown to L1 or registered to a different shard. It is off by
+// default because L2 nodes seed every operator on every shard.
+func (r *Repository) SetOperatorAttribution(enforce bool) {
+	r.enforceOperatorShard = enforce
+}
+
+// SetShardAllowlist makes ReceiveShardCommit reject shards that are not
+// listed, even when they are registered. An empty list allows every shard.
+func (r *Repository) SetShardAllowlist(shards []string) {
+	r.shardAllowlist = make(map[string]struct{}, len(shards))
+	for _, shard := range shards {
+		r.shardAllowlist[shard] = struct{}{}
+	}
 }
 
 // ConnectDB establishes database connection and performs migrations
@@ -72,6 +162,9 @@ func (r *Repository) ConnectDB(dsn string) {
 			time.Sleep(2 * time.Second)
 			continue
 		}
+		if err := r.applyPool(DB); err != nil {
+			log.Printf("Failed to configure connection pool: %v\n", err)
+		}
 		r.db = DB
 		break
 	}
@@ -133,81 +226,144 @@ func (r *Repository) Migrate() {
 		log.Println("✓ Transaction table already exists")
 	}
 
+	// Columns added after the initial schema
+	if !migrator.HasColumn(&models.Transaction{}, "ConsensusMs") {
+		if err := migrator.AddColumn(&models.Transaction{}, "ConsensusMs"); err != nil {
+			log.Printf("Error adding Transaction consensus_ms column: %v", err)
+			return
+		}
+		log.Println("✓ Transaction consensus_ms column added")
+	}
+	if !migrator.HasColumn(&models.Transaction{}, "TxID") {
+		if err := migrator.AddColumn(&models.Transaction{}, "TxID"); err != nil {
+			log.Printf("Error adding Transaction tx_id column: %v", err)
+			return
+		}
+		log.Println("✓ Transaction tx_id column added")
+	}
+	if !migrator.HasColumn(&models.Session{}, "Sequence") {
+		if err := migrator.AddColumn(&models.Session{}, "Sequence"); err != nil {
+			log.Printf("Error adding Session sequence column: %v", err)
+			return
+		}
+		log.Println("✓ Session sequence column added")
+	}
+	if !migrator.HasColumn(&models.ShardInfo{}, "LastSeen") {
+		if err := migrator.AddColumn(&models.ShardInfo{}, "LastSeen"); err != nil {
+			log.Printf("Error adding ShardInfo last_seen column: %v", err)
+			return
+		}
+		log.Println("✓ ShardInfo last_seen column added")
+	}
+	if !migrator.HasColumn(&models.ShardInfo{}, "Priority") {
+		if err := migrator.AddColumn(&models.ShardInfo{}, "Priority"); err != nil {
+			log.Printf("Error adding ShardInfo priority column: %v", err)
+			return
+		}
+		log.Println("✓ ShardInfo priority column added")
+	}
+	if !migrator.HasIndex(&models.Session{}, "OperatorID") {
+		if err := migrator.CreateIndex(&models.Session{}, "OperatorID"); err != nil {
+			log.Printf("Error adding Session operator_id index: %v", err)
+			return
+		}
+		log.Println("✓ Session operator_id index added")
+	}
+	if !migrator.HasIndex(&models.Transaction{}, "TxHash") {
+		if err := migrator.CreateIndex(&models.Transaction{}, "TxHash"); err != nil {
+			log.Printf("Error adding Transaction tx_hash index: %v", err)
+			return
+		}
+		log.Println("✓ Transaction tx_hash index added")
+	}
+
 	log.Println("Database migration completed successfully")
 }
 
-// Seed initializes database with test data
+// SetSeedConfig configures seeding. Call it before ConnectDB.
+func (r *Repository) SetSeedConfig(config SeedConfig) {
+	r.seed = config
+}
+
+// Seed upserts the configured seed data on every startup, so the seed list
+// stays authoritative: missing shards and operators are inserted and existing
+// ones get their seeded fields updated. Rows not in the seed list are left
+// untouched.
 func (r *Repository) Seed() {
-	// Check if data already exists
-	var shardCount int64
-	r.db.Model(&models.ShardInfo{}).Count(&shardCount)
-	if shardCount > 0 {
-		log.Println("Seed data already exists, skipping...")
+	if !r.seed.Enabled {
+		log.Println("Seeding disabled, skipping...")
 		return
 	}
 
-	log.Println("Seeding database with shard data...")
-
-	// Create shard info (4 shards for testing)
-	shards := []models.ShardInfo{
-		{ShardID: "shard-a", ClientGroup: "group-a", L2NodeID: "l2-node-a", L2Endpoint: "http://l2-shard-a:7000", Status: "active"},
-		{ShardID: "shard-b", ClientGroup: "group-b", L2NodeID: "l2-node-b", L2Endpoint: "http://l2-shard-b:7000", Status: "active"},
-		{ShardID: "shard-c", ClientGroup: "group-c", L2NodeID: "l2-node-c", L2Endpoint: "http://l2-shard-c:7000", Status: "active"},
-		{ShardID: "shard-d", ClientGroup: "group-d", L2NodeID: "l2-node-d", L2Endpoint: "http://l2-shard-d:7000", Status: "active"},
+	data := DefaultSeedData()
+	if r.seed.Shards > 0 {
+		data = GenerateSeedData(r.seed.Shards, r.seed.OperatorsPerShard)
 	}
-	for _, shard := range shards {
-		if err := r.db.Create(&shard).Error; err != nil {
-			log.Printf("Error creating shard %s: %v", shard.ShardID, err)
+	if r.seed.File != "" {
+		loaded, err := LoadSeedFile(r.seed.File)
+		if err != nil {
+			log.Printf("Error loading seed data, skipping seeding: %v", err)
+			return
 		}
+		data = loaded
 	}
 
-	// Create cross-shard operators (distribute across 4 shards)
-	operators := []models.Operator{
-		{ID: "OPR-001", Name: "John Smith", Role: "Warehouse Manager", AccessLevel: "Admin", ShardID: "shard-a"},
-		{ID: "OPR-002", Name: "Sarah Lee", Role: "Quality Control", AccessLevel: "Standard", ShardID: "shard-a"},
-		{ID: "OPR-003", Name: "Raj Patel", Role: "Logistics Coordinator", AccessLevel: "Standard", ShardID: "shard-b"},
-		{ID: "OPR-004", Name: "Maria Garcia", Role: "Inventory Clerk", AccessLevel: "Basic", ShardID: "shard-b"},
-		{ID: "OPR-005", Name: "Chen Wei", Role: "Warehouse Supervisor", AccessLevel: "Admin", ShardID: "shard-c"},
-		{ID: "OPR-006", Name: "Ahmed Hassan", Role: "Shipping Clerk", AccessLevel: "Standard", ShardID: "shard-c"},
-		{ID: "OPR-007", Name: "Emma Wilson", Role: "Quality Inspector", AccessLevel: "Standard", ShardID: "shard-d"},
-		{ID: "OPR-008", Name: "Luis Rodriguez", Role: "Inventory Manager", AccessLevel: "Admin", ShardID: "shard-d"},
+	log.Println("Seeding database with shard data...")
+
+	// Status and priority are only seeded on insert: re-seeding must not
+	// reactivate a decommissioned shard or undo a priority set since
+	for _, shard := range data.Shards {
+		err := r.upsertSeed(&shard, "shard_id", "client_group", "l2_node_id", "l2_endpoint", "updated_at")
+		if err != nil {
+			log.Printf("Error seeding shard %s: %v", shard.ShardID, err)
+		}
 	}
 
-	for _, operator := range operators {
-		if err := r.db.Create(&operator).Error; err != nil {
-			log.Printf("Error creating operator %s: %v", operator.ID, err)
+	for _, operator := range data.Operators {
+		err := r.upsertSeed(&operator, "operator_id", "name", "role", "access_level", "shard_id")
+		if err != nil {
+			log.Printf("Error seeding operator %s: %v", operator.ID, err)
 		}
 	}
 
-	log.Println("Database seeding completed successfully with 4 shards")
+	log.Printf("Database seeding completed successfully with %d shards and %d operators", len(data.Shards), len(data.Operators))
+}
+
+// upsertSeed inserts a seed row, or updates columns when a row with the same
+// key already exists
+func (r *Repository) upsertSeed(value interface{}, key string, columns ...string) error {
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: key}},
+		DoUpdates: clause.AssignmentColumns(columns),
+	}).Omit(clause.Associations).Create(value).Error
 }
 
 // SetupRpcClient configures the RPC client for BFT consensus
 func (r *Repository) SetupRpcClient(rpcClient *cmtrpc.Local) {
 	r.rpcClient = rpcClient
+	r.txClient = rpcClient
 }
 
-// ReceiveShardCommit handles commits from L2 shards
-func (r *Repository) ReceiveShardCommit(commitReq *ShardedCommitRequest) (*models.Transaction, *RepositoryError) {
-	dbTx := r.db.Begin()
-	if dbTx.Error != nil {
-		return nil, &RepositoryError{
-			Code:    "DATABASE_ERROR",
-			Message: "Failed to start transaction",
-			Detail:  dbTx.Error.Error(),
-		}
+// ResolveCommitShard returns the registered, active shard a commit from
+// shardID would be recorded against, or the error ReceiveShardCommit would
+// reject it with
+func (r *Repository) ResolveCommitShard(shardID string) (*models.ShardInfo, *RepositoryError) {
+	if repoErr := r.checkShardAllowed(shardID); repoErr != nil {
+		return nil, repoErr
 	}
+	return findActiveShard(r.db, shardID)
+}
 
-	// Verify shard exists
+// findActiveShard loads shardID, rejecting unknown and deregistered shards
+func findActiveShard(db *gorm.DB, shardID string) (*models.ShardInfo, *RepositoryError) {
 	var shard models.ShardInfo
-	err := dbTx.Where("shard_id = ?", commitReq.ShardID).First(&shard).Error
+	err := db.Where("shard_id = ?", shardID).First(&shard).Error
 	if err != nil {
-		dbTx.Rollback()
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, &RepositoryError{
 				Code:    "SHARD_NOT_FOUND",
 				Message: "Unknown shard",
-				Detail:  fmt.Sprintf("Shard %s not registered in L1", commitReq.ShardID),
+				Detail:  fmt.Sprintf("Shard %s not registered in L1", shardID),
 			}
 		}
 		return nil, &RepositoryError{
@@ -217,17 +373,74 @@ func (r *Repository) ReceiveShardCommit(commitReq *ShardedCommitRequest) (*model
 		}
 	}
 
+	if shard.Status == ShardStatusInactive {
+		return nil, &RepositoryError{
+			Code:    "SHARD_INACTIVE",
+			Message: "Shard deregistered",
+			Detail:  fmt.Sprintf("Shard %s has been deregistered from L1", shardID),
+		}
+	}
+	return &shard, nil
+}
+
+// ReceiveShardCommit handles commits from L2 shards, returning the stored
+// transaction along with the consensus result that backed it
+func (r *Repository) ReceiveShardCommit(ctx context.Context, commitReq *ShardedCommitRequest) (*models.Transaction, *ConsensusResult, *RepositoryError) {
+	if repoErr := r.checkShardAllowed(commitReq.ShardID); repoErr != nil {
+		return nil, nil, repoErr
+	}
+
+	dbTx := r.db.WithContext(ctx).Begin()
+	if dbTx.Error != nil {
+		return nil, nil, &RepositoryError{
+			Code:    "DATABASE_ERROR",
+			Message: "Failed to start transaction",
+			Detail:  dbTx.Error.Error(),
+		}
+	}
+
+	// Verify shard exists
+	shard, repoErr := findActiveShard(dbTx, commitReq.ShardID)
+	if repoErr != nil {
+		dbTx.Rollback()
+		return nil, nil, repoErr
+	}
+
+	if r.enforceOperatorShard {
+		if repoErr := checkOperatorAttribution(dbTx, commitReq); repoErr != nil {
+			dbTx.Rollback()
+			return nil, nil, repoErr
+		}
+	}
+
 	// Convert session data to JSON
 	sessionDataBytes, err := json.Marshal(commitReq.SessionData)
 	if err != nil {
 		dbTx.Rollback()
-		return nil, &RepositoryError{
+		return nil, nil, &RepositoryError{
 			Code:    "SERIALIZATION_ERROR",
 			Message: "Failed to serialize session data",
 			Detail:  err.Error(),
 		}
 	}
 
+	// A transaction record means the session is already on chain. Answer
+	// before broadcasting so a duplicate never costs a consensus round.
+	var existing int64
+	err = dbTx.Model(&models.Transaction{}).Where("session_id = ?", commitReq.SessionID).Count(&existing).Error
+	if err != nil {
+		dbTx.Rollback()
+		return nil, nil, &RepositoryError{
+			Code:    "DATABASE_ERROR",
+			Message: "Failed to look up existing transaction",
+			Detail:  err.Error(),
+		}
+	}
+	if existing > 0 {
+		dbTx.Rollback()
+		return r.existingTransaction(commitReq.SessionID)
+	}
+
 	// Create session record
 	session := models.Session{
 		ID:          commitReq.SessionID,
@@ -237,20 +450,39 @@ func (r *Repository) ReceiveShardCommit(commitReq *ShardedCommitRequest) (*model
 		Status:      "committed",
 		IsCommitted: true,
 		SessionData: string(sessionDataBytes),
+		Sequence:    commitReq.Sequence,
 	}
 
-	err = dbTx.Create(&session).Error
+	// A session whose orphaned commit was given up on is committed afresh by
+	// its shard rather than reported as existing
+	redrive := dbTx.Model(&models.Session{}).
+		Where("session_id = ? AND shard_id = ? AND status = ?", session.ID, session.ShardID, SessionFailed).
+		Updates(map[string]interface{}{
+			"client_group": session.ClientGroup,
+			"operator_id":  session.OperatorID,
+			"status":       session.Status,
+			"is_committed": true,
+			"session_data": session.SessionData,
+			"sequence":     session.Sequence,
+		})
+	err = redrive.Error
+	switch {
+	case err == nil && redrive.RowsAffected > 0:
+		err = dbTx.Where("session_id = ?", session.ID).First(&session).Error
+	case err == nil:
+		err = dbTx.Create(&session).Error
+	}
 	if err != nil {
 		dbTx.Rollback()
 		pgErr, isPgError := err.(*pgconn.PgError)
 		if isPgError && pgErr.Code == PgErrUniqueViolation {
-			return nil, &RepositoryError{
+			return nil, nil, &RepositoryError{
 				Code:    "SESSION_EXISTS",
 				Message: "Session already exists",
 				Detail:  fmt.Sprintf("Session %s already committed", commitReq.SessionID),
 			}
 		}
-		return nil, &RepositoryError{
+		return nil, nil, &RepositoryError{
 			Code:    "DATABASE_ERROR",
 			Message: "Failed to create session",
 			Detail:  err.Error(),
@@ -260,29 +492,49 @@ func (r *Repository) ReceiveShardCommit(commitReq *ShardedCommitRequest) (*model
 	// Commit to database first
 	err = dbTx.Commit().Error
 	if err != nil {
-		return nil, &RepositoryError{
+		return nil, nil, &RepositoryError{
 			Code:    "DATABASE_ERROR",
 			Message: "Failed to commit database transaction",
 			Detail:  err.Error(),
 		}
 	}
 
-	// Now run L1 BFT consensus
-	consensusResult, repoErr := r.RunConsensus(context.Background(), commitReq)
+	// Now run L1 BFT consensus. A request that gives up once the tx is
+	// broadcast leaves the session to be settled when the broadcast ends,
+	// since the tx may still land.
+	late := func(consensusResult *ConsensusResult, repoErr *RepositoryError) {
+		if repoErr != nil {
+			log.Printf("Commit of session %s failed after its request ended: %s", session.ID, repoErr.Detail)
+			r.db.Delete(&session)
+			return
+		}
+		if _, _, repoErr := r.recordCommit(&session, commitReq, consensusResult); repoErr != nil {
+			log.Printf("Failed to record commit of session %s after its request ended: %s", session.ID, repoErr.Detail)
+		}
+	}
+	consensusResult, repoErr, pending := r.runConsensusSettled(withConsensusPriority(ctx, shard.Priority), commitReq, late)
 	if repoErr != nil {
 		// Rollback session if consensus fails
-		r.db.Delete(&session)
-		return nil, repoErr
+		if !pending {
+			r.db.Delete(&session)
+		}
+		return nil, nil, repoErr
 	}
 
+	return r.recordCommit(&session, commitReq, consensusResult)
+}
+
+// recordCommit stores the transaction hash of a committed session and its
+// transaction record
+func (r *Repository) recordCommit(session *models.Session, commitReq *ShardedCommitRequest, consensusResult *ConsensusResult) (*models.Transaction, *ConsensusResult, *RepositoryError) {
 	// Update session with transaction hash and create transaction record
-	dbTx = r.db.Begin()
+	dbTx := r.db.Begin()
 
 	session.TxHash = &consensusResult.TxHash
-	err = dbTx.Save(&session).Error
+	err := dbTx.Save(session).Error
 	if err != nil {
 		dbTx.Rollback()
-		return nil, &RepositoryError{
+		return nil, nil, &RepositoryError{
 			Code:    "DATABASE_ERROR",
 			Message: "Failed to update session with tx hash",
 			Detail:  err.Error(),
@@ -292,18 +544,24 @@ func (r *Repository) ReceiveShardCommit(commitReq *ShardedCommitRequest) (*model
 	// Create transaction record
 	transaction := models.Transaction{
 		TxHash:      consensusResult.TxHash,
+		TxID:        consensusResult.TxID,
 		SessionID:   commitReq.SessionID,
 		ShardID:     commitReq.ShardID,
 		ClientGroup: commitReq.ClientGroup,
 		BlockHeight: consensusResult.BlockHeight,
 		Status:      "confirmed",
 		Timestamp:   time.Now(),
+		ConsensusMs: consensusResult.Duration.Milliseconds(),
 	}
 
 	err = dbTx.Create(&transaction).Error
 	if err != nil {
 		dbTx.Rollback()
-		return nil, &RepositoryError{
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == PgErrUniqueViolation {
+			return r.existingTransaction(commitReq.SessionID)
+		}
+		return nil, nil, &RepositoryError{
 			Code:    "DATABASE_ERROR",
 			Message: "Failed to create transaction record",
 			Detail:  err.Error(),
@@ -312,75 +570,298 @@ func (r *Repository) ReceiveShardCommit(commitReq *ShardedCommitRequest) (*model
 
 	err = dbTx.Commit().Error
 	if err != nil {
-		return nil, &RepositoryError{
+		return nil, nil, &RepositoryError{
 			Code:    "DATABASE_ERROR",
 			Message: "Failed to commit final transaction",
 			Detail:  err.Error(),
 		}
 	}
 
-	return &transaction, nil
+	return &transaction, consensusResult, nil
+}
+
+// existingTransaction handles a commit for a session that already has a
+// transaction record, found before broadcasting or, when two commits race,
+// by the record's unique key. The stored record wins: the session is pointed
+// at its tx hash and the record is returned with TRANSACTION_EXISTS.
+func (r *Repository) existingTransaction(sessionID string) (*models.Transaction, *ConsensusResult, *RepositoryError) {
+	var existing models.Transaction
+	if err := r.db.Where("session_id = ?", sessionID).First(&existing).Error; err != nil {
+		return nil, nil, &RepositoryError{
+			Code:    "DATABASE_ERROR",
+			Message: "Failed to load existing transaction record",
+			Detail:  err.Error(),
+		}
+	}
+
+	err := r.db.Model(&models.Session{}).Where("session_id = ?", sessionID).
+		Update("tx_hash", existing.TxHash).Error
+	if err != nil {
+		return nil, nil, &RepositoryError{
+			Code:    "DATABASE_ERROR",
+			Message: "Failed to update session with existing tx hash",
+			Detail:  err.Error(),
+		}
+	}
+
+	return &existing, nil, &RepositoryError{
+		Code:    "TRANSACTION_EXISTS",
+		Message: "Transaction already exists",
+		Detail:  fmt.Sprintf("Session %s already has transaction %s", sessionID, existing.TxHash),
+	}
+}
+
+// checkOperatorAttribution verifies that the commit's operator is registered
+// in L1 under the committing shard, so a shard can't attribute commits to
+// operators it doesn't have
+func checkOperatorAttribution(db *gorm.DB, commitReq *ShardedCommitRequest) *RepositoryError {
+	if commitReq.OperatorID == "" {
+		return &RepositoryError{
+			Code:    "OPERATOR_MISMATCH",
+			Message: "Commit is not attributed to an operator",
+			Detail:  fmt.Sprintf("Commit for session %s has no operator_id", commitReq.SessionID),
+		}
+	}
+
+	var operator models.Operator
+	err := db.Where("operator_id = ?", commitReq.OperatorID).First(&operator).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return &RepositoryError{
+				Code:    "OPERATOR_MISMATCH",
+				Message: "Unknown operator",
+				Detail:  fmt.Sprintf("Operator %s not registered in L1", commitReq.OperatorID),
+			}
+		}
+		return &RepositoryError{
+			Code:    "DATABASE_ERROR",
+			Message: "Database error",
+			Detail:  err.Error(),
+		}
+	}
+	if operator.ShardID != commitReq.ShardID {
+		return &RepositoryError{
+			Code:    "OPERATOR_MISMATCH",
+			Message: "Operator belongs to another shard",
+			Detail:  fmt.Sprintf("Operator %s belongs to shard %s, not %s", commitReq.OperatorID, operator.ShardID, commitReq.ShardID),
+		}
+	}
+	return nil
 }
 
 // RunConsensus submits data to L1 BFT consensus
 func (r *Repository) RunConsensus(ctx context.Context, payload ConsensusPayload) (*ConsensusResult, *RepositoryError) {
-	// Serialize the payload
-	payloadBytes, err := json.Marshal(payload)
+	result, repoErr, _ := r.runConsensusSettled(ctx, payload, nil)
+	return result, repoErr
+}
+
+// runConsensusSettled is RunConsensus for callers that need the outcome of a
+// broadcast they stopped waiting for. When ctx ends after the tx was
+// broadcast it returns the context error with pending set, and late later
+// receives the outcome, since the tx may still land in a block.
+func (r *Repository) runConsensusSettled(ctx context.Context, payload ConsensusPayload, late func(*ConsensusResult, *RepositoryError)) (*ConsensusResult, *RepositoryError, bool) {
+	ctx, span := tracing.Tracer().Start(ctx, "RunConsensus")
+	defer span.End()
+
+	result, repoErr, pending := r.runConsensus(ctx, payload, late)
+	if repoErr != nil {
+		span.SetStatus(codes.Error, repoErr.Code)
+		return nil, repoErr, pending
+	}
+	span.SetAttributes(
+		attribute.String("l1.tx_hash", result.TxHash),
+		attribute.Int64("l1.block_height", result.BlockHeight),
+		attribute.Int("l1.votes", result.Votes),
+	)
+	return result, nil, false
+}
+
+// runConsensus broadcasts payload and waits for it to be committed
+func (r *Repository) runConsensus(ctx context.Context, payload ConsensusPayload, late func(*ConsensusResult, *RepositoryError)) (*ConsensusResult, *RepositoryError, bool) {
+	// Serialize the payload canonically so every validator sees identical tx bytes
+	payloadBytes, err := CanonicalJSON(payload)
 	if err != nil {
 		return nil, &RepositoryError{
 			Code:    "SERIALIZATION_ERROR",
 			Message: "Failed to serialize consensus payload",
 			Detail:  err.Error(),
-		}
+		}, false
 	}
 
 	// Create consensus transaction
 	consensusTx := cmttypes.Tx(payloadBytes)
 
-	// Use a channel for async consensus
-	done := make(chan struct {
-		result *cmtrpctypes.ResultBroadcastTxCommit
-		err    error
-	}, 1)
+	// Wait for a consensus slot so bursts queue here instead of piling onto
+	// the node; waiters give up when the request does
+	if err := r.consensusSlots.acquire(ctx, consensusPriority(ctx)); err != nil {
+		return nil, consensusContextError(ctx), false
+	}
+
+	type outcome struct {
+		result *ConsensusResult
+		err    *RepositoryError
+	}
+	done := make(chan outcome)
+	abandoned := make(chan struct{})
 
+	// Once broadcast the tx can't be recalled, so the broadcast runs on past
+	// the request and its outcome goes to late if the caller gave up. It is
+	// tracked on its own so Drain also waits for it.
+	start := time.Now()
+	r.inflight.Add(1)
 	go func() {
-		result, err := r.rpcClient.BroadcastTxCommit(ctx, consensusTx)
-		done <- struct {
-			result *cmtrpctypes.ResultBroadcastTxCommit
-			err    error
-		}{result, err}
+		defer r.inflight.Done()
+		defer r.consensusSlots.release()
+		broadcastCtx := context.WithoutCancel(ctx)
+		result, err := r.broadcast(broadcastCtx, consensusTx)
+		var o outcome
+		o.result, o.err = r.consensusOutcome(broadcastCtx, result, err, time.Since(start))
+		select {
+		case done <- o:
+		case <-abandoned:
+			if late != nil {
+				late(o.result, o.err)
+			}
+		}
 	}()
 
 	// Wait for consensus result
 	select {
 	case <-ctx.Done():
+		close(abandoned)
+		return nil, consensusContextError(ctx), true
+	case o := <-done:
+		return o.result, o.err, false
+	}
+}
+
+// consensusOutcome turns the result of a broadcast into the consensus result
+// or the error explaining why the tx was not stored
+func (r *Repository) consensusOutcome(ctx context.Context, result *broadcastResult, err error, duration time.Duration) (*ConsensusResult, *RepositoryError) {
+	if err != nil {
 		return nil, &RepositoryError{
-			Code:    "CONSENSUS_TIMEOUT",
-			Message: "Consensus operation timed out",
+			Code:    "CONSENSUS_ERROR",
+			Message: "Failed to commit to blockchain",
+			Detail:  err.Error(),
+		}
+	}
+
+	if result.CheckTxCode != 0 {
+		return nil, &RepositoryError{
+			Code:    "CONSENSUS_ERROR",
+			Message: "Blockchain rejected transaction",
+			Detail:  fmt.Sprintf("CheckTx code: %d", result.CheckTxCode),
+		}
+	}
+
+	// The tx made it into a block but wasn't stored
+	if result.TxCode != 0 {
+		return nil, &RepositoryError{
+			Code:    "CONSENSUS_ERROR",
+			Message: "Blockchain failed to store transaction",
+			Detail:  fmt.Sprintf("Tx code %d: %s", result.TxCode, result.TxLog),
+		}
+	}
+
+	return &ConsensusResult{
+		TxHash:      hex.EncodeToString(result.Hash),
+		TxID:        string(result.TxData),
+		BlockHeight: result.Height,
+		Code:        result.CheckTxCode,
+		Votes:       r.commitVotes(ctx, result.Height),
+		Duration:    duration,
+	}, nil
+}
+
+// consensusContextError reports why a consensus operation's context ended
+func consensusContextError(ctx context.Context) *RepositoryError {
+	if errors.Is(ctx.Err(), context.Canceled) {
+		return &RepositoryError{
+			Code:    "CONSENSUS_CANCELED",
+			Message: "Consensus operation canceled by client",
 			Detail:  ctx.Err().Error(),
 		}
-	case result := <-done:
-		if result.err != nil {
-			return nil, &RepositoryError{
-				Code:    "CONSENSUS_ERROR",
-				Message: "Failed to commit to blockchain",
-				Detail:  result.err.Error(),
-			}
+	}
+	return &RepositoryError{
+		Code:    "CONSENSUS_TIMEOUT",
+		Message: "Consensus operation timed out",
+		Detail:  ctx.Err().Error(),
+	}
+}
+
+// commitVotes counts the validator precommits that committed a block. It
+// returns 0 if the commit can't be loaded; the vote count is informational.
+func (r *Repository) commitVotes(ctx context.Context, height int64) int {
+	commit, err := r.txClient.Commit(ctx, &height)
+	if err != nil {
+		log.Printf("Failed to load commit for height %d: %v", height, err)
+		return 0
+	}
+
+	votes := 0
+	for _, sig := range commit.Commit.Signatures {
+		if sig.BlockIDFlag == cmttypes.BlockIDFlagCommit {
+			votes++
 		}
+	}
+	return votes
+}
 
-		if result.result.CheckTx.Code != 0 {
-			return nil, &RepositoryError{
-				Code:    "CONSENSUS_ERROR",
-				Message: "Blockchain rejected transaction",
-				Detail:  fmt.Sprintf("CheckTx code: %d", result.result.CheckTx.Code),
-			}
+// Drain waits for in-flight consensus broadcasts to finish, giving up when
+// ctx is done. Call it on shutdown before stopping the CometBFT node.
+func (r *Repository) Drain(ctx context.Context) error {
+	drained := make(chan struct{})
+	go func() {
+		r.inflight.Wait()
+		close(drained)
+	}()
+
+	select {
+	case <-drained:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("draining in-flight commits: %w", ctx.Err())
+	}
+}
+
+// VerifiedTransaction is a shard commit as stored in L1 consensus state
+type VerifiedTransaction struct {
+	TxID    string
+	Status  string
+	Payload []byte
+}
+
+// VerifyTransaction looks a transaction up in consensus state through the
+// ABCI verify query, independently of the PostgreSQL mirror
+func (r *Repository) VerifyTransaction(ctx context.Context, txID string) (*VerifiedTransaction, *RepositoryError) {
+	result, err := r.rpcClient.ABCIQuery(ctx, "", []byte("verify:"+txID))
+	if err != nil {
+		return nil, &RepositoryError{
+			Code:    "QUERY_ERROR",
+			Message: "Failed to query consensus state",
+			Detail:  err.Error(),
 		}
+	}
 
-		return &ConsensusResult{
-			TxHash:      hex.EncodeToString(result.result.Hash),
-			BlockHeight: result.result.Height,
-			Code:        result.result.CheckTx.Code,
+	switch result.Response.Code {
+	case 0:
+		return &VerifiedTransaction{
+			TxID:    txID,
+			Status:  result.Response.Log,
+			Payload: result.Response.Value,
 		}, nil
+	case 1:
+		return nil, &RepositoryError{
+			Code:    "TRANSACTION_NOT_FOUND",
+			Message: "Transaction not found",
+			Detail:  fmt.Sprintf("Transaction with ID %s not found in consensus state", txID),
+		}
+	default:
+		return nil, &RepositoryError{
+			Code:    "QUERY_ERROR",
+			Message: "Failed to query consensus state",
+			Detail:  result.Response.Log,
+		}
 	}
 }
 
@@ -389,7 +870,7 @@ func (r *Repository) RunConsensus(ctx context.Context, payload ConsensusPayload)
 // GetSessionsByClientGroup retrieves all sessions for a client group across shards
 func (r *Repository) GetSessionsByClientGroup(clientGroup string) ([]models.Session, *RepositoryError) {
 	var sessions []models.Session
-	err := r.db.Preload("Shard").Preload("Transaction").
+	err := r.reader().Preload("Shard").Preload("Transaction").
 		Where("client_group = ?", clientGroup).Find(&sessions).Error
 
 	if err != nil {
@@ -403,27 +884,185 @@ func (r *Repository) GetSessionsByClientGroup(clientGroup string) ([]models.Sess
 	return sessions, nil
 }
 
-// GetSessionsByShard retrieves all sessions from a specific shard
-func (r *Repository) GetSessionsByShard(shardID string) ([]models.Session, *RepositoryError) {
+// CountSessionsByGroup counts the sessions of a client group without loading
+// them. An empty status counts every status.
+func (r *Repository) CountSessionsByGroup(clientGroup, status string) (int64, *RepositoryError) {
+	query := r.reader().Model(&models.Session{}).Where("client_group = ?", clientGroup)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var count int64
+	if err := query.Count(&count).Error; err != nil {
+		return 0, &RepositoryError{
+			Code:    "DATABASE_ERROR",
+			Message: "Failed to count sessions",
+			Detail:  err.Error(),
+		}
+	}
+	return count, nil
+}
+
+// GetSessionByID retrieves a single session with its shard and transaction
+func (r *Repository) GetSessionByID(sessionID string) (*models.Session, *RepositoryError) {
+	var session models.Session
+	err := r.reader().Preload("Shard").Preload("Transaction").
+		Where("session_id = ?", sessionID).First(&session).Error
+
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, &RepositoryError{
+				Code:    "SESSION_NOT_FOUND",
+				Message: "Session not found",
+				Detail:  fmt.Sprintf("Session with ID %s not found", sessionID),
+			}
+		}
+		return nil, &RepositoryError{
+			Code:    "DATABASE_ERROR",
+			Message: "Failed to query session",
+			Detail:  err.Error(),
+		}
+	}
+
+	return &session, nil
+}
+
+// SessionFilter narrows and pages a session listing. Empty fields match
+// everything; From and To bound created_at inclusively when set.
+type SessionFilter struct {
+	Status      string
+	ClientGroup string
+	ShardID     string
+	OperatorID  string
+	From        time.Time
+	To          time.Time
+	Limit       int
+	Offset      int
+}
+
+// SearchSessions retrieves a page of sessions matching any combination of
+// filters along with the total number matching
+func (r *Repository) SearchSessions(filter SessionFilter) ([]models.Session, int64, *RepositoryError) {
+	return r.listSessions(r.reader().Model(&models.Session{}), filter, "filters")
+}
+
+// GetSessionsByShard retrieves a page of sessions from a specific shard along
+// with the total number of sessions matching the filter
+func (r *Repository) GetSessionsByShard(shardID string, filter SessionFilter) ([]models.Session, int64, *RepositoryError) {
+	return r.listSessions(r.reader().Model(&models.Session{}).Where("shard_id = ?", shardID), filter, "shard")
+}
+
+// GetSessionsByOperator retrieves a page of the sessions an operator handled
+// across all shards along with the total number matching the filter
+func (r *Repository) GetSessionsByOperator(operatorID string, filter SessionFilter) ([]models.Session, int64, *RepositoryError) {
+	var count int64
+	if err := r.reader().Model(&models.Operator{}).Where("operator_id = ?", operatorID).Count(&count).Error; err != nil {
+		return nil, 0, &RepositoryError{
+			Code:    "DATABASE_ERROR",
+			Message: "Failed to query operator",
+			Detail:  err.Error(),
+		}
+	}
+	if count == 0 {
+		return nil, 0, &RepositoryError{
+			Code:    "OPERATOR_NOT_FOUND",
+			Message: "Operator not found",
+			Detail:  fmt.Sprintf("Operator with ID %s not found", operatorID),
+		}
+	}
+
+	return r.listSessions(r.reader().Model(&models.Session{}).Where("operator_id = ?", operatorID), filter, "operator")
+}
+
+// listSessions applies filter to a session query, describing the listing as
+// "sessions by <scope>" in errors
+func (r *Repository) listSessions(query *gorm.DB, filter SessionFilter, scope string) ([]models.Session, int64, *RepositoryError) {
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.ClientGroup != "" {
+		query = query.Where("client_group = ?", filter.ClientGroup)
+	}
+	if filter.ShardID != "" {
+		query = query.Where("shard_id = ?", filter.ShardID)
+	}
+	if filter.OperatorID != "" {
+		query = query.Where("operator_id = ?", filter.OperatorID)
+	}
+	if !filter.From.IsZero() {
+		query = query.Where("created_at >= ?", filter.From)
+	}
+	if !filter.To.IsZero() {
+		query = query.Where("created_at <= ?", filter.To)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, &RepositoryError{
+			Code:    "DATABASE_ERROR",
+			Message: "Failed to count sessions by " + scope,
+			Detail:  err.Error(),
+		}
+	}
+
 	var sessions []models.Session
-	err := r.db.Preload("Shard").Preload("Transaction").
-		Where("shard_id = ?", shardID).Find(&sessions).Error
+	err := query.Preload("Shard").Preload("Transaction").
+		Order("created_at DESC, session_id").
+		Limit(filter.Limit).Offset(filter.Offset).
+		Find(&sessions).Error
 
+	if err != nil {
+		return nil, 0, &RepositoryError{
+			Code:    "DATABASE_ERROR",
+			Message: "Failed to query sessions by " + scope,
+			Detail:  err.Error(),
+		}
+	}
+
+	return sessions, total, nil
+}
+
+// ListTransactionsSince returns transactions committed above sinceHeight in
+// ascending height order. A block is never split across pages: when the limit
+// lands inside a block, the rest of that block is included so the caller can
+// resume from the last height it received.
+func (r *Repository) ListTransactionsSince(sinceHeight int64, limit int) ([]models.Transaction, *RepositoryError) {
+	// Both queries go to the same connection so the page is consistent
+	db := r.reader()
+	var transactions []models.Transaction
+	err := db.Where("block_height > ?", sinceHeight).
+		Order("block_height ASC").Order("session_id ASC").
+		Limit(limit).Find(&transactions).Error
 	if err != nil {
 		return nil, &RepositoryError{
 			Code:    "DATABASE_ERROR",
-			Message: "Failed to query sessions by shard",
+			Message: "Failed to list transactions",
 			Detail:  err.Error(),
 		}
 	}
 
-	return sessions, nil
+	if len(transactions) == limit && limit > 0 {
+		last := transactions[len(transactions)-1]
+		var rest []models.Transaction
+		err = db.Where("block_height = ? AND session_id > ?", last.BlockHeight, last.SessionID).
+			Order("session_id ASC").Find(&rest).Error
+		if err != nil {
+			return nil, &RepositoryError{
+				Code:    "DATABASE_ERROR",
+				Message: "Failed to list transactions",
+				Detail:  err.Error(),
+			}
+		}
+		transactions = append(transactions, rest...)
+	}
+
+	return transactions, nil
 }
 
 // GetTransactionByHash retrieves transaction by hash (cross-shard)
 func (r *Repository) GetTransactionByHash(txHash string) (*models.Transaction, *RepositoryError) {
 	var transaction models.Transaction
-	err := r.db.Preload("Session").Preload("Shard").
+	err := r.reader().Preload("Session").Preload("Shard").
 		Where("tx_hash = ?", txHash).First(&transaction).Error
 
 	if err != nil {
@@ -444,6 +1083,78 @@ func (r *Repository) GetTransactionByHash(txHash string) (*models.Transaction, *
 	return &transaction, nil
 }
 
+// GetTransactionsByHashes retrieves the transactions with any of txHashes in a
+// single query. Hashes without a transaction are simply absent from the result.
+func (r *Repository) GetTransactionsByHashes(txHashes []string) ([]models.Transaction, *RepositoryError) {
+	var transactions []models.Transaction
+	if len(txHashes) == 0 {
+		return transactions, nil
+	}
+
+	err := r.reader().Where("tx_hash IN ?", txHashes).Find(&transactions).Error
+	if err != nil {
+		return nil, &RepositoryError{
+			Code:    "DATABASE_ERROR",
+			Message: "Failed to query transactions",
+			Detail:  err.Error(),
+		}
+	}
+	return transactions, nil
+}
+
+// TransactionStats summarizes consensus latency across committed transactions.
+// Transactions restored by reconciliation have no recorded latency and are
+// left out of the averages.
+type TransactionStats struct {
+	Transactions   int64   `json:"transactions"`
+	Measured       int64   `json:"measured"`
+	AvgConsensusMs float64 `json:"avg_consensus_ms"`
+	MaxConsensusMs int64   `json:"max_consensus_ms"`
+}
+
+// GetTransactionStats aggregates consensus latency over all transactions
+func (r *Repository) GetTransactionStats() (*TransactionStats, *RepositoryError) {
+	var stats TransactionStats
+	err := r.db.Model(&models.Transaction{}).
+		Select("COUNT(*) AS transactions, " +
+			"COUNT(*) FILTER (WHERE consensus_ms > 0) AS measured, " +
+			"COALESCE(AVG(consensus_ms) FILTER (WHERE consensus_ms > 0), 0) AS avg_consensus_ms, " +
+			"COALESCE(MAX(consensus_ms), 0) AS max_consensus_ms").
+		Scan(&stats).Error
+
+	if err != nil {
+		return nil, &RepositoryError{
+			Code:    "DATABASE_ERROR",
+			Message: "Failed to query transaction stats",
+			Detail:  err.Error(),
+		}
+	}
+
+	return &stats, nil
+}
+
+// RecordShardHeartbeat sets a shard's last_seen to now. It leaves updated_at
+// alone so heartbeats don't look like registry changes.
+func (r *Repository) RecordShardHeartbeat(shardID string) (time.Time, *RepositoryError) {
+	now := time.Now()
+	result := r.db.Model(&models.ShardInfo{}).Where("shard_id = ?", shardID).UpdateColumn("last_seen", now)
+	if result.Error != nil {
+		return now, &RepositoryError{
+			Code:    "DATABASE_ERROR",
+			Message: "Failed to record heartbeat",
+			Detail:  result.Error.Error(),
+		}
+	}
+	if result.RowsAffected == 0 {
+		return now, &RepositoryError{
+			Code:    "SHARD_NOT_FOUND",
+			Message: "Unknown shard",
+			Detail:  fmt.Sprintf("Shard %s not registered in L1", shardID),
+		}
+	}
+	return now, nil
+}
+
 // GetAllShards retrieves all registered shards
 func (r *Repository) GetAllShards() ([]models.ShardInfo, *RepositoryError) {
 	var shards []models.ShardInfo
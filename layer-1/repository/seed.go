@@ -0,0 +1,110 @@
+package repository
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/ahmadzakiakmal/thesis-extension/layer-1/repository/models"
+)
+
+// SeedConfig controls what Seed writes into the database
+type SeedConfig struct {
+	// Enabled turns seeding on. Production deployments should disable it.
+	Enabled bool
+	// File is an optional JSON file replacing the built-in seed data
+	File string
+	// Shards, when positive, replaces the built-in data with generated shards
+	// shard-1..N, each with OperatorsPerShard operators. File takes precedence.
+	Shards            int
+	OperatorsPerShard int
+}
+
+// DefaultOperatorsPerShard matches the built-in data: 8 operators over 4 shards
+const DefaultOperatorsPerShard = 2
+
+// SeedData is the data written by Seed. Seed files use the same shape, with
+// entries keyed by their Go field names, e.g.
+// {"shards": [{"ShardID": "shard-a", ...}], "operators": [{"ID": "OPR-001", ...}]}
+type SeedData struct {
+	Shards    []models.ShardInfo `json:"shards"`
+	Operators []models.Operator  `json:"operators"`
+}
+
+// DefaultSeedData returns the built-in demo data: 4 shards and 8 operators
+func DefaultSeedData() *SeedData {
+	return &SeedData{
+		Shards: []models.ShardInfo{
+			{ShardID: "shard-a", ClientGroup: "group-a", L2NodeID: "l2-node-a", L2Endpoint: "http://l2-shard-a:7000", Status: "active"},
+			{ShardID: "shard-b", ClientGroup: "group-b", L2NodeID: "l2-node-b", L2Endpoint: "http://l2-shard-b:7000", Status: "active"},
+			{ShardID: "shard-c", ClientGroup: "group-c", L2NodeID: "l2-node-c", L2Endpoint: "http://l2-shard-c:7000", Status: "active"},
+			{ShardID: "shard-d", ClientGroup: "group-d", L2NodeID: "l2-node-d", L2Endpoint: "http://l2-shard-d:7000", Status: "active"},
+		},
+		// Cross-shard operators, distributed across the 4 shards
+		Operators: []models.Operator{
+			{ID: "OPR-001", Name: "John Smith", Role: "Warehouse Manager", AccessLevel: "Admin", ShardID: "shard-a"},
+			{ID: "OPR-002", Name: "Sarah Lee", Role: "Quality Control", AccessLevel: "Standard", ShardID: "shard-a"},
+			{ID: "OPR-003", Name: "Raj Patel", Role: "Logistics Coordinator", AccessLevel: "Standard", ShardID: "shard-b"},
+			{ID: "OPR-004", Name: "Maria Garcia", Role: "Inventory Clerk", AccessLevel: "Basic", ShardID: "shard-b"},
+			{ID: "OPR-005", Name: "Chen Wei", Role: "Warehouse Supervisor", AccessLevel: "Admin", ShardID: "shard-c"},
+			{ID: "OPR-006", Name: "Ahmed Hassan", Role: "Shipping Clerk", AccessLevel: "Standard", ShardID: "shard-c"},
+			{ID: "OPR-007", Name: "Emma Wilson", Role: "Quality Inspector", AccessLevel: "Standard", ShardID: "shard-d"},
+			{ID: "OPR-008", Name: "Luis Rodriguez", Role: "Inventory Manager", AccessLevel: "Admin", ShardID: "shard-d"},
+		},
+	}
+}
+
+// generatedOperatorLevels cycles through access levels for generated
+// operators so every shard has an admin and the other levels are represented
+var generatedOperatorLevels = []struct{ AccessLevel, Role string }{
+	{"Admin", "Warehouse Manager"},
+	{"Standard", "Quality Control"},
+	{"Basic", "Inventory Clerk"},
+}
+
+// GenerateSeedData builds shards shard-1..shards with client groups
+// group-1..shards and operatorsPerShard operators each
+func GenerateSeedData(shards, operatorsPerShard int) *SeedData {
+	data := &SeedData{
+		Shards:    make([]models.ShardInfo, 0, shards),
+		Operators: make([]models.Operator, 0, shards*operatorsPerShard),
+	}
+
+	for i := 1; i <= shards; i++ {
+		shardID := fmt.Sprintf("shard-%d", i)
+		data.Shards = append(data.Shards, models.ShardInfo{
+			ShardID:     shardID,
+			ClientGroup: fmt.Sprintf("group-%d", i),
+			L2NodeID:    fmt.Sprintf("l2-node-%d", i),
+			L2Endpoint:  fmt.Sprintf("http://l2-shard-%d:7000", i),
+			Status:      "active",
+		})
+
+		for j := 1; j <= operatorsPerShard; j++ {
+			level := generatedOperatorLevels[(j-1)%len(generatedOperatorLevels)]
+			data.Operators = append(data.Operators, models.Operator{
+				ID:          fmt.Sprintf("OPR-%d-%03d", i, j),
+				Name:        fmt.Sprintf("Operator %d-%d", i, j),
+				Role:        level.Role,
+				AccessLevel: level.AccessLevel,
+				ShardID:     shardID,
+			})
+		}
+	}
+
+	return data
+}
+
+// LoadSeedFile reads seed data from a JSON file
+func LoadSeedFile(path string) (*SeedData, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading seed file: %w", err)
+	}
+
+	var data SeedData
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return nil, fmt.Errorf("parsing seed file %s: %w", path, err)
+	}
+	return &data, nil
+}
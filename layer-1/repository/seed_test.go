@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ahmadzakiakmal/thesis-extension/layer-1/repository/models"
+)
+
+// writeSeedFile writes data as a seed file and returns its path
+func writeSeedFile(t *testing.T, data *SeedData) string {
+	t.Helper()
+	raw, err := json.Marshal(data)
+	if err != nil {
+		t.Fatalf("encoding seed data: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "seed.json")
+	if err := os.WriteFile(path, raw, 0o600); err != nil {
+		t.Fatalf("writing seed file: %v", err)
+	}
+	return path
+}
+
+func TestSeedAppliesChangedValuesOnNextStartup(t *testing.T) {
+	r := testRepository(t)
+	// testShard is created by hand, so it stands in for a row outside the
+	// seed list
+	unlisted := testShard(t, r)
+	shard := models.ShardInfo{
+		ShardID:     unlisted.ShardID + "-seeded",
+		ClientGroup: "group-old",
+		L2NodeID:    "node-old",
+		L2Endpoint:  "http://old:7000",
+	}
+	operator := models.Operator{ID: shard.ShardID + "-OPR", Name: "Old Name", AccessLevel: "Basic", ShardID: shard.ShardID}
+	t.Cleanup(func() {
+		r.db.Delete(&models.Operator{}, "operator_id = ?", operator.ID)
+		r.db.Delete(&models.ShardInfo{}, "shard_id = ?", shard.ShardID)
+	})
+
+	seed := &SeedData{Shards: []models.ShardInfo{shard}, Operators: []models.Operator{operator}}
+	r.SetSeedConfig(SeedConfig{Enabled: true, File: writeSeedFile(t, seed)})
+	r.Seed()
+
+	// Status and priority are runtime state that re-seeding must keep
+	err := r.db.Model(&models.ShardInfo{}).Where("shard_id = ?", shard.ShardID).
+		Updates(map[string]interface{}{"status": "decommissioned", "priority": 5}).Error
+	if err != nil {
+		t.Fatalf("updating shard: %v", err)
+	}
+
+	seed.Shards[0].ClientGroup = "group-new"
+	seed.Shards[0].L2Endpoint = "http://new:7000"
+	seed.Operators[0].Name = "New Name"
+	seed.Operators[0].AccessLevel = "Admin"
+	restarted := testRepository(t)
+	restarted.SetSeedConfig(SeedConfig{Enabled: true, File: writeSeedFile(t, seed)})
+	restarted.Seed()
+
+	var gotShard models.ShardInfo
+	if err := r.db.First(&gotShard, "shard_id = ?", shard.ShardID).Error; err != nil {
+		t.Fatalf("loading shard: %v", err)
+	}
+	if gotShard.ClientGroup != "group-new" || gotShard.L2Endpoint != "http://new:7000" {
+		t.Errorf("shard = %+v, want the re-seeded group and endpoint", gotShard)
+	}
+	if gotShard.Status != "decommissioned" || gotShard.Priority != 5 {
+		t.Errorf("shard status = %s priority = %d, want them kept", gotShard.Status, gotShard.Priority)
+	}
+
+	var gotOperator models.Operator
+	if err := r.db.First(&gotOperator, "operator_id = ?", operator.ID).Error; err != nil {
+		t.Fatalf("loading operator: %v", err)
+	}
+	if gotOperator.Name != "New Name" || gotOperator.AccessLevel != "Admin" {
+		t.Errorf("operator = %+v, want the re-seeded name and access level", gotOperator)
+	}
+
+	var count int64
+	r.db.Model(&models.ShardInfo{}).Where("shard_id = ?", unlisted.ShardID).Count(&count)
+	if count != 1 {
+		t.Error("re-seeding removed a shard that is not in the seed list")
+	}
+}
@@ -0,0 +1,93 @@
+package repository
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/ahmadzakiakmal/thesis-extension/layer-1/repository/models"
+)
+
+// sinceSessions returns the session IDs of transactions, in order
+func sinceSessions(transactions []models.Transaction) string {
+	sessionIDs := make([]string, len(transactions))
+	for i, transaction := range transactions {
+		sessionIDs[i] = transaction.SessionID
+	}
+	return fmt.Sprint(sessionIDs)
+}
+
+// seedHeights commits A and B at base+1, C at base+2 and D at base+3
+func seedHeights(t *testing.T, r *Repository, shard *models.ShardInfo, base int64) {
+	t.Helper()
+	testCommit(t, r, shard, shard.ShardID+"-B", base+1)
+	testCommit(t, r, shard, shard.ShardID+"-A", base+1)
+	testCommit(t, r, shard, shard.ShardID+"-C", base+2)
+	testCommit(t, r, shard, shard.ShardID+"-D", base+3)
+}
+
+func TestListTransactionsSince(t *testing.T) {
+	r := testRepository(t)
+	shard := testShard(t, r)
+	base := testHeight()
+	seedHeights(t, r, shard, base)
+
+	id := func(suffix string) string { return shard.ShardID + "-" + suffix }
+	tests := []struct {
+		since int64
+		want  []string
+	}{
+		{base, []string{id("A"), id("B"), id("C"), id("D")}},
+		{base + 1, []string{id("C"), id("D")}},
+		{base + 2, []string{id("D")}},
+		{base + 3, []string{}},
+	}
+
+	for _, tt := range tests {
+		transactions, repoErr := r.ListTransactionsSince(tt.since, 100)
+		if repoErr != nil {
+			t.Fatalf("ListTransactionsSince(%d): %v", tt.since, repoErr)
+		}
+		if got := sinceSessions(transactions); got != fmt.Sprint(tt.want) {
+			t.Errorf("since base+%d = %s, want %s in height then session order", tt.since-base, got, tt.want)
+		}
+	}
+}
+
+func TestListTransactionsSinceKeepsBlocksWhole(t *testing.T) {
+	r := testRepository(t)
+	shard := testShard(t, r)
+	base := testHeight()
+	seedHeights(t, r, shard, base)
+
+	// A limit landing inside base+1 still returns all of it
+	page, repoErr := r.ListTransactionsSince(base, 1)
+	if repoErr != nil {
+		t.Fatalf("ListTransactionsSince: %v", repoErr)
+	}
+	want := fmt.Sprint([]string{shard.ShardID + "-A", shard.ShardID + "-B"})
+	if got := sinceSessions(page); got != want {
+		t.Fatalf("first page = %s, want %s", got, want)
+	}
+
+	// Resuming from the last height seen visits every transaction once
+	var seen []models.Transaction
+	since := base
+	for pages := 0; ; pages++ {
+		if pages > 4 {
+			t.Fatal("paging did not finish")
+		}
+		page, repoErr := r.ListTransactionsSince(since, 1)
+		if repoErr != nil {
+			t.Fatalf("ListTransactionsSince(%d): %v", since, repoErr)
+		}
+		if len(page) == 0 {
+			break
+		}
+		seen = append(seen, page...)
+		since = page[len(page)-1].BlockHeight
+	}
+	want = fmt.Sprint([]string{shard.ShardID + "-A", shard.ShardID + "-B", shard.ShardID + "-C", shard.ShardID + "-D"})
+	if got := sinceSessions(seen); got != want {
+		t.Errorf("paged through %s, want %s", got, want)
+	}
+}
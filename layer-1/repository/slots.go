@@ -0,0 +1,124 @@
+package repository
+
+import (
+	"container/heap"
+	"context"
+	"sync"
+)
+
+// consensusPriorityKey carries the priority of a consensus submission
+type consensusPriorityKey struct{}
+
+// withConsensusPriority marks the consensus submission made with ctx as
+// having priority, e.g. that of the committing shard
+func withConsensusPriority(ctx context.Context, priority int) context.Context {
+	return context.WithValue(ctx, consensusPriorityKey{}, priority)
+}
+
+// consensusPriority returns the priority set by withConsensusPriority, 0 if
+// none was
+func consensusPriority(ctx context.Context) int {
+	priority, _ := ctx.Value(consensusPriorityKey{}).(int)
+	return priority
+}
+
+// slotQueue bounds concurrent consensus submissions. While slots are free it
+// grants them immediately; once they run out, waiters are served highest
+// priority first and in arrival order within a priority.
+type slotQueue struct {
+	mu      sync.Mutex
+	free    int
+	seq     uint64
+	waiters slotWaiters
+}
+
+// slotWaiter is a submission waiting for a slot. ready is closed once the
+// slot is handed to it.
+type slotWaiter struct {
+	priority int
+	seq      uint64
+	ready    chan struct{}
+	index    int // position in the heap, -1 once granted or abandoned
+}
+
+func newSlotQueue(slots int) *slotQueue {
+	return &slotQueue{free: slots}
+}
+
+// acquire waits for a slot, giving up with ctx's error when ctx ends first
+func (q *slotQueue) acquire(ctx context.Context, priority int) error {
+	q.mu.Lock()
+	if q.free > 0 && len(q.waiters) == 0 {
+		q.free--
+		q.mu.Unlock()
+		return nil
+	}
+	waiter := &slotWaiter{priority: priority, seq: q.seq, ready: make(chan struct{})}
+	q.seq++
+	heap.Push(&q.waiters, waiter)
+	q.mu.Unlock()
+
+	select {
+	case <-waiter.ready:
+		return nil
+	case <-ctx.Done():
+	}
+
+	q.mu.Lock()
+	granted := waiter.index < 0
+	if !granted {
+		heap.Remove(&q.waiters, waiter.index)
+		waiter.index = -1
+	}
+	q.mu.Unlock()
+	if granted {
+		// The slot arrived as ctx ended; pass it on
+		q.release()
+	}
+	return ctx.Err()
+}
+
+// release returns a slot, handing it straight to the next waiter if any
+func (q *slotQueue) release() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.waiters) == 0 {
+		q.free++
+		return
+	}
+	waiter := heap.Pop(&q.waiters).(*slotWaiter)
+	waiter.index = -1
+	close(waiter.ready)
+}
+
+// slotWaiters is a heap of waiters, highest priority and then earliest first
+type slotWaiters []*slotWaiter
+
+func (w slotWaiters) Len() int { return len(w) }
+
+func (w slotWaiters) Less(i, j int) bool {
+	if w[i].priority != w[j].priority {
+		return w[i].priority > w[j].priority
+	}
+	return w[i].seq < w[j].seq
+}
+
+func (w slotWaiters) Swap(i, j int) {
+	w[i], w[j] = w[j], w[i]
+	w[i].index = i
+	w[j].index = j
+}
+
+func (w *slotWaiters) Push(x any) {
+	waiter := x.(*slotWaiter)
+	waiter.index = len(*w)
+	*w = append(*w, waiter)
+}
+
+func (w *slotWaiters) Pop() any {
+	old := *w
+	waiter := old[len(old)-1]
+	old[len(old)-1] = nil
+	*w = old[:len(old)-1]
+	return waiter
+}
@@ -0,0 +1,112 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSlotQueueBoundsConcurrency(t *testing.T) {
+	const limit = 3
+	q := newSlotQueue(limit)
+
+	var active, peak int32
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(priority int) {
+			defer wg.Done()
+			if err := q.acquire(context.Background(), priority); err != nil {
+				t.Errorf("acquire: %v", err)
+				return
+			}
+			n := atomic.AddInt32(&active, 1)
+			for {
+				p := atomic.LoadInt32(&peak)
+				if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+					break
+				}
+			}
+			time.Sleep(2 * time.Millisecond)
+			atomic.AddInt32(&active, -1)
+			q.release()
+		}(i % 4)
+	}
+	wg.Wait()
+
+	if peak > limit {
+		t.Fatalf("%d submissions ran at once, limit is %d", peak, limit)
+	}
+	if q.free != limit {
+		t.Fatalf("%d slots free after every submission finished, want %d", q.free, limit)
+	}
+}
+
+func TestSlotQueueAbandonedWaiter(t *testing.T) {
+	q := newSlotQueue(1)
+	if err := q.acquire(context.Background(), 0); err != nil {
+		t.Fatalf("acquire: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := q.acquire(ctx, 0); err == nil {
+		t.Fatal("acquire succeeded with every slot taken")
+	}
+
+	q.release()
+	if q.free != 1 || len(q.waiters) != 0 {
+		t.Fatalf("free = %d with %d waiters, want the slot back and no waiters", q.free, len(q.waiters))
+	}
+}
+
+func TestSlotQueuePriorityUnderSaturation(t *testing.T) {
+	q := newSlotQueue(1)
+	if err := q.acquire(context.Background(), 0); err != nil {
+		t.Fatalf("acquire: %v", err)
+	}
+
+	// Queue waiters one at a time so their arrival order is known
+	granted := make(chan string, 4)
+	for i, priority := range []int{1, 5, 3, 5} {
+		name := fmt.Sprintf("p%d-%d", priority, i)
+		go func(priority int) {
+			if err := q.acquire(context.Background(), priority); err != nil {
+				t.Errorf("%s: acquire: %v", name, err)
+				return
+			}
+			granted <- name
+		}(priority)
+		waitForWaiters(t, q, i+1)
+	}
+
+	var order []string
+	for i := 0; i < 4; i++ {
+		q.release()
+		order = append(order, <-granted)
+	}
+	if got, want := fmt.Sprint(order), "[p5-1 p5-3 p3-2 p1-0]"; got != want {
+		t.Fatalf("slots granted in order %s, want %s", got, want)
+	}
+}
+
+// waitForWaiters blocks until n submissions are waiting for a slot
+func waitForWaiters(t *testing.T, q *slotQueue, n int) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for {
+		q.mu.Lock()
+		waiting := len(q.waiters)
+		q.mu.Unlock()
+		if waiting == n {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%d waiters after 1s, want %d", waiting, n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
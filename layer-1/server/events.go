@@ -0,0 +1,160 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// eventBufferSize is how many commit events are buffered per subscriber
+	// before CometBFT starts dropping them for a slow client
+	eventBufferSize = 100
+	// eventWriteTimeout bounds how long a single write to a client may block
+	eventWriteTimeout = 10 * time.Second
+	// eventPingInterval is how often idle connections are pinged
+	eventPingInterval = 30 * time.Second
+	// eventPongWait is how long a client may go without answering a ping
+	// before it is considered gone
+	eventPongWait = 2 * eventPingInterval
+)
+
+var eventUpgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+	// Dashboards are served from other origins
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// CommitEvent is streamed to WebSocket clients for every committed shard commit
+type CommitEvent struct {
+	Type        string `json:"type"`
+	TxHash      string `json:"tx_hash"`
+	TxID        string `json:"tx_id"`
+	BlockHeight string `json:"block_height"`
+	SessionID   string `json:"session_id"`
+	ShardID     string `json:"shard_id"`
+	ClientGroup string `json:"client_group"`
+	Status      string `json:"status"`
+}
+
+// handleEventsWS streams l1_shard_commit events over a WebSocket, optionally
+// filtered by the client_group query parameter
+func (ws *WebServer) handleEventsWS(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		JSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := "tm.event = 'Tx' AND l1_shard_commit.session_id EXISTS"
+	clientGroup := r.URL.Query().Get("client_group")
+	if clientGroup != "" {
+		if strings.ContainsAny(clientGroup, `'"\`) {
+			JSONError(w, "Invalid client_group", http.StatusBadRequest)
+			return
+		}
+		query = fmt.Sprintf("tm.event = 'Tx' AND l1_shard_commit.client_group = '%s'", clientGroup)
+	}
+
+	requestID, err := generateRequestID()
+	if err != nil {
+		JSONError(w, "Internal Server Error", http.StatusInternalServerError)
+		ws.logger.Error("Failed to generate request ID", "err", err)
+		return
+	}
+	subscriber := "ws-events-" + requestID
+
+	conn, err := eventUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// Upgrade already wrote an HTTP error response
+		ws.logger.Error("Failed to upgrade events connection", "err", err)
+		return
+	}
+	defer conn.Close()
+
+	// The hijacked connection keeps the server's ReadTimeout deadline, which
+	// would end the stream after ReadTimeout. Replace it; liveness comes from
+	// pongs, each extending the deadline.
+	conn.SetReadDeadline(time.Now().Add(eventPongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(eventPongWait))
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events, err := ws.cometBftRpcClient.Subscribe(ctx, subscriber, query, eventBufferSize)
+	if err != nil {
+		ws.logger.Error("Failed to subscribe to commit events", "err", err)
+		conn.WriteMessage(websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.CloseInternalServerErr, "subscription failed"))
+		return
+	}
+	defer func() {
+		if err := ws.cometBftRpcClient.UnsubscribeAll(context.Background(), subscriber); err != nil {
+			ws.logger.Error("Failed to unsubscribe events client", "subscriber", subscriber, "err", err)
+		}
+	}()
+
+	ws.logger.Info("Events client connected", "subscriber", subscriber, "client_group", clientGroup, "remote_addr", r.RemoteAddr)
+
+	// Read side: we don't expect messages, but reading is required to process
+	// control frames and notice when the client goes away
+	go func() {
+		defer cancel()
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	ticker := time.NewTicker(eventPingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			ws.logger.Info("Events client disconnected", "subscriber", subscriber)
+			return
+		case <-ws.quit:
+			conn.WriteControl(websocket.CloseMessage,
+				websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutting down"),
+				time.Now().Add(eventWriteTimeout))
+			return
+		case <-ticker.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(eventWriteTimeout)); err != nil {
+				return
+			}
+		case result := <-events:
+			event := CommitEvent{
+				Type:        "l1_shard_commit",
+				TxHash:      firstEventValue(result.Events, "tx.hash"),
+				TxID:        firstEventValue(result.Events, "l1_shard_commit.tx_id"),
+				BlockHeight: firstEventValue(result.Events, "tx.height"),
+				SessionID:   firstEventValue(result.Events, "l1_shard_commit.session_id"),
+				ShardID:     firstEventValue(result.Events, "l1_shard_commit.shard_id"),
+				ClientGroup: firstEventValue(result.Events, "l1_shard_commit.client_group"),
+				Status:      firstEventValue(result.Events, "l1_shard_commit.status"),
+			}
+
+			conn.SetWriteDeadline(time.Now().Add(eventWriteTimeout))
+			if err := conn.WriteJSON(event); err != nil {
+				ws.logger.Info("Dropping slow or closed events client", "subscriber", subscriber, "err", err)
+				return
+			}
+		}
+	}
+}
+
+// firstEventValue returns the first value of an event attribute, if any
+func firstEventValue(events map[string][]string, key string) string {
+	if values, ok := events[key]; ok && len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
@@ -0,0 +1,267 @@
+package server
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/ahmadzakiakmal/thesis-extension/layer-1/tracing"
+)
+
+type contextKey string
+
+const accessLogKey contextKey = "access_log"
+
+// accessLogEntry carries per-request fields that handlers can fill in for the
+// access log line written once the request completes
+type accessLogEntry struct {
+	RequestID   string
+	BlockHeight int64
+}
+
+// statusRecorder captures the status code and size written by a handler
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func (rec *statusRecorder) WriteHeader(status int) {
+	if rec.status == 0 {
+		rec.status = status
+	}
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *statusRecorder) Write(b []byte) (int, error) {
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+	n, err := rec.ResponseWriter.Write(b)
+	rec.bytes += n
+	return n, err
+}
+
+// Hijack lets WebSocket upgrades pass through the recorder
+func (rec *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := rec.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("response writer does not support hijacking")
+	}
+	rec.status = http.StatusSwitchingProtocols
+	return hijacker.Hijack()
+}
+
+// Flush forwards flushes to the underlying writer when supported
+func (rec *statusRecorder) Flush() {
+	if flusher, ok := rec.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// RequestIDHeader carries the request ID across L2 and L1
+const RequestIDHeader = "X-Request-ID"
+
+// validRequestID accepts incoming request IDs that are safe to log
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// withAccessLog writes one structured log line per request with method, path,
+// status, duration, request ID and, for commits, the block height. An incoming
+// X-Request-ID is reused so L2 and L1 log lines can be correlated.
+func (ws *WebServer) withAccessLog(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+
+		requestID := r.Header.Get(RequestIDHeader)
+		if !validRequestID.MatchString(requestID) {
+			var err error
+			requestID, err = generateRequestID()
+			if err != nil {
+				ws.logger.Error("Failed to generate request ID", "err", err)
+			}
+		}
+		if requestID != "" {
+			w.Header().Set(RequestIDHeader, requestID)
+		}
+		entry := &accessLogEntry{RequestID: requestID}
+		r = r.WithContext(context.WithValue(r.Context(), accessLogKey, entry))
+
+		rec := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(rec, r)
+
+		if rec.status == 0 {
+			rec.status = http.StatusOK
+		}
+
+		fields := []interface{}{
+			"method", r.Method,
+			"path", r.URL.Path,
+			"status", rec.status,
+			"duration_ms", float64(time.Since(start).Microseconds()) / 1000,
+			"bytes", rec.bytes,
+			"request_id", entry.RequestID,
+			"remote_addr", r.RemoteAddr,
+		}
+		if entry.BlockHeight > 0 {
+			fields = append(fields, "block_height", entry.BlockHeight)
+		}
+		ws.logger.Info("access", fields...)
+	})
+}
+
+// accessLogFromContext returns the access log entry for the request, if any
+func accessLogFromContext(ctx context.Context) *accessLogEntry {
+	entry, _ := ctx.Value(accessLogKey).(*accessLogEntry)
+	return entry
+}
+
+// gzipMinSize is the smallest response body worth compressing
+const gzipMinSize = 1024
+
+// gzipResponseWriter buffers the start of a response and only switches to
+// gzip once the body grows past gzipMinSize
+type gzipResponseWriter struct {
+	http.ResponseWriter
+	status      int
+	buf         []byte
+	gz          *gzip.Writer
+	passthrough bool
+}
+
+func (g *gzipResponseWriter) WriteHeader(status int) {
+	if g.status == 0 {
+		g.status = status
+	}
+}
+
+func (g *gzipResponseWriter) Write(b []byte) (int, error) {
+	if g.gz != nil {
+		return g.gz.Write(b)
+	}
+	if g.passthrough {
+		return g.ResponseWriter.Write(b)
+	}
+
+	g.buf = append(g.buf, b...)
+	if len(g.buf) < gzipMinSize {
+		return len(b), nil
+	}
+
+	// Large enough: commit to compression unless the handler already encoded the body
+	header := g.ResponseWriter.Header()
+	if header.Get("Content-Encoding") != "" {
+		g.passthrough = true
+	} else {
+		header.Set("Content-Encoding", "gzip")
+		header.Del("Content-Length")
+		g.gz = gzip.NewWriter(g.ResponseWriter)
+	}
+	g.ResponseWriter.WriteHeader(g.statusCode())
+
+	buffered := g.buf
+	g.buf = nil
+	if g.gz != nil {
+		if _, err := g.gz.Write(buffered); err != nil {
+			return 0, err
+		}
+	} else if _, err := g.ResponseWriter.Write(buffered); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// Flush writes out whatever is buffered so far
+func (g *gzipResponseWriter) Flush() {
+	if g.gz != nil {
+		g.gz.Flush()
+	} else if !g.passthrough {
+		g.flushPlain()
+	}
+	if flusher, ok := g.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// close finishes the response, writing small bodies uncompressed
+func (g *gzipResponseWriter) close() {
+	if g.gz != nil {
+		g.gz.Close()
+		return
+	}
+	if !g.passthrough {
+		g.flushPlain()
+	}
+}
+
+func (g *gzipResponseWriter) flushPlain() {
+	g.passthrough = true
+	g.ResponseWriter.WriteHeader(g.statusCode())
+	if len(g.buf) > 0 {
+		g.ResponseWriter.Write(g.buf)
+		g.buf = nil
+	}
+}
+
+func (g *gzipResponseWriter) statusCode() int {
+	if g.status == 0 {
+		return http.StatusOK
+	}
+	return g.status
+}
+
+// withTracing continues the trace of an incoming traceparent header so spans
+// created while handling the request join the caller's trace
+func withTracing(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(tracing.Extract(r.Context(), r.Header)))
+	})
+}
+
+// withGzip compresses responses for clients that accept gzip once the body
+// exceeds gzipMinSize. WebSocket upgrades and HEAD requests are left alone.
+func withGzip(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead || r.Header.Get("Upgrade") != "" ||
+			!strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Add("Vary", "Accept-Encoding")
+		gw := &gzipResponseWriter{ResponseWriter: w}
+		defer gw.close()
+		next.ServeHTTP(gw, r)
+	})
+}
+
+// withInflightLimit answers 503 once max requests are being served, so an
+// overload degrades into fast rejections instead of piling up goroutines and
+// database connections. WebSocket upgrades are not counted since they hold a
+// connection for as long as the client stays subscribed. max <= 0 disables
+// the limit.
+func withInflightLimit(max int, next http.Handler) http.Handler {
+	if max <= 0 {
+		return next
+	}
+	slots := make(chan struct{}, max)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Upgrade") != "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		select {
+		case slots <- struct{}{}:
+			defer func() { <-slots }()
+			next.ServeHTTP(w, r)
+		default:
+			w.Header().Set("Retry-After", "1")
+			JSONError(w, "Server overloaded", http.StatusServiceUnavailable)
+		}
+	})
+}
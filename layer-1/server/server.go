@@ -3,10 +3,16 @@ package server
 import (
 	"context"
 	"crypto/rand"
+	"crypto/subtle"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"net"
 	"net/http"
+	"net/url"
+	"strconv"
 	"strings"
 	"time"
 
@@ -19,6 +25,9 @@ import (
 	"github.com/cometbft/cometbft/rpc/client"
 	cmthttp "github.com/cometbft/cometbft/rpc/client/http"
 	cmtrpc "github.com/cometbft/cometbft/rpc/client/local"
+	coretypes "github.com/cometbft/cometbft/rpc/core/types"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
 )
 
 // WebServer handles HTTP requests for L1
@@ -33,8 +42,71 @@ type WebServer struct {
 	cometBftHttpClient client.Client
 	cometBftRpcClient  *cmtrpc.Local
 	repository         *repository.Repository
+	config             *ServerConfig
+	quit               chan struct{}
 }
 
+// ServerConfig contains configuration for the L1 web server
+type ServerConfig struct {
+	// APIKeys lists the keys accepted in the X-L1-Api-Key header on write
+	// endpoints. Authentication is disabled when empty.
+	APIKeys []string
+
+	// MaxBodyBytes caps the size of request bodies. Defaults to DefaultMaxBodyBytes.
+	MaxBodyBytes int64
+
+	// RPCTimeout bounds each CometBFT RPC call. Defaults to DefaultRPCTimeout.
+	RPCTimeout time.Duration
+
+	// RPCAddress is the CometBFT RPC URL the web server calls, e.g.
+	// "http://cometbft:26657". Empty derives it from the node's RPC listen
+	// address.
+	RPCAddress string
+
+	// BindAddress is the interface the server listens on. Defaults to
+	// DefaultBindAddress, which binds all interfaces.
+	BindAddress string
+
+	// Connection timeouts, defaulting to the Default*Timeout values.
+	// WriteTimeout must outlast the slowest commit, which waits for consensus.
+	ReadHeaderTimeout time.Duration
+	ReadTimeout       time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+
+	// BasePath mounts every route under a prefix, e.g. "/tenant-x" serves
+	// /tenant-x/l1/commit, for gateways fronting several L1 instances. The
+	// prefix is stripped before routing. Empty serves routes at the root.
+	BasePath string
+
+	// MaxInflight caps the requests served at once; further requests get 503.
+	// Zero is unlimited.
+	MaxInflight int
+}
+
+// DefaultMaxBodyBytes is the request body limit used when none is configured
+const DefaultMaxBodyBytes int64 = 1 << 20
+
+// DefaultBindAddress is the listen interface used when none is configured
+const DefaultBindAddress = "0.0.0.0"
+
+// DefaultRPCTimeout is the CometBFT RPC timeout used when none is configured
+const DefaultRPCTimeout = 10 * time.Second
+
+// Connection timeouts used when none are configured
+const (
+	DefaultReadHeaderTimeout = 10 * time.Second
+	DefaultReadTimeout       = 30 * time.Second
+	DefaultWriteTimeout      = 90 * time.Second
+	DefaultIdleTimeout       = 120 * time.Second
+)
+
+// rpcRetryDelay is how long to wait before retrying a failed debug RPC call
+const rpcRetryDelay = 200 * time.Millisecond
+
+// APIKeyHeader is the header carrying the L1 API key
+const APIKeyHeader = "X-L1-Api-Key"
+
 // L1Response is the response format for L1 API calls
 type L1Response struct {
 	StatusCode int                 `json:"-"`
@@ -51,6 +123,8 @@ type L1TransactionStatus struct {
 	BlockHeight int64     `json:"block_height"`
 	ConfirmTime time.Time `json:"confirm_time"`
 	ShardInfo   ShardInfo `json:"shard_info"`
+	// Votes is the number of validator precommits backing the block
+	Votes int `json:"votes,omitempty"`
 }
 
 // ShardInfo contains information about the originating shard
@@ -61,17 +135,47 @@ type ShardInfo struct {
 }
 
 // NewWebServer creates a new L1 web server
-func NewWebServer(app *app.Application, httpPort string, logger cmtlog.Logger, node *nm.Node, serviceRegistry *srvreg.ServiceRegistry, repository *repository.Repository) (*WebServer, error) {
+func NewWebServer(app *app.Application, httpPort string, logger cmtlog.Logger, node *nm.Node, serviceRegistry *srvreg.ServiceRegistry, repository *repository.Repository, config *ServerConfig) (*WebServer, error) {
+	if config == nil {
+		config = &ServerConfig{}
+	}
+	if config.MaxBodyBytes <= 0 {
+		config.MaxBodyBytes = DefaultMaxBodyBytes
+	}
+	if config.BindAddress == "" {
+		config.BindAddress = DefaultBindAddress
+	}
+	if config.RPCTimeout <= 0 {
+		config.RPCTimeout = DefaultRPCTimeout
+	}
+	if config.ReadHeaderTimeout <= 0 {
+		config.ReadHeaderTimeout = DefaultReadHeaderTimeout
+	}
+	if config.ReadTimeout <= 0 {
+		config.ReadTimeout = DefaultReadTimeout
+	}
+	if config.WriteTimeout <= 0 {
+		config.WriteTimeout = DefaultWriteTimeout
+	}
+	if config.IdleTimeout <= 0 {
+		config.IdleTimeout = DefaultIdleTimeout
+	}
+	config.BasePath = NormalizeBasePath(config.BasePath)
+	if config.RPCAddress == "" {
+		config.RPCAddress = ResolveRPCAddress(node.Config().RPC.ListenAddress)
+	}
+	httpAddr := net.JoinHostPort(config.BindAddress, httpPort)
+
 	mux := http.NewServeMux()
 
-	rpcAddr := fmt.Sprintf("http://localhost:%s", extractPortFromAddress(node.Config().RPC.ListenAddress))
+	rpcAddr := config.RPCAddress
 	logger.Info("Connecting to CometBFT RPC", "address", rpcAddr)
 
 	// Create HTTP client for CometBFT
 	cometBftHttpClient, err := cmthttp.NewWithClient(
 		rpcAddr,
 		&http.Client{
-			Timeout: 10 * time.Second,
+			Timeout: config.RPCTimeout,
 		},
 	)
 	if err != nil {
@@ -84,10 +188,14 @@ func NewWebServer(app *app.Application, httpPort string, logger cmtlog.Logger, n
 
 	server := &WebServer{
 		app:      app,
-		httpAddr: ":" + httpPort,
+		httpAddr: httpAddr,
 		server: &http.Server{
-			Addr:    ":" + httpPort,
-			Handler: mux,
+			Addr:              httpAddr,
+			Handler:           mux,
+			ReadHeaderTimeout: config.ReadHeaderTimeout,
+			ReadTimeout:       config.ReadTimeout,
+			WriteTimeout:      config.WriteTimeout,
+			IdleTimeout:       config.IdleTimeout,
 		},
 		logger:             logger,
 		node:               node,
@@ -96,12 +204,33 @@ func NewWebServer(app *app.Application, httpPort string, logger cmtlog.Logger, n
 		cometBftHttpClient: cometBftHttpClient,
 		cometBftRpcClient:  cmtrpc.New(node),
 		repository:         repository,
+		config:             config,
+		quit:               make(chan struct{}),
+	}
+
+	if config.BasePath != "" {
+		logger.Info("Serving routes under base path", "base_path", config.BasePath)
+	}
+
+	if len(config.APIKeys) == 0 {
+		logger.Info("API key authentication disabled, write endpoints are open")
+	} else {
+		logger.Info("API key authentication enabled", "keys", len(config.APIKeys))
 	}
 
 	// Register routes
 	mux.HandleFunc("/", server.handleRoot)
 	mux.HandleFunc("/debug", server.handleDebug)
-	mux.HandleFunc("/l1/", server.handleL1API)
+	mux.HandleFunc("/l1/", server.requireAPIKey(server.handleL1API))
+	mux.HandleFunc("/l1/events/ws", server.handleEventsWS)
+	mux.HandleFunc("/openapi.json", server.handleOpenAPI)
+
+	// Serve HTTP/2 over cleartext alongside HTTP/1.1. WebSocket upgrades still
+	// arrive over HTTP/1.1 and pass through untouched.
+	server.server.Handler = h2c.NewHandler(
+		server.withAccessLog(withInflightLimit(config.MaxInflight, withTracing(withGzip(withBasePath(config.BasePath, mux))))),
+		&http2.Server{IdleTimeout: config.IdleTimeout},
+	)
 
 	return server, nil
 }
@@ -120,9 +249,46 @@ func (ws *WebServer) Start() error {
 // Shutdown gracefully shuts down the web server
 func (ws *WebServer) Shutdown(ctx context.Context) error {
 	ws.logger.Info("Shutting down L1 web server")
+	// Hijacked WebSocket connections are not tracked by http.Server
+	close(ws.quit)
 	return ws.server.Shutdown(ctx)
 }
 
+// requireAPIKey rejects write requests that do not carry a configured API key.
+// Read-only requests pass through untouched.
+func (ws *WebServer) requireAPIKey(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if len(ws.config.APIKeys) == 0 || isReadOnlyMethod(r.Method) {
+			next(w, r)
+			return
+		}
+
+		key := r.Header.Get(APIKeyHeader)
+		if key == "" {
+			JSONError(w, "Missing API key", http.StatusUnauthorized)
+			return
+		}
+		if !ws.isValidAPIKey(key) {
+			ws.logger.Info("Rejected request with invalid API key", "path", r.URL.Path, "remote_addr", r.RemoteAddr)
+			JSONError(w, "Invalid API key", http.StatusUnauthorized)
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+// isValidAPIKey checks the key against all configured keys in constant time
+func (ws *WebServer) isValidAPIKey(key string) bool {
+	valid := false
+	for _, configured := range ws.config.APIKeys {
+		if subtle.ConstantTimeCompare([]byte(key), []byte(configured)) == 1 {
+			valid = true
+		}
+	}
+	return valid
+}
+
 // handleRoot shows L1 node information
 func (ws *WebServer) handleRoot(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
@@ -136,8 +302,7 @@ func (ws *WebServer) handleRoot(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte("<p>Type: BFT Consensus Layer</p>"))
 	w.Write([]byte("<p>Architecture: Sharded L2 + Unified L1</p>"))
 
-	rpcPort := extractPortFromAddress(ws.node.Config().RPC.ListenAddress)
-	rpcAddrHtml := fmt.Sprintf("<p>RPC Address: <a href=\"http://localhost:%s\">http://localhost:%s</a></p>", rpcPort, rpcPort)
+	rpcAddrHtml := fmt.Sprintf("<p>RPC Address: <a href=\"%s\">%s</a></p>", ws.config.RPCAddress, ws.config.RPCAddress)
 	w.Write([]byte(rpcAddrHtml))
 
 	// Add API documentation
@@ -145,11 +310,31 @@ func (ws *WebServer) handleRoot(w http.ResponseWriter, r *http.Request) {
 	<h2>L1 API Endpoints</h2>
 	<ul>
 		<li><strong>POST /l1/commit</strong> - Receive commits from L2 shards</li>
+		<li><strong>GET /l1/sessions?group={group}&amp;shard={shard}&amp;operator={id}&amp;status={status}&amp;from={time}&amp;to={time}&amp;limit={n}&amp;offset={n}</strong> - Search sessions with combined filters</li>
+		<li><strong>GET /l1/sessions/{id}</strong> - Get a session by ID</li>
 		<li><strong>GET /l1/sessions/group/{group}</strong> - Get sessions by client group</li>
-		<li><strong>GET /l1/sessions/shard/{shard}</strong> - Get sessions by shard</li>
+		<li><strong>GET /l1/sessions/group/{group}/count?status={status}</strong> - Count sessions by client group</li>
+		<li><strong>GET /l1/sessions/shard/{shard}?status={status}&amp;limit={n}&amp;offset={n}</strong> - Get sessions by shard, paginated</li>
 		<li><strong>GET /l1/transaction/{hash}</strong> - Get transaction by hash</li>
-		<li><strong>GET /l1/status</strong> - Get L1 status</li>
-		<li><strong>GET /l1/shards</strong> - Get all registered shards</li>
+		<li><strong>POST /l1/verify-batch</strong> - Look up many transactions by hash at once</li>
+		<li><strong>GET /l1/transactions?since={height}&amp;limit={n}</strong> - List transactions above a block height</li>
+		<li><strong>GET /l1/verify/{txid}</strong> - Verify transaction against consensus state</li>
+		<li><strong>GET /l1/block/{height}</strong> - List the shard commits in a block</li>
+		<li><strong>GET /l1/audit/{session_id}</strong> - Prove a session's commit end-to-end</li>
+		<li><strong>GET /l1/status</strong> - Get L1 status and consensus state</li>
+		<li><strong>GET /l1/reconcile</strong> - Compare recent blocks with the PostgreSQL mirror</li>
+		<li><strong>GET /l1/mempool?limit={n}</strong> - Pending transactions in the mempool</li>
+		<li><strong>POST /l1/mempool/flush</strong> - Drop pending transactions (dev only)</li>
+		<li><strong>GET /l1/stats</strong> - Consensus latency statistics</li>
+		<li><strong>GET /l1/shards</strong> - Get all registered shards with their health</li>
+		<li><strong>DELETE /l1/shards/{shard}?purge={bool}&amp;confirm={shard}</strong> - Deregister a shard, or purge an inactive shard's data</li>
+		<li><strong>POST /l1/shards/{shard}/heartbeat</strong> - Report that a shard's L2 node is alive</li>
+		<li><strong>GET /l1/shards/{shard}/sessions?status={status}&amp;limit={n}&amp;offset={n}</strong> - Same as /l1/sessions/shard/{shard}</li>
+		<li><strong>GET /l1/operators?shard={shard}&amp;limit={n}&amp;offset={n}</strong> - List registered operators</li>
+		<li><strong>POST /l1/operators</strong> - Register an operator with an existing shard</li>
+		<li><strong>GET /l1/operators/{id}/sessions?status={status}&amp;limit={n}&amp;offset={n}</strong> - Query sessions by operator across shards</li>
+		<li><strong>GET /l1/events/ws?client_group={group}</strong> - Stream commit events over WebSocket</li>
+		<li><strong>GET /openapi.json</strong> - OpenAPI 3 document</li>
 	</ul>
 	`
 	w.Write([]byte(apiDocs))
@@ -163,7 +348,7 @@ func (ws *WebServer) handleDebug(w http.ResponseWriter, r *http.Request) {
 	}
 
 	nodeStatus := "online"
-	if ws.node.ConsensusReactor().WaitSync() {
+	if ws.isSyncing() {
 		nodeStatus = "syncing"
 	}
 	if !ws.node.IsListening() {
@@ -181,8 +366,14 @@ func (ws *WebServer) handleDebug(w http.ResponseWriter, r *http.Request) {
 		"architecture": "Sharded L2 + Unified L1",
 	}
 
-	// Get consensus info
-	status, err := ws.cometBftRpcClient.Status(context.Background())
+	// Get consensus info. Calls are retried once since a node busy producing
+	// a block can miss a single call.
+	var status *coretypes.ResultStatus
+	err := ws.retryRPC(r.Context(), func(ctx context.Context) error {
+		var err error
+		status, err = ws.cometBftRpcClient.Status(ctx)
+		return err
+	})
 	outboundPeers, inboundPeers, dialingPeers := ws.node.Switch().NumPeers()
 	debugInfo["num_peers_out"] = outboundPeers
 	debugInfo["num_peers_in"] = inboundPeers
@@ -197,7 +388,12 @@ func (ws *WebServer) handleDebug(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Add ABCI info
-	abciInfo, err := ws.cometBftRpcClient.ABCIInfo(context.Background())
+	var abciInfo *coretypes.ResultABCIInfo
+	err = ws.retryRPC(r.Context(), func(ctx context.Context) error {
+		var err error
+		abciInfo, err = ws.cometBftRpcClient.ABCIInfo(ctx)
+		return err
+	})
 	if err != nil {
 		debugInfo["abci_error"] = err.Error()
 	} else {
@@ -208,25 +404,123 @@ func (ws *WebServer) handleDebug(w http.ResponseWriter, r *http.Request) {
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	encoder := json.NewEncoder(w)
-	encoder.SetIndent("", "  ")
+	encoder := newJSONEncoder(w, wantsPrettyJSON(r, true))
 	if err := encoder.Encode(debugInfo); err != nil {
 		JSONError(w, "Error encoding response: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
 }
 
+// retryRPC runs an RPC call bounded by the configured timeout, retrying it
+// once after a short delay if it fails
+func (ws *WebServer) retryRPC(ctx context.Context, call func(ctx context.Context) error) error {
+	attempt := func() error {
+		callCtx, cancel := context.WithTimeout(ctx, ws.config.RPCTimeout)
+		defer cancel()
+		return call(callCtx)
+	}
+
+	err := attempt()
+	if err == nil {
+		return nil
+	}
+
+	select {
+	case <-ctx.Done():
+		return err
+	case <-time.After(rpcRetryDelay):
+	}
+	return attempt()
+}
+
+// handleOpenAPI serves the OpenAPI document generated from registered routes
+func (ws *WebServer) handleOpenAPI(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		JSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	encoder := newJSONEncoder(w, wantsPrettyJSON(r, true))
+	spec := ws.serviceRegistry.OpenAPISpec()
+	if ws.config.BasePath != "" {
+		spec["servers"] = []map[string]string{{"url": ws.config.BasePath}}
+	}
+	if err := encoder.Encode(spec); err != nil {
+		ws.logger.Error("Failed to encode OpenAPI document", "err", err)
+	}
+}
+
+// NormalizeBasePath turns "tenant-x/" or "/tenant-x" into "/tenant-x". An
+// empty or "/" base path stays empty.
+func NormalizeBasePath(basePath string) string {
+	basePath = strings.Trim(strings.TrimSpace(basePath), "/")
+	if basePath == "" {
+		return ""
+	}
+	return "/" + basePath
+}
+
+// withBasePath strips basePath from request paths before routing and
+// answers 404 for paths outside it
+func withBasePath(basePath string, next http.Handler) http.Handler {
+	if basePath == "" {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rest, ok := strings.CutPrefix(r.URL.Path, basePath)
+		if !ok || (rest != "" && !strings.HasPrefix(rest, "/")) {
+			http.NotFound(w, r)
+			return
+		}
+		if rest == "" {
+			rest = "/"
+		}
+
+		stripped := new(http.Request)
+		*stripped = *r
+		stripped.URL = new(url.URL)
+		*stripped.URL = *r.URL
+		stripped.URL.Path = rest
+		stripped.URL.RawPath = ""
+		next.ServeHTTP(w, stripped)
+	})
+}
+
 // handleL1API handles all L1 API requests
 func (ws *WebServer) handleL1API(w http.ResponseWriter, r *http.Request) {
-	requestID, err := generateRequestID()
-	if err != nil {
-		JSONError(w, "Internal Server Error", http.StatusInternalServerError)
-		ws.logger.Error("Failed to generate request ID", "err", err)
+	// Writes made mid-sync would be checked against stale state and fail
+	// confusingly; reads keep being served from what the node has so far
+	if !isReadOnlyMethod(r.Method) && ws.isSyncing() {
+		w.Header().Set("Retry-After", strconv.Itoa(syncRetryAfterSeconds))
+		JSONError(w, "Node catching up", http.StatusServiceUnavailable)
 		return
 	}
 
+	logEntry := accessLogFromContext(r.Context())
+	if logEntry == nil {
+		logEntry = &accessLogEntry{}
+	}
+
+	requestID := logEntry.RequestID
+	if requestID == "" {
+		var err error
+		requestID, err = generateRequestID()
+		if err != nil {
+			JSONError(w, "Internal Server Error", http.StatusInternalServerError)
+			ws.logger.Error("Failed to generate request ID", "err", err)
+			return
+		}
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, ws.config.MaxBodyBytes)
 	request, err := srvreg.ConvertHttpRequestToConsensusRequest(r, requestID)
 	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			JSONError(w, fmt.Sprintf("Request body exceeds %d bytes", maxBytesErr.Limit), http.StatusRequestEntityTooLarge)
+			return
+		}
 		JSONError(w, "Failed to convert request: "+err.Error(), http.StatusUnprocessableEntity)
 		ws.logger.Error("Failed to convert HTTP request", "err", err)
 		return
@@ -236,31 +530,39 @@ func (ws *WebServer) handleL1API(w http.ResponseWriter, r *http.Request) {
 	// Only the /l1/commit endpoint triggers BFT consensus
 	response, err := request.GenerateResponse(ws.serviceRegistry)
 	if err != nil {
-		JSONError(w, "Failed to generate response: "+err.Error(), http.StatusUnprocessableEntity)
 		ws.logger.Error("Failed to generate response", "err", err)
-		return
+		// Handlers return a response alongside the error when they know the
+		// right status code (e.g. 429); only fall back when they don't
+		if response == nil {
+			JSONError(w, "Failed to generate response: "+err.Error(), http.StatusUnprocessableEntity)
+			return
+		}
 	}
 
 	// Check if this was a commit request that went through consensus
 	var l1Response L1Response
-	if strings.Contains(r.URL.Path, "/commit") && response.StatusCode == http.StatusAccepted {
+	if strings.TrimSuffix(r.URL.Path, "/") == "/l1/commit" && response.StatusCode == http.StatusAccepted {
 		// Parse the response to get transaction info
-		var txInfo map[string]interface{}
-		json.Unmarshal([]byte(response.Body), &txInfo)
+		var txInfo srvreg.ShardCommitResponse
+		if err := json.Unmarshal([]byte(response.Body), &txInfo); err != nil {
+			ws.logger.Error("Failed to parse commit response", "err", err)
+		}
+		logEntry.BlockHeight = txInfo.BlockHeight
 
 		l1Response = L1Response{
 			StatusCode: response.StatusCode,
 			Headers:    response.Headers,
 			Data:       txInfo,
 			Meta: L1TransactionStatus{
-				TxID:        fmt.Sprintf("%v", txInfo["tx_hash"]),
+				TxID:        txInfo.TxID,
 				Status:      "confirmed",
-				BlockHeight: int64(txInfo["block_height"].(float64)),
+				BlockHeight: txInfo.BlockHeight,
 				ConfirmTime: time.Now(),
+				Votes:       txInfo.Votes,
 				ShardInfo: ShardInfo{
-					ShardID:     fmt.Sprintf("%v", txInfo["shard_id"]),
-					ClientGroup: "", // Could be extracted from request if needed
-					L2NodeID:    "",
+					ShardID:     txInfo.ShardID,
+					ClientGroup: txInfo.ClientGroup,
+					L2NodeID:    txInfo.L2NodeID,
 				},
 			},
 			NodeID: string(ws.node.NodeInfo().ID()),
@@ -288,17 +590,10 @@ func (ws *WebServer) handleL1API(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(response.StatusCode)
 
-	encoder := json.NewEncoder(w)
-	encoder.SetIndent("", "  ")
+	encoder := newJSONEncoder(w, wantsPrettyJSON(r, false))
 	if err := encoder.Encode(l1Response); err != nil {
 		ws.logger.Error("Failed to encode L1 response", "err", err)
 	}
-
-	ws.logger.Info("L1 API Request Processed",
-		"path", request.Path,
-		"method", request.Method,
-		"status", response.StatusCode,
-	)
 }
 
 // Helper functions
@@ -312,13 +607,64 @@ func generateRequestID() (string, error) {
 	return hex.EncodeToString(bytes), nil
 }
 
-func extractPortFromAddress(address string) string {
-	for i := len(address) - 1; i >= 0; i-- {
-		if address[i] == ':' {
-			return address[i+1:]
+func isReadOnlyMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+}
+
+// wantsPrettyJSON decides whether a JSON response is indented. An explicit
+// ?pretty= wins; otherwise browsers, which ask for text/html, get indented
+// output and other clients get def.
+func wantsPrettyJSON(r *http.Request, def bool) bool {
+	if raw := r.URL.Query().Get("pretty"); raw != "" {
+		if pretty, err := strconv.ParseBool(raw); err == nil {
+			return pretty
 		}
 	}
-	return ""
+	if strings.Contains(r.Header.Get("Accept"), "text/html") {
+		return true
+	}
+	return def
+}
+
+// newJSONEncoder returns an encoder writing compact or indented JSON to w
+func newJSONEncoder(w io.Writer, pretty bool) *json.Encoder {
+	encoder := json.NewEncoder(w)
+	if pretty {
+		encoder.SetIndent("", "  ")
+	}
+	return encoder
+}
+
+// syncRetryAfterSeconds is the Retry-After sent with writes refused while
+// the node is catching up
+const syncRetryAfterSeconds = 5
+
+// isSyncing reports whether the node is still catching up with the network
+// through block or state sync
+func (ws *WebServer) isSyncing() bool {
+	return ws.node.ConsensusReactor().WaitSync()
+}
+
+// ResolveRPCAddress turns a CometBFT RPC listen address such as
+// "tcp://127.0.0.1:26657" into the URL to reach it. Wildcard hosts
+// (0.0.0.0, ::) are reached through localhost; unix sockets are kept as-is.
+func ResolveRPCAddress(listenAddress string) string {
+	if strings.HasPrefix(listenAddress, "unix://") {
+		return listenAddress
+	}
+	hostPort := listenAddress
+	if i := strings.Index(hostPort, "://"); i >= 0 {
+		hostPort = hostPort[i+3:]
+	}
+
+	host, port, err := net.SplitHostPort(hostPort)
+	if err != nil {
+		return "http://" + hostPort
+	}
+	if ip := net.ParseIP(host); host == "" || (ip != nil && ip.IsUnspecified()) {
+		host = "localhost"
+	}
+	return "http://" + net.JoinHostPort(host, port)
 }
 
 func JSONError(w http.ResponseWriter, message string, statusCode int) {
@@ -0,0 +1,81 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	cmtlog "github.com/cometbft/cometbft/libs/log"
+)
+
+func TestRequireAPIKey(t *testing.T) {
+	tests := []struct {
+		name   string
+		keys   []string
+		method string
+		key    string
+		status int
+		error  string
+	}{
+		{"disabled", nil, http.MethodPost, "", http.StatusOK, ""},
+		{"disabled ignores a key", nil, http.MethodPost, "anything", http.StatusOK, ""},
+		{"valid key", []string{"key-1", "key-2"}, http.MethodPost, "key-1", http.StatusOK, ""},
+		{"rotated key", []string{"key-1", "key-2"}, http.MethodDelete, "key-2", http.StatusOK, ""},
+		{"missing key", []string{"key-1"}, http.MethodPost, "", http.StatusUnauthorized, "Missing API key"},
+		{"invalid key", []string{"key-1"}, http.MethodPost, "key-3", http.StatusUnauthorized, "Invalid API key"},
+		{"read without key", []string{"key-1"}, http.MethodGet, "", http.StatusOK, ""},
+	}
+
+	for _, tt := range tests {
+		ws := &WebServer{config: &ServerConfig{APIKeys: tt.keys}, logger: cmtlog.NewNopLogger()}
+		reached := false
+		handler := ws.requireAPIKey(func(w http.ResponseWriter, r *http.Request) {
+			reached = true
+			w.WriteHeader(http.StatusOK)
+		})
+
+		req := httptest.NewRequest(tt.method, "/l1/commit", nil)
+		if tt.key != "" {
+			req.Header.Set(APIKeyHeader, tt.key)
+		}
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+
+		if rec.Code != tt.status {
+			t.Errorf("%s: status = %d, want %d", tt.name, rec.Code, tt.status)
+		}
+		if reached != (tt.status == http.StatusOK) {
+			t.Errorf("%s: handler reached = %v, want %v", tt.name, reached, !reached)
+		}
+		if tt.error != "" {
+			var body struct {
+				Error string `json:"error"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || body.Error != tt.error {
+				t.Errorf("%s: body = %s, want error %q", tt.name, rec.Body, tt.error)
+			}
+		}
+	}
+}
+
+func TestHandleL1APIRejectsOversizedBody(t *testing.T) {
+	ws := &WebServer{config: &ServerConfig{MaxBodyBytes: 16}, logger: cmtlog.NewNopLogger()}
+
+	// A read skips the sync check, which needs a running node, and still
+	// has its body limited
+	req := httptest.NewRequest(http.MethodGet, "/l1/sessions", strings.NewReader(strings.Repeat("x", 64)))
+	rec := httptest.NewRecorder()
+	ws.handleL1API(rec, req)
+
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("status = %d, want 413", rec.Code)
+	}
+	var body struct {
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || body.Error != "Request body exceeds 16 bytes" {
+		t.Fatalf("body = %s, want the limit reported", rec.Body)
+	}
+}
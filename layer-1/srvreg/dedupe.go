@@ -0,0 +1,116 @@
+package srvreg
+
+import (
+	"maps"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// DefaultDedupeTTL is how long a write response is replayed for retries
+const DefaultDedupeTTL = 30 * time.Second
+
+// ReplayedHeader marks a response served from the dedupe cache
+const ReplayedHeader = "X-Idempotent-Replayed"
+
+// dedupeEntry is a write request seen recently. done is closed once the first
+// submission finishes; response stays nil when it was not cached.
+type dedupeEntry struct {
+	done     chan struct{}
+	response *Response
+	expires  time.Time
+}
+
+// DedupeCache remembers successful write responses by deterministic request
+// ID so client retries get the original response instead of a second commit
+type DedupeCache struct {
+	ttl     time.Duration
+	entries map[string]*dedupeEntry
+	mu      sync.Mutex
+}
+
+// NewDedupeCache creates a cache replaying responses for ttl
+func NewDedupeCache(ttl time.Duration) *DedupeCache {
+	return &DedupeCache{
+		ttl:     ttl,
+		entries: make(map[string]*dedupeEntry),
+	}
+}
+
+// isWriteMethod reports whether requests with method change state
+func isWriteMethod(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// Do runs handle for key unless an identical request is cached or in flight.
+// A duplicate waits for the in-flight request and replays its response; only
+// 2xx responses are cached so failed requests can be retried.
+func (dc *DedupeCache) Do(key string, handle func() (*Response, error)) (*Response, error) {
+	for {
+		dc.mu.Lock()
+		now := time.Now()
+		dc.evictExpired(now)
+
+		entry, ok := dc.entries[key]
+		if !ok {
+			entry = &dedupeEntry{done: make(chan struct{})}
+			dc.entries[key] = entry
+			dc.mu.Unlock()
+			return dc.run(key, entry, handle)
+		}
+		dc.mu.Unlock()
+
+		<-entry.done
+		if entry.response != nil {
+			return replay(entry.response), nil
+		}
+		// The first submission failed and was dropped; process this one
+	}
+}
+
+// run executes the first submission of key and records its outcome. The
+// entry is settled even if handle panics, so duplicates waiting on it are
+// released and the key can be submitted again.
+func (dc *DedupeCache) run(key string, entry *dedupeEntry, handle func() (*Response, error)) (*Response, error) {
+	defer func() {
+		dc.mu.Lock()
+		if entry.response == nil {
+			delete(dc.entries, key)
+		}
+		dc.mu.Unlock()
+		close(entry.done)
+	}()
+
+	response, err := handle()
+	if err == nil && response != nil && response.StatusCode >= 200 && response.StatusCode < 300 {
+		dc.mu.Lock()
+		entry.response = response
+		entry.expires = time.Now().Add(dc.ttl)
+		dc.mu.Unlock()
+	}
+	return response, err
+}
+
+// evictExpired drops finished entries past their TTL. Callers hold dc.mu.
+func (dc *DedupeCache) evictExpired(now time.Time) {
+	for key, entry := range dc.entries {
+		if entry.response != nil && now.After(entry.expires) {
+			delete(dc.entries, key)
+		}
+	}
+}
+
+// replay copies a cached response and marks it as replayed
+func replay(response *Response) *Response {
+	headers := make(map[string]string, len(response.Headers)+1)
+	maps.Copy(headers, response.Headers)
+	headers[ReplayedHeader] = "true"
+
+	replayed := *response
+	replayed.Headers = headers
+	return &replayed
+}
@@ -0,0 +1,136 @@
+package srvreg
+
+import (
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// countingHandler answers with status and counts its calls
+func countingHandler(calls *atomic.Int32, status int) func() (*Response, error) {
+	return func() (*Response, error) {
+		calls.Add(1)
+		return &Response{StatusCode: status, Headers: map[string]string{"Content-Type": "application/json"}, Body: "{}"}, nil
+	}
+}
+
+func TestDedupeReplaysSuccess(t *testing.T) {
+	dc := NewDedupeCache(time.Minute)
+	var calls atomic.Int32
+
+	first, _ := dc.Do("req-1", countingHandler(&calls, http.StatusAccepted))
+	second, _ := dc.Do("req-1", countingHandler(&calls, http.StatusAccepted))
+	if calls.Load() != 1 {
+		t.Fatalf("handler ran %d times, want once", calls.Load())
+	}
+	if second.StatusCode != http.StatusAccepted || second.Headers[ReplayedHeader] != "true" {
+		t.Fatalf("second response = %+v, want the replayed 202", second)
+	}
+	if _, ok := first.Headers[ReplayedHeader]; ok {
+		t.Error("first response marked as replayed")
+	}
+}
+
+func TestDedupeRetriesFailures(t *testing.T) {
+	dc := NewDedupeCache(time.Minute)
+	var calls atomic.Int32
+
+	dc.Do("req-1", countingHandler(&calls, http.StatusServiceUnavailable))
+	dc.Do("req-1", func() (*Response, error) {
+		calls.Add(1)
+		return nil, errors.New("handler failed")
+	})
+	resp, _ := dc.Do("req-1", countingHandler(&calls, http.StatusAccepted))
+	if calls.Load() != 3 || resp.Headers[ReplayedHeader] != "" {
+		t.Fatalf("handler ran %d times, want every failed submission retried", calls.Load())
+	}
+}
+
+func TestDedupeExpires(t *testing.T) {
+	dc := NewDedupeCache(time.Millisecond)
+	var calls atomic.Int32
+
+	dc.Do("req-1", countingHandler(&calls, http.StatusOK))
+	time.Sleep(5 * time.Millisecond)
+	dc.Do("req-1", countingHandler(&calls, http.StatusOK))
+	if calls.Load() != 2 {
+		t.Fatalf("handler ran %d times, want the expired response not replayed", calls.Load())
+	}
+}
+
+func TestDedupeDuplicateWaitsForFirst(t *testing.T) {
+	dc := NewDedupeCache(time.Minute)
+	var calls atomic.Int32
+	release := make(chan struct{})
+	started := make(chan struct{})
+
+	go dc.Do("req-1", func() (*Response, error) {
+		close(started)
+		<-release
+		return countingHandler(&calls, http.StatusAccepted)()
+	})
+	<-started
+
+	duplicate := make(chan *Response)
+	go func() {
+		resp, _ := dc.Do("req-1", countingHandler(&calls, http.StatusAccepted))
+		duplicate <- resp
+	}()
+	time.Sleep(10 * time.Millisecond) // let the duplicate start waiting
+	close(release)
+
+	select {
+	case resp := <-duplicate:
+		if calls.Load() != 1 || resp.Headers[ReplayedHeader] != "true" {
+			t.Fatalf("handler ran %d times, want the duplicate replayed", calls.Load())
+		}
+	case <-time.After(time.Second):
+		t.Fatal("duplicate never answered")
+	}
+}
+
+func TestDedupeSettlesAfterPanic(t *testing.T) {
+	dc := NewDedupeCache(time.Minute)
+	var calls atomic.Int32
+	release := make(chan struct{})
+	started := make(chan struct{})
+
+	panicked := make(chan interface{})
+	go func() {
+		defer func() { panicked <- recover() }()
+		dc.Do("req-1", func() (*Response, error) {
+			close(started)
+			<-release
+			panic("handler bug")
+		})
+	}()
+	<-started
+
+	// A duplicate waiting on the panicking submission runs once it is released
+	duplicate := make(chan *Response)
+	go func() {
+		resp, _ := dc.Do("req-1", countingHandler(&calls, http.StatusAccepted))
+		duplicate <- resp
+	}()
+	time.Sleep(10 * time.Millisecond) // let the duplicate start waiting
+	close(release)
+
+	if recovered := <-panicked; recovered != "handler bug" {
+		t.Fatalf("recovered %v, want the handler's panic passed on", recovered)
+	}
+	select {
+	case resp := <-duplicate:
+		if calls.Load() != 1 || resp.Headers[ReplayedHeader] != "" {
+			t.Fatalf("handler ran %d times, want the duplicate processed afresh", calls.Load())
+		}
+	case <-time.After(time.Second):
+		t.Fatal("duplicate stuck behind the panicked submission")
+	}
+
+	resp, _ := dc.Do("req-1", countingHandler(&calls, http.StatusAccepted))
+	if calls.Load() != 1 || resp.Headers[ReplayedHeader] != "true" {
+		t.Fatalf("handler ran %d times, want the duplicate's response replayed", calls.Load())
+	}
+}
@@ -0,0 +1,237 @@
+package srvreg
+
+import (
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RouteDoc describes a registered route in the generated OpenAPI document.
+// Request and Response are sample values whose types describe the bodies.
+type RouteDoc struct {
+	Summary  string
+	Status   int
+	Request  interface{}
+	Response interface{}
+}
+
+// l1MetaSchema is the hand-written schema of the meta block in every L1 response
+var l1MetaSchema = map[string]interface{}{
+	"type": "object",
+	"properties": map[string]interface{}{
+		"tx_id":        map[string]interface{}{"type": "string"},
+		"status":       map[string]interface{}{"type": "string"},
+		"block_height": map[string]interface{}{"type": "integer", "format": "int64"},
+		"confirm_time": map[string]interface{}{"type": "string", "format": "date-time"},
+		"shard_info": map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"shard_id":     map[string]interface{}{"type": "string"},
+				"client_group": map[string]interface{}{"type": "string"},
+				"l2_node_id":   map[string]interface{}{"type": "string"},
+			},
+		},
+	},
+}
+
+// DocumentRoute attaches documentation to a registered route
+func (sr *ServiceRegistry) DocumentRoute(method, path string, doc RouteDoc) {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+
+	sr.docs[RouteKey{Method: strings.ToUpper(method), Path: normalizePath(path)}] = doc
+}
+
+// OpenAPISpec builds an OpenAPI 3 document from the registered routes
+func (sr *ServiceRegistry) OpenAPISpec() map[string]interface{} {
+	sr.mu.RLock()
+	defer sr.mu.RUnlock()
+
+	routes := make([]RouteKey, 0, len(sr.handlers))
+	for key := range sr.handlers {
+		routes = append(routes, key)
+	}
+	sort.Slice(routes, func(i, j int) bool {
+		if routes[i].Path != routes[j].Path {
+			return routes[i].Path < routes[j].Path
+		}
+		return routes[i].Method < routes[j].Method
+	})
+
+	gen := &schemaGenerator{components: map[string]interface{}{"L1Meta": l1MetaSchema}}
+	paths := make(map[string]interface{})
+
+	for _, route := range routes {
+		oasPath, params := openAPIPath(route.Path)
+		doc := sr.docs[route]
+
+		status := doc.Status
+		if status == 0 {
+			status = http.StatusOK
+		}
+
+		var dataSchema map[string]interface{}
+		if doc.Response != nil {
+			dataSchema = gen.schemaFor(reflect.TypeOf(doc.Response))
+		} else {
+			dataSchema = map[string]interface{}{}
+		}
+
+		operation := map[string]interface{}{
+			"summary": doc.Summary,
+			"responses": map[string]interface{}{
+				strconv.Itoa(status): map[string]interface{}{
+					"description": http.StatusText(status),
+					"content":     jsonContent(l1Envelope(dataSchema)),
+				},
+				"default": map[string]interface{}{
+					"description": "Error",
+					"content":     jsonContent(l1Envelope(gen.schemaFor(reflect.TypeOf(ErrorResponse{})))),
+				},
+			},
+		}
+		if len(params) > 0 {
+			operation["parameters"] = params
+		}
+		if doc.Request != nil {
+			operation["requestBody"] = map[string]interface{}{
+				"required": true,
+				"content":  jsonContent(gen.schemaFor(reflect.TypeOf(doc.Request))),
+			}
+		}
+
+		pathItem, ok := paths[oasPath].(map[string]interface{})
+		if !ok {
+			pathItem = make(map[string]interface{})
+			paths[oasPath] = pathItem
+		}
+		pathItem[strings.ToLower(route.Method)] = operation
+	}
+
+	return map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]interface{}{
+			"title":       "Layer 1 BFT Consensus API",
+			"description": "Unified L1 consensus layer for sharded L2 nodes",
+			"version":     "1.0.0",
+		},
+		"paths": paths,
+		"components": map[string]interface{}{
+			"schemas": gen.components,
+		},
+	}
+}
+
+// l1Envelope wraps a data schema in the L1Response envelope
+func l1Envelope(data map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"data":    data,
+			"meta":    map[string]interface{}{"$ref": "#/components/schemas/L1Meta"},
+			"node_id": map[string]interface{}{"type": "string"},
+		},
+	}
+}
+
+func jsonContent(schema map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"application/json": map[string]interface{}{"schema": schema},
+	}
+}
+
+// openAPIPath converts "/l1/sessions/group/:group" into
+// "/l1/sessions/group/{group}" along with its path parameters
+func openAPIPath(path string) (string, []interface{}) {
+	parts := strings.Split(path, "/")
+	params := []interface{}{}
+	for i, part := range parts {
+		if strings.HasPrefix(part, ":") {
+			name := part[1:]
+			parts[i] = "{" + name + "}"
+			params = append(params, map[string]interface{}{
+				"name":     name,
+				"in":       "path",
+				"required": true,
+				"schema":   map[string]interface{}{"type": "string"},
+			})
+		}
+	}
+	return strings.Join(parts, "/"), params
+}
+
+// schemaGenerator derives JSON schemas from Go types, collecting named structs
+// as reusable components so recursive models terminate
+type schemaGenerator struct {
+	components map[string]interface{}
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+func (g *schemaGenerator) schemaFor(t reflect.Type) map[string]interface{} {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == timeType {
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Int64, reflect.Uint64:
+		return map[string]interface{}{"type": "integer", "format": "int64"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]interface{}{"type": "string", "format": "byte"}
+		}
+		return map[string]interface{}{"type": "array", "items": g.schemaFor(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": g.schemaFor(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.structSchema(t)
+		}
+		if _, ok := g.components[t.Name()]; !ok {
+			// Reserve the name before recursing to break reference cycles
+			g.components[t.Name()] = map[string]interface{}{}
+			g.components[t.Name()] = g.structSchema(t)
+		}
+		return map[string]interface{}{"$ref": "#/components/schemas/" + t.Name()}
+	default:
+		return map[string]interface{}{}
+	}
+}
+
+func (g *schemaGenerator) structSchema(t reflect.Type) map[string]interface{} {
+	properties := make(map[string]interface{})
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name := field.Name
+		if tag := field.Tag.Get("json"); tag != "" {
+			tagName := strings.Split(tag, ",")[0]
+			if tagName == "-" {
+				continue
+			}
+			if tagName != "" {
+				name = tagName
+			}
+		}
+		properties[name] = g.schemaFor(field.Type)
+	}
+	return map[string]interface{}{"type": "object", "properties": properties}
+}
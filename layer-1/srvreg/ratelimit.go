@@ -0,0 +1,93 @@
+package srvreg
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// tokenBucket is a single token bucket refilled continuously at a fixed rate
+type tokenBucket struct {
+	tokens     float64
+	lastRefill time.Time
+}
+
+// RateLimiter is a token-bucket rate limiter keyed by client group
+type RateLimiter struct {
+	rate      float64 // tokens added per second
+	burst     float64 // bucket capacity
+	idleTTL   time.Duration
+	lastSweep time.Time
+	buckets   map[string]*tokenBucket
+	mu        sync.Mutex
+}
+
+// NewRateLimiter creates a rate limiter allowing rate requests per second
+// per key, with bursts of up to burst requests
+func NewRateLimiter(rate float64, burst int) *RateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	rl := &RateLimiter{
+		rate:    rate,
+		burst:   float64(burst),
+		buckets: make(map[string]*tokenBucket),
+	}
+	// A bucket idle for this long has refilled completely, so dropping it
+	// is indistinguishable from keeping it
+	if rate > 0 {
+		rl.idleTTL = time.Duration(rl.burst / rate * float64(time.Second))
+	}
+	return rl
+}
+
+// Allow consumes a token for key. When the bucket is empty it returns false
+// along with how long the caller should wait before retrying.
+func (rl *RateLimiter) Allow(key string) (bool, time.Duration) {
+	return rl.allow(key, time.Now())
+}
+
+func (rl *RateLimiter) allow(key string, now time.Time) (bool, time.Duration) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	rl.evictIdle(now)
+
+	bucket, ok := rl.buckets[key]
+	if !ok {
+		bucket = &tokenBucket{tokens: rl.burst, lastRefill: now}
+		rl.buckets[key] = bucket
+	}
+
+	// Refill based on elapsed time
+	elapsed := now.Sub(bucket.lastRefill).Seconds()
+	if elapsed > 0 {
+		bucket.tokens = math.Min(rl.burst, bucket.tokens+elapsed*rl.rate)
+		bucket.lastRefill = now
+	}
+
+	if bucket.tokens >= 1 {
+		bucket.tokens--
+		return true, 0
+	}
+
+	if rl.rate <= 0 {
+		return false, time.Second
+	}
+	wait := time.Duration((1 - bucket.tokens) / rl.rate * float64(time.Second))
+	return false, wait
+}
+
+// evictIdle drops buckets that have refilled completely, at most once per
+// idle period. Buckets that never refill are kept.
+func (rl *RateLimiter) evictIdle(now time.Time) {
+	if rl.idleTTL <= 0 || now.Sub(rl.lastSweep) < rl.idleTTL {
+		return
+	}
+	for key, bucket := range rl.buckets {
+		if now.Sub(bucket.lastRefill) >= rl.idleTTL {
+			delete(rl.buckets, key)
+		}
+	}
+	rl.lastSweep = now
+}
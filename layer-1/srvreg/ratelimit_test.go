@@ -0,0 +1,60 @@
+package srvreg
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRateLimiterBurstAndRefill(t *testing.T) {
+	rl := NewRateLimiter(2, 3)
+	start := time.Now()
+
+	for i := 0; i < 3; i++ {
+		if allowed, _ := rl.allow("group-a", start); !allowed {
+			t.Fatalf("request %d denied within the burst", i+1)
+		}
+	}
+	allowed, retryAfter := rl.allow("group-a", start)
+	if allowed {
+		t.Fatal("request past the burst allowed")
+	}
+	if retryAfter != 500*time.Millisecond {
+		t.Errorf("retryAfter = %v, want 500ms", retryAfter)
+	}
+	if allowed, _ := rl.allow("group-b", start); !allowed {
+		t.Error("group-b denied by group-a's bucket")
+	}
+	if allowed, _ := rl.allow("group-a", start.Add(500*time.Millisecond)); !allowed {
+		t.Error("request denied after refilling a token")
+	}
+}
+
+func TestRateLimiterEvictsIdleBuckets(t *testing.T) {
+	rl := NewRateLimiter(1, 2) // refills completely in 2s
+	start := time.Now()
+
+	rl.allow("idle", start)
+	rl.allow("busy", start)
+	rl.allow("busy", start.Add(1500*time.Millisecond))
+	if len(rl.buckets) != 2 {
+		t.Fatalf("%d buckets before the idle period, want 2", len(rl.buckets))
+	}
+
+	rl.allow("busy", start.Add(2*time.Second))
+	if _, ok := rl.buckets["idle"]; ok {
+		t.Error("idle bucket kept after refilling completely")
+	}
+	if _, ok := rl.buckets["busy"]; !ok {
+		t.Error("busy bucket evicted")
+	}
+}
+
+func TestRateLimiterKeepsBucketsThatNeverRefill(t *testing.T) {
+	rl := NewRateLimiter(0, 1)
+	start := time.Now()
+
+	rl.allow("group-a", start)
+	if allowed, _ := rl.allow("group-a", start.Add(time.Hour)); allowed {
+		t.Fatal("bucket refilled with a zero rate")
+	}
+}
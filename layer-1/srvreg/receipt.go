@@ -0,0 +1,55 @@
+package srvreg
+
+import (
+	"fmt"
+
+	"github.com/cometbft/cometbft/crypto"
+)
+
+// receiptDomain prefixes signed receipts so a receipt signature can never be
+// mistaken for a consensus message signed by the same validator key
+const receiptDomain = "l1-commit-receipt"
+
+// CommitReceipt is the node's signed statement that a session was committed
+// in a block. Anyone holding the node's public key can check it offline.
+type CommitReceipt struct {
+	TxHash      string `json:"tx_hash"`
+	SessionID   string `json:"session_id"`
+	BlockHeight int64  `json:"block_height"`
+	Algorithm   string `json:"algorithm"`
+	PublicKey   []byte `json:"public_key"` // base64 in JSON
+	Signature   []byte `json:"signature"`  // base64 in JSON
+}
+
+// ReceiptMessage returns the bytes signed for a receipt. Fields are
+// NUL-separated so different splits of the same characters cannot collide.
+func ReceiptMessage(txHash, sessionID string, blockHeight int64) []byte {
+	return []byte(fmt.Sprintf("%s\x00%s\x00%s\x00%d", receiptDomain, txHash, sessionID, blockHeight))
+}
+
+// SetReceiptKey signs commit responses with key, normally the node's
+// validator key. Without a key commit responses carry no receipt.
+func (sr *ServiceRegistry) SetReceiptKey(key crypto.PrivKey) {
+	sr.receiptKey = key
+}
+
+// signReceipt signs a receipt for a committed session, or returns nil when
+// no key is configured or signing fails
+func (sr *ServiceRegistry) signReceipt(txHash, sessionID string, blockHeight int64) *CommitReceipt {
+	if sr.receiptKey == nil {
+		return nil
+	}
+	signature, err := sr.receiptKey.Sign(ReceiptMessage(txHash, sessionID, blockHeight))
+	if err != nil {
+		sr.logger.Error("Failed to sign commit receipt", "session_id", sessionID, "err", err)
+		return nil
+	}
+	return &CommitReceipt{
+		TxHash:      txHash,
+		SessionID:   sessionID,
+		BlockHeight: blockHeight,
+		Algorithm:   sr.receiptKey.Type(),
+		PublicKey:   sr.receiptKey.PubKey().Bytes(),
+		Signature:   signature,
+	}
+}
@@ -0,0 +1,53 @@
+package srvreg
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/cometbft/cometbft/crypto/ed25519"
+)
+
+func TestSignReceipt(t *testing.T) {
+	key := ed25519.GenPrivKey()
+	sr := &ServiceRegistry{}
+	sr.SetReceiptKey(key)
+
+	receipt := sr.signReceipt("AB12", "SES-1", 42)
+	if receipt == nil {
+		t.Fatal("no receipt signed")
+	}
+	if receipt.TxHash != "AB12" || receipt.SessionID != "SES-1" || receipt.BlockHeight != 42 {
+		t.Errorf("receipt = %+v, want AB12 SES-1 at 42", receipt)
+	}
+	if receipt.Algorithm != "ed25519" || !bytes.Equal(receipt.PublicKey, key.PubKey().Bytes()) {
+		t.Errorf("receipt key = %s %x, want the node's ed25519 key", receipt.Algorithm, receipt.PublicKey)
+	}
+	if !key.PubKey().VerifySignature(ReceiptMessage("AB12", "SES-1", 42), receipt.Signature) {
+		t.Error("receipt signature does not verify")
+	}
+}
+
+func TestSignReceiptTampered(t *testing.T) {
+	key := ed25519.GenPrivKey()
+	sr := &ServiceRegistry{}
+	sr.SetReceiptKey(key)
+	receipt := sr.signReceipt("AB12", "SES-1", 42)
+
+	for _, message := range [][]byte{
+		ReceiptMessage("CD34", "SES-1", 42),
+		ReceiptMessage("AB12", "SES-2", 42),
+		ReceiptMessage("AB12", "SES-1", 43),
+		// A different split of the same characters must not collide
+		ReceiptMessage("AB12\x00SES-1", "", 42),
+	} {
+		if key.PubKey().VerifySignature(message, receipt.Signature) {
+			t.Errorf("signature verifies for %q", message)
+		}
+	}
+}
+
+func TestSignReceiptWithoutKey(t *testing.T) {
+	if receipt := (&ServiceRegistry{}).signReceipt("AB12", "SES-1", 42); receipt != nil {
+		t.Errorf("receipt = %+v, want none without a key", receipt)
+	}
+}
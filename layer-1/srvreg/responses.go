@@ -0,0 +1,232 @@
+package srvreg
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/ahmadzakiakmal/thesis-extension/layer-1/repository"
+	"github.com/ahmadzakiakmal/thesis-extension/layer-1/repository/models"
+)
+
+// ErrorResponse is the body returned for failed requests
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
+
+// TransactionExistsResponse is the body returned for a commit whose session
+// already has a transaction record, carrying that record's hash
+type TransactionExistsResponse struct {
+	Error       string `json:"error"`
+	TxHash      string `json:"tx_hash"`
+	TxID        string `json:"tx_id,omitempty"`
+	SessionID   string `json:"session_id"`
+	BlockHeight int64  `json:"block_height"`
+}
+
+// SchemaErrorResponse is the body returned for a commit whose session_data
+// does not match the configured schema
+type SchemaErrorResponse struct {
+	Error  string   `json:"error"`
+	Errors []string `json:"errors"`
+}
+
+// ShardCommitResponse is the body returned for an accepted shard commit
+type ShardCommitResponse struct {
+	Message     string `json:"message"`
+	TxHash      string `json:"tx_hash"`
+	TxID        string `json:"tx_id"` // pass to /l1/verify/:txid
+	SessionID   string `json:"session_id"`
+	ShardID     string `json:"shard_id"`
+	ClientGroup string `json:"client_group"`
+	L2NodeID    string `json:"l2_node_id"`
+	BlockHeight int64  `json:"block_height"`
+	Votes       int    `json:"votes"`
+	ConsensusMs int64  `json:"consensus_ms"`
+
+	// Receipt proves to third parties that this node accepted the commit
+	Receipt *CommitReceipt `json:"receipt,omitempty"`
+}
+
+// VerifyTransactionResponse is the body returned by the verify endpoint
+type VerifyTransactionResponse struct {
+	TxID     string          `json:"tx_id"`
+	Status   string          `json:"status"`
+	Verified bool            `json:"verified"`
+	Payload  json.RawMessage `json:"payload"`
+}
+
+// MaxVerifyBatchSize caps the hashes accepted by one verify-batch request
+const MaxVerifyBatchSize = 1000
+
+// VerifyBatchRequest lists the transaction hashes to look up
+type VerifyBatchRequest struct {
+	TxHashes []string `json:"tx_hashes"`
+}
+
+// VerifyBatchResult is the outcome for one hash, in request order. The
+// transaction fields are empty when the hash was not found.
+type VerifyBatchResult struct {
+	TxHash      string `json:"tx_hash"`
+	Found       bool   `json:"found"`
+	Status      string `json:"status,omitempty"`
+	SessionID   string `json:"session_id,omitempty"`
+	ShardID     string `json:"shard_id,omitempty"`
+	BlockHeight int64  `json:"block_height,omitempty"`
+}
+
+// VerifyBatchResponse is the body returned by the verify-batch endpoint
+type VerifyBatchResponse struct {
+	Results  []VerifyBatchResult `json:"results"`
+	Found    int                 `json:"found"`
+	NotFound int                 `json:"not_found"`
+}
+
+// TransactionsResponse is the body returned by the transaction listing.
+// NextSince is the height to pass as since on the next incremental pull.
+type TransactionsResponse struct {
+	Transactions []models.Transaction `json:"transactions"`
+	Count        int                  `json:"count"`
+	NextSince    int64                `json:"next_since"`
+}
+
+// SessionsResponse is one page of a session listing. Total counts every
+// session matching the filter, not just this page.
+type SessionsResponse struct {
+	Sessions []models.Session `json:"sessions"`
+	Count    int              `json:"count"`
+	Total    int64            `json:"total"`
+	Limit    int              `json:"limit"`
+	Offset   int              `json:"offset"`
+}
+
+// SessionCountResponse is the number of sessions in a client group
+type SessionCountResponse struct {
+	ClientGroup string `json:"client_group"`
+	Status      string `json:"status,omitempty"`
+	Count       int64  `json:"count"`
+}
+
+// FlushMempoolResponse is the body returned when the mempool is flushed
+type FlushMempoolResponse struct {
+	Message string `json:"message"`
+	Removed int    `json:"removed"`
+}
+
+// StatusResponse is the body returned by the status endpoint
+type StatusResponse struct {
+	Status string    `json:"status"`
+	Layer  string    `json:"layer"`
+	Type   string    `json:"type"`
+	Time   time.Time `json:"time"`
+
+	// Consensus is the node's consensus state, or nil when it could not be
+	// read, in which case ConsensusError says why
+	Consensus      *repository.ConsensusStatus `json:"consensus,omitempty"`
+	ConsensusError string                      `json:"consensus_error,omitempty"`
+}
+
+// Shard health as derived from heartbeats
+const (
+	ShardHealthy = "healthy"
+	ShardStale   = "stale"
+	ShardUnknown = "unknown" // never sent a heartbeat
+)
+
+// ShardStatus is a registered shard along with its heartbeat health
+type ShardStatus struct {
+	models.ShardInfo
+	Health string `json:"Health"`
+}
+
+// ShardsResponse is the body returned by the shards endpoint
+type ShardsResponse struct {
+	Shards []ShardStatus `json:"shards"`
+	Count  int           `json:"count"`
+}
+
+// OperatorsResponse is a page of the operator listing
+type OperatorsResponse struct {
+	Operators []models.Operator `json:"operators"`
+	Count     int               `json:"count"`
+	Total     int64             `json:"total"`
+	Limit     int               `json:"limit"`
+	Offset    int               `json:"offset"`
+}
+
+// CreateOperatorRequest is the body of POST /l1/operators. An empty
+// access_level gets the database default, Basic.
+type CreateOperatorRequest struct {
+	OperatorID  string `json:"operator_id"`
+	Name        string `json:"name"`
+	Role        string `json:"role"`
+	AccessLevel string `json:"access_level"`
+	ShardID     string `json:"shard_id"`
+}
+
+// validate returns why the request can't be stored, or "" if it can. The
+// limits match the operators column sizes.
+func (c *CreateOperatorRequest) validate() string {
+	switch {
+	case c.OperatorID == "" || len(c.OperatorID) > 50:
+		return "operator_id must be 1-50 characters"
+	case c.Name == "" || len(c.Name) > 100:
+		return "name must be 1-100 characters"
+	case len(c.Role) > 50:
+		return "role must be at most 50 characters"
+	case len(c.AccessLevel) > 20:
+		return "access_level must be at most 20 characters"
+	case c.ShardID == "":
+		return "shard_id is required"
+	}
+	return ""
+}
+
+// ShardHeartbeatRequest is the body an L2 node sends with a heartbeat. SentAt
+// makes each heartbeat distinct so the duplicate request cache never
+// swallows one.
+type ShardHeartbeatRequest struct {
+	L2NodeID string    `json:"l2_node_id"`
+	SentAt   time.Time `json:"sent_at"`
+}
+
+// ShardHeartbeatResponse acknowledges a heartbeat
+type ShardHeartbeatResponse struct {
+	ShardID  string    `json:"shard_id"`
+	LastSeen time.Time `json:"last_seen"`
+}
+
+// DeregisterShardResponse is the body returned when a shard is deregistered
+// or its data purged
+type DeregisterShardResponse struct {
+	ShardID string `json:"shard_id"`
+	Status  string `json:"status"`
+	Purged  bool   `json:"purged"`
+
+	SessionsDeleted     int64 `json:"sessions_deleted,omitempty"`
+	TransactionsDeleted int64 `json:"transactions_deleted,omitempty"`
+}
+
+// jsonResponse marshals body into a JSON response with the given status code
+func jsonResponse(statusCode int, body interface{}) (*Response, error) {
+	bodyBytes, err := json.Marshal(body)
+	if err != nil {
+		return &Response{
+			StatusCode: http.StatusInternalServerError,
+			Headers:    defaultHeaders,
+			Body:       `{"error":"Failed to serialize response"}`,
+		}, err
+	}
+
+	return &Response{
+		StatusCode: statusCode,
+		Headers:    defaultHeaders,
+		Body:       string(bodyBytes),
+	}, nil
+}
+
+// errorResponse builds a JSON error response with the given status code
+func errorResponse(statusCode int, message string) *Response {
+	response, _ := jsonResponse(statusCode, ErrorResponse{Error: message})
+	return response
+}
@@ -0,0 +1,45 @@
+package srvreg
+
+import "strings"
+
+// pathPattern is a route path split into segments once at registration.
+// Segments starting with ':' are named parameters, e.g. ":shard" in
+// "/l1/shards/:shard/sessions".
+type pathPattern []string
+
+// compilePattern splits a route path into a pathPattern
+func compilePattern(path string) pathPattern {
+	return pathPattern(strings.Split(path, "/"))
+}
+
+// splitPath splits a request path into the segments patterns match against
+func splitPath(path string) []string {
+	return strings.Split(path, "/")
+}
+
+// match reports whether the path segments match the pattern and returns the
+// named parameters, keyed without the ':'. Parameters must capture a value,
+// so "/l1/shards//sessions" does not match.
+func (p pathPattern) match(segments []string) (map[string]string, bool) {
+	if len(p) != len(segments) {
+		return nil, false
+	}
+
+	var params map[string]string
+	for i, part := range p {
+		if name, ok := strings.CutPrefix(part, ":"); ok {
+			if segments[i] == "" {
+				return nil, false
+			}
+			if params == nil {
+				params = make(map[string]string)
+			}
+			params[name] = segments[i]
+			continue
+		}
+		if part != segments[i] {
+			return nil, false
+		}
+	}
+	return params, true
+}
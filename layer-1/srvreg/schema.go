@@ -0,0 +1,130 @@
+package srvreg
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+)
+
+// SessionSchema is the subset of JSON Schema used to validate a commit's
+// session_data: type, required, properties and items. Other keywords are
+// ignored, so a full JSON Schema document can be used as long as it only
+// relies on these for what must be enforced.
+type SessionSchema struct {
+	Type       string                    `json:"type,omitempty"`
+	Required   []string                  `json:"required,omitempty"`
+	Properties map[string]*SessionSchema `json:"properties,omitempty"`
+	Items      *SessionSchema            `json:"items,omitempty"`
+}
+
+// LoadSessionSchema reads a session_data schema from a JSON file
+func LoadSessionSchema(path string) (*SessionSchema, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading session schema: %w", err)
+	}
+
+	var schema SessionSchema
+	if err := json.Unmarshal(raw, &schema); err != nil {
+		return nil, fmt.Errorf("parsing session schema %s: %w", path, err)
+	}
+	if err := schema.check("session_data"); err != nil {
+		return nil, fmt.Errorf("session schema %s: %w", path, err)
+	}
+	return &schema, nil
+}
+
+// schemaTypes are the JSON Schema types a schema may require
+var schemaTypes = map[string]bool{
+	"object": true, "array": true, "string": true, "number": true,
+	"integer": true, "boolean": true, "null": true,
+}
+
+// check rejects unknown types so a typo in the schema fails at startup
+// instead of rejecting every commit
+func (s *SessionSchema) check(path string) error {
+	if s.Type != "" && !schemaTypes[s.Type] {
+		return fmt.Errorf("%s: unknown type %q", path, s.Type)
+	}
+	for name, property := range s.Properties {
+		if property == nil {
+			continue
+		}
+		if err := property.check(path + "." + name); err != nil {
+			return err
+		}
+	}
+	if s.Items != nil {
+		return s.Items.check(path + "[]")
+	}
+	return nil
+}
+
+// Validate returns every way value fails the schema, or nil if it conforms.
+// value is a decoded JSON value rooted at session_data.
+func (s *SessionSchema) Validate(value interface{}) []string {
+	var errs []string
+	s.validate("session_data", value, &errs)
+	return errs
+}
+
+func (s *SessionSchema) validate(path string, value interface{}, errs *[]string) {
+	if s.Type != "" && !matchesType(s.Type, value) {
+		*errs = append(*errs, fmt.Sprintf("%s: expected %s, got %s", path, s.Type, jsonType(value)))
+		return
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for _, name := range s.Required {
+			if _, ok := v[name]; !ok {
+				*errs = append(*errs, fmt.Sprintf("%s.%s: required", path, name))
+			}
+		}
+		names := make([]string, 0, len(s.Properties))
+		for name := range s.Properties {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if property, ok := v[name]; ok && s.Properties[name] != nil {
+				s.Properties[name].validate(path+"."+name, property, errs)
+			}
+		}
+	case []interface{}:
+		if s.Items != nil {
+			for i, item := range v {
+				s.Items.validate(fmt.Sprintf("%s[%d]", path, i), item, errs)
+			}
+		}
+	}
+}
+
+// matchesType reports whether value is of the JSON Schema type typ
+func matchesType(typ string, value interface{}) bool {
+	if typ == "integer" {
+		number, ok := value.(float64)
+		return ok && number == float64(int64(number))
+	}
+	return jsonType(value) == typ
+}
+
+// jsonType names the JSON type of a value decoded by encoding/json
+func jsonType(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case string:
+		return "string"
+	case float64:
+		return "number"
+	case bool:
+		return "boolean"
+	}
+	return fmt.Sprintf("%T", value)
+}
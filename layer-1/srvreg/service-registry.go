@@ -2,18 +2,29 @@ package srvreg
 
 import (
 	"bytes"
+	"context"
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
+	"math"
 	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/ahmadzakiakmal/thesis-extension/layer-1/repository"
+	"github.com/ahmadzakiakmal/thesis-extension/layer-1/repository/models"
+	"github.com/ahmadzakiakmal/thesis-extension/layer-1/tracing"
+	"github.com/cometbft/cometbft/crypto"
 	cmtlog "github.com/cometbft/cometbft/libs/log"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // Request represents the client's HTTP request
@@ -22,9 +33,25 @@ type Request struct {
 	Path       string            `json:"path"`
 	Headers    map[string]string `json:"headers"`
 	Body       string            `json:"body"`
+	Query      url.Values        `json:"query,omitempty"`
 	RemoteAddr string            `json:"remote_addr"`
 	RequestID  string            `json:"request_id"`
 	Timestamp  time.Time         `json:"timestamp"`
+
+	// Params holds the named path parameters of the matched route, e.g.
+	// Params["shard"] for "/l1/shards/:shard/sessions"
+	Params map[string]string `json:"-"`
+
+	// Context is canceled when the client disconnects
+	Context context.Context `json:"-"`
+}
+
+// Ctx returns the request context, falling back to context.Background
+func (r *Request) Ctx() context.Context {
+	if r.Context == nil {
+		return context.Background()
+	}
+	return r.Context
 }
 
 // Response represents the computed response from server
@@ -56,28 +83,124 @@ type RouteKey struct {
 type ServiceRegistry struct {
 	handlers    map[RouteKey]ServiceHandler
 	exactRoutes map[RouteKey]bool
+	patterns    map[RouteKey]pathPattern
+	docs        map[RouteKey]RouteDoc
 	mu          sync.RWMutex
 	repository  *repository.Repository
 	logger      cmtlog.Logger
+	rateLimiter *RateLimiter
+	dedupe      *DedupeCache
+
+	mempoolFlush bool
+
+	// maxSessionDataBytes caps a commit's serialized session_data, 0 disables
+	maxSessionDataBytes int
+
+	// sessionSchema validates commit session_data, nil disables
+	sessionSchema *SessionSchema
+
+	// receiptKey signs commit receipts, nil leaves them out
+	receiptKey crypto.PrivKey
+
+	// heartbeatTimeout is how long a shard stays healthy after a heartbeat
+	heartbeatTimeout time.Duration
 }
 
 var defaultHeaders = map[string]string{"Content-Type": "application/json"}
 
+// Page sizes for the transaction listing
+const (
+	defaultTransactionLimit = 100
+	maxTransactionLimit     = 1000
+)
+
+// Page sizes for the mempool listing
+const (
+	defaultMempoolLimit = 30
+	maxMempoolLimit     = 100
+)
+
+// Page sizes for the session listings
+const (
+	defaultSessionLimit = 50
+	maxSessionLimit     = 500
+)
+
 // NewServiceRegistry creates a new service registry for L1
 func NewServiceRegistry(repository *repository.Repository, logger cmtlog.Logger) *ServiceRegistry {
 	return &ServiceRegistry{
 		handlers:    make(map[RouteKey]ServiceHandler),
 		exactRoutes: make(map[RouteKey]bool),
+		patterns:    make(map[RouteKey]pathPattern),
+		docs:        make(map[RouteKey]RouteDoc),
 		repository:  repository,
 		logger:      logger,
+
+		heartbeatTimeout: DefaultHeartbeatTimeout,
+	}
+}
+
+// DefaultHeartbeatTimeout is how long a shard is reported healthy after its
+// last heartbeat
+const DefaultHeartbeatTimeout = 30 * time.Second
+
+// SetHeartbeatTimeout sets how long a shard is reported healthy after its last
+// heartbeat. Non-positive values keep the current timeout.
+func (sr *ServiceRegistry) SetHeartbeatTimeout(timeout time.Duration) {
+	if timeout > 0 {
+		sr.heartbeatTimeout = timeout
+	}
+}
+
+// SetMempoolFlush enables POST /l1/mempool/flush. It drops pending commits
+// and is meant for development only.
+func (sr *ServiceRegistry) SetMempoolFlush(enabled bool) {
+	sr.mempoolFlush = enabled
+}
+
+// SetMaxSessionDataBytes limits the serialized session_data accepted on
+// commits. A non-positive limit disables the check.
+func (sr *ServiceRegistry) SetMaxSessionDataBytes(limit int) {
+	sr.maxSessionDataBytes = max(limit, 0)
+}
+
+// SetSessionSchema validates the session_data of commits against schema
+// before they reach consensus. A nil schema disables validation.
+func (sr *ServiceRegistry) SetSessionSchema(schema *SessionSchema) {
+	sr.sessionSchema = schema
+}
+
+// SetCommitRateLimit enables per client group rate limiting on shard commits.
+// A non-positive rate disables the limiter.
+func (sr *ServiceRegistry) SetCommitRateLimit(rate float64, burst int) {
+	if rate <= 0 {
+		sr.rateLimiter = nil
+		return
+	}
+	sr.rateLimiter = NewRateLimiter(rate, burst)
+}
+
+// SetDedupeTTL replays the response of a successful write request to
+// identical requests received within ttl. A non-positive ttl disables it.
+func (sr *ServiceRegistry) SetDedupeTTL(ttl time.Duration) {
+	if ttl <= 0 {
+		sr.dedupe = nil
+		return
 	}
+	sr.dedupe = NewDedupeCache(ttl)
 }
 
 // GenerateRequestID generates a deterministic ID for the request
 func (r *Request) GenerateRequestID() {
+	r.RequestID = r.DeterministicID()
+}
+
+// DeterministicID hashes the path, method and body. The receive timestamp is
+// left out so a client retrying the same request gets the same ID.
+func (r *Request) DeterministicID() string {
 	hasher := sha256.New()
-	hasher.Write([]byte(fmt.Sprintf("%s-%s-%s-%s", r.Path, r.Method, r.Body, r.Timestamp)))
-	r.RequestID = hex.EncodeToString(hasher.Sum(nil)[:16])
+	hasher.Write([]byte(fmt.Sprintf("%s-%s-%s", r.Path, r.Method, r.Body)))
+	return hex.EncodeToString(hasher.Sum(nil)[:16])
 }
 
 // RegisterHandler registers a new service handler
@@ -85,25 +208,30 @@ func (sr *ServiceRegistry) RegisterHandler(method, path string, isExactPath bool
 	sr.mu.Lock()
 	defer sr.mu.Unlock()
 
-	key := RouteKey{Method: strings.ToUpper(method), Path: path}
+	key := RouteKey{Method: strings.ToUpper(method), Path: normalizePath(path)}
 	sr.handlers[key] = handler
 	sr.exactRoutes[key] = isExactPath
+	sr.patterns[key] = compilePattern(key.Path)
 }
 
-// GetHandlerForPath finds the appropriate handler for a given path
-func (sr *ServiceRegistry) GetHandlerForPath(method, path string) (ServiceHandler, bool) {
+// GetHandlerForPath finds the appropriate handler for a given path, along
+// with the named path parameters of its route
+func (sr *ServiceRegistry) GetHandlerForPath(method, path string) (ServiceHandler, map[string]string, bool) {
 	sr.mu.RLock()
 	defer sr.mu.RUnlock()
 
+	path = normalizePath(path)
+
 	// Try exact match first
 	key := RouteKey{Method: strings.ToUpper(method), Path: path}
 	if handler, ok := sr.handlers[key]; ok {
 		if sr.exactRoutes[key] {
-			return handler, true
+			return handler, nil, true
 		}
 	}
 
 	// Try pattern matching
+	segments := splitPath(path)
 	for routeKey, handler := range sr.handlers {
 		if routeKey.Method != strings.ToUpper(method) {
 			continue
@@ -113,48 +241,184 @@ func (sr *ServiceRegistry) GetHandlerForPath(method, path string) (ServiceHandle
 			continue
 		}
 
-		if matchPath(routeKey.Path, path) {
-			return handler, true
+		if params, ok := sr.patterns[routeKey].match(segments); ok {
+			return handler, params, true
 		}
 	}
 
-	return nil, false
+	return nil, nil, false
 }
 
-// matchPath does simple pattern matching for routes
-func matchPath(pattern, path string) bool {
-	patternParts := strings.Split(pattern, "/")
-	pathParts := strings.Split(path, "/")
+// AllowedMethods returns the sorted methods registered for a path, regardless
+// of the method used in the request
+func (sr *ServiceRegistry) AllowedMethods(path string) []string {
+	sr.mu.RLock()
+	defer sr.mu.RUnlock()
 
-	if len(patternParts) != len(pathParts) {
-		return false
-	}
+	path = normalizePath(path)
+	segments := splitPath(path)
 
-	for i := range len(patternParts) {
-		if strings.HasPrefix(patternParts[i], ":") {
-			continue
+	seen := make(map[string]bool)
+	for routeKey := range sr.handlers {
+		matched := routeKey.Path == path
+		if !sr.exactRoutes[routeKey] {
+			_, ok := sr.patterns[routeKey].match(segments)
+			matched = matched || ok
 		}
-		if patternParts[i] != pathParts[i] {
-			return false
+		if matched {
+			seen[routeKey.Method] = true
 		}
 	}
 
-	return true
+	methods := make([]string, 0, len(seen))
+	for method := range seen {
+		methods = append(methods, method)
+	}
+	sort.Strings(methods)
+	return methods
+}
+
+// normalizePath trims a single trailing slash so "/l1/status/" and
+// "/l1/status" resolve to the same route. The root path is left untouched.
+func normalizePath(path string) string {
+	if len(path) > 1 && strings.HasSuffix(path, "/") {
+		return path[:len(path)-1]
+	}
+	return path
 }
 
 // RegisterDefaultServices sets up default services for L1
 func (sr *ServiceRegistry) RegisterDefaultServices() {
 	// Main endpoint: Receive commits from L2 shards
 	sr.RegisterHandler("POST", "/l1/commit", true, sr.ReceiveShardCommitHandler)
+	sr.DocumentRoute("POST", "/l1/commit", RouteDoc{
+		Summary:  "Receive a session commit from an L2 shard",
+		Status:   http.StatusAccepted,
+		Request:  repository.ShardedCommitRequest{},
+		Response: ShardCommitResponse{},
+	})
 
 	// Cross-shard query endpoints
+	sr.RegisterHandler("GET", "/l1/sessions", true, sr.SearchSessionsHandler)
+	sr.DocumentRoute("GET", "/l1/sessions", RouteDoc{
+		Summary:  "Search sessions by any combination of filters (?group=&shard=&operator=&status=&from=&to=&limit=&offset=)",
+		Response: SessionsResponse{},
+	})
+	sr.RegisterHandler("GET", "/l1/sessions/:id", false, sr.GetSessionHandler)
+	sr.DocumentRoute("GET", "/l1/sessions/:id", RouteDoc{
+		Summary:  "Get a session by ID with its shard and transaction",
+		Response: models.Session{},
+	})
 	sr.RegisterHandler("GET", "/l1/sessions/group/:group", false, sr.GetSessionsByGroupHandler)
+	sr.DocumentRoute("GET", "/l1/sessions/group/:group", RouteDoc{
+		Summary:  "List sessions for a client group",
+		Response: []models.Session{},
+	})
+	sr.RegisterHandler("GET", "/l1/sessions/group/:group/count", false, sr.CountSessionsByGroupHandler)
+	sr.DocumentRoute("GET", "/l1/sessions/group/:group/count", RouteDoc{
+		Summary:  "Count sessions for a client group (?status=)",
+		Response: SessionCountResponse{},
+	})
 	sr.RegisterHandler("GET", "/l1/sessions/shard/:shard", false, sr.GetSessionsByShardHandler)
+	sr.DocumentRoute("GET", "/l1/sessions/shard/:shard", RouteDoc{
+		Summary:  "List sessions committed by a shard (?status=&limit=&offset=)",
+		Response: SessionsResponse{},
+	})
 	sr.RegisterHandler("GET", "/l1/transaction/:hash", false, sr.GetTransactionHandler)
+	sr.DocumentRoute("GET", "/l1/transaction/:hash", RouteDoc{
+		Summary:  "Get a committed transaction by hash",
+		Response: models.Transaction{},
+	})
+	sr.RegisterHandler("POST", "/l1/verify-batch", true, sr.VerifyBatchHandler)
+	sr.DocumentRoute("POST", "/l1/verify-batch", RouteDoc{
+		Summary:  "Look up many transactions by hash at once",
+		Request:  VerifyBatchRequest{},
+		Response: VerifyBatchResponse{},
+	})
+	sr.RegisterHandler("GET", "/l1/transactions", true, sr.ListTransactionsHandler)
+	sr.DocumentRoute("GET", "/l1/transactions", RouteDoc{
+		Summary:  "List transactions committed above a block height (?since=&limit=)",
+		Response: TransactionsResponse{},
+	})
+	sr.RegisterHandler("GET", "/l1/verify/:txid", false, sr.VerifyTransactionHandler)
+	sr.DocumentRoute("GET", "/l1/verify/:txid", RouteDoc{
+		Summary:  "Verify a transaction against consensus state",
+		Response: VerifyTransactionResponse{},
+	})
+	sr.RegisterHandler("GET", "/l1/block/:height", false, sr.GetBlockHandler)
+	sr.DocumentRoute("GET", "/l1/block/:height", RouteDoc{
+		Summary:  "List the shard commits in a block",
+		Response: repository.BlockSummary{},
+	})
+	sr.RegisterHandler("GET", "/l1/audit/:session_id", false, sr.AuditSessionHandler)
+	sr.DocumentRoute("GET", "/l1/audit/:session_id", RouteDoc{
+		Summary:  "Prove a session's commit against the chain and the mirror",
+		Response: repository.AuditReport{},
+	})
 
 	// System endpoints
+	sr.RegisterHandler("GET", "/l1/reconcile", true, sr.ReconcileHandler)
+	sr.DocumentRoute("GET", "/l1/reconcile", RouteDoc{
+		Summary:  "Dry-run comparison of recent blocks against the PostgreSQL mirror (?depth=)",
+		Response: repository.ReconcileReport{},
+	})
+	sr.RegisterHandler("GET", "/l1/mempool", true, sr.MempoolHandler)
+	sr.DocumentRoute("GET", "/l1/mempool", RouteDoc{
+		Summary:  "Pending transactions in the mempool (?limit=)",
+		Response: repository.MempoolStatus{},
+	})
+	sr.RegisterHandler("POST", "/l1/mempool/flush", true, sr.FlushMempoolHandler)
+	sr.DocumentRoute("POST", "/l1/mempool/flush", RouteDoc{
+		Summary:  "Drop all pending transactions (requires --enable-mempool-flush)",
+		Response: FlushMempoolResponse{},
+	})
 	sr.RegisterHandler("GET", "/l1/status", true, sr.StatusHandler)
+	sr.DocumentRoute("GET", "/l1/status", RouteDoc{
+		Summary:  "Get L1 status with consensus state (503 when it cannot be read)",
+		Response: StatusResponse{},
+	})
+	sr.RegisterHandler("GET", "/l1/stats", true, sr.StatsHandler)
+	sr.DocumentRoute("GET", "/l1/stats", RouteDoc{
+		Summary:  "Consensus latency across committed transactions",
+		Response: repository.TransactionStats{},
+	})
 	sr.RegisterHandler("GET", "/l1/shards", true, sr.GetShardsHandler)
+	sr.DocumentRoute("GET", "/l1/shards", RouteDoc{
+		Summary:  "List registered shards with their heartbeat health",
+		Response: ShardsResponse{},
+	})
+	sr.RegisterHandler("POST", "/l1/shards/:shard/heartbeat", false, sr.ShardHeartbeatHandler)
+	sr.DocumentRoute("POST", "/l1/shards/:shard/heartbeat", RouteDoc{
+		Summary:  "Record that a shard's L2 node is alive",
+		Request:  ShardHeartbeatRequest{},
+		Response: ShardHeartbeatResponse{},
+	})
+	sr.RegisterHandler("DELETE", "/l1/shards/:shard", false, sr.DeregisterShardHandler)
+	sr.DocumentRoute("DELETE", "/l1/shards/:shard", RouteDoc{
+		Summary:  "Deregister a shard; ?purge=true&confirm=<shard> then deletes an inactive shard's sessions and transactions",
+		Response: DeregisterShardResponse{},
+	})
+	sr.RegisterHandler("GET", "/l1/shards/:shard/sessions", false, sr.GetShardSessionsHandler)
+	sr.DocumentRoute("GET", "/l1/shards/:shard/sessions", RouteDoc{
+		Summary:  "List sessions committed by a shard (?status=&limit=&offset=)",
+		Response: SessionsResponse{},
+	})
+	sr.RegisterHandler("GET", "/l1/operators", true, sr.ListOperatorsHandler)
+	sr.DocumentRoute("GET", "/l1/operators", RouteDoc{
+		Summary:  "List registered operators (?shard=&limit=&offset=)",
+		Response: OperatorsResponse{},
+	})
+	sr.RegisterHandler("POST", "/l1/operators", true, sr.CreateOperatorHandler)
+	sr.DocumentRoute("POST", "/l1/operators", RouteDoc{
+		Summary:  "Register an operator with an existing shard; 409 if the ID is taken",
+		Request:  CreateOperatorRequest{},
+		Response: models.Operator{},
+	})
+	sr.RegisterHandler("GET", "/l1/operators/:id/sessions", false, sr.GetOperatorSessionsHandler)
+	sr.DocumentRoute("GET", "/l1/operators/:id/sessions", RouteDoc{
+		Summary:  "List sessions handled by an operator across all shards (?status=&limit=&offset=)",
+		Response: SessionsResponse{},
+	})
 }
 
 // ReceiveShardCommitHandler handles commits from L2 shards
@@ -163,204 +427,651 @@ func (sr *ServiceRegistry) ReceiveShardCommitHandler(req *Request) (*Response, e
 	err := json.Unmarshal([]byte(req.Body), &commitReq)
 	if err != nil {
 		sr.logger.Error("Failed to parse shard commit request", "error", err.Error())
-		return &Response{
-			StatusCode: http.StatusBadRequest,
-			Headers:    defaultHeaders,
-			Body:       fmt.Sprintf(`{"error":"Invalid request format: %s"}`, err.Error()),
-		}, err
+		return errorResponse(http.StatusBadRequest, "Invalid request format: "+err.Error()), err
 	}
 
 	// Validate required fields
 	if commitReq.ShardID == "" || commitReq.SessionID == "" || commitReq.ClientGroup == "" {
-		return &Response{
-			StatusCode: http.StatusBadRequest,
-			Headers:    defaultHeaders,
-			Body:       `{"error":"Missing required fields: shard_id, session_id, client_group"}`,
-		}, fmt.Errorf("missing required fields")
+		return errorResponse(http.StatusBadRequest, "Missing required fields: shard_id, session_id, client_group"),
+			fmt.Errorf("missing required fields")
+	}
+	if commitReq.Sequence < 0 {
+		return errorResponse(http.StatusBadRequest, "sequence must not be negative"),
+			fmt.Errorf("negative sequence %d", commitReq.Sequence)
+	}
+
+	// Reject oversized payloads before they reach the mempool
+	if sr.maxSessionDataBytes > 0 {
+		size, err := commitReq.SessionDataSize()
+		if err != nil {
+			return errorResponse(http.StatusBadRequest, "Invalid session_data: "+err.Error()), err
+		}
+		if size > sr.maxSessionDataBytes {
+			message := fmt.Sprintf("session_data is %d bytes, exceeding the limit of %d bytes", size, sr.maxSessionDataBytes)
+			return errorResponse(http.StatusRequestEntityTooLarge, message), fmt.Errorf("%s", message)
+		}
+	}
+
+	// Reject payloads that don't match the session_data schema
+	if sr.sessionSchema != nil {
+		if errs := sr.sessionSchema.Validate(map[string]interface{}(commitReq.SessionData)); len(errs) > 0 {
+			response, _ := jsonResponse(http.StatusUnprocessableEntity, SchemaErrorResponse{
+				Error:  "session_data does not match the schema",
+				Errors: errs,
+			})
+			return response, fmt.Errorf("session_data does not match the schema: %d errors", len(errs))
+		}
+	}
+
+	// Enforce per client group rate limit. Buckets are keyed by the client
+	// group registered for the shard, so unknown shards and made-up groups
+	// can't grow the limiter.
+	if sr.rateLimiter != nil {
+		shard, repoErr := sr.repository.ResolveCommitShard(commitReq.ShardID)
+		if repoErr != nil {
+			return shardErrorResponse(repoErr)
+		}
+		if allowed, retryAfter := sr.rateLimiter.Allow(shard.ClientGroup); !allowed {
+			sr.logger.Info("Rate limit exceeded", "client_group", shard.ClientGroup, "retry_after", retryAfter)
+			response := errorResponse(http.StatusTooManyRequests, "Rate limit exceeded for client group "+shard.ClientGroup)
+			response.Headers = map[string]string{
+				"Content-Type": "application/json",
+				"Retry-After":  strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))),
+			}
+			return response, fmt.Errorf("rate limit exceeded for client group %s", shard.ClientGroup)
+		}
 	}
 
+	ctx, span := tracing.Tracer().Start(req.Ctx(), "ReceiveShardCommit", trace.WithAttributes(
+		attribute.String("l1.shard_id", commitReq.ShardID),
+		attribute.String("l1.session_id", commitReq.SessionID),
+		attribute.String("l1.client_group", commitReq.ClientGroup),
+	))
+	defer span.End()
+
 	// Process the shard commit
-	transaction, repoErr := sr.repository.ReceiveShardCommit(&commitReq)
+	transaction, consensusResult, repoErr := sr.repository.ReceiveShardCommit(ctx, &commitReq)
 	if repoErr != nil {
+		span.SetStatus(codes.Error, repoErr.Code)
 		switch repoErr.Code {
-		case "SHARD_NOT_FOUND":
-			return &Response{
-				StatusCode: http.StatusBadRequest,
-				Headers:    defaultHeaders,
-				Body:       fmt.Sprintf(`{"error":"%s"}`, repoErr.Detail),
-			}, fmt.Errorf("shard not found: %s", repoErr.Detail)
+		case "SHARD_NOT_FOUND", "SHARD_NOT_ALLOWED", "SHARD_INACTIVE":
+			return shardErrorResponse(repoErr)
+		case "OPERATOR_MISMATCH":
+			return errorResponse(http.StatusForbidden, repoErr.Detail),
+				fmt.Errorf("operator mismatch: %s", repoErr.Detail)
 		case "SESSION_EXISTS":
-			return &Response{
-				StatusCode: http.StatusConflict,
-				Headers:    defaultHeaders,
-				Body:       fmt.Sprintf(`{"error":"%s"}`, repoErr.Detail),
-			}, fmt.Errorf("session exists: %s", repoErr.Detail)
+			return errorResponse(http.StatusConflict, repoErr.Detail),
+				fmt.Errorf("session exists: %s", repoErr.Detail)
+		case "TRANSACTION_EXISTS":
+			response, _ := jsonResponse(http.StatusConflict, TransactionExistsResponse{
+				Error:       repoErr.Detail,
+				TxHash:      transaction.TxHash,
+				TxID:        transaction.TxID,
+				SessionID:   transaction.SessionID,
+				BlockHeight: transaction.BlockHeight,
+			})
+			return response, fmt.Errorf("transaction exists: %s", repoErr.Detail)
 		default:
-			return &Response{
-				StatusCode: http.StatusInternalServerError,
-				Headers:    defaultHeaders,
-				Body:       `{"error":"Internal server error"}`,
-			}, fmt.Errorf("repository error: %s", repoErr.Detail)
-		}
-	}
-
-	return &Response{
-		StatusCode: http.StatusAccepted,
-		Headers:    defaultHeaders,
-		Body: fmt.Sprintf(`{
-			"message": "Shard commit processed successfully",
-			"tx_hash": "%s",
-			"session_id": "%s",
-			"shard_id": "%s",
-			"block_height": %d
-		}`, transaction.TxHash, transaction.SessionID, transaction.ShardID, transaction.BlockHeight),
-	}, nil
+			return errorResponse(http.StatusInternalServerError, "Internal server error"),
+				fmt.Errorf("repository error: %s", repoErr.Detail)
+		}
+	}
+
+	return jsonResponse(http.StatusAccepted, ShardCommitResponse{
+		Message:     "Shard commit processed successfully",
+		TxHash:      transaction.TxHash,
+		TxID:        transaction.TxID,
+		SessionID:   transaction.SessionID,
+		ShardID:     transaction.ShardID,
+		ClientGroup: transaction.ClientGroup,
+		L2NodeID:    commitReq.L2NodeID,
+		BlockHeight: transaction.BlockHeight,
+		Votes:       consensusResult.Votes,
+		ConsensusMs: transaction.ConsensusMs,
+		Receipt:     sr.signReceipt(transaction.TxHash, transaction.SessionID, transaction.BlockHeight),
+	})
 }
 
-// GetSessionsByGroupHandler retrieves sessions by client group
-func (sr *ServiceRegistry) GetSessionsByGroupHandler(req *Request) (*Response, error) {
-	pathParts := strings.Split(req.Path, "/")
-	if len(pathParts) != 5 {
-		return &Response{
-			StatusCode: http.StatusBadRequest,
-			Headers:    defaultHeaders,
-			Body:       `{"error":"Invalid path format"}`,
-		}, fmt.Errorf("invalid path format")
+// shardErrorResponse maps a failure to resolve the committing shard
+func shardErrorResponse(repoErr *repository.RepositoryError) (*Response, error) {
+	switch repoErr.Code {
+	case "SHARD_NOT_FOUND":
+		return errorResponse(http.StatusBadRequest, repoErr.Detail),
+			fmt.Errorf("shard not found: %s", repoErr.Detail)
+	case "SHARD_NOT_ALLOWED", "SHARD_INACTIVE":
+		return errorResponse(http.StatusForbidden, repoErr.Detail),
+			fmt.Errorf("shard not allowed: %s", repoErr.Detail)
+	default:
+		return errorResponse(http.StatusInternalServerError, "Internal server error"),
+			fmt.Errorf("repository error: %s", repoErr.Detail)
+	}
+}
+
+// GetSessionHandler retrieves a single session by ID
+func (sr *ServiceRegistry) GetSessionHandler(req *Request) (*Response, error) {
+	sessionID := req.Params["id"]
+
+	session, repoErr := sr.repository.GetSessionByID(sessionID)
+	if repoErr != nil {
+		if repoErr.Code == "SESSION_NOT_FOUND" {
+			return errorResponse(http.StatusNotFound, repoErr.Detail),
+				fmt.Errorf("session not found: %s", repoErr.Detail)
+		}
+		return errorResponse(http.StatusInternalServerError, "Internal server error"),
+			fmt.Errorf("repository error: %s", repoErr.Detail)
 	}
 
-	clientGroup := pathParts[4]
+	return jsonResponse(http.StatusOK, session)
+}
+
+// GetSessionsByGroupHandler retrieves sessions by client group
+func (sr *ServiceRegistry) GetSessionsByGroupHandler(req *Request) (*Response, error) {
+	clientGroup := req.Params["group"]
 
 	sessions, repoErr := sr.repository.GetSessionsByClientGroup(clientGroup)
 	if repoErr != nil {
-		return &Response{
-			StatusCode: http.StatusInternalServerError,
-			Headers:    defaultHeaders,
-			Body:       `{"error":"Internal server error"}`,
-		}, fmt.Errorf("repository error: %s", repoErr.Detail)
+		return errorResponse(http.StatusInternalServerError, "Internal server error"),
+			fmt.Errorf("repository error: %s", repoErr.Detail)
 	}
 
-	sessionsJSON, err := json.Marshal(sessions)
-	if err != nil {
-		return &Response{
-			StatusCode: http.StatusInternalServerError,
-			Headers:    defaultHeaders,
-			Body:       `{"error":"Failed to serialize sessions"}`,
-		}, err
+	return jsonResponse(http.StatusOK, sessions)
+}
+
+// CountSessionsByGroupHandler counts a client group's sessions, optionally
+// filtered by ?status=
+func (sr *ServiceRegistry) CountSessionsByGroupHandler(req *Request) (*Response, error) {
+	clientGroup := req.Params["group"]
+	status := req.Query.Get("status")
+
+	count, repoErr := sr.repository.CountSessionsByGroup(clientGroup, status)
+	if repoErr != nil {
+		return errorResponse(http.StatusInternalServerError, "Internal server error"),
+			fmt.Errorf("repository error: %s", repoErr.Detail)
 	}
 
-	return &Response{
-		StatusCode: http.StatusOK,
-		Headers:    defaultHeaders,
-		Body:       string(sessionsJSON),
-	}, nil
+	return jsonResponse(http.StatusOK, SessionCountResponse{
+		ClientGroup: clientGroup,
+		Status:      status,
+		Count:       count,
+	})
 }
 
 // GetSessionsByShardHandler retrieves sessions by shard
 func (sr *ServiceRegistry) GetSessionsByShardHandler(req *Request) (*Response, error) {
-	pathParts := strings.Split(req.Path, "/")
-	if len(pathParts) != 5 {
-		return &Response{
-			StatusCode: http.StatusBadRequest,
-			Headers:    defaultHeaders,
-			Body:       `{"error":"Invalid path format"}`,
-		}, fmt.Errorf("invalid path format")
+	return sr.listShardSessions(req.Params["shard"], req)
+}
+
+// GetShardSessionsHandler serves the nested /l1/shards/:shard/sessions form of
+// GetSessionsByShardHandler with the same filters and paging
+func (sr *ServiceRegistry) GetShardSessionsHandler(req *Request) (*Response, error) {
+	return sr.listShardSessions(req.Params["shard"], req)
+}
+
+// GetOperatorSessionsHandler lists the sessions an operator handled across
+// all shards, with the same filters and paging as the shard listing
+func (sr *ServiceRegistry) GetOperatorSessionsHandler(req *Request) (*Response, error) {
+	filter, response, err := parseSessionFilter(req)
+	if err != nil {
+		return response, err
+	}
+
+	sessions, total, repoErr := sr.repository.GetSessionsByOperator(req.Params["id"], filter)
+	if repoErr != nil {
+		if repoErr.Code == "OPERATOR_NOT_FOUND" {
+			return errorResponse(http.StatusNotFound, repoErr.Detail),
+				fmt.Errorf("operator not found: %s", repoErr.Detail)
+		}
+		return errorResponse(http.StatusInternalServerError, "Internal server error"),
+			fmt.Errorf("repository error: %s", repoErr.Detail)
+	}
+
+	return jsonResponse(http.StatusOK, SessionsResponse{
+		Sessions: sessions,
+		Count:    len(sessions),
+		Total:    total,
+		Limit:    filter.Limit,
+		Offset:   filter.Offset,
+	})
+}
+
+// ListOperatorsHandler lists registered operators ordered by ID, optionally
+// only those of one shard (?shard=)
+func (sr *ServiceRegistry) ListOperatorsHandler(req *Request) (*Response, error) {
+	limit, offset, response, err := parsePage(req)
+	if err != nil {
+		return response, err
 	}
+	filter := repository.OperatorFilter{
+		ShardID: req.Query.Get("shard"),
+		Limit:   limit,
+		Offset:  offset,
+	}
+
+	operators, total, repoErr := sr.repository.ListOperators(filter)
+	if repoErr != nil {
+		return errorResponse(http.StatusInternalServerError, "Internal server error"),
+			fmt.Errorf("repository error: %s", repoErr.Detail)
+	}
+
+	return jsonResponse(http.StatusOK, OperatorsResponse{
+		Operators: operators,
+		Count:     len(operators),
+		Total:     total,
+		Limit:     filter.Limit,
+		Offset:    filter.Offset,
+	})
+}
+
+// CreateOperatorHandler registers an operator with an existing shard at
+// runtime. An operator ID already in use is a 409; seeding is the way to
+// update operators.
+func (sr *ServiceRegistry) CreateOperatorHandler(req *Request) (*Response, error) {
+	var createReq CreateOperatorRequest
+	if err := json.Unmarshal([]byte(req.Body), &createReq); err != nil {
+		return errorResponse(http.StatusBadRequest, "Invalid request format: "+err.Error()), err
+	}
+	if message := createReq.validate(); message != "" {
+		return errorResponse(http.StatusBadRequest, message), fmt.Errorf("invalid operator: %s", message)
+	}
+
+	operator := models.Operator{
+		ID:          createReq.OperatorID,
+		Name:        createReq.Name,
+		Role:        createReq.Role,
+		AccessLevel: createReq.AccessLevel,
+		ShardID:     createReq.ShardID,
+	}
+	if repoErr := sr.repository.CreateOperator(&operator); repoErr != nil {
+		switch repoErr.Code {
+		case "OPERATOR_EXISTS":
+			return errorResponse(http.StatusConflict, repoErr.Detail),
+				fmt.Errorf("operator exists: %s", repoErr.Detail)
+		case "SHARD_NOT_FOUND":
+			return errorResponse(http.StatusNotFound, repoErr.Detail),
+				fmt.Errorf("shard not found: %s", repoErr.Detail)
+		}
+		return errorResponse(http.StatusInternalServerError, "Internal server error"),
+			fmt.Errorf("repository error: %s", repoErr.Detail)
+	}
+
+	sr.logger.Info("Operator created", "operator_id", operator.ID, "shard_id", operator.ShardID)
+	return jsonResponse(http.StatusCreated, operator)
+}
 
-	shardID := pathParts[4]
+// SearchSessionsHandler lists sessions matching every filter given, newest
+// first. Without filters it pages through all sessions.
+func (sr *ServiceRegistry) SearchSessionsHandler(req *Request) (*Response, error) {
+	filter, response, err := parseSessionFilter(req)
+	if err != nil {
+		return response, err
+	}
+	filter.ClientGroup = req.Query.Get("group")
+	filter.ShardID = req.Query.Get("shard")
+	filter.OperatorID = req.Query.Get("operator")
+	for _, bound := range []struct {
+		name string
+		dest *time.Time
+	}{{"from", &filter.From}, {"to", &filter.To}} {
+		raw := req.Query.Get(bound.name)
+		if raw == "" {
+			continue
+		}
+		parsed, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			return errorResponse(http.StatusBadRequest, bound.name+" must be an RFC 3339 timestamp"),
+				fmt.Errorf("invalid %s parameter: %q", bound.name, raw)
+		}
+		*bound.dest = parsed
+	}
+	if !filter.From.IsZero() && !filter.To.IsZero() && filter.To.Before(filter.From) {
+		return errorResponse(http.StatusBadRequest, "to must not be before from"),
+			fmt.Errorf("invalid time range: %s to %s", filter.From, filter.To)
+	}
 
-	sessions, repoErr := sr.repository.GetSessionsByShard(shardID)
+	sessions, total, repoErr := sr.repository.SearchSessions(filter)
 	if repoErr != nil {
-		return &Response{
-			StatusCode: http.StatusInternalServerError,
-			Headers:    defaultHeaders,
-			Body:       `{"error":"Internal server error"}`,
-		}, fmt.Errorf("repository error: %s", repoErr.Detail)
+		return errorResponse(http.StatusInternalServerError, "Internal server error"),
+			fmt.Errorf("repository error: %s", repoErr.Detail)
 	}
 
-	sessionsJSON, err := json.Marshal(sessions)
+	return jsonResponse(http.StatusOK, SessionsResponse{
+		Sessions: sessions,
+		Count:    len(sessions),
+		Total:    total,
+		Limit:    filter.Limit,
+		Offset:   filter.Offset,
+	})
+}
+
+// listShardSessions applies the status, limit and offset query parameters to a
+// shard's session listing
+func (sr *ServiceRegistry) listShardSessions(shardID string, req *Request) (*Response, error) {
+	filter, response, err := parseSessionFilter(req)
 	if err != nil {
-		return &Response{
-			StatusCode: http.StatusInternalServerError,
-			Headers:    defaultHeaders,
-			Body:       `{"error":"Failed to serialize sessions"}`,
-		}, err
+		return response, err
 	}
 
-	return &Response{
-		StatusCode: http.StatusOK,
-		Headers:    defaultHeaders,
-		Body:       string(sessionsJSON),
-	}, nil
+	sessions, total, repoErr := sr.repository.GetSessionsByShard(shardID, filter)
+	if repoErr != nil {
+		return errorResponse(http.StatusInternalServerError, "Internal server error"),
+			fmt.Errorf("repository error: %s", repoErr.Detail)
+	}
+
+	return jsonResponse(http.StatusOK, SessionsResponse{
+		Sessions: sessions,
+		Count:    len(sessions),
+		Total:    total,
+		Limit:    filter.Limit,
+		Offset:   filter.Offset,
+	})
+}
+
+// parseSessionFilter reads the status, limit and offset query parameters of a
+// session listing. On invalid input it returns the 400 response to send.
+func parseSessionFilter(req *Request) (repository.SessionFilter, *Response, error) {
+	filter := repository.SessionFilter{Status: req.Query.Get("status")}
+	limit, offset, response, err := parsePage(req)
+	filter.Limit, filter.Offset = limit, offset
+	return filter, response, err
+}
+
+// parsePage reads the limit and offset query parameters of a listing,
+// defaulting to the first defaultSessionLimit rows. On invalid input it
+// returns the 400 response to send.
+func parsePage(req *Request) (int, int, *Response, error) {
+	limit, offset := defaultSessionLimit, 0
+	if raw := req.Query.Get("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed <= 0 || parsed > maxSessionLimit {
+			return limit, offset, errorResponse(http.StatusBadRequest, fmt.Sprintf("limit must be between 1 and %d", maxSessionLimit)),
+				fmt.Errorf("invalid limit parameter: %q", raw)
+		}
+		limit = parsed
+	}
+	if raw := req.Query.Get("offset"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 0 {
+			return limit, offset, errorResponse(http.StatusBadRequest, "offset must be a non-negative integer"),
+				fmt.Errorf("invalid offset parameter: %q", raw)
+		}
+		offset = parsed
+	}
+
+	return limit, offset, nil, nil
 }
 
 // GetTransactionHandler retrieves transaction by hash
 func (sr *ServiceRegistry) GetTransactionHandler(req *Request) (*Response, error) {
-	pathParts := strings.Split(req.Path, "/")
-	if len(pathParts) != 4 {
-		return &Response{
-			StatusCode: http.StatusBadRequest,
-			Headers:    defaultHeaders,
-			Body:       `{"error":"Invalid path format"}`,
-		}, fmt.Errorf("invalid path format")
+	txHash := req.Params["hash"]
+
+	transaction, repoErr := sr.repository.GetTransactionByHash(txHash)
+	if repoErr != nil {
+		if repoErr.Code == "TRANSACTION_NOT_FOUND" {
+			return errorResponse(http.StatusNotFound, repoErr.Detail),
+				fmt.Errorf("transaction not found: %s", repoErr.Detail)
+		}
+		return errorResponse(http.StatusInternalServerError, "Internal server error"),
+			fmt.Errorf("repository error: %s", repoErr.Detail)
 	}
 
-	txHash := pathParts[3]
+	return jsonResponse(http.StatusOK, transaction)
+}
 
-	transaction, repoErr := sr.repository.GetTransactionByHash(txHash)
+// VerifyBatchHandler reports for each requested hash whether L1 holds the
+// transaction, using one query for the whole batch
+func (sr *ServiceRegistry) VerifyBatchHandler(req *Request) (*Response, error) {
+	var batch VerifyBatchRequest
+	if err := json.Unmarshal([]byte(req.Body), &batch); err != nil {
+		return errorResponse(http.StatusBadRequest, "Invalid request format: "+err.Error()), err
+	}
+	if len(batch.TxHashes) == 0 {
+		return errorResponse(http.StatusBadRequest, "tx_hashes must not be empty"), fmt.Errorf("empty batch")
+	}
+	if len(batch.TxHashes) > MaxVerifyBatchSize {
+		message := fmt.Sprintf("tx_hashes has %d entries, exceeding the limit of %d", len(batch.TxHashes), MaxVerifyBatchSize)
+		return errorResponse(http.StatusRequestEntityTooLarge, message), fmt.Errorf("%s", message)
+	}
+
+	transactions, repoErr := sr.repository.GetTransactionsByHashes(batch.TxHashes)
+	if repoErr != nil {
+		return errorResponse(http.StatusInternalServerError, "Internal server error"),
+			fmt.Errorf("repository error: %s", repoErr.Detail)
+	}
+	byHash := make(map[string]*models.Transaction, len(transactions))
+	for i := range transactions {
+		byHash[transactions[i].TxHash] = &transactions[i]
+	}
+
+	response := VerifyBatchResponse{Results: make([]VerifyBatchResult, 0, len(batch.TxHashes))}
+	for _, txHash := range batch.TxHashes {
+		result := VerifyBatchResult{TxHash: txHash}
+		if transaction, ok := byHash[txHash]; ok {
+			result.Found = true
+			result.Status = transaction.Status
+			result.SessionID = transaction.SessionID
+			result.ShardID = transaction.ShardID
+			result.BlockHeight = transaction.BlockHeight
+			response.Found++
+		} else {
+			response.NotFound++
+		}
+		response.Results = append(response.Results, result)
+	}
+
+	return jsonResponse(http.StatusOK, response)
+}
+
+// ListTransactionsHandler returns transactions with block_height > since in
+// ascending order so L2 nodes can pull incrementally
+func (sr *ServiceRegistry) ListTransactionsHandler(req *Request) (*Response, error) {
+	since := int64(0)
+	if raw := req.Query.Get("since"); raw != "" {
+		parsed, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || parsed < 0 {
+			return errorResponse(http.StatusBadRequest, "since must be a non-negative block height"),
+				fmt.Errorf("invalid since parameter: %q", raw)
+		}
+		since = parsed
+	}
+
+	limit := defaultTransactionLimit
+	if raw := req.Query.Get("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed <= 0 || parsed > maxTransactionLimit {
+			return errorResponse(http.StatusBadRequest, fmt.Sprintf("limit must be between 1 and %d", maxTransactionLimit)),
+				fmt.Errorf("invalid limit parameter: %q", raw)
+		}
+		limit = parsed
+	}
+
+	transactions, repoErr := sr.repository.ListTransactionsSince(since, limit)
+	if repoErr != nil {
+		return errorResponse(http.StatusInternalServerError, "Internal server error"),
+			fmt.Errorf("repository error: %s", repoErr.Detail)
+	}
+
+	nextSince := since
+	if len(transactions) > 0 {
+		nextSince = transactions[len(transactions)-1].BlockHeight
+	}
+
+	return jsonResponse(http.StatusOK, TransactionsResponse{
+		Transactions: transactions,
+		Count:        len(transactions),
+		NextSince:    nextSince,
+	})
+}
+
+// VerifyTransactionHandler checks a transaction ID against consensus state
+// rather than the PostgreSQL mirror
+func (sr *ServiceRegistry) VerifyTransactionHandler(req *Request) (*Response, error) {
+	txID := req.Params["txid"]
+
+	verified, repoErr := sr.repository.VerifyTransaction(req.Ctx(), txID)
 	if repoErr != nil {
 		if repoErr.Code == "TRANSACTION_NOT_FOUND" {
-			return &Response{
-				StatusCode: http.StatusNotFound,
-				Headers:    defaultHeaders,
-				Body:       fmt.Sprintf(`{"error":"%s"}`, repoErr.Detail),
-			}, fmt.Errorf("transaction not found: %s", repoErr.Detail)
+			return errorResponse(http.StatusNotFound, repoErr.Detail),
+				fmt.Errorf("transaction not found: %s", repoErr.Detail)
 		}
-		return &Response{
-			StatusCode: http.StatusInternalServerError,
-			Headers:    defaultHeaders,
-			Body:       `{"error":"Internal server error"}`,
-		}, fmt.Errorf("repository error: %s", repoErr.Detail)
+		return errorResponse(http.StatusInternalServerError, "Internal server error"),
+			fmt.Errorf("repository error: %s", repoErr.Detail)
 	}
 
-	txJSON, err := json.Marshal(transaction)
-	if err != nil {
-		return &Response{
-			StatusCode: http.StatusInternalServerError,
-			Headers:    defaultHeaders,
-			Body:       `{"error":"Failed to serialize transaction"}`,
-		}, err
+	// The stored payload is the raw commit JSON; fall back to a string for
+	// anything that isn't
+	payload := json.RawMessage(verified.Payload)
+	if !json.Valid(payload) {
+		payload, _ = json.Marshal(string(verified.Payload))
 	}
 
-	return &Response{
-		StatusCode: http.StatusOK,
-		Headers:    defaultHeaders,
-		Body:       string(txJSON),
-	}, nil
+	return jsonResponse(http.StatusOK, VerifyTransactionResponse{
+		TxID:     verified.TxID,
+		Status:   verified.Status,
+		Verified: true,
+		Payload:  payload,
+	})
+}
+
+// GetBlockHandler lists the shard commits in the block at :height
+func (sr *ServiceRegistry) GetBlockHandler(req *Request) (*Response, error) {
+	rawHeight := req.Params["height"]
+	height, err := strconv.ParseInt(rawHeight, 10, 64)
+	if err != nil || height <= 0 {
+		return errorResponse(http.StatusBadRequest, "height must be a positive integer"),
+			fmt.Errorf("invalid block height: %q", rawHeight)
+	}
+
+	summary, repoErr := sr.repository.GetBlockSummary(req.Ctx(), height)
+	if repoErr != nil {
+		if repoErr.Code == "BLOCK_NOT_FOUND" {
+			return errorResponse(http.StatusNotFound, repoErr.Detail),
+				fmt.Errorf("block not found: %s", repoErr.Detail)
+		}
+		return errorResponse(http.StatusInternalServerError, "Internal server error"),
+			fmt.Errorf("repository error: %s", repoErr.Detail)
+	}
+
+	return jsonResponse(http.StatusOK, summary)
+}
+
+// AuditSessionHandler proves a session's commit end-to-end: inclusion proof,
+// app hash, consensus state and mirror. A session that fails any check still
+// returns 200 with verified set to false.
+func (sr *ServiceRegistry) AuditSessionHandler(req *Request) (*Response, error) {
+	sessionID := req.Params["session_id"]
+
+	report, repoErr := sr.repository.AuditSession(req.Ctx(), sessionID)
+	if repoErr != nil {
+		switch repoErr.Code {
+		case "SESSION_NOT_FOUND":
+			return errorResponse(http.StatusNotFound, repoErr.Detail),
+				fmt.Errorf("session not found: %s", repoErr.Detail)
+		case "SESSION_NOT_COMMITTED":
+			return errorResponse(http.StatusConflict, repoErr.Detail),
+				fmt.Errorf("session not committed: %s", repoErr.Detail)
+		}
+		return errorResponse(http.StatusInternalServerError, "Internal server error"),
+			fmt.Errorf("repository error: %s", repoErr.Detail)
+	}
+
+	return jsonResponse(http.StatusOK, report)
+}
+
+// ReconcileHandler compares recent blocks with the PostgreSQL mirror and
+// reports discrepancies without repairing them
+func (sr *ServiceRegistry) ReconcileHandler(req *Request) (*Response, error) {
+	depth := int64(repository.DefaultReconcileDepth)
+	if raw := req.Query.Get("depth"); raw != "" {
+		parsed, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || parsed <= 0 {
+			return errorResponse(http.StatusBadRequest, "depth must be a positive number of blocks"),
+				fmt.Errorf("invalid depth parameter: %q", raw)
+		}
+		depth = parsed
+	}
+
+	report, repoErr := sr.repository.Reconcile(req.Ctx(), depth, false)
+	if repoErr != nil {
+		return errorResponse(http.StatusInternalServerError, "Internal server error"),
+			fmt.Errorf("repository error: %s", repoErr.Detail)
+	}
+
+	return jsonResponse(http.StatusOK, report)
 }
 
 // StatusHandler provides L1 system status
 func (sr *ServiceRegistry) StatusHandler(req *Request) (*Response, error) {
-	status := map[string]interface{}{
-		"status": "active",
-		"layer":  "L1",
-		"type":   "Byzantine Fault Tolerant",
-		"time":   time.Now(),
+	response := StatusResponse{
+		Status: "active",
+		Layer:  "L1",
+		Type:   "Byzantine Fault Tolerant",
+		Time:   time.Now(),
 	}
 
-	statusJSON, err := json.Marshal(status)
-	if err != nil {
-		return &Response{
-			StatusCode: http.StatusInternalServerError,
-			Headers:    defaultHeaders,
-			Body:       `{"error":"Failed to serialize status"}`,
-		}, err
+	// A node whose consensus state can't be read is not a usable L1
+	consensus, repoErr := sr.repository.GetConsensusStatus(req.Ctx())
+	if repoErr != nil {
+		response.Status = "unavailable"
+		response.ConsensusError = repoErr.Detail
+		return jsonResponse(http.StatusServiceUnavailable, response)
+	}
+	response.Consensus = consensus
+	if consensus.CatchingUp {
+		response.Status = "syncing"
+	}
+	return jsonResponse(http.StatusOK, response)
+}
+
+// MempoolHandler summarizes transactions waiting in the mempool
+func (sr *ServiceRegistry) MempoolHandler(req *Request) (*Response, error) {
+	limit := defaultMempoolLimit
+	if raw := req.Query.Get("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed <= 0 || parsed > maxMempoolLimit {
+			return errorResponse(http.StatusBadRequest, fmt.Sprintf("limit must be between 1 and %d", maxMempoolLimit)),
+				fmt.Errorf("invalid limit parameter: %q", raw)
+		}
+		limit = parsed
 	}
 
-	return &Response{
-		StatusCode: http.StatusOK,
-		Headers:    defaultHeaders,
-		Body:       string(statusJSON),
-	}, nil
+	status, repoErr := sr.repository.GetMempool(req.Ctx(), limit)
+	if repoErr != nil {
+		return errorResponse(http.StatusInternalServerError, "Internal server error"),
+			fmt.Errorf("repository error: %s", repoErr.Detail)
+	}
+
+	return jsonResponse(http.StatusOK, status)
+}
+
+// FlushMempoolHandler drops all pending transactions when enabled
+func (sr *ServiceRegistry) FlushMempoolHandler(req *Request) (*Response, error) {
+	if !sr.mempoolFlush {
+		return errorResponse(http.StatusForbidden, "Mempool flush is disabled; start the node with --enable-mempool-flush"),
+			fmt.Errorf("mempool flush disabled")
+	}
+
+	removed, repoErr := sr.repository.FlushMempool()
+	if repoErr != nil {
+		return errorResponse(http.StatusInternalServerError, repoErr.Message),
+			fmt.Errorf("repository error: %s", repoErr.Detail)
+	}
+
+	sr.logger.Info("Mempool flushed", "removed", removed)
+	return jsonResponse(http.StatusOK, FlushMempoolResponse{
+		Message: "Mempool flushed",
+		Removed: removed,
+	})
+}
+
+// StatsHandler returns consensus latency statistics
+func (sr *ServiceRegistry) StatsHandler(req *Request) (*Response, error) {
+	stats, repoErr := sr.repository.GetTransactionStats()
+	if repoErr != nil {
+		return errorResponse(http.StatusInternalServerError, "Internal server error"),
+			fmt.Errorf("repository error: %s", repoErr.Detail)
+	}
+
+	return jsonResponse(http.StatusOK, stats)
 }
 
 // GetShardsHandler returns information about all registered shards
@@ -369,34 +1080,119 @@ func (sr *ServiceRegistry) GetShardsHandler(req *Request) (*Response, error) {
 	shards, repoErr := sr.repository.GetAllShards()
 	if repoErr != nil {
 		sr.logger.Error("Failed to retrieve shards", "error", repoErr.Detail)
-		return &Response{
-			StatusCode: http.StatusInternalServerError,
-			Headers:    defaultHeaders,
-			Body:       `{"error":"Failed to retrieve shards"}`,
-		}, fmt.Errorf("repository error: %s", repoErr.Detail)
+		return errorResponse(http.StatusInternalServerError, "Failed to retrieve shards"),
+			fmt.Errorf("repository error: %s", repoErr.Detail)
 	}
 
-	// Format response
-	response := map[string]interface{}{
-		"shards": shards,
-		"count":  len(shards),
+	now := time.Now()
+	statuses := make([]ShardStatus, 0, len(shards))
+	for _, shard := range shards {
+		health := ShardUnknown
+		if shard.LastSeen != nil {
+			health = ShardStale
+			if now.Sub(*shard.LastSeen) <= sr.heartbeatTimeout {
+				health = ShardHealthy
+			}
+		}
+		statuses = append(statuses, ShardStatus{ShardInfo: shard, Health: health})
 	}
 
-	shardsJSON, err := json.Marshal(response)
-	if err != nil {
-		sr.logger.Error("Failed to serialize shards", "error", err.Error())
-		return &Response{
-			StatusCode: http.StatusInternalServerError,
-			Headers:    defaultHeaders,
-			Body:       `{"error":"Failed to serialize shards"}`,
-		}, err
-	}
-
-	return &Response{
-		StatusCode: http.StatusOK,
-		Headers:    defaultHeaders,
-		Body:       string(shardsJSON),
-	}, nil
+	return jsonResponse(http.StatusOK, ShardsResponse{
+		Shards: statuses,
+		Count:  len(statuses),
+	})
+}
+
+// ShardHeartbeatHandler records that a shard's L2 node is alive
+func (sr *ServiceRegistry) ShardHeartbeatHandler(req *Request) (*Response, error) {
+	shardID := req.Params["shard"]
+
+	// The body is informational; an empty one is accepted
+	var heartbeat ShardHeartbeatRequest
+	if strings.TrimSpace(req.Body) != "" {
+		if err := json.Unmarshal([]byte(req.Body), &heartbeat); err != nil {
+			return errorResponse(http.StatusBadRequest, "Invalid heartbeat format"),
+				fmt.Errorf("invalid heartbeat: %w", err)
+		}
+	}
+
+	lastSeen, repoErr := sr.repository.RecordShardHeartbeat(shardID)
+	if repoErr != nil {
+		if repoErr.Code == "SHARD_NOT_FOUND" {
+			return errorResponse(http.StatusNotFound, repoErr.Detail),
+				fmt.Errorf("shard not found: %s", repoErr.Detail)
+		}
+		return errorResponse(http.StatusInternalServerError, "Internal server error"),
+			fmt.Errorf("repository error: %s", repoErr.Detail)
+	}
+
+	return jsonResponse(http.StatusOK, ShardHeartbeatResponse{
+		ShardID:  shardID,
+		LastSeen: lastSeen,
+	})
+}
+
+// DeregisterShardHandler marks a shard inactive. With ?purge=true it instead
+// deletes the sessions and transactions of a shard that is already inactive,
+// which must be confirmed by repeating the shard ID in ?confirm=.
+func (sr *ServiceRegistry) DeregisterShardHandler(req *Request) (*Response, error) {
+	shardID := req.Params["shard"]
+
+	purge := false
+	if raw := req.Query.Get("purge"); raw != "" {
+		parsed, err := strconv.ParseBool(raw)
+		if err != nil {
+			return errorResponse(http.StatusBadRequest, "purge must be true or false"),
+				fmt.Errorf("invalid purge parameter: %q", raw)
+		}
+		purge = parsed
+	}
+
+	if !purge {
+		if repoErr := sr.repository.DeregisterShard(shardID); repoErr != nil {
+			if repoErr.Code == "SHARD_NOT_FOUND" {
+				return errorResponse(http.StatusNotFound, repoErr.Detail),
+					fmt.Errorf("shard not found: %s", repoErr.Detail)
+			}
+			return errorResponse(http.StatusInternalServerError, "Internal server error"),
+				fmt.Errorf("repository error: %s", repoErr.Detail)
+		}
+		sr.logger.Info("Shard deregistered", "shard_id", shardID)
+		return jsonResponse(http.StatusOK, DeregisterShardResponse{
+			ShardID: shardID,
+			Status:  repository.ShardStatusInactive,
+		})
+	}
+
+	if req.Query.Get("confirm") != shardID {
+		return errorResponse(http.StatusBadRequest, "Purging deletes the shard's sessions and transactions; confirm with ?confirm="+shardID),
+			fmt.Errorf("purge of shard %s not confirmed", shardID)
+	}
+
+	result, repoErr := sr.repository.PurgeShardData(shardID)
+	if repoErr != nil {
+		switch repoErr.Code {
+		case "SHARD_NOT_FOUND":
+			return errorResponse(http.StatusNotFound, repoErr.Detail),
+				fmt.Errorf("shard not found: %s", repoErr.Detail)
+		case "SHARD_ACTIVE":
+			return errorResponse(http.StatusConflict, repoErr.Detail),
+				fmt.Errorf("shard active: %s", repoErr.Detail)
+		default:
+			return errorResponse(http.StatusInternalServerError, "Internal server error"),
+				fmt.Errorf("repository error: %s", repoErr.Detail)
+		}
+	}
+	sr.logger.Info("Shard data purged", "shard_id", shardID,
+		"sessions", result.SessionsDeleted, "transactions", result.TransactionsDeleted)
+
+	return jsonResponse(http.StatusOK, DeregisterShardResponse{
+		ShardID:             shardID,
+		Status:              repository.ShardStatusInactive,
+		Purged:              true,
+		SessionsDeleted:     result.SessionsDeleted,
+		TransactionsDeleted: result.TransactionsDeleted,
+	})
 }
 
 // ConvertHttpRequestToConsensusRequest converts an http.Request to Request
@@ -423,21 +1219,36 @@ func ConvertHttpRequestToConsensusRequest(r *http.Request, requestID string) (*R
 		Path:       r.URL.Path,
 		Headers:    headers,
 		Body:       body,
+		Query:      r.URL.Query(),
 		RemoteAddr: r.RemoteAddr,
 		RequestID:  requestID,
 		Timestamp:  time.Now(),
+		Context:    r.Context(),
 	}, nil
 }
 
 // GenerateResponse executes the request and generates a response
 func (req *Request) GenerateResponse(services *ServiceRegistry) (*Response, error) {
-	handler, found := services.GetHandlerForPath(req.Method, req.Path)
+	req.Path = normalizePath(req.Path)
+
+	handler, params, found := services.GetHandlerForPath(req.Method, req.Path)
 	if !found {
-		return &Response{
-			StatusCode: http.StatusNotFound,
-			Headers:    defaultHeaders,
-			Body:       fmt.Sprintf(`{"error":"Service not found for %s %s"}`, req.Method, req.Path),
-		}, nil
+		if allowed := services.AllowedMethods(req.Path); len(allowed) > 0 {
+			response := errorResponse(http.StatusMethodNotAllowed, fmt.Sprintf("Method %s not allowed for %s", req.Method, req.Path))
+			response.Headers = map[string]string{
+				"Content-Type": "application/json",
+				"Allow":        strings.Join(allowed, ", "),
+			}
+			return response, nil
+		}
+		return errorResponse(http.StatusNotFound, fmt.Sprintf("Service not found for %s %s", req.Method, req.Path)), nil
+	}
+	req.Params = params
+
+	if services.dedupe != nil && isWriteMethod(req.Method) {
+		return services.dedupe.Do(req.DeterministicID(), func() (*Response, error) {
+			return handler(req)
+		})
 	}
 
 	response, err := handler(req)
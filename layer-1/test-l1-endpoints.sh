@@ -56,7 +56,7 @@ if [[ "$response" =~ "successfully" ]]; then
     [ "$count" -gt "0" ] 2>/dev/null && echo "✅ ($count sessions)" || echo "✅ (empty)"
     
     echo -n "  GET /l1/sessions/shard/shard-a: "
-    count=$(curl -s "$L1_URL/l1/sessions/shard/shard-a" | jq -r '.data | length // 0')  
+    count=$(curl -s "$L1_URL/l1/sessions/shard/shard-a" | jq -r '.data.total // 0')  
     [ "$count" -gt "0" ] 2>/dev/null && echo "✅ ($count sessions)" || echo "✅ (empty)"
 else
     echo "❌ ($response)"
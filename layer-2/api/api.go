@@ -0,0 +1,240 @@
+// Package api defines the headers and JSON bodies of the L2 HTTP API. It is
+// shared by the shard's handlers and its clients, and depends on nothing but
+// the standard library so clients don't pull in the server.
+package api
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// OperatorHeader names the operator performing a session action. The
+// operator's own access level, not that of the session's owner, decides
+// whether the action is allowed.
+const OperatorHeader = "X-Operator-ID"
+
+// EnvelopeHeader marks a response whose body is an L2Response, so clients
+// know to unwrap it and a forwarding shard passes it through untouched
+const EnvelopeHeader = "X-L2-Envelope"
+
+// L2Response is the optional envelope around L2 response bodies, the
+// counterpart of L1's L1Response. Data is the handler's body on success and
+// null on failure, when Error carries the error body instead.
+type L2Response struct {
+	Data      json.RawMessage `json:"data"`
+	Error     *ErrorResponse  `json:"error"`
+	ShardID   string          `json:"shard_id"`
+	Timestamp time.Time       `json:"timestamp"`
+}
+
+// CreateSessionRequest is the body accepted when starting a session
+type CreateSessionRequest struct {
+	OperatorID string `json:"operator_id"`
+}
+
+// CreateSessionsRequest is the body accepted when starting sessions in bulk.
+// Either OperatorID and Count, or an explicit OperatorIDs list, is given.
+type CreateSessionsRequest struct {
+	OperatorID  string   `json:"operator_id,omitempty"`
+	Count       int      `json:"count,omitempty"`
+	OperatorIDs []string `json:"operator_ids,omitempty"`
+}
+
+// ScanPackageRequest is the body accepted when scanning a package
+type ScanPackageRequest struct {
+	PackageID string `json:"package_id"`
+}
+
+// ValidatePackageRequest is the body accepted when validating a package
+type ValidatePackageRequest struct {
+	Signature string `json:"signature"`
+	PackageID string `json:"package_id"`
+}
+
+// QualityCheckRequest is the body accepted when recording a quality check
+type QualityCheckRequest struct {
+	Passed bool     `json:"passed"`
+	Issues []string `json:"issues"`
+}
+
+// LabelPackageRequest is the body accepted when creating a shipping label.
+// CourierID may be omitted on creation when COURIER_STRATEGY is set.
+type LabelPackageRequest struct {
+	CourierID string `json:"courier_id"`
+}
+
+// CommitSessionRequest is the optional body accepted when committing. When
+// CallbackURL is set the final status is also POSTed there.
+type CommitSessionRequest struct {
+	CallbackURL string `json:"callback_url,omitempty"`
+}
+
+// ErrorResponse is the body returned for failed requests
+type ErrorResponse struct {
+	Error         string `json:"error"`
+	Code          string `json:"code"`
+	TxHash        string `json:"tx_hash,omitempty"`
+	CurrentStatus string `json:"current_status,omitempty"`
+}
+
+// InfoResponse is the body returned by the info endpoint
+type InfoResponse struct {
+	ShardID     string `json:"shard_id"`
+	ClientGroup string `json:"client_group"`
+	Type        string `json:"type"`
+	Status      string `json:"status"`
+}
+
+// HealthResponse is the body returned by the liveness and readiness probes
+type HealthResponse struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks,omitempty"`
+}
+
+// CreateSessionResponse is the body returned when a session is created
+type CreateSessionResponse struct {
+	Message    string `json:"message"`
+	SessionID  string `json:"session_id"`
+	OperatorID string `json:"operator_id"`
+	Status     string `json:"status"`
+	ShardID    string `json:"shard_id"`
+}
+
+// CreateSessionsResponse is the body returned when sessions are created in bulk
+type CreateSessionsResponse struct {
+	Message    string   `json:"message"`
+	SessionIDs []string `json:"session_ids"`
+	Count      int      `json:"count"`
+	ShardID    string   `json:"shard_id"`
+}
+
+// PackageItem describes one expected item in a scanned package
+type PackageItem struct {
+	ItemID      string `json:"item_id"`
+	Description string `json:"description"`
+	Quantity    int    `json:"quantity"`
+}
+
+// ScanPackageResponse is the body returned when a package is scanned
+type ScanPackageResponse struct {
+	Message           string        `json:"message"`
+	PackageID         string        `json:"package_id"`
+	Supplier          string        `json:"supplier"`
+	ExpectedContents  []PackageItem `json:"expected_contents"`
+	SupplierSignature string        `json:"supplier_signature"`
+	Status            string        `json:"status"`
+	NextStep          string        `json:"next_step"`
+}
+
+// ValidatePackageResponse is the body returned when a package is validated
+type ValidatePackageResponse struct {
+	Message   string `json:"message"`
+	PackageID string `json:"package_id"`
+	Supplier  string `json:"supplier"`
+	IsTrusted bool   `json:"is_trusted"`
+	Status    string `json:"status"`
+	NextStep  string `json:"next_step"`
+}
+
+// QualityCheckResponse is the body returned when a quality check is recorded
+type QualityCheckResponse struct {
+	Message   string `json:"message"`
+	QCID      string `json:"qc_id"`
+	Passed    bool   `json:"passed"`
+	PackageID string `json:"package_id"`
+	Status    string `json:"status"`
+	NextStep  string `json:"next_step"`
+}
+
+// LabelPackageResponse is the body returned when a shipping label is created
+type LabelPackageResponse struct {
+	Message    string `json:"message"`
+	LabelID    string `json:"label_id"`
+	TrackingNo string `json:"tracking_no"`
+	Courier    string `json:"courier"`
+	SessionID  string `json:"session_id"`
+	NextStep   string `json:"next_step"`
+}
+
+// CommitSessionResponse is the body returned when a session is committed to L1
+type CommitSessionResponse struct {
+	Message     string `json:"message"`
+	SessionID   string `json:"session_id"`
+	TxHash      string `json:"tx_hash"`
+	BlockHeight int64  `json:"block_height"`
+	Votes       int    `json:"votes"`
+	ShardID     string `json:"shard_id"`
+	Status      string `json:"status"`
+}
+
+// ImportSessionsResponse is the body returned after importing sessions
+type ImportSessionsResponse struct {
+	Message  string `json:"message"`
+	Imported int    `json:"imported"`
+	Skipped  int    `json:"skipped"`
+}
+
+// DeleteSessionResponse is the body returned when a session is deleted
+type DeleteSessionResponse struct {
+	Message   string `json:"message"`
+	SessionID string `json:"session_id"`
+}
+
+// CatalogPackage describes a seeded package. Its signature is only revealed
+// by scanning it.
+type CatalogPackage struct {
+	PackageID  string        `json:"package_id"`
+	SupplierID string        `json:"supplier_id"`
+	Supplier   string        `json:"supplier"`
+	Status     string        `json:"status"`
+	IsTrusted  bool          `json:"is_trusted"`
+	Items      []PackageItem `json:"items"`
+}
+
+// PackagesResponse is the body returned by the package catalog
+type PackagesResponse struct {
+	Packages []CatalogPackage `json:"packages"`
+	Count    int              `json:"count"`
+}
+
+// CatalogCourier describes a courier that can be used for labels
+type CatalogCourier struct {
+	CourierID   string `json:"courier_id"`
+	Name        string `json:"name"`
+	Assignments int64  `json:"assignments"` // labels assigned since startup
+
+	TrackingFormat string `json:"tracking_format,omitempty"`
+}
+
+// CouriersResponse is the body returned by the courier catalog
+type CouriersResponse struct {
+	Couriers []CatalogCourier `json:"couriers"`
+	Count    int              `json:"count"`
+	Strategy string           `json:"strategy,omitempty"` // courier selection when courier_id is omitted
+}
+
+// CatalogSupplier describes a package supplier
+type CatalogSupplier struct {
+	SupplierID string `json:"supplier_id"`
+	Name       string `json:"name"`
+	Country    string `json:"country"`
+}
+
+// SuppliersResponse is the body returned by the supplier catalog
+type SuppliersResponse struct {
+	Suppliers []CatalogSupplier `json:"suppliers"`
+	Count     int               `json:"count"`
+}
+
+// LabelLookupResponse is the body returned when looking up a tracking number
+type LabelLookupResponse struct {
+	LabelID       string    `json:"label_id"`
+	TrackingNo    string    `json:"tracking_no"`
+	CourierID     string    `json:"courier_id"`
+	Courier       string    `json:"courier"`
+	SessionID     string    `json:"session_id"`
+	SessionStatus string    `json:"session_status"`
+	PackageID     string    `json:"package_id,omitempty"`
+	L1TxHash      string    `json:"l1_tx_hash,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+}
@@ -2,7 +2,17 @@ package config
 
 import (
 	"fmt"
+	"log"
 	"os"
+	"strconv"
+	"time"
+)
+
+// Startup self-test modes
+const (
+	SelfTestOff    = "off"
+	SelfTestWarn   = "warn"
+	SelfTestStrict = "strict"
 )
 
 // Config holds all configuration for an L2 shard
@@ -13,7 +23,30 @@ type Config struct {
 	L2NodeID    string
 
 	// Server Configuration
-	HTTPPort string
+	HTTPPort     string
+	BindAddress  string
+	MaxBodyBytes int64
+	MaxInflight  int // requests served at once before answering 503, 0 is unlimited
+
+	// Session backup: AdminAPIKey must be sent as X-Admin-Key to export or
+	// import sessions (empty disables both), and MaxImportBytes caps an
+	// import, which is streamed rather than bound by MaxBodyBytes
+	AdminAPIKey    string
+	MaxImportBytes int64
+
+	// ResponseEnvelope wraps JSON responses in {data, error, shard_id,
+	// timestamp} like L1's responses
+	ResponseEnvelope bool
+
+	// ShutdownTimeout bounds how long shutdown waits for in-flight requests
+	// and for queued commit callbacks to be delivered
+	ShutdownTimeout time.Duration
+
+	// Timeouts for requests forwarded to the shard owning their client
+	// group: reads, other writes, and commits that wait for L1 consensus
+	ForwardReadTimeout   time.Duration
+	ForwardWriteTimeout  time.Duration
+	ForwardCommitTimeout time.Duration
 
 	// Database Configuration
 	DatabaseHost string
@@ -22,8 +55,62 @@ type Config struct {
 	DatabasePass string
 	DatabaseName string
 
+	// Connection pool; zero keeps the database/sql defaults
+	DatabaseMaxOpenConns    int
+	DatabaseMaxIdleConns    int
+	DatabaseConnMaxLifetime time.Duration
+
 	// L1 Configuration
 	L1Endpoint string // e.g., "http://localhost:5000"
+	L1APIKey   string // sent as X-L1-Api-Key on commits, empty when L1 auth is disabled
+
+	// Commit payload redaction: comma-separated session_data field paths
+	// hashed or dropped before commits reach L1, e.g. "package.signature".
+	// CommitHashSalt keys the hash; empty uses plain SHA-256.
+	CommitHashFields string
+	CommitDropFields string
+	CommitHashSalt   string
+
+	// Seeding
+	SeedData bool   // upsert demo data on startup
+	SeedFile string // optional JSON file replacing the built-in seed data
+
+	// ShardRegistryTTL is how often the shard registry is refreshed from L1
+	// and how old a persisted registry may get before it is reported stale
+	ShardRegistryTTL time.Duration
+
+	// OperatorSyncInterval is how often the operator mirror is replaced
+	// with L1's operators; zero syncs only at startup
+	OperatorSyncInterval time.Duration
+
+	// ShardOverrideFile is an optional JSON file pinning client groups to
+	// shards, taking precedence over the registry from L1
+	ShardOverrideFile string
+
+	// Commit callbacks: CallbackSecret signs webhook bodies and enables
+	// callback_url on commits; empty disables callbacks
+	CallbackSecret      string
+	CallbackMaxAttempts int
+
+	// HeartbeatInterval is how often the shard reports to L1 that it is
+	// alive; zero disables heartbeats
+	HeartbeatInterval time.Duration
+
+	// OTLPEndpoint is the OTLP/HTTP collector URL traces are exported to;
+	// empty disables export
+	OTLPEndpoint string
+
+	// CourierStrategy picks the courier when a label request omits
+	// courier_id: round-robin or lru. Empty requires courier_id.
+	CourierStrategy string
+
+	// StartupSelfTest runs a synthetic session through the pipeline on
+	// startup: off, warn (log failures) or strict (refuse to start)
+	StartupSelfTest string
+
+	// LogLevel gates service registry logging: error, warn, info or debug.
+	// Per-request redirect logs are debug; the default keeps them on.
+	LogLevel string
 }
 
 // LoadConfig loads configuration from environment variables with defaults
@@ -35,7 +122,21 @@ func LoadConfig() *Config {
 		L2NodeID:    getEnv("L2_NODE_ID", "l2-node-a"),
 
 		// Server
-		HTTPPort: getEnv("HTTP_PORT", "6000"),
+		HTTPPort:     getEnv("HTTP_PORT", "6000"),
+		BindAddress:  getEnv("BIND_ADDRESS", "0.0.0.0"),
+		MaxBodyBytes: getEnvInt64("MAX_BODY_BYTES", 1<<20),
+		MaxInflight:  int(getEnvInt64("MAX_INFLIGHT_REQUESTS", 0)),
+
+		AdminAPIKey:    getEnv("ADMIN_API_KEY", ""),
+		MaxImportBytes: getEnvInt64("MAX_IMPORT_BYTES", 256<<20),
+
+		ResponseEnvelope: getEnv("RESPONSE_ENVELOPE", "false") == "true",
+
+		ShutdownTimeout: getEnvDuration("SHUTDOWN_TIMEOUT", 10*time.Second),
+
+		ForwardReadTimeout:   getEnvDuration("FORWARD_READ_TIMEOUT", 5*time.Second),
+		ForwardWriteTimeout:  getEnvDuration("FORWARD_WRITE_TIMEOUT", 15*time.Second),
+		ForwardCommitTimeout: getEnvDuration("FORWARD_COMMIT_TIMEOUT", 30*time.Second),
 
 		// Database
 		DatabaseHost: getEnv("DB_HOST", "localhost"),
@@ -44,8 +145,38 @@ func LoadConfig() *Config {
 		DatabasePass: getEnv("DB_PASS", "postgrespassword"),
 		DatabaseName: getEnv("DB_NAME", "l2_shard_db"),
 
+		DatabaseMaxOpenConns:    int(getEnvInt64("DB_MAX_OPEN_CONNS", 0)),
+		DatabaseMaxIdleConns:    int(getEnvInt64("DB_MAX_IDLE_CONNS", 0)),
+		DatabaseConnMaxLifetime: getEnvDuration("DB_CONN_MAX_LIFETIME", 0),
+
 		// L1
 		L1Endpoint: getEnv("L1_ENDPOINT", "http://localhost:5000"),
+		L1APIKey:   getEnv("L1_API_KEY", ""),
+
+		CommitHashFields: getEnv("COMMIT_HASH_FIELDS", ""),
+		CommitDropFields: getEnv("COMMIT_DROP_FIELDS", ""),
+		CommitHashSalt:   getEnv("COMMIT_HASH_SALT", ""),
+
+		SeedData: getEnv("SEED_DATA", "true") != "false",
+		SeedFile: getEnv("SEED_FILE", ""),
+
+		ShardRegistryTTL:  getEnvDuration("SHARD_REGISTRY_TTL", 5*time.Minute),
+		ShardOverrideFile: getEnv("SHARD_OVERRIDE_FILE", ""),
+
+		OperatorSyncInterval: getEnvDuration("OPERATOR_SYNC_INTERVAL", time.Minute),
+
+		CallbackSecret:      getEnv("CALLBACK_SECRET", ""),
+		CallbackMaxAttempts: int(getEnvInt64("CALLBACK_MAX_ATTEMPTS", 8)),
+
+		HeartbeatInterval: getEnvDuration("HEARTBEAT_INTERVAL", 10*time.Second),
+
+		OTLPEndpoint: getEnv("OTEL_EXPORTER_OTLP_ENDPOINT", ""),
+
+		CourierStrategy: getEnv("COURIER_STRATEGY", ""),
+
+		StartupSelfTest: getEnv("STARTUP_SELFTEST", SelfTestOff),
+
+		LogLevel: getEnv("LOG_LEVEL", "debug"),
 	}
 }
 
@@ -75,6 +206,41 @@ func (c *Config) Validate() error {
 	if c.L1Endpoint == "" {
 		return fmt.Errorf("L1_ENDPOINT is required")
 	}
+	if c.DatabaseMaxOpenConns < 0 || c.DatabaseMaxIdleConns < 0 || c.DatabaseConnMaxLifetime < 0 {
+		return fmt.Errorf("DB_MAX_OPEN_CONNS, DB_MAX_IDLE_CONNS and DB_CONN_MAX_LIFETIME must not be negative")
+	}
+	if c.MaxBodyBytes <= 0 {
+		return fmt.Errorf("MAX_BODY_BYTES must be positive")
+	}
+	if c.MaxImportBytes <= 0 {
+		return fmt.Errorf("MAX_IMPORT_BYTES must be positive")
+	}
+	if c.MaxInflight < 0 {
+		return fmt.Errorf("MAX_INFLIGHT_REQUESTS must not be negative")
+	}
+	if c.ShutdownTimeout <= 0 {
+		return fmt.Errorf("SHUTDOWN_TIMEOUT must be positive")
+	}
+	if c.ForwardReadTimeout <= 0 || c.ForwardWriteTimeout <= 0 || c.ForwardCommitTimeout <= 0 {
+		return fmt.Errorf("FORWARD_READ_TIMEOUT, FORWARD_WRITE_TIMEOUT and FORWARD_COMMIT_TIMEOUT must be positive")
+	}
+	if c.ShardRegistryTTL <= 0 {
+		return fmt.Errorf("SHARD_REGISTRY_TTL must be positive")
+	}
+	if c.HeartbeatInterval < 0 {
+		return fmt.Errorf("HEARTBEAT_INTERVAL must not be negative")
+	}
+	if c.OperatorSyncInterval < 0 {
+		return fmt.Errorf("OPERATOR_SYNC_INTERVAL must not be negative")
+	}
+	if c.CallbackMaxAttempts <= 0 {
+		return fmt.Errorf("CALLBACK_MAX_ATTEMPTS must be positive")
+	}
+	switch c.StartupSelfTest {
+	case SelfTestOff, SelfTestWarn, SelfTestStrict:
+	default:
+		return fmt.Errorf("STARTUP_SELFTEST must be %s, %s or %s", SelfTestOff, SelfTestWarn, SelfTestStrict)
+	}
 	return nil
 }
 
@@ -86,3 +252,31 @@ func getEnv(key, defaultValue string) string {
 	}
 	return value
 }
+
+// Helper function to get an int64 environment variable with default
+func getEnvInt64(key string, defaultValue int64) int64 {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	parsed, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		log.Printf("⚠️  Invalid value for %s (%q), using default %d", key, value, defaultValue)
+		return defaultValue
+	}
+	return parsed
+}
+
+// Helper function to get a duration environment variable (e.g. "5m") with default
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		log.Printf("⚠️  Invalid value for %s (%q), using default %s", key, value, defaultValue)
+		return defaultValue
+	}
+	return parsed
+}
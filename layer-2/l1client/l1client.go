@@ -2,24 +2,50 @@ package l1client
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"os"
 	"sync"
 	"time"
 
 	"github.com/ahmadzakiakmal/thesis-extension/layer-2/repository/models"
+	"github.com/ahmadzakiakmal/thesis-extension/layer-2/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// RequestIDHeader carries the request ID across L2 and L1
+const RequestIDHeader = "X-Request-ID"
+
+type requestIDKey struct{}
+
+// WithRequestID attaches a request ID to ctx so calls to L1 forward it
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+// RequestIDFromContext returns the request ID attached to ctx, if any
+func RequestIDFromContext(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey{}).(string)
+	return requestID
+}
+
 // L1Client handles communication with L1 BFT network
 type L1Client struct {
 	endpoint   string
+	apiKey     string
 	shardID    string
 	nodeID     string
 	httpClient *http.Client
 	shardCache map[string]ShardInfo // cache: client_group -> ShardInfo
-	mu         sync.RWMutex         // protect the cache
+	overrides  map[string]ShardInfo // local client_group pins that win over L1
+	redaction  Redaction            // session_data fields hashed or dropped on commit
+	mu         sync.RWMutex         // protect the cache, overrides and redaction
 }
 
 // CommitRequest represents the request to commit a session to L1
@@ -40,6 +66,9 @@ type CommitResponse struct {
 		TxHash    string `json:"tx_hash"`
 		SessionID string `json:"session_id"`
 		ShardID   string `json:"shard_id"`
+
+		// Receipt is L1's signed proof of the commit; check it with VerifyReceipt
+		Receipt *CommitReceipt `json:"receipt,omitempty"`
 	} `json:"data"`
 	Meta struct {
 		TxID        string    `json:"tx_id"`
@@ -51,10 +80,19 @@ type CommitResponse struct {
 			ClientGroup string `json:"client_group"`
 			L2NodeID    string `json:"l2_node_id"`
 		} `json:"shard_info"`
+		Votes int `json:"votes"` // validator precommits backing the block
 	} `json:"meta"`
 	NodeID string `json:"node_id"`
+
+	// Existing is set when L1 already held the commit and this response was
+	// built from L1's copy of the session
+	Existing bool `json:"-"`
 }
 
+// ErrForeignCommit is returned when L1 already holds a commit for the session
+// ID that came from another shard
+var ErrForeignCommit = errors.New("L1 holds a different commit for this session ID")
+
 // NewL1Client creates a new L1 client
 func NewL1Client(endpoint, shardID, nodeID string) *L1Client {
 	return &L1Client{
@@ -67,36 +105,66 @@ func NewL1Client(endpoint, shardID, nodeID string) *L1Client {
 	}
 }
 
-// CommitSession commits a completed session to L1
-func (c *L1Client) CommitSession(session *models.Session, clientGroup string) (*CommitResponse, error) {
-	// Build session data
-	sessionData := c.buildSessionData(session)
+// Endpoint returns the L1 base URL this client talks to
+func (c *L1Client) Endpoint() string {
+	return c.endpoint
+}
 
-	// Create commit request
-	commitReq := CommitRequest{
-		ShardID:     c.shardID,
-		ClientGroup: clientGroup,
-		SessionID:   session.ID,
-		OperatorID:  session.OperatorID,
-		SessionData: sessionData,
-		L2NodeID:    c.nodeID,
-		Timestamp:   time.Now(),
+// SetAPIKey sets the key sent to L1 on write requests
+func (c *L1Client) SetAPIKey(apiKey string) {
+	c.apiKey = apiKey
+}
+
+// CommitSession commits a completed session to L1. When L1 already holds
+// the session, e.g. because the response to an earlier attempt was lost, the
+// commit L1 holds is returned instead, so retrying a commit is safe.
+func (c *L1Client) CommitSession(ctx context.Context, session *models.Session, clientGroup string) (*CommitResponse, error) {
+	ctx, span := tracing.Tracer().Start(ctx, "L1Client.CommitSession",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String("l2.session_id", session.ID)),
+	)
+	defer span.End()
+
+	commitResp, err := c.commitSession(ctx, session, clientGroup)
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusConflict {
+		commitResp, err = c.existingCommit(ctx, session.ID)
+		span.SetAttributes(attribute.Bool("l1.existing_commit", err == nil))
+	}
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
 	}
+	span.SetAttributes(
+		attribute.String("l1.tx_hash", commitResp.Data.TxHash),
+		attribute.Int64("l1.block_height", commitResp.Meta.BlockHeight),
+	)
+	return commitResp, nil
+}
 
-	// Marshal to JSON
-	jsonData, err := json.Marshal(commitReq)
+// commitSession sends the commit for session to L1, forwarding the trace
+// context of ctx
+func (c *L1Client) commitSession(ctx context.Context, session *models.Session, clientGroup string) (*CommitResponse, error) {
+	jsonData, err := c.buildCommitRequest(session, clientGroup)
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal commit request: %w", err)
+		return nil, err
 	}
 
 	// Make HTTP request to L1
 	url := fmt.Sprintf("%s/l1/commit", c.endpoint)
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create HTTP request: %w", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
+	if c.apiKey != "" {
+		req.Header.Set("X-L1-Api-Key", c.apiKey)
+	}
+	if requestID := RequestIDFromContext(ctx); requestID != "" {
+		req.Header.Set(RequestIDHeader, requestID)
+	}
+	tracing.Inject(ctx, req.Header)
 
 	// Send request
 	resp, err := c.httpClient.Do(req)
@@ -113,7 +181,7 @@ func (c *L1Client) CommitSession(session *models.Session, clientGroup string) (*
 
 	// Check status code
 	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusAccepted {
-		return nil, fmt.Errorf("L1 returned error status %d: %s", resp.StatusCode, string(body))
+		return nil, &StatusError{StatusCode: resp.StatusCode, Message: string(body)}
 	}
 
 	// Parse response
@@ -125,7 +193,67 @@ func (c *L1Client) CommitSession(session *models.Session, clientGroup string) (*
 	return &commitResp, nil
 }
 
-// buildSessionData builds the session data payload for L1
+// existingCommit loads the commit L1 already holds for sessionID after L1
+// answered a commit with 409
+func (c *L1Client) existingCommit(ctx context.Context, sessionID string) (*CommitResponse, error) {
+	l1Session, err := c.GetSession(ctx, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("L1 reports the session as committed but it could not be loaded: %w", err)
+	}
+	if l1Session.ShardID != c.shardID {
+		return nil, ErrForeignCommit
+	}
+	if l1Session.Transaction == nil {
+		return nil, fmt.Errorf("L1 holds session %s but has no transaction for it", sessionID)
+	}
+
+	transaction := l1Session.Transaction
+	commitResp := &CommitResponse{Existing: true}
+	commitResp.Data.Message = "Session was already committed"
+	commitResp.Data.TxHash = transaction.TxHash
+	commitResp.Data.SessionID = sessionID
+	commitResp.Data.ShardID = l1Session.ShardID
+	commitResp.Meta.TxID = transaction.TxID
+	commitResp.Meta.Status = transaction.Status
+	commitResp.Meta.BlockHeight = transaction.BlockHeight
+	commitResp.Meta.ConfirmTime = transaction.Timestamp
+	commitResp.Meta.ShardInfo.ShardID = l1Session.ShardID
+	commitResp.Meta.ShardInfo.ClientGroup = l1Session.ClientGroup
+	return commitResp, nil
+}
+
+// DryRunCommit builds the commit request for session without sending it
+func (c *L1Client) DryRunCommit(session *models.Session, clientGroup string) error {
+	_, err := c.buildCommitRequest(session, clientGroup)
+	return err
+}
+
+// buildCommitRequest builds the JSON commit request for session
+func (c *L1Client) buildCommitRequest(session *models.Session, clientGroup string) ([]byte, error) {
+	// Build session data
+	sessionData := c.buildSessionData(session)
+
+	// Create commit request
+	commitReq := CommitRequest{
+		ShardID:     c.shardID,
+		ClientGroup: clientGroup,
+		SessionID:   session.ID,
+		OperatorID:  session.OperatorID,
+		SessionData: sessionData,
+		L2NodeID:    c.nodeID,
+		Timestamp:   time.Now(),
+	}
+
+	// Marshal to JSON
+	jsonData, err := json.Marshal(commitReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal commit request: %w", err)
+	}
+	return jsonData, nil
+}
+
+// buildSessionData builds the session data payload for L1, with the
+// configured fields redacted
 func (c *L1Client) buildSessionData(session *models.Session) map[string]interface{} {
 	data := map[string]interface{}{
 		"session_id":  session.ID,
@@ -197,6 +325,7 @@ func (c *L1Client) buildSessionData(session *models.Session) map[string]interfac
 		data["label"] = labelData
 	}
 
+	c.redact(data)
 	return data
 }
 
@@ -217,6 +346,40 @@ func (c *L1Client) HealthCheck() error {
 	return nil
 }
 
+// SendHeartbeat tells L1 that this shard's node is alive
+func (c *L1Client) SendHeartbeat(ctx context.Context) error {
+	jsonData, err := json.Marshal(map[string]interface{}{
+		"l2_node_id": c.nodeID,
+		"sent_at":    time.Now(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal heartbeat: %w", err)
+	}
+
+	url := fmt.Sprintf("%s/l1/shards/%s/heartbeat", c.endpoint, c.shardID)
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return fmt.Errorf("failed to create HTTP request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.apiKey != "" {
+		req.Header.Set("X-L1-Api-Key", c.apiKey)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send heartbeat to L1: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return &StatusError{StatusCode: resp.StatusCode, Message: string(body)}
+	}
+	io.Copy(io.Discard, resp.Body)
+	return nil
+}
+
 // ShardInfo represents shard information from L1
 type ShardInfo struct {
 	ShardID     string `json:"ShardID"`
@@ -228,29 +391,14 @@ type ShardInfo struct {
 
 // GetAllShards retrieves all registered shards from L1
 func (c *L1Client) GetAllShards() ([]ShardInfo, error) {
-	url := fmt.Sprintf("%s/l1/shards", c.endpoint)
-
-	resp, err := http.Get(url)
-	if err != nil {
-		return nil, fmt.Errorf("failed to query L1 shards: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("L1 returned status %d", resp.StatusCode)
-	}
-
 	var response struct {
-		Data struct {
-			Shards []ShardInfo `json:"shards"`
-		} `json:"data"`
+		Shards []ShardInfo `json:"shards"`
 	}
-
-	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
-		return nil, fmt.Errorf("failed to decode L1 response: %w", err)
+	if err := c.getData(context.Background(), "/l1/shards", &response); err != nil {
+		return nil, fmt.Errorf("failed to query L1 shards: %w", err)
 	}
 
-	return response.Data.Shards, nil
+	return response.Shards, nil
 }
 
 // LoadShards fetches and caches all shard information from L1
@@ -260,6 +408,12 @@ func (c *L1Client) LoadShards() error {
 		return fmt.Errorf("failed to load shards: %w", err)
 	}
 
+	c.SetShards(shards)
+	return nil
+}
+
+// SetShards replaces the shard cache, e.g. with a registry persisted earlier
+func (c *L1Client) SetShards(shards []ShardInfo) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
@@ -267,19 +421,66 @@ func (c *L1Client) LoadShards() error {
 	c.shardCache = make(map[string]ShardInfo)
 	for _, shard := range shards {
 		c.shardCache[shard.ClientGroup] = shard
-		// ADD THIS DEBUG LOG
-		fmt.Printf("📋 Cached shard: group=%s, shard_id=%s, endpoint=%s\n",
-			shard.ClientGroup, shard.ShardID, shard.L2Endpoint)
 	}
+}
+
+// LoadShardOverrides reads a JSON array of ShardInfo from path and pins those
+// client groups to the listed shards, taking precedence over the L1 registry.
+// Each entry needs a ClientGroup and an L2Endpoint.
+func (c *L1Client) LoadShardOverrides(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read shard override file: %w", err)
+	}
+
+	var shards []ShardInfo
+	if err := json.Unmarshal(data, &shards); err != nil {
+		return fmt.Errorf("failed to parse shard override file: %w", err)
+	}
+
+	overrides := make(map[string]ShardInfo, len(shards))
+	for i, shard := range shards {
+		if shard.ClientGroup == "" || shard.L2Endpoint == "" {
+			return fmt.Errorf("shard override %d: ClientGroup and L2Endpoint are required", i)
+		}
+		if _, ok := overrides[shard.ClientGroup]; ok {
+			return fmt.Errorf("shard override %d: duplicate client group %s", i, shard.ClientGroup)
+		}
+		if shard.Status == "" {
+			shard.Status = "active"
+		}
+		overrides[shard.ClientGroup] = shard
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
 
+	c.overrides = overrides
 	return nil
 }
 
-// GetShardByClientGroup returns shard info for a given client group
+// Shards returns a copy of the shard registry as provided by L1, without
+// local overrides, so it can be persisted as-is
+func (c *L1Client) Shards() []ShardInfo {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	shards := make([]ShardInfo, 0, len(c.shardCache))
+	for _, shard := range c.shardCache {
+		shards = append(shards, shard)
+	}
+	return shards
+}
+
+// GetShardByClientGroup returns shard info for a given client group, preferring
+// a local override over the L1 registry
 func (c *L1Client) GetShardByClientGroup(clientGroup string) (ShardInfo, bool) {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 
+	if shard, found := c.overrides[clientGroup]; found {
+		return shard, true
+	}
 	shard, found := c.shardCache[clientGroup]
 	return shard, found
 }
@@ -0,0 +1,90 @@
+package l1client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ahmadzakiakmal/thesis-extension/layer-2/repository/models"
+)
+
+// conflictL1 answers every commit with 409 and serves SES-1 as committed by
+// shardID
+func conflictL1(t *testing.T, shardID string) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/l1/commit", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusConflict)
+		fmt.Fprint(w, `{"data":{"error":"Transaction already exists"}}`)
+	})
+	mux.HandleFunc("/l1/sessions/SES-1", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"data":{"ID":"SES-1","ShardID":%q,"ClientGroup":"group-a","Transaction":{
+			"TxHash":"AB12","TxID":"tx-1","BlockHeight":42,"Status":"confirmed"}}}`, shardID)
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestCommitSessionConflictReturnsExistingCommit(t *testing.T) {
+	server := conflictL1(t, "shard-a")
+	client := NewL1Client(server.URL, "shard-a", "node-a")
+
+	resp, err := client.CommitSession(context.Background(), &models.Session{ID: "SES-1"}, "group-a")
+	if err != nil {
+		t.Fatalf("CommitSession: %v", err)
+	}
+	if !resp.Existing {
+		t.Error("response not marked as existing")
+	}
+	if resp.Data.TxHash != "AB12" || resp.Meta.TxID != "tx-1" || resp.Meta.BlockHeight != 42 {
+		t.Errorf("response = %+v, want L1's stored commit", resp)
+	}
+}
+
+func TestCommitSessionConflictFromAnotherShard(t *testing.T) {
+	server := conflictL1(t, "shard-b")
+	client := NewL1Client(server.URL, "shard-a", "node-a")
+
+	_, err := client.CommitSession(context.Background(), &models.Session{ID: "SES-1"}, "group-a")
+	if !errors.Is(err, ErrForeignCommit) {
+		t.Fatalf("err = %v, want ErrForeignCommit", err)
+	}
+}
+
+func TestGetOperatorsPages(t *testing.T) {
+	const total = operatorPageSize + 3
+	var offsets []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/l1/operators" || r.URL.Query().Get("limit") != fmt.Sprint(operatorPageSize) {
+			t.Errorf("unexpected request %s", r.URL)
+		}
+		offset := 0
+		fmt.Sscan(r.URL.Query().Get("offset"), &offset)
+		offsets = append(offsets, r.URL.Query().Get("offset"))
+
+		var page []Operator
+		for i := offset; i < total && i < offset+operatorPageSize; i++ {
+			page = append(page, Operator{ID: fmt.Sprintf("OPR-%04d", i), AccessLevel: "Basic"})
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"data": map[string]interface{}{"operators": page, "total": total},
+		})
+	}))
+	t.Cleanup(server.Close)
+
+	operators, err := NewL1Client(server.URL, "shard-a", "node-a").GetOperators(context.Background())
+	if err != nil {
+		t.Fatalf("GetOperators: %v", err)
+	}
+	if len(operators) != total || operators[total-1].ID != fmt.Sprintf("OPR-%04d", total-1) {
+		t.Fatalf("got %d operators, want %d ending with the last page", len(operators), total)
+	}
+	if want := []string{"0", fmt.Sprint(operatorPageSize)}; fmt.Sprint(offsets) != fmt.Sprint(want) {
+		t.Fatalf("offsets = %v, want %v", offsets, want)
+	}
+}
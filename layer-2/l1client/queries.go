@@ -0,0 +1,174 @@
+package l1client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/ahmadzakiakmal/thesis-extension/layer-2/tracing"
+)
+
+// Session is a session as mirrored by L1
+type Session struct {
+	ID          string       `json:"ID"`
+	ShardID     string       `json:"ShardID"`
+	ClientGroup string       `json:"ClientGroup"`
+	OperatorID  string       `json:"OperatorID"`
+	Status      string       `json:"Status"`
+	IsCommitted bool         `json:"IsCommitted"`
+	TxHash      *string      `json:"TxHash"`
+	CreatedAt   time.Time    `json:"CreatedAt"`
+	UpdatedAt   time.Time    `json:"UpdatedAt"`
+	SessionData string       `json:"SessionData"` // JSON as committed by the shard
+	Transaction *Transaction `json:"Transaction"`
+}
+
+// Transaction is the L1 record of a committed session
+type Transaction struct {
+	TxHash      string    `json:"TxHash"`
+	TxID        string    `json:"TxID"` // pass to VerifyTransaction
+	SessionID   string    `json:"SessionID"`
+	ShardID     string    `json:"ShardID"`
+	ClientGroup string    `json:"ClientGroup"`
+	BlockHeight int64     `json:"BlockHeight"`
+	Timestamp   time.Time `json:"Timestamp"`
+	Status      string    `json:"Status"`
+	ConsensusMs int64     `json:"ConsensusMs"`
+}
+
+// Operator is an operator registered on L1
+type Operator struct {
+	ID          string `json:"ID"`
+	Name        string `json:"Name"`
+	Role        string `json:"Role"`
+	AccessLevel string `json:"AccessLevel"`
+	ShardID     string `json:"ShardID"`
+}
+
+// operatorPageSize is the largest page of operators L1 serves
+const operatorPageSize = 500
+
+// VerifyResult is L1's check of a transaction against its consensus state
+type VerifyResult struct {
+	TxID     string          `json:"tx_id"`
+	Status   string          `json:"status"`
+	Verified bool            `json:"verified"`
+	Payload  json.RawMessage `json:"payload"`
+}
+
+// StatusError is a non-2xx response from L1
+type StatusError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("L1 returned status %d: %s", e.StatusCode, e.Message)
+}
+
+// GetSession retrieves a session mirrored by L1
+func (c *L1Client) GetSession(ctx context.Context, sessionID string) (*Session, error) {
+	var session Session
+	if err := c.getData(ctx, "/l1/sessions/"+url.PathEscape(sessionID), &session); err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// GetSessionsByGroup retrieves every session L1 holds for a client group
+func (c *L1Client) GetSessionsByGroup(ctx context.Context, clientGroup string) ([]Session, error) {
+	var sessions []Session
+	if err := c.getData(ctx, "/l1/sessions/group/"+url.PathEscape(clientGroup), &sessions); err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+// GetOperators retrieves every operator registered on L1, across all shards
+func (c *L1Client) GetOperators(ctx context.Context) ([]Operator, error) {
+	var operators []Operator
+	for {
+		var page struct {
+			Operators []Operator `json:"operators"`
+			Total     int        `json:"total"`
+		}
+		path := fmt.Sprintf("/l1/operators?limit=%d&offset=%d", operatorPageSize, len(operators))
+		if err := c.getData(ctx, path, &page); err != nil {
+			return nil, err
+		}
+		operators = append(operators, page.Operators...)
+		if len(page.Operators) < operatorPageSize || len(operators) >= page.Total {
+			return operators, nil
+		}
+	}
+}
+
+// GetTransaction retrieves an L1 transaction by hash
+func (c *L1Client) GetTransaction(ctx context.Context, txHash string) (*Transaction, error) {
+	var transaction Transaction
+	if err := c.getData(ctx, "/l1/transaction/"+url.PathEscape(txHash), &transaction); err != nil {
+		return nil, err
+	}
+	return &transaction, nil
+}
+
+// VerifyTransaction checks a transaction ID against L1's consensus state
+func (c *L1Client) VerifyTransaction(ctx context.Context, txID string) (*VerifyResult, error) {
+	var result VerifyResult
+	if err := c.getData(ctx, "/l1/verify/"+url.PathEscape(txID), &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// getData GETs path from L1 and decodes the data field of the response
+// envelope into out. Non-2xx responses are returned as *StatusError.
+func (c *L1Client) getData(ctx context.Context, path string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.endpoint+path, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create HTTP request: %w", err)
+	}
+	if c.apiKey != "" {
+		req.Header.Set("X-L1-Api-Key", c.apiKey)
+	}
+	if requestID := RequestIDFromContext(ctx); requestID != "" {
+		req.Header.Set(RequestIDHeader, requestID)
+	}
+	tracing.Inject(ctx, req.Header)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request to L1: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read L1 response: %w", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		statusErr := &StatusError{StatusCode: resp.StatusCode, Message: string(body)}
+		var envelope struct {
+			Data struct {
+				Error string `json:"error"`
+			} `json:"data"`
+		}
+		if json.Unmarshal(body, &envelope) == nil && envelope.Data.Error != "" {
+			statusErr.Message = envelope.Data.Error
+		}
+		return statusErr
+	}
+
+	envelope := struct {
+		Data interface{} `json:"data"`
+	}{Data: out}
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		return fmt.Errorf("failed to decode L1 response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package l1client
+
+import (
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+)
+
+// CommitReceipt is an L1 node's signed statement that it committed a session
+type CommitReceipt struct {
+	TxHash      string `json:"tx_hash"`
+	SessionID   string `json:"session_id"`
+	BlockHeight int64  `json:"block_height"`
+	Algorithm   string `json:"algorithm"`
+	PublicKey   []byte `json:"public_key"`
+	Signature   []byte `json:"signature"`
+}
+
+// receiptMessage rebuilds the bytes L1 signs for a receipt; it must match
+// srvreg.ReceiptMessage on L1
+func receiptMessage(receipt *CommitReceipt) []byte {
+	return []byte(fmt.Sprintf("l1-commit-receipt\x00%s\x00%s\x00%d", receipt.TxHash, receipt.SessionID, receipt.BlockHeight))
+}
+
+// VerifyReceipt checks that the receipt was signed by the key it carries and
+// has not been altered since. It does not check who that key belongs to:
+// compare PublicKey against the L1 validator keys you trust before relying
+// on the receipt as proof of commitment.
+func VerifyReceipt(receipt *CommitReceipt) error {
+	if receipt == nil {
+		return errors.New("no receipt")
+	}
+	if receipt.Algorithm != "ed25519" {
+		return fmt.Errorf("unsupported receipt algorithm %q", receipt.Algorithm)
+	}
+	if len(receipt.PublicKey) != ed25519.PublicKeySize {
+		return fmt.Errorf("receipt public key is %d bytes, want %d", len(receipt.PublicKey), ed25519.PublicKeySize)
+	}
+	if !ed25519.Verify(receipt.PublicKey, receiptMessage(receipt), receipt.Signature) {
+		return errors.New("receipt signature does not match its contents")
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package l1client
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"testing"
+)
+
+// signedReceipt returns a receipt signed the way an L1 node signs one
+func signedReceipt(t *testing.T) *CommitReceipt {
+	t.Helper()
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("generating key: %v", err)
+	}
+	receipt := &CommitReceipt{
+		TxHash:      "AB12",
+		SessionID:   "SES-1",
+		BlockHeight: 42,
+		Algorithm:   "ed25519",
+		PublicKey:   publicKey,
+	}
+	receipt.Signature = ed25519.Sign(privateKey, receiptMessage(receipt))
+	return receipt
+}
+
+func TestReceiptMessageMatchesL1(t *testing.T) {
+	// The bytes L1's srvreg.ReceiptMessage signs for the same receipt
+	want := "l1-commit-receipt\x00AB12\x00SES-1\x0042"
+	if got := string(receiptMessage(&CommitReceipt{TxHash: "AB12", SessionID: "SES-1", BlockHeight: 42})); got != want {
+		t.Errorf("receiptMessage = %q, want %q", got, want)
+	}
+}
+
+func TestVerifyReceipt(t *testing.T) {
+	if err := VerifyReceipt(signedReceipt(t)); err != nil {
+		t.Errorf("VerifyReceipt: %v", err)
+	}
+}
+
+func TestVerifyReceiptRejectsTampering(t *testing.T) {
+	otherKey, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("generating key: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		tamper func(r *CommitReceipt)
+	}{
+		{"tx hash", func(r *CommitReceipt) { r.TxHash = "CD34" }},
+		{"session id", func(r *CommitReceipt) { r.SessionID = "SES-2" }},
+		{"block height", func(r *CommitReceipt) { r.BlockHeight++ }},
+		{"signature", func(r *CommitReceipt) { r.Signature[0] ^= 0xff }},
+		{"public key", func(r *CommitReceipt) { r.PublicKey = otherKey }},
+		{"truncated public key", func(r *CommitReceipt) { r.PublicKey = r.PublicKey[:16] }},
+		{"algorithm", func(r *CommitReceipt) { r.Algorithm = "secp256k1" }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			receipt := signedReceipt(t)
+			tt.tamper(receipt)
+			if err := VerifyReceipt(receipt); err == nil {
+				t.Error("VerifyReceipt accepted a tampered receipt")
+			}
+		})
+	}
+}
+
+func TestVerifyReceiptMissing(t *testing.T) {
+	if err := VerifyReceipt(nil); err == nil {
+		t.Error("VerifyReceipt accepted a missing receipt")
+	}
+}
@@ -0,0 +1,123 @@
+package l1client
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Redaction lists session_data fields to hash or drop before a commit is
+// sent to L1. Fields are dotted paths such as "package.signature"; a path
+// through a list, like "package.items.description", applies to every
+// element. The L2 database keeps the full data.
+type Redaction struct {
+	Hash []string
+	Drop []string
+
+	// Salt keys the hash as HMAC-SHA256 so short values can't be recovered
+	// by hashing guesses; empty uses plain SHA-256
+	Salt string
+}
+
+// ParseFieldList splits a comma-separated list of field paths, dropping
+// blanks
+func ParseFieldList(list string) []string {
+	var fields []string
+	for _, field := range strings.Split(list, ",") {
+		if field = strings.TrimSpace(field); field != "" {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+// SetRedaction sets the fields redacted from commit payloads
+func (c *L1Client) SetRedaction(redaction Redaction) error {
+	seen := make(map[string]string)
+	for _, list := range []struct {
+		name   string
+		fields []string
+	}{{"hash", redaction.Hash}, {"drop", redaction.Drop}} {
+		for _, field := range list.fields {
+			for _, part := range strings.Split(field, ".") {
+				if part == "" {
+					return fmt.Errorf("invalid redaction field %q", field)
+				}
+			}
+			if other, ok := seen[field]; ok {
+				return fmt.Errorf("redaction field %q is listed under both %s and %s", field, other, list.name)
+			}
+			seen[field] = list.name
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.redaction = redaction
+	return nil
+}
+
+// redact applies the configured redaction to session data in place
+func (c *L1Client) redact(data map[string]interface{}) {
+	c.mu.RLock()
+	redaction := c.redaction
+	c.mu.RUnlock()
+
+	for _, field := range redaction.Drop {
+		applyField(data, strings.Split(field, "."), func(obj map[string]interface{}, key string) {
+			delete(obj, key)
+		})
+	}
+	for _, field := range redaction.Hash {
+		applyField(data, strings.Split(field, "."), func(obj map[string]interface{}, key string) {
+			if value, ok := obj[key]; ok && value != nil {
+				obj[key] = hashValue(value, redaction.Salt)
+			}
+		})
+	}
+}
+
+// applyField calls fn on the object holding the last element of path,
+// descending into nested objects and every element of lists
+func applyField(value interface{}, path []string, fn func(obj map[string]interface{}, key string)) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if len(path) == 1 {
+			fn(v, path[0])
+			return
+		}
+		if next, ok := v[path[0]]; ok {
+			applyField(next, path[1:], fn)
+		}
+	case []map[string]interface{}:
+		for _, elem := range v {
+			applyField(elem, path, fn)
+		}
+	case []interface{}:
+		for _, elem := range v {
+			applyField(elem, path, fn)
+		}
+	}
+}
+
+// hashValue returns "sha256:<hex>" of a value: strings are hashed as-is and
+// other values as JSON
+func hashValue(value interface{}, salt string) string {
+	var raw []byte
+	if s, ok := value.(string); ok {
+		raw = []byte(s)
+	} else {
+		raw, _ = json.Marshal(value)
+	}
+
+	if salt != "" {
+		mac := hmac.New(sha256.New, []byte(salt))
+		mac.Write(raw)
+		return "hmac-sha256:" + hex.EncodeToString(mac.Sum(nil))
+	}
+	sum := sha256.Sum256(raw)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,219 @@
+package l2client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/ahmadzakiakmal/thesis-extension/layer-2/api"
+)
+
+// ClientGroupHeader routes a request to the shard serving a client group
+const ClientGroupHeader = "X-Client-Group"
+
+// OperatorHeader names the operator acting on a session
+const OperatorHeader = api.OperatorHeader
+
+// APIError is a non-2xx response from an L2 node
+type APIError struct {
+	StatusCode int
+	Code       string
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("L2 returned status %d: %s", e.StatusCode, e.Message)
+	}
+	return fmt.Sprintf("L2 returned status %d (%s): %s", e.StatusCode, e.Code, e.Message)
+}
+
+// L2Client is a typed client for the L2 session workflow API
+type L2Client struct {
+	endpoint    string
+	clientGroup string
+	operatorID  string
+	httpClient  *http.Client
+}
+
+// NewL2Client creates a client for the L2 node at endpoint
+func NewL2Client(endpoint string) *L2Client {
+	return &L2Client{
+		endpoint: endpoint,
+		httpClient: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+	}
+}
+
+// SetHTTPClient replaces the HTTP client, e.g. to share a transport or
+// trace connections across several clients
+func (c *L2Client) SetHTTPClient(httpClient *http.Client) {
+	c.httpClient = httpClient
+}
+
+// SetClientGroup sends X-Client-Group on every request so the node forwards
+// it to the shard serving that group. Empty lets the node serve it locally.
+func (c *L2Client) SetClientGroup(clientGroup string) {
+	c.clientGroup = clientGroup
+}
+
+// SetOperator sends X-Operator-ID on every request. L2 authorizes each
+// session step against this operator's access level.
+func (c *L2Client) SetOperator(operatorID string) {
+	c.operatorID = operatorID
+}
+
+// StartSession starts a session for an operator
+func (c *L2Client) StartSession(ctx context.Context, operatorID string) (*api.CreateSessionResponse, error) {
+	var resp api.CreateSessionResponse
+	err := c.do(ctx, http.MethodPost, "/session/start", api.CreateSessionRequest{OperatorID: operatorID}, &resp)
+	if err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// ScanPackage scans a package into a session
+func (c *L2Client) ScanPackage(ctx context.Context, sessionID, packageID string) (*api.ScanPackageResponse, error) {
+	var resp api.ScanPackageResponse
+	path := fmt.Sprintf("/session/%s/scan", sessionID)
+	if err := c.do(ctx, http.MethodGet, path, api.ScanPackageRequest{PackageID: packageID}, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// ValidatePackage checks the supplier signature of the session's package
+func (c *L2Client) ValidatePackage(ctx context.Context, sessionID, packageID, signature string) (*api.ValidatePackageResponse, error) {
+	var resp api.ValidatePackageResponse
+	path := fmt.Sprintf("/session/%s/validate", sessionID)
+	body := api.ValidatePackageRequest{PackageID: packageID, Signature: signature}
+	if err := c.do(ctx, http.MethodPost, path, body, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// QualityCheck records the quality check of the session's package
+func (c *L2Client) QualityCheck(ctx context.Context, sessionID string, passed bool, issues []string) (*api.QualityCheckResponse, error) {
+	if issues == nil {
+		issues = []string{}
+	}
+	var resp api.QualityCheckResponse
+	path := fmt.Sprintf("/session/%s/qc", sessionID)
+	if err := c.do(ctx, http.MethodPost, path, api.QualityCheckRequest{Passed: passed, Issues: issues}, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// LabelPackage creates the shipping label for a session
+func (c *L2Client) LabelPackage(ctx context.Context, sessionID, courierID string) (*api.LabelPackageResponse, error) {
+	var resp api.LabelPackageResponse
+	path := fmt.Sprintf("/session/%s/label", sessionID)
+	if err := c.do(ctx, http.MethodPost, path, api.LabelPackageRequest{CourierID: courierID}, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// CommitSession commits a session to L1. A non-empty callbackURL also gets
+// the final commit status.
+func (c *L2Client) CommitSession(ctx context.Context, sessionID, callbackURL string) (*api.CommitSessionResponse, error) {
+	var resp api.CommitSessionResponse
+	path := fmt.Sprintf("/session/%s/commit", sessionID)
+	if err := c.do(ctx, http.MethodPost, path, api.CommitSessionRequest{CallbackURL: callbackURL}, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// DeleteSession deletes an uncommitted session
+func (c *L2Client) DeleteSession(ctx context.Context, sessionID string) (*api.DeleteSessionResponse, error) {
+	var resp api.DeleteSessionResponse
+	if err := c.do(ctx, http.MethodDelete, "/session/"+sessionID, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// Info returns the shard served by the node
+func (c *L2Client) Info(ctx context.Context) (*api.InfoResponse, error) {
+	var resp api.InfoResponse
+	if err := c.do(ctx, http.MethodGet, "/info", nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// do sends body as JSON to path and decodes a 2xx response into out. Other
+// statuses are returned as *APIError.
+func (c *L2Client) do(ctx context.Context, method, path string, body, out interface{}) error {
+	var bodyReader io.Reader
+	if body != nil {
+		jsonData, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to marshal request: %w", err)
+		}
+		bodyReader = bytes.NewReader(jsonData)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.endpoint+path, bodyReader)
+	if err != nil {
+		return fmt.Errorf("failed to create HTTP request: %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.clientGroup != "" {
+		req.Header.Set(ClientGroupHeader, c.clientGroup)
+	}
+	if c.operatorID != "" {
+		req.Header.Set(OperatorHeader, c.operatorID)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request to L2: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read L2 response: %w", err)
+	}
+
+	// Nodes with RESPONSE_ENVELOPE wrap bodies; unwrap to the handler's body
+	var envelopeErr *api.ErrorResponse
+	if resp.Header.Get(api.EnvelopeHeader) != "" {
+		var envelope api.L2Response
+		if err := json.Unmarshal(respBody, &envelope); err != nil {
+			return fmt.Errorf("failed to parse L2 response envelope: %w", err)
+		}
+		respBody = envelope.Data
+		envelopeErr = envelope.Error
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		apiErr := &APIError{StatusCode: resp.StatusCode, Message: string(respBody)}
+		var errResp api.ErrorResponse
+		if envelopeErr != nil {
+			apiErr.Code = envelopeErr.Code
+			apiErr.Message = envelopeErr.Error
+		} else if json.Unmarshal(respBody, &errResp) == nil && errResp.Error != "" {
+			apiErr.Code = errResp.Code
+			apiErr.Message = errResp.Error
+		}
+		return apiErr
+	}
+
+	if err := json.Unmarshal(respBody, out); err != nil {
+		return fmt.Errorf("failed to parse L2 response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,232 @@
+package l2client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/ahmadzakiakmal/thesis-extension/layer-2/api"
+)
+
+// recordedRequest is what fakeL2 saw of a request
+type recordedRequest struct {
+	method  string
+	path    string
+	body    string
+	headers http.Header
+}
+
+// fakeL2 answers every request with status and body, recording the last
+// request it received
+func fakeL2(t *testing.T, status int, headers map[string]string, body string) (*httptest.Server, *recordedRequest) {
+	t.Helper()
+	got := &recordedRequest{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reqBody, _ := io.ReadAll(r.Body)
+		*got = recordedRequest{method: r.Method, path: r.URL.Path, body: string(reqBody), headers: r.Header}
+		for key, value := range headers {
+			w.Header().Set(key, value)
+		}
+		w.WriteHeader(status)
+		io.WriteString(w, body)
+	}))
+	t.Cleanup(server.Close)
+	return server, got
+}
+
+func TestClientMethods(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name       string
+		call       func(c *L2Client) (interface{}, error)
+		wantMethod string
+		wantPath   string
+		wantBody   string
+		response   string
+		want       interface{}
+	}{
+		{
+			name:       "StartSession",
+			call:       func(c *L2Client) (interface{}, error) { return c.StartSession(ctx, "OPR-001") },
+			wantMethod: http.MethodPost,
+			wantPath:   "/session/start",
+			wantBody:   `{"operator_id":"OPR-001"}`,
+			response:   `{"session_id":"SES-1","operator_id":"OPR-001","status":"active","shard_id":"shard-a"}`,
+			want:       &api.CreateSessionResponse{SessionID: "SES-1", OperatorID: "OPR-001", Status: "active", ShardID: "shard-a"},
+		},
+		{
+			name:       "ScanPackage",
+			call:       func(c *L2Client) (interface{}, error) { return c.ScanPackage(ctx, "SES-1", "PKG-001") },
+			wantMethod: http.MethodGet,
+			wantPath:   "/session/SES-1/scan",
+			wantBody:   `{"package_id":"PKG-001"}`,
+			response:   `{"package_id":"PKG-001","supplier":"SUP-001","supplier_signature":"sig","expected_contents":[{"item_id":"ITM-1","quantity":2}]}`,
+			want: &api.ScanPackageResponse{
+				PackageID: "PKG-001", Supplier: "SUP-001", SupplierSignature: "sig",
+				ExpectedContents: []api.PackageItem{{ItemID: "ITM-1", Quantity: 2}},
+			},
+		},
+		{
+			name:       "ValidatePackage",
+			call:       func(c *L2Client) (interface{}, error) { return c.ValidatePackage(ctx, "SES-1", "PKG-001", "sig") },
+			wantMethod: http.MethodPost,
+			wantPath:   "/session/SES-1/validate",
+			wantBody:   `{"signature":"sig","package_id":"PKG-001"}`,
+			response:   `{"package_id":"PKG-001","is_trusted":true,"status":"validated"}`,
+			want:       &api.ValidatePackageResponse{PackageID: "PKG-001", IsTrusted: true, Status: "validated"},
+		},
+		{
+			name:       "QualityCheck",
+			call:       func(c *L2Client) (interface{}, error) { return c.QualityCheck(ctx, "SES-1", true, nil) },
+			wantMethod: http.MethodPost,
+			wantPath:   "/session/SES-1/qc",
+			wantBody:   `{"passed":true,"issues":[]}`,
+			response:   `{"qc_id":"QC-1","passed":true,"package_id":"PKG-001"}`,
+			want:       &api.QualityCheckResponse{QCID: "QC-1", Passed: true, PackageID: "PKG-001"},
+		},
+		{
+			name:       "LabelPackage",
+			call:       func(c *L2Client) (interface{}, error) { return c.LabelPackage(ctx, "SES-1", "CUR-001") },
+			wantMethod: http.MethodPost,
+			wantPath:   "/session/SES-1/label",
+			wantBody:   `{"courier_id":"CUR-001"}`,
+			response:   `{"label_id":"LBL-1","tracking_no":"TRK-1","courier":"CUR-001","session_id":"SES-1"}`,
+			want:       &api.LabelPackageResponse{LabelID: "LBL-1", TrackingNo: "TRK-1", Courier: "CUR-001", SessionID: "SES-1"},
+		},
+		{
+			name:       "CommitSession",
+			call:       func(c *L2Client) (interface{}, error) { return c.CommitSession(ctx, "SES-1", "http://hook") },
+			wantMethod: http.MethodPost,
+			wantPath:   "/session/SES-1/commit",
+			wantBody:   `{"callback_url":"http://hook"}`,
+			response:   `{"session_id":"SES-1","tx_hash":"AB12","block_height":42,"status":"committed"}`,
+			want:       &api.CommitSessionResponse{SessionID: "SES-1", TxHash: "AB12", BlockHeight: 42, Status: "committed"},
+		},
+		{
+			name:       "DeleteSession",
+			call:       func(c *L2Client) (interface{}, error) { return c.DeleteSession(ctx, "SES-1") },
+			wantMethod: http.MethodDelete,
+			wantPath:   "/session/SES-1",
+			response:   `{"message":"Session deleted","session_id":"SES-1"}`,
+			want:       &api.DeleteSessionResponse{Message: "Session deleted", SessionID: "SES-1"},
+		},
+		{
+			name:       "Info",
+			call:       func(c *L2Client) (interface{}, error) { return c.Info(ctx) },
+			wantMethod: http.MethodGet,
+			wantPath:   "/info",
+			response:   `{"shard_id":"shard-a","client_group":"group-a","type":"L2","status":"ready"}`,
+			want:       &api.InfoResponse{ShardID: "shard-a", ClientGroup: "group-a", Type: "L2", Status: "ready"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, got := fakeL2(t, http.StatusOK, nil, tt.response)
+
+			resp, err := tt.call(NewL2Client(server.URL))
+			if err != nil {
+				t.Fatalf("%s: %v", tt.name, err)
+			}
+			if got.method != tt.wantMethod || got.path != tt.wantPath {
+				t.Errorf("request = %s %s, want %s %s", got.method, got.path, tt.wantMethod, tt.wantPath)
+			}
+			if got.body != tt.wantBody {
+				t.Errorf("request body = %s, want %s", got.body, tt.wantBody)
+			}
+			if tt.wantBody != "" && got.headers.Get("Content-Type") != "application/json" {
+				t.Errorf("Content-Type = %q, want application/json", got.headers.Get("Content-Type"))
+			}
+			if !reflect.DeepEqual(resp, tt.want) {
+				t.Errorf("response = %+v, want %+v", resp, tt.want)
+			}
+		})
+	}
+}
+
+func TestClientSendsRoutingHeaders(t *testing.T) {
+	server, got := fakeL2(t, http.StatusOK, nil, `{}`)
+	client := NewL2Client(server.URL)
+
+	if _, err := client.Info(context.Background()); err != nil {
+		t.Fatalf("Info: %v", err)
+	}
+	if got.headers.Get(ClientGroupHeader) != "" || got.headers.Get(OperatorHeader) != "" {
+		t.Errorf("unset headers sent: %v", got.headers)
+	}
+
+	client.SetClientGroup("group-b")
+	client.SetOperator("OPR-001")
+	if _, err := client.Info(context.Background()); err != nil {
+		t.Fatalf("Info: %v", err)
+	}
+	if got.headers.Get(ClientGroupHeader) != "group-b" {
+		t.Errorf("%s = %q, want group-b", ClientGroupHeader, got.headers.Get(ClientGroupHeader))
+	}
+	if got.headers.Get(OperatorHeader) != "OPR-001" {
+		t.Errorf("%s = %q, want OPR-001", OperatorHeader, got.headers.Get(OperatorHeader))
+	}
+}
+
+func TestClientReturnsAPIError(t *testing.T) {
+	tests := []struct {
+		name     string
+		headers  map[string]string
+		body     string
+		wantCode string
+		wantMsg  string
+	}{
+		{
+			name:     "error body",
+			body:     `{"error":"Session not found","code":"SESSION_NOT_FOUND"}`,
+			wantCode: "SESSION_NOT_FOUND",
+			wantMsg:  "Session not found",
+		},
+		{
+			name:     "enveloped error",
+			headers:  map[string]string{api.EnvelopeHeader: "1"},
+			body:     `{"data":null,"error":{"error":"Session not found","code":"SESSION_NOT_FOUND"},"shard_id":"shard-a"}`,
+			wantCode: "SESSION_NOT_FOUND",
+			wantMsg:  "Session not found",
+		},
+		{
+			name:    "plain text",
+			body:    "upstream unavailable",
+			wantMsg: "upstream unavailable",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, _ := fakeL2(t, http.StatusNotFound, tt.headers, tt.body)
+
+			_, err := NewL2Client(server.URL).DeleteSession(context.Background(), "SES-1")
+			var apiErr *APIError
+			if !errors.As(err, &apiErr) {
+				t.Fatalf("err = %v, want *APIError", err)
+			}
+			if apiErr.StatusCode != http.StatusNotFound || apiErr.Code != tt.wantCode || apiErr.Message != tt.wantMsg {
+				t.Errorf("err = %+v, want 404 %q %q", apiErr, tt.wantCode, tt.wantMsg)
+			}
+		})
+	}
+}
+
+func TestClientUnwrapsEnvelope(t *testing.T) {
+	data, _ := json.Marshal(api.InfoResponse{ShardID: "shard-a", Type: "L2"})
+	envelope, _ := json.Marshal(api.L2Response{Data: data, ShardID: "shard-a"})
+	server, _ := fakeL2(t, http.StatusOK, map[string]string{api.EnvelopeHeader: "1"}, string(envelope))
+
+	info, err := NewL2Client(server.URL).Info(context.Background())
+	if err != nil {
+		t.Fatalf("Info: %v", err)
+	}
+	if info.ShardID != "shard-a" || info.Type != "L2" {
+		t.Errorf("info = %+v, want the enveloped body", info)
+	}
+}
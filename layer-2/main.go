@@ -12,13 +12,18 @@ import (
 	"github.com/ahmadzakiakmal/thesis-extension/layer-2/config"
 	"github.com/ahmadzakiakmal/thesis-extension/layer-2/l1client"
 	"github.com/ahmadzakiakmal/thesis-extension/layer-2/repository"
+	"github.com/ahmadzakiakmal/thesis-extension/layer-2/repository/models"
 	"github.com/ahmadzakiakmal/thesis-extension/layer-2/server"
 	"github.com/ahmadzakiakmal/thesis-extension/layer-2/srvreg"
+	"github.com/ahmadzakiakmal/thesis-extension/layer-2/tracing"
+	"github.com/ahmadzakiakmal/thesis-extension/layer-2/webhook"
 )
 
 func main() {
 	// Parse command line flags (optional, for overriding env vars)
 	configFile := flag.String("config", "", "Config file path (optional)")
+	seedData := flag.Bool("seed", os.Getenv("SEED_DATA") != "false", "Upsert demo data on startup (env SEED_DATA=false disables); operators are mirrored either way")
+	seedFile := flag.String("seed-file", os.Getenv("SEED_FILE"), "JSON file replacing the built-in seed data")
 	flag.Parse()
 
 	if *configFile != "" {
@@ -31,6 +36,8 @@ func main() {
 
 	// Load configuration
 	cfg := config.LoadConfig()
+	cfg.SeedData = *seedData
+	cfg.SeedFile = *seedFile
 	if err := cfg.Validate(); err != nil {
 		log.Fatalf("❌ Configuration validation failed: %v", err)
 	}
@@ -40,19 +47,53 @@ func main() {
 	log.Printf("   Client Group: %s", cfg.ClientGroup)
 	log.Printf("   L2 Node ID: %s", cfg.L2NodeID)
 	log.Printf("   HTTP Port: %s", cfg.HTTPPort)
+	log.Printf("   Bind Address: %s", cfg.BindAddress)
 	log.Printf("   L1 Endpoint: %s", cfg.L1Endpoint)
+	log.Printf("   Log Level: %s", cfg.LogLevel)
+	log.Printf("   Startup Self-Test: %s", cfg.StartupSelfTest)
+	log.Printf("   Seed Data: %v", cfg.SeedData)
 	log.Printf("   Database: %s:%s/%s", cfg.DatabaseHost, cfg.DatabasePort, cfg.DatabaseName)
 
 	// Initialize repository
 	log.Println("\n📦 Initializing database...")
 	repo := repository.NewRepository()
+	repo.SetSeedConfig(repository.SeedConfig{Enabled: cfg.SeedData, File: cfg.SeedFile})
+	repo.SetPoolConfig(repository.PoolConfig{
+		MaxOpenConns:    cfg.DatabaseMaxOpenConns,
+		MaxIdleConns:    cfg.DatabaseMaxIdleConns,
+		ConnMaxLifetime: cfg.DatabaseConnMaxLifetime,
+	})
+	if err := repo.SetCourierStrategy(cfg.CourierStrategy); err != nil {
+		log.Fatalf("❌ Invalid courier strategy: %v", err)
+	}
 	if err := repo.ConnectDB(cfg.GetDSN()); err != nil {
 		log.Fatalf("❌ Failed to connect to database: %v", err)
 	}
 
+	// Export traces of the commit path when a collector is configured
+	shutdownTracing, err := tracing.Setup(context.Background(), "l2-"+cfg.L2NodeID, cfg.OTLPEndpoint)
+	if err != nil {
+		log.Fatalf("❌ Failed to set up tracing: %v", err)
+	}
+	if cfg.OTLPEndpoint != "" {
+		log.Printf("✓ Exporting traces to %s", cfg.OTLPEndpoint)
+	}
+
 	// Initialize L1 client
 	log.Println("\n🔗 Initializing L1 client...")
 	l1Client := l1client.NewL1Client(cfg.L1Endpoint, cfg.ShardID, cfg.L2NodeID)
+	l1Client.SetAPIKey(cfg.L1APIKey)
+	redaction := l1client.Redaction{
+		Hash: l1client.ParseFieldList(cfg.CommitHashFields),
+		Drop: l1client.ParseFieldList(cfg.CommitDropFields),
+		Salt: cfg.CommitHashSalt,
+	}
+	if err := l1Client.SetRedaction(redaction); err != nil {
+		log.Fatalf("❌ Invalid commit redaction: %v", err)
+	}
+	if len(redaction.Hash) > 0 || len(redaction.Drop) > 0 {
+		log.Printf("✓ Commit redaction: hashing %v, dropping %v", redaction.Hash, redaction.Drop)
+	}
 
 	// Test L1 connection
 	if err := l1Client.HealthCheck(); err != nil {
@@ -62,23 +103,85 @@ func main() {
 		log.Println("✓ L1 connection verified")
 	}
 
-	// Load shard information from L1
+	// Local overrides pin client groups to shards ahead of the L1 registry
+	if cfg.ShardOverrideFile != "" {
+		if err := l1Client.LoadShardOverrides(cfg.ShardOverrideFile); err != nil {
+			log.Fatalf("❌ Failed to load shard overrides: %v", err)
+		}
+		log.Printf("✓ Shard overrides loaded from %s", cfg.ShardOverrideFile)
+	}
+
+	// Load shard information from L1, falling back to the persisted registry
 	log.Println("📋 Loading shard registry from L1...")
-	if err := l1Client.LoadShards(); err != nil {
-		log.Printf("⚠️  Warning: Failed to load shards: %v", err)
-		log.Println("   Redirect functionality will not be available")
+	loadShardRegistry(l1Client, repo, cfg.ShardRegistryTTL)
+	go refreshShardRegistry(l1Client, repo, cfg.ShardRegistryTTL)
+
+	// Session steps are authorized against L1's operators; the seeded mirror
+	// is only used until the first sync succeeds
+	log.Println("👷 Syncing operators from L1...")
+	if n, err := syncOperators(l1Client, repo); err != nil {
+		log.Printf("⚠️  Warning: Failed to sync operators, using the local mirror: %v", err)
 	} else {
-		log.Println("✓ Shard registry loaded")
+		log.Printf("✓ %d operators synced", n)
+	}
+	if cfg.OperatorSyncInterval > 0 {
+		go refreshOperators(l1Client, repo, cfg.OperatorSyncInterval)
+	}
+
+	// Exercise the pipeline before serving when a self-test is configured
+	if err := startupSelfTest(cfg, l1Client, repo); err != nil {
+		log.Fatalf("❌ %v", err)
 	}
 
 	// Initialize service registry
 	log.Println("\nSetting up service registry...")
 	serviceRegistry := srvreg.NewServiceRegistry(repo, l1Client, cfg.ShardID, cfg.ClientGroup)
+	logLevel, err := srvreg.ParseLogLevel(cfg.LogLevel)
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	serviceRegistry.SetLogLevel(logLevel)
+	if err := serviceRegistry.SetForwardTimeouts(srvreg.ForwardTimeouts{
+		Read:   cfg.ForwardReadTimeout,
+		Write:  cfg.ForwardWriteTimeout,
+		Commit: cfg.ForwardCommitTimeout,
+	}); err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	serviceRegistry.SetAdminKey(cfg.AdminAPIKey)
 	serviceRegistry.RegisterDefaultServices()
+	if cfg.AdminAPIKey != "" {
+		log.Println("✓ Session backup endpoints enabled")
+	}
+
+	// Deliver commit callbacks, including any left pending by a previous run
+	callbackCtx, stopCallbacks := context.WithCancel(context.Background())
+	defer stopCallbacks()
+	var dispatcher *webhook.Dispatcher
+	if cfg.CallbackSecret != "" {
+		dispatcher = webhook.NewDispatcher(repo, cfg.CallbackSecret, cfg.CallbackMaxAttempts)
+		serviceRegistry.SetCallbackDispatcher(dispatcher)
+		go dispatcher.Run(callbackCtx)
+		log.Printf("✓ Commit callbacks enabled (max %d attempts)", cfg.CallbackMaxAttempts)
+	}
+
+	// Report to L1 that this shard is alive
+	heartbeatCtx, stopHeartbeats := context.WithCancel(context.Background())
+	defer stopHeartbeats()
+	if cfg.HeartbeatInterval > 0 {
+		go sendHeartbeats(heartbeatCtx, l1Client, cfg.HeartbeatInterval)
+		log.Printf("✓ Heartbeats to L1 every %s", cfg.HeartbeatInterval)
+	}
 
 	// Initialize web server
 	log.Println("\nStarting web server...")
-	webServer := server.NewWebServer(cfg.HTTPPort, serviceRegistry, cfg.ShardID, cfg.ClientGroup)
+	webServer := server.NewWebServer(cfg.HTTPPort, serviceRegistry, cfg.ShardID, cfg.ClientGroup, &server.ServerConfig{
+		MaxBodyBytes:   cfg.MaxBodyBytes,
+		MaxImportBytes: cfg.MaxImportBytes,
+		BindAddress:    cfg.BindAddress,
+		MaxInflight:    cfg.MaxInflight,
+		Envelope:       cfg.ResponseEnvelope,
+	})
 	if err := webServer.Start(); err != nil {
 		log.Fatalf("❌ Failed to start web server: %v", err)
 	}
@@ -98,14 +201,160 @@ func main() {
 	log.Println("\n🛑 Shutdown signal received, gracefully shutting down...")
 
 	// Create shutdown context with timeout
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
 	defer cancel()
 
-	// Shutdown web server
+	// Shutdown web server; commits in progress finish and queue their callbacks
 	if err := webServer.Shutdown(ctx); err != nil {
 		log.Printf("❌ Error during server shutdown: %v", err)
 	}
 
+	// Flush queued commit callbacks within what is left of the deadline.
+	// Undelivered ones stay in the database and are sent after the restart.
+	stopCallbacks()
+	if dispatcher != nil {
+		delivered, deferred := dispatcher.Drain(ctx)
+		log.Printf("✓ Commit callbacks drained: %d delivered, %d deferred to next startup", delivered, deferred)
+	}
+	stopHeartbeats()
+	if err := shutdownTracing(ctx); err != nil {
+		log.Printf("❌ Error flushing traces: %v", err)
+	}
+
 	log.Println("✓ L2 Shard Node stopped")
 	log.Println("Goodbye! 👋")
 }
+
+// loadShardRegistry loads the shard registry from L1 and persists it. When L1
+// is unreachable the last persisted registry is used instead, with a warning
+// if it is older than ttl.
+func loadShardRegistry(l1Client *l1client.L1Client, repo *repository.Repository, ttl time.Duration) {
+	err := l1Client.LoadShards()
+	if err == nil {
+		log.Println("✓ Shard registry loaded")
+		saveShardRegistry(l1Client, repo)
+		return
+	}
+	log.Printf("⚠️  Warning: Failed to load shards: %v", err)
+
+	entries, savedAt, dbErr := repo.GetShardRegistry()
+	if dbErr != nil || len(entries) == 0 {
+		log.Println("   No persisted shard registry, redirect functionality will not be available")
+		return
+	}
+
+	shards := make([]l1client.ShardInfo, 0, len(entries))
+	for _, entry := range entries {
+		shards = append(shards, l1client.ShardInfo{
+			ShardID:     entry.ShardID,
+			ClientGroup: entry.ClientGroup,
+			L2NodeID:    entry.L2NodeID,
+			L2Endpoint:  entry.L2Endpoint,
+			Status:      entry.Status,
+		})
+	}
+	l1Client.SetShards(shards)
+
+	age := time.Since(savedAt).Round(time.Second)
+	if age > ttl {
+		log.Printf("⚠️  Warning: Using stale shard registry from database (saved %s ago)", age)
+	} else {
+		log.Printf("✓ Shard registry loaded from database (saved %s ago)", age)
+	}
+}
+
+// refreshShardRegistry reloads the shard registry from L1 every ttl
+func refreshShardRegistry(l1Client *l1client.L1Client, repo *repository.Repository, ttl time.Duration) {
+	ticker := time.NewTicker(ttl)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if err := l1Client.LoadShards(); err != nil {
+			log.Printf("⚠️  Warning: Failed to refresh shards: %v", err)
+			continue
+		}
+		saveShardRegistry(l1Client, repo)
+	}
+}
+
+// syncOperators replaces the operator mirror with the operators registered on
+// L1, returning how many there are. The mirror is left as it is when L1 can't
+// be reached.
+func syncOperators(l1Client *l1client.L1Client, repo *repository.Repository) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	operators, err := l1Client.GetOperators(ctx)
+	if err != nil {
+		return 0, err
+	}
+	mirrored := make([]models.Operator, 0, len(operators))
+	for _, operator := range operators {
+		mirrored = append(mirrored, models.Operator{
+			ID:          operator.ID,
+			Name:        operator.Name,
+			Role:        operator.Role,
+			AccessLevel: operator.AccessLevel,
+		})
+	}
+	if dbErr := repo.SyncOperators(mirrored); dbErr != nil {
+		return 0, dbErr
+	}
+	return len(mirrored), nil
+}
+
+// refreshOperators syncs the operator mirror from L1 every interval
+func refreshOperators(l1Client *l1client.L1Client, repo *repository.Repository, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if _, err := syncOperators(l1Client, repo); err != nil {
+			log.Printf("⚠️  Warning: Failed to refresh operators: %v", err)
+		}
+	}
+}
+
+// sendHeartbeats reports to L1 every interval until ctx is canceled. A failed
+// heartbeat is logged once until one succeeds again.
+func sendHeartbeats(ctx context.Context, l1Client *l1client.L1Client, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	failing := false
+	for {
+		if err := l1Client.SendHeartbeat(ctx); err != nil {
+			if !failing && ctx.Err() == nil {
+				log.Printf("⚠️  Warning: Failed to send heartbeat to L1: %v", err)
+			}
+			failing = true
+		} else if failing {
+			log.Println("✓ Heartbeats to L1 resumed")
+			failing = false
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// saveShardRegistry persists the L1 client's current shard cache
+func saveShardRegistry(l1Client *l1client.L1Client, repo *repository.Repository) {
+	shards := l1Client.Shards()
+	entries := make([]models.ShardRegistryEntry, 0, len(shards))
+	for _, shard := range shards {
+		entries = append(entries, models.ShardRegistryEntry{
+			ClientGroup: shard.ClientGroup,
+			ShardID:     shard.ShardID,
+			L2NodeID:    shard.L2NodeID,
+			L2Endpoint:  shard.L2Endpoint,
+			Status:      shard.Status,
+		})
+	}
+	if dbErr := repo.SaveShardRegistry(entries); dbErr != nil {
+		log.Printf("⚠️  Warning: Failed to persist shard registry: %v", dbErr)
+	}
+}
@@ -0,0 +1,185 @@
+package repository
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/ahmadzakiakmal/thesis-extension/layer-2/repository/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// exportBatchSize is how many sessions are loaded per query while exporting
+const exportBatchSize = 200
+
+// maxImportLineBytes bounds a single NDJSON line read during import
+const maxImportLineBytes = 1 << 20
+
+// ImportResult summarizes a session import
+type ImportResult struct {
+	Imported int
+	Skipped  int
+}
+
+// ExportSessions writes every session with its package, items, supplier, QC
+// record, label and courier to w as NDJSON, one session per line, oldest
+// first. It returns the number of sessions written.
+func (r *Repository) ExportSessions(w io.Writer) (int, *RepositoryError) {
+	encoder := json.NewEncoder(w)
+	written := 0
+
+	// Page on (created_at, session_id) by hand: FindInBatches pages on the
+	// primary key alone, which skips rows under any other order
+	var last *models.Session
+	for {
+		query := r.db.Preload("Package.Items").
+			Preload("Package.Supplier").
+			Preload("QCRecord").
+			Preload("Label.Courier").
+			Order("created_at, session_id").
+			Limit(exportBatchSize)
+		if last != nil {
+			query = query.Where("(created_at, session_id) > (?, ?)", last.CreatedAt, last.ID)
+		}
+
+		var sessions []models.Session
+		if err := query.Find(&sessions).Error; err != nil {
+			return written, &RepositoryError{
+				Code:    "DATABASE_ERROR",
+				Message: "Failed to export sessions",
+				Detail:  err.Error(),
+			}
+		}
+		for _, session := range sessions {
+			if err := encoder.Encode(session); err != nil {
+				return written, &RepositoryError{
+					Code:    "DATABASE_ERROR",
+					Message: "Failed to export sessions",
+					Detail:  err.Error(),
+				}
+			}
+			written++
+		}
+		if len(sessions) < exportBatchSize {
+			return written, nil
+		}
+		last = &sessions[len(sessions)-1]
+	}
+}
+
+// ImportSessions reads NDJSON produced by ExportSessions and recreates the
+// sessions that do not exist yet. Existing sessions are skipped, so an import
+// can be repeated safely. Packages, suppliers, items and couriers are only
+// created when missing, so an import never rewinds a package's live state. Each session
+// is imported in its own transaction; the first invalid line stops the import.
+func (r *Repository) ImportSessions(reader io.Reader) (*ImportResult, *RepositoryError) {
+	result := &ImportResult{}
+
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxImportLineBytes)
+
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var session models.Session
+		if err := json.Unmarshal(scanner.Bytes(), &session); err != nil {
+			return result, &RepositoryError{
+				Code:    "INVALID_REQUEST",
+				Message: fmt.Sprintf("Invalid session on line %d", line),
+				Detail:  err.Error(),
+			}
+		}
+		if session.ID == "" {
+			return result, &RepositoryError{
+				Code:    "INVALID_REQUEST",
+				Message: fmt.Sprintf("Invalid session on line %d", line),
+				Detail:  "session ID is required",
+			}
+		}
+
+		imported, err := r.importSession(&session)
+		if err != nil {
+			return result, &RepositoryError{
+				Code:    "DATABASE_ERROR",
+				Message: fmt.Sprintf("Failed to import session %s", session.ID),
+				Detail:  err.Error(),
+			}
+		}
+		if imported {
+			result.Imported++
+		} else {
+			result.Skipped++
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return result, &RepositoryError{
+			Code:    "INVALID_REQUEST",
+			Message: fmt.Sprintf("Failed to read import after line %d", line),
+			Detail:  err.Error(),
+		}
+	}
+
+	return result, nil
+}
+
+// importSession creates one exported session and its relations in foreign key
+// order. It reports false when the session already exists.
+func (r *Repository) importSession(session *models.Session) (bool, error) {
+	imported := false
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&models.Session{}).Where("session_id = ?", session.ID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return nil
+		}
+
+		if pkg := session.Package; pkg != nil {
+			if pkg.Supplier != nil {
+				if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(pkg.Supplier).Error; err != nil {
+					return fmt.Errorf("restoring supplier: %w", err)
+				}
+			}
+			err := tx.Clauses(clause.OnConflict{DoNothing: true}).Omit(clause.Associations).Create(pkg).Error
+			if err != nil {
+				return fmt.Errorf("restoring package: %w", err)
+			}
+			if len(pkg.Items) > 0 {
+				if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&pkg.Items).Error; err != nil {
+					return fmt.Errorf("restoring items: %w", err)
+				}
+			}
+		}
+
+		if err := tx.Omit(clause.Associations).Create(session).Error; err != nil {
+			return fmt.Errorf("restoring session: %w", err)
+		}
+
+		if session.QCRecord != nil {
+			if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(session.QCRecord).Error; err != nil {
+				return fmt.Errorf("restoring QC record: %w", err)
+			}
+		}
+		if label := session.Label; label != nil {
+			if label.Courier != nil {
+				if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(label.Courier).Error; err != nil {
+					return fmt.Errorf("restoring courier: %w", err)
+				}
+			}
+			if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Omit(clause.Associations).Create(label).Error; err != nil {
+				return fmt.Errorf("restoring label: %w", err)
+			}
+		}
+
+		imported = true
+		return nil
+	})
+	return imported, err
+}
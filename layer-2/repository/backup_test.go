@@ -0,0 +1,98 @@
+package repository
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ahmadzakiakmal/thesis-extension/layer-2/repository/models"
+	"github.com/google/uuid"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// testRepository connects to the PostgreSQL database named by L2_TEST_DSN and
+// migrates it without seeding, skipping the test when the variable is unset
+func testRepository(t *testing.T) *Repository {
+	t.Helper()
+	dsn := os.Getenv("L2_TEST_DSN")
+	if dsn == "" {
+		t.Skip("L2_TEST_DSN is not set")
+	}
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("connecting to %s: %v", dsn, err)
+	}
+	r := NewRepository()
+	r.db = db
+	if err := r.Migrate(); err != nil {
+		t.Fatalf("migrating: %v", err)
+	}
+	return r
+}
+
+// testPrefix returns a session ID prefix whose sessions are deleted when the
+// test ends
+func testPrefix(t *testing.T, r *Repository) string {
+	t.Helper()
+	prefix := fmt.Sprintf("T%d-", time.Now().UnixNano())
+	t.Cleanup(func() {
+		r.db.Where("session_id LIKE ?", prefix+"%").Delete(&models.Session{})
+	})
+	return prefix
+}
+
+func TestExportSessionsAcrossBatches(t *testing.T) {
+	r := testRepository(t)
+	prefix := testPrefix(t, r)
+
+	// Random IDs with shared creation times, so neither column alone orders
+	// the sessions
+	created := time.Now().Add(-time.Hour).Truncate(time.Second)
+	want := make(map[string]bool)
+	var sessions []models.Session
+	for i := 0; i < exportBatchSize*2+5; i++ {
+		session := models.Session{
+			ID:         prefix + uuid.New().String()[:8],
+			OperatorID: "OPR-001",
+			Status:     "active",
+			CreatedAt:  created.Add(time.Duration(i%7) * time.Second),
+		}
+		sessions = append(sessions, session)
+		want[session.ID] = true
+	}
+	if err := r.db.CreateInBatches(&sessions, 100).Error; err != nil {
+		t.Fatalf("creating sessions: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if _, repoErr := r.ExportSessions(&buf); repoErr != nil {
+		t.Fatalf("ExportSessions: %v", repoErr)
+	}
+
+	seen := make(map[string]bool)
+	scanner := bufio.NewScanner(&buf)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxImportLineBytes)
+	for scanner.Scan() {
+		var session models.Session
+		if err := json.Unmarshal(scanner.Bytes(), &session); err != nil {
+			t.Fatalf("decoding %s: %v", scanner.Bytes(), err)
+		}
+		if !strings.HasPrefix(session.ID, prefix) {
+			continue
+		}
+		if seen[session.ID] {
+			t.Errorf("session %s exported twice", session.ID)
+		}
+		seen[session.ID] = true
+	}
+	if len(seen) != len(want) {
+		t.Fatalf("exported %d of %d sessions", len(seen), len(want))
+	}
+}
@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/ahmadzakiakmal/thesis-extension/layer-2/repository/models"
+)
+
+// Commit callback statuses
+const (
+	CallbackPending   = "pending"
+	CallbackDelivered = "delivered"
+	CallbackFailed    = "failed"
+)
+
+// CreateCommitCallback stores a callback for delivery as soon as possible
+func (r *Repository) CreateCommitCallback(sessionID, url, payload string) (*models.CommitCallback, *RepositoryError) {
+	callback := models.CommitCallback{
+		SessionID:     sessionID,
+		URL:           url,
+		Payload:       payload,
+		Status:        CallbackPending,
+		NextAttemptAt: time.Now(),
+	}
+	if err := r.db.Create(&callback).Error; err != nil {
+		return nil, &RepositoryError{
+			Code:    "DATABASE_ERROR",
+			Message: "Failed to store commit callback",
+			Detail:  err.Error(),
+		}
+	}
+	return &callback, nil
+}
+
+// DueCommitCallbacks returns up to limit pending callbacks whose next attempt
+// is due, oldest first
+func (r *Repository) DueCommitCallbacks(now time.Time, limit int) ([]models.CommitCallback, *RepositoryError) {
+	var callbacks []models.CommitCallback
+	err := r.db.Where("status = ? AND next_attempt_at <= ?", CallbackPending, now).
+		Order("next_attempt_at, callback_id").
+		Limit(limit).
+		Find(&callbacks).Error
+	if err != nil {
+		return nil, &RepositoryError{
+			Code:    "DATABASE_ERROR",
+			Message: "Failed to load commit callbacks",
+			Detail:  err.Error(),
+		}
+	}
+	return callbacks, nil
+}
+
+// CountPendingCommitCallbacks counts the callbacks not yet delivered or given
+// up on
+func (r *Repository) CountPendingCommitCallbacks() (int64, *RepositoryError) {
+	var count int64
+	if err := r.db.Model(&models.CommitCallback{}).Where("status = ?", CallbackPending).Count(&count).Error; err != nil {
+		return 0, &RepositoryError{
+			Code:    "DATABASE_ERROR",
+			Message: "Failed to count commit callbacks",
+			Detail:  err.Error(),
+		}
+	}
+	return count, nil
+}
+
+// UpdateCommitCallback records the outcome of a delivery attempt
+func (r *Repository) UpdateCommitCallback(id uint, fields map[string]interface{}) *RepositoryError {
+	if err := r.db.Model(&models.CommitCallback{}).Where("callback_id = ?", id).Updates(fields).Error; err != nil {
+		return &RepositoryError{
+			Code:    "DATABASE_ERROR",
+			Message: "Failed to update commit callback",
+			Detail:  err.Error(),
+		}
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package repository
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Courier selection strategies used when a label request omits courier_id
+const (
+	// CourierStrategyRoundRobin cycles through the couriers in ID order
+	CourierStrategyRoundRobin = "round-robin"
+	// CourierStrategyLRU picks the courier that was assigned longest ago,
+	// counting couriers that were never assigned first
+	CourierStrategyLRU = "lru"
+)
+
+// ValidateCourierStrategy reports whether strategy is a known courier
+// selection strategy. Empty disables automatic selection.
+func ValidateCourierStrategy(strategy string) error {
+	switch strategy {
+	case "", CourierStrategyRoundRobin, CourierStrategyLRU:
+		return nil
+	}
+	return fmt.Errorf("unknown courier strategy %q (want %q or %q)", strategy, CourierStrategyRoundRobin, CourierStrategyLRU)
+}
+
+// courierAssignments tracks label assignments per courier since startup
+type courierAssignments struct {
+	mu       sync.Mutex
+	strategy string
+	next     int
+	counts   map[string]int64
+	lastUsed map[string]time.Time
+}
+
+func newCourierAssignments() *courierAssignments {
+	return &courierAssignments{
+		counts:   make(map[string]int64),
+		lastUsed: make(map[string]time.Time),
+	}
+}
+
+// SetCourierStrategy sets how a courier is chosen when a label request
+// omits courier_id. An empty strategy requires callers to name the courier.
+func (r *Repository) SetCourierStrategy(strategy string) error {
+	if err := ValidateCourierStrategy(strategy); err != nil {
+		return err
+	}
+	r.couriers.mu.Lock()
+	defer r.couriers.mu.Unlock()
+	r.couriers.strategy = strategy
+	return nil
+}
+
+// CourierStrategy returns the configured courier selection strategy
+func (r *Repository) CourierStrategy() string {
+	r.couriers.mu.Lock()
+	defer r.couriers.mu.Unlock()
+	return r.couriers.strategy
+}
+
+// SelectCourier picks a courier with the configured strategy. The pick
+// counts as a use for LRU right away, so concurrent labels spread out.
+func (r *Repository) SelectCourier() (string, *RepositoryError) {
+	couriers, repoErr := r.ListCouriers()
+	if repoErr != nil {
+		return "", repoErr
+	}
+	if len(couriers) == 0 {
+		return "", &RepositoryError{
+			Code:    "NO_COURIER_AVAILABLE",
+			Message: "No courier available",
+			Detail:  "No couriers are registered on this shard",
+		}
+	}
+
+	r.couriers.mu.Lock()
+	defer r.couriers.mu.Unlock()
+
+	var courierID string
+	switch r.couriers.strategy {
+	case CourierStrategyRoundRobin:
+		courierID = couriers[r.couriers.next%len(couriers)].ID
+		r.couriers.next++
+	case CourierStrategyLRU:
+		courierID = couriers[0].ID
+		for _, courier := range couriers[1:] {
+			if r.couriers.lastUsed[courier.ID].Before(r.couriers.lastUsed[courierID]) {
+				courierID = courier.ID
+			}
+		}
+		r.couriers.lastUsed[courierID] = time.Now()
+	default:
+		return "", &RepositoryError{
+			Code:    "INVALID_REQUEST",
+			Message: "courier_id is required",
+			Detail:  "Automatic courier selection is disabled",
+		}
+	}
+	return courierID, nil
+}
+
+// recordCourierAssignment counts a label assigned to courierID
+func (r *Repository) recordCourierAssignment(courierID string) {
+	r.couriers.mu.Lock()
+	defer r.couriers.mu.Unlock()
+	r.couriers.counts[courierID]++
+	r.couriers.lastUsed[courierID] = time.Now()
+}
+
+// CourierAssignments returns the labels assigned to each courier since
+// startup
+func (r *Repository) CourierAssignments() map[string]int64 {
+	r.couriers.mu.Lock()
+	defer r.couriers.mu.Unlock()
+	counts := make(map[string]int64, len(r.couriers.counts))
+	for courierID, count := range r.couriers.counts {
+		counts[courierID] = count
+	}
+	return counts
+}
@@ -6,7 +6,7 @@ import "time"
 type Session struct {
 	ID          string    `gorm:"column:session_id;primaryKey;type:varchar(50)"`
 	OperatorID  string    `gorm:"column:operator_id;type:varchar(50);not null"`
-	Status      string    `gorm:"column:status;type:varchar(20);not null"` // active, completed, committed
+	Status      string    `gorm:"column:status;type:varchar(20);not null"` // active, completed, committing, committed, cancelled
 	IsCommitted bool      `gorm:"column:is_committed;default:false"`
 	PackageID   *string   `gorm:"column:package_id;type:varchar(50)"`
 	CreatedAt   time.Time `gorm:"column:created_at;autoCreateTime"`
@@ -17,12 +17,24 @@ type Session struct {
 	L1BlockHeight *int64     `gorm:"column:l1_block_height"`
 	L1CommitTime  *time.Time `gorm:"column:l1_commit_time"`
 
+	// Version is bumped on every update; updates carrying a stale version fail
+	Version int `gorm:"column:version;not null;default:1"`
+
 	// Relationships
 	Package  *Package  `gorm:"foreignKey:PackageID;references:ID"`
 	QCRecord *QCRecord `gorm:"foreignKey:SessionID"`
 	Label    *Label    `gorm:"foreignKey:SessionID"`
 }
 
+// Operator mirrors an L1 operator and the access level used to authorize
+// session actions on this shard
+type Operator struct {
+	ID          string `gorm:"column:operator_id;primaryKey;type:varchar(50)"`
+	Name        string `gorm:"column:name;type:varchar(100);not null"`
+	Role        string `gorm:"column:role;type:varchar(50)"`
+	AccessLevel string `gorm:"column:access_level;type:varchar(20);default:'Basic'"` // Basic, Standard, Admin
+}
+
 // Package represents a package being processed
 type Package struct {
 	ID         string  `gorm:"column:package_id;primaryKey;type:varchar(50)"`
@@ -66,15 +78,47 @@ type Label struct {
 	ID         string    `gorm:"column:label_id;primaryKey;type:varchar(50)"`
 	SessionID  string    `gorm:"column:session_id;type:varchar(50);uniqueIndex;not null"`
 	CourierID  string    `gorm:"column:courier_id;type:varchar(50);not null"`
-	TrackingNo string    `gorm:"column:tracking_no;type:varchar(100);not null"`
+	TrackingNo string    `gorm:"column:tracking_no;type:varchar(100);not null;uniqueIndex"`
 	CreatedAt  time.Time `gorm:"column:created_at;autoCreateTime"`
 
 	// Relationships
 	Courier *Courier `gorm:"foreignKey:CourierID"`
+	Session *Session `gorm:"foreignKey:SessionID;references:ID"`
 }
 
 // Courier represents a shipping courier
 type Courier struct {
 	ID   string `gorm:"column:courier_id;primaryKey;type:varchar(50)"`
 	Name string `gorm:"column:name;type:varchar(100);not null"`
+
+	// TrackingFormat renders tracking numbers, e.g. "FS-{seq:8}"; empty
+	// uses TRK-<uuid>. TrackingSeq is the last sequence number issued.
+	TrackingFormat string `gorm:"column:tracking_format;type:varchar(80)"`
+	TrackingSeq    int64  `gorm:"column:tracking_seq;not null;default:0"`
+}
+
+// ShardRegistryEntry is the last-known L1 shard registry, persisted so the
+// node can keep forwarding when L1 is unreachable at startup
+type ShardRegistryEntry struct {
+	ClientGroup string    `gorm:"column:client_group;primaryKey;type:varchar(100)"`
+	ShardID     string    `gorm:"column:shard_id;type:varchar(50);not null"`
+	L2NodeID    string    `gorm:"column:l2_node_id;type:varchar(50)"`
+	L2Endpoint  string    `gorm:"column:l2_endpoint;type:varchar(255)"`
+	Status      string    `gorm:"column:status;type:varchar(20)"`
+	UpdatedAt   time.Time `gorm:"column:updated_at;autoUpdateTime"`
+}
+
+// CommitCallback is a pending or finished webhook notifying an integrator of
+// a commit outcome. Rows are kept so deliveries survive restarts.
+type CommitCallback struct {
+	ID            uint      `gorm:"column:callback_id;primaryKey;autoIncrement"`
+	SessionID     string    `gorm:"column:session_id;type:varchar(50);not null;index"`
+	URL           string    `gorm:"column:url;type:varchar(2048);not null"`
+	Payload       string    `gorm:"column:payload;type:text;not null"`
+	Status        string    `gorm:"column:status;type:varchar(20);not null;index"` // pending, delivered, failed
+	Attempts      int       `gorm:"column:attempts;not null;default:0"`
+	NextAttemptAt time.Time `gorm:"column:next_attempt_at;index"`
+	LastError     string    `gorm:"column:last_error;type:text"`
+	CreatedAt     time.Time `gorm:"column:created_at;autoCreateTime"`
+	UpdatedAt     time.Time `gorm:"column:updated_at;autoUpdateTime"`
 }
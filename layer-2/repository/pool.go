@@ -0,0 +1,40 @@
+package repository
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// PoolConfig sizes the PostgreSQL connection pool. Zero values keep the
+// database/sql defaults: unlimited open connections, 2 idle connections and
+// no lifetime limit.
+type PoolConfig struct {
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+}
+
+// SetPoolConfig sets the connection pool applied by ConnectDB
+func (r *Repository) SetPoolConfig(config PoolConfig) {
+	r.pool = config
+}
+
+// applyPool configures the connection pool underlying db
+func (r *Repository) applyPool(db *gorm.DB) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return fmt.Errorf("getting connection pool: %w", err)
+	}
+	if r.pool.MaxOpenConns > 0 {
+		sqlDB.SetMaxOpenConns(r.pool.MaxOpenConns)
+	}
+	if r.pool.MaxIdleConns > 0 {
+		sqlDB.SetMaxIdleConns(r.pool.MaxIdleConns)
+	}
+	if r.pool.ConnMaxLifetime > 0 {
+		sqlDB.SetConnMaxLifetime(r.pool.ConnMaxLifetime)
+	}
+	return nil
+}
@@ -11,6 +11,7 @@ import (
 	"github.com/google/uuid"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // RepositoryError represents repository layer errors
@@ -26,12 +27,15 @@ func (e *RepositoryError) Error() string {
 
 // Repository handles all database operations for L2 shard
 type Repository struct {
-	db *gorm.DB
+	db       *gorm.DB
+	seed     SeedConfig
+	pool     PoolConfig
+	couriers *courierAssignments
 }
 
 // NewRepository creates a new repository instance
 func NewRepository() *Repository {
-	return &Repository{}
+	return &Repository{seed: SeedConfig{Enabled: true}, couriers: newCourierAssignments()}
 }
 
 // ConnectDB establishes database connection and performs migrations
@@ -44,6 +48,9 @@ func (r *Repository) ConnectDB(dsn string) error {
 			time.Sleep(2 * time.Second)
 			continue
 		}
+		if err := r.applyPool(db); err != nil {
+			return err
+		}
 		r.db = db
 		log.Println("✓ Connected to database")
 
@@ -54,12 +61,21 @@ func (r *Repository) ConnectDB(dsn string) error {
 
 		// Seed data
 		r.Seed()
+		r.SeedOperators()
 
 		return nil
 	}
 	return fmt.Errorf("failed to connect to database after 10 attempts")
 }
 
+// Ping checks that the database is reachable
+func (r *Repository) Ping() error {
+	if r.db == nil {
+		return fmt.Errorf("database not connected")
+	}
+	return r.db.Exec("SELECT 1").Error
+}
+
 // Migrate performs database schema migrations
 func (r *Repository) Migrate() error {
 	log.Println("Running database migrations...")
@@ -75,6 +91,9 @@ func (r *Repository) Migrate() error {
 		&models.QCRecord{},
 		&models.Courier{},
 		&models.Label{},
+		&models.Operator{},
+		&models.ShardRegistryEntry{},
+		&models.CommitCallback{},
 	}
 
 	for _, table := range tables {
@@ -85,73 +104,227 @@ func (r *Repository) Migrate() error {
 		}
 	}
 
+	// Columns added after the initial schema
+	if !migrator.HasColumn(&models.Session{}, "Version") {
+		if err := migrator.AddColumn(&models.Session{}, "Version"); err != nil {
+			return fmt.Errorf("failed to add session version column: %w", err)
+		}
+	}
+
+	// Indexes added after the initial schema
+	if !migrator.HasIndex(&models.Label{}, "TrackingNo") {
+		if err := migrator.CreateIndex(&models.Label{}, "TrackingNo"); err != nil {
+			return fmt.Errorf("failed to create label tracking number index: %w", err)
+		}
+	}
+
 	log.Println("✓ Database migrations completed")
 	return nil
 }
 
-// Seed initializes database with test data
+// SetSeedConfig configures seeding. Call it before ConnectDB.
+func (r *Repository) SetSeedConfig(config SeedConfig) {
+	r.seed = config
+}
+
+// seedData returns the configured seed data, or nil if it can't be loaded
+func (r *Repository) seedData() *SeedData {
+	if r.seed.File == "" {
+		return DefaultSeedData()
+	}
+	data, err := LoadSeedFile(r.seed.File)
+	if err != nil {
+		log.Printf("⚠️  Error loading seed data, skipping seeding: %v", err)
+		return nil
+	}
+	return data
+}
+
+// Seed upserts the configured seed data on every startup, so the seed list
+// stays authoritative: missing rows are inserted and existing rows get their
+// seeded fields updated. A package's status and session are runtime state and
+// are never reset. Rows not in the seed list are left untouched.
 func (r *Repository) Seed() {
-	// Check if data already exists
-	var supplierCount int64
-	r.db.Model(&models.Supplier{}).Count(&supplierCount)
-	if supplierCount > 0 {
-		log.Println("Seed data already exists, skipping...")
+	if !r.seed.Enabled {
+		log.Println("Seeding disabled, skipping...")
+		return
+	}
+
+	data := r.seedData()
+	if data == nil {
 		return
 	}
 
 	log.Println("Seeding database with test data...")
 
-	// Create suppliers
-	suppliers := []models.Supplier{
-		{ID: "SUP-001", Name: "Acme Electronics", Country: "Japan"},
-		{ID: "SUP-002", Name: "Global Tech Supply", Country: "Taiwan"},
-		{ID: "SUP-003", Name: "Premium Parts Co", Country: "Germany"},
+	for _, supplier := range data.Suppliers {
+		if err := r.upsertSeed(&supplier, "supplier_id", "name", "country"); err != nil {
+			log.Printf("⚠️  Error seeding supplier %s: %v", supplier.ID, err)
+		}
 	}
-	for _, supplier := range suppliers {
-		r.db.Create(&supplier)
+	for _, courier := range data.Couriers {
+		if err := ValidateTrackingFormat(courier.TrackingFormat); err != nil {
+			log.Printf("⚠️  Error seeding courier %s: %v", courier.ID, err)
+			continue
+		}
+		if err := r.upsertSeed(&courier, "courier_id", "name", "tracking_format"); err != nil {
+			log.Printf("⚠️  Error seeding courier %s: %v", courier.ID, err)
+		}
+	}
+	for _, pkg := range data.Packages {
+		if err := r.upsertSeed(&pkg, "package_id", "signature", "supplier_id", "is_trusted"); err != nil {
+			log.Printf("⚠️  Error seeding package %s: %v", pkg.ID, err)
+		}
+	}
+	for _, item := range data.Items {
+		if err := r.upsertSeed(&item, "item_id", "package_id", "description", "quantity"); err != nil {
+			log.Printf("⚠️  Error seeding item %s: %v", item.ID, err)
+		}
 	}
 
-	// Create couriers
-	couriers := []models.Courier{
-		{ID: "CUR-001", Name: "FastShip Express"},
-		{ID: "CUR-002", Name: "Global Logistics"},
-		{ID: "CUR-003", Name: "Quick Delivery Co"},
+	log.Println("✓ Database seeding completed")
+}
+
+// SeedOperators mirrors the operators seeded on L1 so access levels can be
+// checked locally, upserting them like Seed. Every session step is
+// authorized against this mirror, so it runs even when demo seeding is
+// disabled.
+func (r *Repository) SeedOperators() {
+	data := r.seedData()
+	if data == nil {
+		return
 	}
-	for _, courier := range couriers {
-		r.db.Create(&courier)
+	for _, operator := range data.Operators {
+		if err := r.upsertSeed(&operator, "operator_id", "name", "role", "access_level"); err != nil {
+			log.Printf("⚠️  Error seeding operator %s: %v", operator.ID, err)
+		}
 	}
 
-	// Create sample packages
-	packages := []models.Package{
-		{
-			ID:         "PKG-001",
-			Signature:  "sig_acme_electronics_001",
-			SupplierID: "SUP-001",
-			Status:     "pending",
-		},
-		{
-			ID:         "PKG-002",
-			Signature:  "sig_global_tech_002",
-			SupplierID: "SUP-002",
-			Status:     "pending",
-		},
+	log.Println("✓ Operators seeded")
+}
+
+// SyncOperators replaces the operator mirror with operators, the full list
+// registered on L1, so operators removed there lose their access here too
+func (r *Repository) SyncOperators(operators []models.Operator) *RepositoryError {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("1 = 1").Delete(&models.Operator{}).Error; err != nil {
+			return err
+		}
+		if len(operators) == 0 {
+			return nil
+		}
+		return tx.Create(&operators).Error
+	})
+	if err != nil {
+		return &RepositoryError{
+			Code:    "DATABASE_ERROR",
+			Message: "Failed to sync operators",
+			Detail:  err.Error(),
+		}
 	}
-	for _, pkg := range packages {
-		r.db.Create(&pkg)
+	return nil
+}
+
+// upsertSeed inserts a seed row, or updates columns when a row with the same
+// key already exists
+func (r *Repository) upsertSeed(value interface{}, key string, columns ...string) error {
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: key}},
+		DoUpdates: clause.AssignmentColumns(columns),
+	}).Omit(clause.Associations).Create(value).Error
+}
+
+// GetOperator retrieves an operator by ID
+func (r *Repository) GetOperator(operatorID string) (*models.Operator, *RepositoryError) {
+	var operator models.Operator
+	err := r.db.Where("operator_id = ?", operatorID).First(&operator).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, &RepositoryError{
+				Code:    "NOT_FOUND",
+				Message: "Operator not found",
+				Detail:  fmt.Sprintf("Operator %s does not exist", operatorID),
+			}
+		}
+		return nil, &RepositoryError{
+			Code:    "DATABASE_ERROR",
+			Message: "Database error",
+			Detail:  err.Error(),
+		}
 	}
 
-	// Create items for packages
-	items := []models.Item{
-		{ID: "ITEM-001", PackageID: "PKG-001", Description: "Microcontroller Unit", Quantity: 100},
-		{ID: "ITEM-002", PackageID: "PKG-001", Description: "LED Display Module", Quantity: 50},
-		{ID: "ITEM-003", PackageID: "PKG-002", Description: "Power Supply Unit", Quantity: 25},
-		{ID: "ITEM-004", PackageID: "PKG-002", Description: "Circuit Board", Quantity: 75},
+	return &operator, nil
+}
+
+// SessionCancelled is the status of a committed session retired by an
+// Admin. Its L1 commit stays on chain; only the shard's record changes.
+const SessionCancelled = "cancelled"
+
+// CancelSession marks a committed session cancelled
+func (r *Repository) CancelSession(sessionID string) *RepositoryError {
+	result := r.db.Model(&models.Session{}).
+		Where("session_id = ? AND is_committed = ? AND status <> ?", sessionID, true, SessionCancelled).
+		Updates(map[string]interface{}{
+			"status":  SessionCancelled,
+			"version": gorm.Expr("version + 1"),
+		})
+	if result.Error != nil {
+		return &RepositoryError{
+			Code:    "UPDATE_FAILED",
+			Message: "Failed to cancel session",
+			Detail:  result.Error.Error(),
+		}
 	}
-	for _, item := range items {
-		r.db.Create(&item)
+	if result.RowsAffected == 0 {
+		return &RepositoryError{
+			Code:    "CONFLICT",
+			Message: "Only committed sessions that are not cancelled yet can be cancelled",
+			Detail:  fmt.Sprintf("Session %s is not committed or already cancelled", sessionID),
+		}
 	}
+	return nil
+}
 
-	log.Println("✓ Database seeding completed")
+// SaveShardRegistry replaces the persisted shard registry with entries
+func (r *Repository) SaveShardRegistry(entries []models.ShardRegistryEntry) *RepositoryError {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("1 = 1").Delete(&models.ShardRegistryEntry{}).Error; err != nil {
+			return err
+		}
+		if len(entries) == 0 {
+			return nil
+		}
+		return tx.Create(&entries).Error
+	})
+	if err != nil {
+		return &RepositoryError{
+			Code:    "DATABASE_ERROR",
+			Message: "Failed to save shard registry",
+			Detail:  err.Error(),
+		}
+	}
+	return nil
+}
+
+// GetShardRegistry returns the persisted shard registry and when it was last
+// saved. The time is zero when nothing has been saved yet.
+func (r *Repository) GetShardRegistry() ([]models.ShardRegistryEntry, time.Time, *RepositoryError) {
+	var entries []models.ShardRegistryEntry
+	if err := r.db.Find(&entries).Error; err != nil {
+		return nil, time.Time{}, &RepositoryError{
+			Code:    "DATABASE_ERROR",
+			Message: "Failed to load shard registry",
+			Detail:  err.Error(),
+		}
+	}
+
+	var savedAt time.Time
+	for _, entry := range entries {
+		if savedAt.IsZero() || entry.UpdatedAt.Before(savedAt) {
+			savedAt = entry.UpdatedAt
+		}
+	}
+	return entries, savedAt, nil
 }
 
 // CreateSession creates a new session
@@ -176,6 +349,72 @@ func (r *Repository) CreateSession(operatorID string) (*models.Session, *Reposit
 	return &session, nil
 }
 
+// CreateSessions creates one session per operator ID in a single transaction,
+// so either all sessions are created or none are
+func (r *Repository) CreateSessions(operatorIDs []string) ([]models.Session, *RepositoryError) {
+	sessions := make([]models.Session, 0, len(operatorIDs))
+	for _, operatorID := range operatorIDs {
+		sessions = append(sessions, models.Session{
+			ID:          fmt.Sprintf("SES-%s", uuid.New().String()[:8]),
+			OperatorID:  operatorID,
+			Status:      "active",
+			IsCommitted: false,
+		})
+	}
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		return tx.Create(&sessions).Error
+	})
+	if err != nil {
+		return nil, &RepositoryError{
+			Code:    "CREATE_FAILED",
+			Message: "Failed to create sessions",
+			Detail:  err.Error(),
+		}
+	}
+
+	return sessions, nil
+}
+
+// ListPackages returns every package with its items and supplier
+func (r *Repository) ListPackages() ([]models.Package, *RepositoryError) {
+	var packages []models.Package
+	if err := r.db.Preload("Items").Preload("Supplier").Order("package_id").Find(&packages).Error; err != nil {
+		return nil, &RepositoryError{
+			Code:    "DATABASE_ERROR",
+			Message: "Failed to list packages",
+			Detail:  err.Error(),
+		}
+	}
+	return packages, nil
+}
+
+// ListCouriers returns every courier
+func (r *Repository) ListCouriers() ([]models.Courier, *RepositoryError) {
+	var couriers []models.Courier
+	if err := r.db.Order("courier_id").Find(&couriers).Error; err != nil {
+		return nil, &RepositoryError{
+			Code:    "DATABASE_ERROR",
+			Message: "Failed to list couriers",
+			Detail:  err.Error(),
+		}
+	}
+	return couriers, nil
+}
+
+// ListSuppliers returns every supplier
+func (r *Repository) ListSuppliers() ([]models.Supplier, *RepositoryError) {
+	var suppliers []models.Supplier
+	if err := r.db.Order("supplier_id").Find(&suppliers).Error; err != nil {
+		return nil, &RepositoryError{
+			Code:    "DATABASE_ERROR",
+			Message: "Failed to list suppliers",
+			Detail:  err.Error(),
+		}
+	}
+	return suppliers, nil
+}
+
 // GetSession retrieves a session by ID
 func (r *Repository) GetSession(sessionID string) (*models.Session, *RepositoryError) {
 	var session models.Session
@@ -204,10 +443,65 @@ func (r *Repository) GetSession(sessionID string) (*models.Session, *RepositoryE
 	return &session, nil
 }
 
+// sessionVersion returns the current version of a session
+func sessionVersion(tx *gorm.DB, sessionID string) (int, *RepositoryError) {
+	var session models.Session
+	err := tx.Select("version").Where("session_id = ?", sessionID).First(&session).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return 0, &RepositoryError{
+				Code:    "NOT_FOUND",
+				Message: "Session not found",
+				Detail:  fmt.Sprintf("Session %s does not exist", sessionID),
+			}
+		}
+		return 0, &RepositoryError{
+			Code:    "DATABASE_ERROR",
+			Message: "Database error",
+			Detail:  err.Error(),
+		}
+	}
+	return session.Version, nil
+}
+
+// updateSession applies fields to a session only if it is still at version,
+// bumping the version. A concurrent update in between yields a CONFLICT error.
+func updateSession(tx *gorm.DB, sessionID string, version int, fields map[string]interface{}) *RepositoryError {
+	updates := map[string]interface{}{"version": gorm.Expr("version + 1")}
+	for column, value := range fields {
+		updates[column] = value
+	}
+
+	result := tx.Model(&models.Session{}).
+		Where("session_id = ? AND version = ?", sessionID, version).
+		Updates(updates)
+	if result.Error != nil {
+		return &RepositoryError{
+			Code:    "UPDATE_FAILED",
+			Message: "Failed to update session",
+			Detail:  result.Error.Error(),
+		}
+	}
+	if result.RowsAffected == 0 {
+		return &RepositoryError{
+			Code:    "CONFLICT",
+			Message: "Session was modified concurrently, retry the request",
+			Detail:  fmt.Sprintf("Session %s is no longer at version %d", sessionID, version),
+		}
+	}
+	return nil
+}
+
 // ScanPackage scans a package and links it to session
 func (r *Repository) ScanPackage(sessionID, packageID string) (*models.Package, *RepositoryError) {
 	dbTx := r.db.Begin()
 
+	version, repoErr := sessionVersion(dbTx, sessionID)
+	if repoErr != nil {
+		dbTx.Rollback()
+		return nil, repoErr
+	}
+
 	// Find the package
 	var pkg models.Package
 	err := dbTx.Preload("Items").Preload("Supplier").Where("package_id = ?", packageID).First(&pkg).Error
@@ -241,13 +535,9 @@ func (r *Repository) ScanPackage(sessionID, packageID string) (*models.Package,
 	}
 
 	// Update session with package ID
-	if err := dbTx.Model(&models.Session{}).Where("session_id = ?", sessionID).Update("package_id", packageID).Error; err != nil {
+	if repoErr := updateSession(dbTx, sessionID, version, map[string]interface{}{"package_id": packageID}); repoErr != nil {
 		dbTx.Rollback()
-		return nil, &RepositoryError{
-			Code:    "UPDATE_FAILED",
-			Message: "Failed to update session",
-			Detail:  err.Error(),
-		}
+		return nil, repoErr
 	}
 
 	if err := dbTx.Commit().Error; err != nil {
@@ -265,6 +555,12 @@ func (r *Repository) ScanPackage(sessionID, packageID string) (*models.Package,
 func (r *Repository) ValidatePackage(signature, packageID, sessionID string) (*models.Package, *RepositoryError) {
 	dbTx := r.db.Begin()
 
+	version, repoErr := sessionVersion(dbTx, sessionID)
+	if repoErr != nil {
+		dbTx.Rollback()
+		return nil, repoErr
+	}
+
 	var pkg models.Package
 	err := dbTx.Preload("Items").Preload("Supplier").Where("package_id = ?", packageID).First(&pkg).Error
 	if err != nil {
@@ -297,6 +593,11 @@ func (r *Repository) ValidatePackage(signature, packageID, sessionID string) (*m
 		}
 	}
 
+	if repoErr := updateSession(dbTx, sessionID, version, nil); repoErr != nil {
+		dbTx.Rollback()
+		return nil, repoErr
+	}
+
 	if err := dbTx.Commit().Error; err != nil {
 		return nil, &RepositoryError{
 			Code:    "COMMIT_FAILED",
@@ -384,6 +685,11 @@ func (r *Repository) QualityCheck(sessionID string, passed bool, issues []string
 		}
 	}
 
+	if repoErr := updateSession(dbTx, sessionID, session.Version, nil); repoErr != nil {
+		dbTx.Rollback()
+		return nil, nil, repoErr
+	}
+
 	if err := dbTx.Commit().Error; err != nil {
 		return nil, nil, &RepositoryError{
 			Code:    "COMMIT_FAILED",
@@ -399,6 +705,12 @@ func (r *Repository) QualityCheck(sessionID string, passed bool, issues []string
 func (r *Repository) LabelPackage(sessionID, courierID string) (*models.Label, *RepositoryError) {
 	dbTx := r.db.Begin()
 
+	version, repoErr := sessionVersion(dbTx, sessionID)
+	if repoErr != nil {
+		dbTx.Rollback()
+		return nil, repoErr
+	}
+
 	// Verify courier exists
 	var courier models.Courier
 	if err := dbTx.Where("courier_id = ?", courierID).First(&courier).Error; err != nil {
@@ -417,12 +729,18 @@ func (r *Repository) LabelPackage(sessionID, courierID string) (*models.Label, *
 		}
 	}
 
+	trackingNo, repoErr := nextTrackingNo(dbTx, &courier)
+	if repoErr != nil {
+		dbTx.Rollback()
+		return nil, repoErr
+	}
+
 	// Create label
 	label := models.Label{
 		ID:         fmt.Sprintf("LBL-%s", uuid.New().String()[:8]),
 		SessionID:  sessionID,
 		CourierID:  courierID,
-		TrackingNo: fmt.Sprintf("TRK-%s", uuid.New().String()[:12]),
+		TrackingNo: trackingNo,
 	}
 
 	if err := dbTx.Create(&label).Error; err != nil {
@@ -445,13 +763,9 @@ func (r *Repository) LabelPackage(sessionID, courierID string) (*models.Label, *
 	}
 
 	// Update session status to completed
-	if err := dbTx.Model(&models.Session{}).Where("session_id = ?", sessionID).Update("status", "completed").Error; err != nil {
+	if repoErr := updateSession(dbTx, sessionID, version, map[string]interface{}{"status": "completed"}); repoErr != nil {
 		dbTx.Rollback()
-		return nil, &RepositoryError{
-			Code:    "UPDATE_FAILED",
-			Message: "Failed to update session",
-			Detail:  err.Error(),
-		}
+		return nil, repoErr
 	}
 
 	if err := dbTx.Commit().Error; err != nil {
@@ -462,6 +776,8 @@ func (r *Repository) LabelPackage(sessionID, courierID string) (*models.Label, *
 		}
 	}
 
+	r.recordCourierAssignment(courierID)
+
 	// Reload with courier info
 	dbTx = r.db.Begin()
 	dbTx.Preload("Courier").Where("label_id = ?", label.ID).First(&label)
@@ -470,24 +786,272 @@ func (r *Repository) LabelPackage(sessionID, courierID string) (*models.Label, *
 	return &label, nil
 }
 
-// MarkSessionCommitted updates session with L1 commitment info
-func (r *Repository) MarkSessionCommitted(sessionID, txHash string, blockHeight int64) *RepositoryError {
-	commitTime := time.Now()
+// RelabelPackage moves a session's existing label to another courier with a
+// fresh tracking number. Only uncommitted sessions can be relabeled.
+func (r *Repository) RelabelPackage(sessionID, courierID string) (*models.Label, *RepositoryError) {
+	dbTx := r.db.Begin()
 
-	err := r.db.Model(&models.Session{}).
-		Where("session_id = ?", sessionID).
-		Updates(map[string]interface{}{
-			"is_committed":    true,
-			"status":          "committed",
-			"l1_tx_hash":      txHash,
-			"l1_block_height": blockHeight,
-			"l1_commit_time":  commitTime,
-		}).Error
+	var session models.Session
+	if err := dbTx.Select("session_id", "status", "is_committed", "version").
+		Where("session_id = ?", sessionID).First(&session).Error; err != nil {
+		dbTx.Rollback()
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, &RepositoryError{
+				Code:    "NOT_FOUND",
+				Message: "Session not found",
+				Detail:  fmt.Sprintf("Session %s does not exist", sessionID),
+			}
+		}
+		return nil, &RepositoryError{
+			Code:    "DATABASE_ERROR",
+			Message: "Database error",
+			Detail:  err.Error(),
+		}
+	}
+
+	if session.IsCommitted {
+		dbTx.Rollback()
+		return nil, &RepositoryError{
+			Code:    "CONFLICT",
+			Message: "Committed sessions cannot be relabeled",
+			Detail:  fmt.Sprintf("Session %s is already committed to L1", sessionID),
+		}
+	}
+	if session.Status == SessionCommitting {
+		dbTx.Rollback()
+		return nil, &RepositoryError{
+			Code:    "CONFLICT",
+			Message: "Sessions being committed cannot be relabeled",
+			Detail:  fmt.Sprintf("Session %s is being committed to L1", sessionID),
+		}
+	}
+
+	var label models.Label
+	if err := dbTx.Where("session_id = ?", sessionID).First(&label).Error; err != nil {
+		dbTx.Rollback()
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, &RepositoryError{
+				Code:    "NOT_FOUND",
+				Message: "Label not found",
+				Detail:  fmt.Sprintf("Session %s has no label yet", sessionID),
+			}
+		}
+		return nil, &RepositoryError{
+			Code:    "DATABASE_ERROR",
+			Message: "Database error",
+			Detail:  err.Error(),
+		}
+	}
+
+	// Verify courier exists
+	var courier models.Courier
+	if err := dbTx.Where("courier_id = ?", courierID).First(&courier).Error; err != nil {
+		dbTx.Rollback()
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, &RepositoryError{
+				Code:    "NOT_FOUND",
+				Message: "Courier not found",
+				Detail:  fmt.Sprintf("Courier %s does not exist", courierID),
+			}
+		}
+		return nil, &RepositoryError{
+			Code:    "DATABASE_ERROR",
+			Message: "Database error",
+			Detail:  err.Error(),
+		}
+	}
+
+	trackingNo, repoErr := nextTrackingNo(dbTx, &courier)
+	if repoErr != nil {
+		dbTx.Rollback()
+		return nil, repoErr
+	}
+
+	label.CourierID = courierID
+	label.TrackingNo = trackingNo
+	if err := dbTx.Model(&label).Updates(map[string]interface{}{
+		"courier_id":  label.CourierID,
+		"tracking_no": label.TrackingNo,
+	}).Error; err != nil {
+		dbTx.Rollback()
+		return nil, &RepositoryError{
+			Code:    "UPDATE_FAILED",
+			Message: "Failed to update label",
+			Detail:  err.Error(),
+		}
+	}
+
+	// Bump the version so a commit that read the old label fails
+	if repoErr := updateSession(dbTx, sessionID, session.Version, nil); repoErr != nil {
+		dbTx.Rollback()
+		return nil, repoErr
+	}
+
+	if err := dbTx.Commit().Error; err != nil {
+		return nil, &RepositoryError{
+			Code:    "COMMIT_FAILED",
+			Message: "Failed to commit transaction",
+			Detail:  err.Error(),
+		}
+	}
+
+	r.recordCourierAssignment(courierID)
+
+	label.Courier = &courier
+	return &label, nil
+}
+
+// GetLabelByTrackingNo retrieves a label with its session and courier by
+// tracking number
+func (r *Repository) GetLabelByTrackingNo(trackingNo string) (*models.Label, *RepositoryError) {
+	var label models.Label
+	err := r.db.Preload("Courier").Preload("Session").
+		Where("tracking_no = ?", trackingNo).
+		First(&label).Error
 
 	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, &RepositoryError{
+				Code:    "NOT_FOUND",
+				Message: "Label not found",
+				Detail:  fmt.Sprintf("No label with tracking number %s", trackingNo),
+			}
+		}
+		return nil, &RepositoryError{
+			Code:    "DATABASE_ERROR",
+			Message: "Database error",
+			Detail:  err.Error(),
+		}
+	}
+
+	return &label, nil
+}
+
+// SessionCommitting is the status of a session whose L1 commit is in flight
+const SessionCommitting = "committing"
+
+// BeginCommit marks a completed session committing before it is sent to L1,
+// so it can't be deleted or relabeled while L1 holds it up. It returns the
+// session's new version.
+func (r *Repository) BeginCommit(sessionID string, version int) (int, *RepositoryError) {
+	if repoErr := updateSession(r.db, sessionID, version, map[string]interface{}{"status": SessionCommitting}); repoErr != nil {
+		return 0, repoErr
+	}
+	return version + 1, nil
+}
+
+// AbortCommit returns a session whose L1 commit failed to completed, so it
+// can be recommitted or deleted
+func (r *Repository) AbortCommit(sessionID string, version int) *RepositoryError {
+	return updateSession(r.db, sessionID, version, map[string]interface{}{"status": "completed"})
+}
+
+// MarkSessionCommitted updates session with L1 commitment info. version is
+// the session version returned by BeginCommit.
+func (r *Repository) MarkSessionCommitted(sessionID string, version int, txHash string, blockHeight int64) *RepositoryError {
+	commitTime := time.Now()
+
+	return updateSession(r.db, sessionID, version, map[string]interface{}{
+		"is_committed":    true,
+		"status":          "committed",
+		"l1_tx_hash":      txHash,
+		"l1_block_height": blockHeight,
+		"l1_commit_time":  commitTime,
+	})
+}
+
+// DeleteSession removes an uncommitted session together with its QC record
+// and label. The scanned package is released back to pending so it can be
+// scanned again. Committed sessions and sessions being committed are kept
+// and yield a CONFLICT error.
+func (r *Repository) DeleteSession(sessionID string) *RepositoryError {
+	dbTx := r.db.Begin()
+
+	var session models.Session
+	if err := dbTx.Where("session_id = ?", sessionID).First(&session).Error; err != nil {
+		dbTx.Rollback()
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return &RepositoryError{
+				Code:    "NOT_FOUND",
+				Message: "Session not found",
+				Detail:  fmt.Sprintf("Session %s does not exist", sessionID),
+			}
+		}
+		return &RepositoryError{
+			Code:    "DATABASE_ERROR",
+			Message: "Database error",
+			Detail:  err.Error(),
+		}
+	}
+
+	if session.IsCommitted {
+		dbTx.Rollback()
+		return &RepositoryError{
+			Code:    "CONFLICT",
+			Message: "Committed sessions cannot be deleted",
+			Detail:  fmt.Sprintf("Session %s is already committed to L1", sessionID),
+		}
+	}
+	if session.Status == SessionCommitting {
+		dbTx.Rollback()
+		return &RepositoryError{
+			Code:    "CONFLICT",
+			Message: "Sessions being committed cannot be deleted",
+			Detail:  fmt.Sprintf("Session %s is being committed to L1", sessionID),
+		}
+	}
+
+	if err := dbTx.Where("session_id = ?", sessionID).Delete(&models.QCRecord{}).Error; err != nil {
+		dbTx.Rollback()
+		return &RepositoryError{
+			Code:    "DELETE_FAILED",
+			Message: "Failed to delete QC record",
+			Detail:  err.Error(),
+		}
+	}
+
+	if err := dbTx.Where("session_id = ?", sessionID).Delete(&models.Label{}).Error; err != nil {
+		dbTx.Rollback()
+		return &RepositoryError{
+			Code:    "DELETE_FAILED",
+			Message: "Failed to delete label",
+			Detail:  err.Error(),
+		}
+	}
+
+	if err := dbTx.Model(&models.Package{}).Where("session_id = ?", sessionID).
+		Updates(map[string]interface{}{"session_id": nil, "status": "pending"}).Error; err != nil {
+		dbTx.Rollback()
 		return &RepositoryError{
 			Code:    "UPDATE_FAILED",
-			Message: "Failed to mark session as committed",
+			Message: "Failed to release package",
+			Detail:  err.Error(),
+		}
+	}
+
+	// Guard on the version so a commit that began since the read wins
+	result := dbTx.Where("session_id = ? AND version = ?", sessionID, session.Version).Delete(&models.Session{})
+	if result.Error != nil {
+		dbTx.Rollback()
+		return &RepositoryError{
+			Code:    "DELETE_FAILED",
+			Message: "Failed to delete session",
+			Detail:  result.Error.Error(),
+		}
+	}
+	if result.RowsAffected == 0 {
+		dbTx.Rollback()
+		return &RepositoryError{
+			Code:    "CONFLICT",
+			Message: "Session changed while deleting",
+			Detail:  fmt.Sprintf("Session %s was modified or committed while deleting", sessionID),
+		}
+	}
+
+	if err := dbTx.Commit().Error; err != nil {
+		return &RepositoryError{
+			Code:    "COMMIT_FAILED",
+			Message: "Failed to commit transaction",
 			Detail:  err.Error(),
 		}
 	}
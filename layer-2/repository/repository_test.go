@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/ahmadzakiakmal/thesis-extension/layer-2/repository/models"
+)
+
+// testSession creates an active session under prefix, so it is deleted when
+// the test ends
+func testSession(t *testing.T, r *Repository, prefix string) *models.Session {
+	t.Helper()
+	session := &models.Session{ID: prefix + "SES", OperatorID: "OPR-001", Status: "active"}
+	if err := r.db.Create(session).Error; err != nil {
+		t.Fatalf("creating session: %v", err)
+	}
+	return session
+}
+
+func TestUpdateSessionAtSameVersion(t *testing.T) {
+	r := testRepository(t)
+	session := testSession(t, r, testPrefix(t, r))
+
+	first := updateSession(r.db, session.ID, session.Version, map[string]interface{}{"status": "completed"})
+	second := updateSession(r.db, session.ID, session.Version, map[string]interface{}{"status": "active"})
+	if first != nil {
+		t.Fatalf("first update: %v", first)
+	}
+	if second == nil || second.Code != "CONFLICT" {
+		t.Fatalf("second update = %v, want CONFLICT", second)
+	}
+
+	stored, repoErr := r.GetSession(session.ID)
+	if repoErr != nil {
+		t.Fatalf("GetSession: %v", repoErr)
+	}
+	if stored.Status != "completed" || stored.Version != session.Version+1 {
+		t.Fatalf("session at %s version %d, want the first update at version %d", stored.Status, stored.Version, session.Version+1)
+	}
+}
+
+func TestUpdateSessionConcurrentlyAtSameVersion(t *testing.T) {
+	r := testRepository(t)
+	session := testSession(t, r, testPrefix(t, r))
+
+	var wg sync.WaitGroup
+	results := make([]*RepositoryError, 2)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, results[i] = r.BeginCommit(session.ID, session.Version)
+		}(i)
+	}
+	wg.Wait()
+
+	succeeded, conflicts := 0, 0
+	for _, repoErr := range results {
+		switch {
+		case repoErr == nil:
+			succeeded++
+		case repoErr.Code == "CONFLICT":
+			conflicts++
+		default:
+			t.Fatalf("BeginCommit: %v", repoErr)
+		}
+	}
+	if succeeded != 1 || conflicts != 1 {
+		t.Fatalf("%d succeeded and %d conflicted, want one of each", succeeded, conflicts)
+	}
+}
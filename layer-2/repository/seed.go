@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/ahmadzakiakmal/thesis-extension/layer-2/repository/models"
+)
+
+// SeedConfig controls what Seed writes into the database
+type SeedConfig struct {
+	// Enabled turns seeding of the demo data on. Production deployments
+	// should disable it. Operators are mirrored either way.
+	Enabled bool
+	// File is an optional JSON file replacing the built-in seed data,
+	// operators included
+	File string
+}
+
+// SeedData is the data written by Seed and SeedOperators. Seed files use the
+// same shape, with entries keyed by their Go field names, e.g.
+// {"suppliers": [{"ID": "SUP-001", "Name": "Acme Electronics"}], ...}
+type SeedData struct {
+	Suppliers []models.Supplier `json:"suppliers"`
+	Couriers  []models.Courier  `json:"couriers"`
+	Packages  []models.Package  `json:"packages"`
+	Items     []models.Item     `json:"items"`
+	Operators []models.Operator `json:"operators"`
+}
+
+// DefaultSeedData returns the built-in demo data
+func DefaultSeedData() *SeedData {
+	return &SeedData{
+		Suppliers: []models.Supplier{
+			{ID: "SUP-001", Name: "Acme Electronics", Country: "Japan"},
+			{ID: "SUP-002", Name: "Global Tech Supply", Country: "Taiwan"},
+			{ID: "SUP-003", Name: "Premium Parts Co", Country: "Germany"},
+		},
+		Couriers: []models.Courier{
+			{ID: "CUR-001", Name: "FastShip Express"},
+			{ID: "CUR-002", Name: "Global Logistics"},
+			{ID: "CUR-003", Name: "Quick Delivery Co"},
+		},
+		Packages: []models.Package{
+			{
+				ID:         "PKG-001",
+				Signature:  "sig_acme_electronics_001",
+				SupplierID: "SUP-001",
+				Status:     "pending",
+			},
+			{
+				ID:         "PKG-002",
+				Signature:  "sig_global_tech_002",
+				SupplierID: "SUP-002",
+				Status:     "pending",
+			},
+		},
+		Items: []models.Item{
+			{ID: "ITEM-001", PackageID: "PKG-001", Description: "Microcontroller Unit", Quantity: 100},
+			{ID: "ITEM-002", PackageID: "PKG-001", Description: "LED Display Module", Quantity: 50},
+			{ID: "ITEM-003", PackageID: "PKG-002", Description: "Power Supply Unit", Quantity: 25},
+			{ID: "ITEM-004", PackageID: "PKG-002", Description: "Circuit Board", Quantity: 75},
+		},
+		// Mirrors the operators seeded on L1
+		Operators: []models.Operator{
+			{ID: "OPR-001", Name: "John Smith", Role: "Warehouse Manager", AccessLevel: "Admin"},
+			{ID: "OPR-002", Name: "Sarah Lee", Role: "Quality Control", AccessLevel: "Standard"},
+			{ID: "OPR-003", Name: "Raj Patel", Role: "Logistics Coordinator", AccessLevel: "Standard"},
+			{ID: "OPR-004", Name: "Maria Garcia", Role: "Inventory Clerk", AccessLevel: "Basic"},
+			{ID: "OPR-005", Name: "Chen Wei", Role: "Warehouse Supervisor", AccessLevel: "Admin"},
+			{ID: "OPR-006", Name: "Ahmed Hassan", Role: "Shipping Clerk", AccessLevel: "Standard"},
+			{ID: "OPR-007", Name: "Emma Wilson", Role: "Quality Inspector", AccessLevel: "Standard"},
+			{ID: "OPR-008", Name: "Luis Rodriguez", Role: "Inventory Manager", AccessLevel: "Admin"},
+		},
+	}
+}
+
+// LoadSeedFile reads seed data from a JSON file
+func LoadSeedFile(path string) (*SeedData, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading seed file: %w", err)
+	}
+
+	var data SeedData
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return nil, fmt.Errorf("parsing seed file %s: %w", path, err)
+	}
+	return &data, nil
+}
@@ -0,0 +1,183 @@
+package repository
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ahmadzakiakmal/thesis-extension/layer-2/repository/models"
+)
+
+// clearOperators deletes operators so a test sees only what seeding writes
+func clearOperators(t *testing.T, r *Repository, operators []models.Operator) {
+	t.Helper()
+	for _, operator := range operators {
+		if err := r.db.Delete(&models.Operator{}, "operator_id = ?", operator.ID).Error; err != nil {
+			t.Fatalf("deleting operator %s: %v", operator.ID, err)
+		}
+	}
+}
+
+// requireOperators fails the test unless every operator is mirrored as given
+func requireOperators(t *testing.T, r *Repository, operators []models.Operator) {
+	t.Helper()
+	for _, want := range operators {
+		got, repoErr := r.GetOperator(want.ID)
+		if repoErr != nil {
+			t.Fatalf("GetOperator(%s): %v", want.ID, repoErr)
+		}
+		if got.AccessLevel != want.AccessLevel || got.Name != want.Name {
+			t.Fatalf("operator %s = %+v, want %+v", want.ID, got, want)
+		}
+	}
+}
+
+func TestSeedOperatorsWhenSeedingEnabled(t *testing.T) {
+	r := testRepository(t)
+	operators := DefaultSeedData().Operators
+	clearOperators(t, r, operators)
+
+	r.SetSeedConfig(SeedConfig{Enabled: true})
+	r.SeedOperators()
+	requireOperators(t, r, operators)
+}
+
+func TestSeedOperatorsWhenSeedingDisabled(t *testing.T) {
+	r := testRepository(t)
+	operators := DefaultSeedData().Operators
+	clearOperators(t, r, operators)
+
+	r.SetSeedConfig(SeedConfig{Enabled: false})
+	r.Seed()
+	r.SeedOperators()
+	requireOperators(t, r, operators)
+}
+
+func TestSeedOperatorsFromFile(t *testing.T) {
+	r := testRepository(t)
+	operators := []models.Operator{{ID: "OPR-FILE-1", Name: "File Operator", Role: "Auditor", AccessLevel: "Admin"}}
+	clearOperators(t, r, operators)
+	t.Cleanup(func() { clearOperators(t, r, operators) })
+
+	path := filepath.Join(t.TempDir(), "seed.json")
+	seed := `{"operators": [{"ID": "OPR-FILE-1", "Name": "File Operator", "Role": "Auditor", "AccessLevel": "Admin"}]}`
+	if err := os.WriteFile(path, []byte(seed), 0o600); err != nil {
+		t.Fatalf("writing seed file: %v", err)
+	}
+
+	r.SetSeedConfig(SeedConfig{Enabled: false, File: path})
+	r.SeedOperators()
+	requireOperators(t, r, operators)
+}
+
+func TestSyncOperatorsReplacesMirror(t *testing.T) {
+	r := testRepository(t)
+	defaults := DefaultSeedData().Operators
+	t.Cleanup(func() {
+		r.SetSeedConfig(SeedConfig{})
+		r.SeedOperators()
+	})
+
+	synced := []models.Operator{
+		{ID: "OPR-001", Name: "John Smith", Role: "Warehouse Manager", AccessLevel: "Basic"},
+		{ID: "OPR-L1-1", Name: "Registered On L1", Role: "Auditor", AccessLevel: "Standard"},
+	}
+	if repoErr := r.SyncOperators(synced); repoErr != nil {
+		t.Fatalf("SyncOperators: %v", repoErr)
+	}
+	requireOperators(t, r, synced)
+
+	// Operators L1 no longer lists lose their access
+	for _, operator := range defaults[1:] {
+		if _, repoErr := r.GetOperator(operator.ID); repoErr == nil || repoErr.Code != "NOT_FOUND" {
+			t.Fatalf("GetOperator(%s) = %v, want NOT_FOUND after the sync", operator.ID, repoErr)
+		}
+	}
+	if err := r.db.Delete(&models.Operator{}, "operator_id = ?", "OPR-L1-1").Error; err != nil {
+		t.Fatalf("deleting operator: %v", err)
+	}
+}
+
+// writeSeedFile writes data as a seed file and returns its path
+func writeSeedFile(t *testing.T, data *SeedData) string {
+	t.Helper()
+	raw, err := json.Marshal(data)
+	if err != nil {
+		t.Fatalf("encoding seed data: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "seed.json")
+	if err := os.WriteFile(path, raw, 0o600); err != nil {
+		t.Fatalf("writing seed file: %v", err)
+	}
+	return path
+}
+
+func TestSeedAppliesChangedValuesOnNextStartup(t *testing.T) {
+	r := testRepository(t)
+	suffix := fmt.Sprint(time.Now().UnixNano())
+	supplier := models.Supplier{ID: "SUP-T" + suffix, Name: "Old Supplier", Country: "ID"}
+	courier := models.Courier{ID: "CUR-T" + suffix, Name: "Old Courier"}
+	pkg := models.Package{ID: "PKG-T" + suffix, Signature: "sig-old", SupplierID: supplier.ID}
+	unlisted := models.Courier{ID: "CUR-U" + suffix, Name: "Created By Hand"}
+	t.Cleanup(func() {
+		r.db.Delete(&models.Package{}, "package_id = ?", pkg.ID)
+		r.db.Delete(&models.Supplier{}, "supplier_id = ?", supplier.ID)
+		r.db.Delete(&models.Courier{}, "courier_id IN ?", []string{courier.ID, unlisted.ID})
+	})
+
+	seed := &SeedData{Suppliers: []models.Supplier{supplier}, Couriers: []models.Courier{courier}, Packages: []models.Package{pkg}}
+	r.SetSeedConfig(SeedConfig{Enabled: true, File: writeSeedFile(t, seed)})
+	r.Seed()
+
+	// Runtime state and rows outside the seed list must survive re-seeding
+	if err := r.db.Model(&models.Package{}).Where("package_id = ?", pkg.ID).Update("status", "validated").Error; err != nil {
+		t.Fatalf("updating package status: %v", err)
+	}
+	if err := r.db.Create(&unlisted).Error; err != nil {
+		t.Fatalf("creating courier: %v", err)
+	}
+
+	seed.Suppliers[0].Name = "New Supplier"
+	seed.Couriers[0].Name = "New Courier"
+	seed.Packages[0].Signature = "sig-new"
+	seed.Packages[0].IsTrusted = true
+	restarted := testRepository(t)
+	restarted.SetSeedConfig(SeedConfig{Enabled: true, File: writeSeedFile(t, seed)})
+	restarted.Seed()
+
+	var gotSupplier models.Supplier
+	if err := r.db.First(&gotSupplier, "supplier_id = ?", supplier.ID).Error; err != nil {
+		t.Fatalf("loading supplier: %v", err)
+	}
+	if gotSupplier.Name != "New Supplier" {
+		t.Errorf("supplier name = %q, want the re-seeded name", gotSupplier.Name)
+	}
+
+	var gotCourier models.Courier
+	if err := r.db.First(&gotCourier, "courier_id = ?", courier.ID).Error; err != nil {
+		t.Fatalf("loading courier: %v", err)
+	}
+	if gotCourier.Name != "New Courier" {
+		t.Errorf("courier name = %q, want the re-seeded name", gotCourier.Name)
+	}
+
+	var gotPackage models.Package
+	if err := r.db.First(&gotPackage, "package_id = ?", pkg.ID).Error; err != nil {
+		t.Fatalf("loading package: %v", err)
+	}
+	if gotPackage.Signature != "sig-new" || !gotPackage.IsTrusted {
+		t.Errorf("package = %+v, want the re-seeded signature and trust", gotPackage)
+	}
+	if gotPackage.Status != "validated" {
+		t.Errorf("package status = %q, want it kept as validated", gotPackage.Status)
+	}
+
+	var count int64
+	r.db.Model(&models.Courier{}).Where("courier_id = ?", unlisted.ID).Count(&count)
+	if count != 1 {
+		t.Error("re-seeding removed a courier that is not in the seed list")
+	}
+}
@@ -0,0 +1,120 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ahmadzakiakmal/thesis-extension/layer-2/repository/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// SelfTestFixture is the synthetic package and the existing operator and
+// courier the startup self-test runs a session with
+type SelfTestFixture struct {
+	OperatorID string
+	PackageID  string
+	CourierID  string
+
+	// Courier counters before the run, restored on cleanup
+	courierCount    int64
+	courierLastUsed time.Time
+	courierWasUsed  bool
+}
+
+// PrepareSelfTest creates a synthetic package for the startup self-test,
+// using the first operator, supplier and courier on the shard
+func (r *Repository) PrepareSelfTest() (*SelfTestFixture, *RepositoryError) {
+	var operator models.Operator
+	if repoErr := firstRow(r.db.Order("operator_id"), &operator, "operator"); repoErr != nil {
+		return nil, repoErr
+	}
+	var supplier models.Supplier
+	if repoErr := firstRow(r.db.Order("supplier_id"), &supplier, "supplier"); repoErr != nil {
+		return nil, repoErr
+	}
+	var courier models.Courier
+	if repoErr := firstRow(r.db.Order("courier_id"), &courier, "courier"); repoErr != nil {
+		return nil, repoErr
+	}
+
+	pkg := models.Package{
+		ID:         fmt.Sprintf("PKG-SELFTEST-%s", uuid.New().String()[:8]),
+		Signature:  "selftest",
+		SupplierID: supplier.ID,
+		Status:     "pending",
+	}
+	if err := r.db.Omit("Supplier", "Items").Create(&pkg).Error; err != nil {
+		return nil, &RepositoryError{
+			Code:    "CREATE_FAILED",
+			Message: "Failed to create self-test package",
+			Detail:  err.Error(),
+		}
+	}
+
+	fixture := &SelfTestFixture{
+		OperatorID: operator.ID,
+		PackageID:  pkg.ID,
+		CourierID:  courier.ID,
+	}
+	r.couriers.mu.Lock()
+	fixture.courierCount = r.couriers.counts[courier.ID]
+	fixture.courierLastUsed, fixture.courierWasUsed = r.couriers.lastUsed[courier.ID]
+	r.couriers.mu.Unlock()
+
+	return fixture, nil
+}
+
+// CleanupSelfTest removes the self-test session, when one was created, and
+// the synthetic package, and undoes the courier assignment the run counted
+func (r *Repository) CleanupSelfTest(fixture *SelfTestFixture, sessionID string) *RepositoryError {
+	if sessionID != "" {
+		if repoErr := r.DeleteSession(sessionID); repoErr != nil && repoErr.Code != "NOT_FOUND" {
+			return repoErr
+		}
+	}
+
+	if err := r.db.Where("package_id = ?", fixture.PackageID).Delete(&models.Package{}).Error; err != nil {
+		return &RepositoryError{
+			Code:    "DELETE_FAILED",
+			Message: "Failed to delete self-test package",
+			Detail:  err.Error(),
+		}
+	}
+
+	r.couriers.mu.Lock()
+	defer r.couriers.mu.Unlock()
+	if fixture.courierCount > 0 {
+		r.couriers.counts[fixture.CourierID] = fixture.courierCount
+	} else {
+		delete(r.couriers.counts, fixture.CourierID)
+	}
+	if fixture.courierWasUsed {
+		r.couriers.lastUsed[fixture.CourierID] = fixture.courierLastUsed
+	} else {
+		delete(r.couriers.lastUsed, fixture.CourierID)
+	}
+	return nil
+}
+
+// firstRow loads the first row of query into dest, naming kind when the
+// table is empty
+func firstRow(query *gorm.DB, dest interface{}, kind string) *RepositoryError {
+	err := query.First(dest).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &RepositoryError{
+			Code:    "NOT_FOUND",
+			Message: fmt.Sprintf("No %s available", kind),
+			Detail:  fmt.Sprintf("The self-test needs at least one %s on the shard", kind),
+		}
+	}
+	if err != nil {
+		return &RepositoryError{
+			Code:    "DATABASE_ERROR",
+			Message: "Database error",
+			Detail:  err.Error(),
+		}
+	}
+	return nil
+}
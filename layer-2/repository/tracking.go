@@ -0,0 +1,109 @@
+package repository
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"github.com/ahmadzakiakmal/thesis-extension/layer-2/repository/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// trackingSeqPattern matches the sequence placeholder of a tracking format:
+// {seq}, or {seq:N} for a sequence zero-padded to N digits
+var trackingSeqPattern = regexp.MustCompile(`\{seq(?::(\d+))?\}`)
+
+// maxTrackingAttempts bounds how many sequence numbers a label skips when
+// the rendered tracking number is already taken
+const maxTrackingAttempts = 100
+
+// ValidateTrackingFormat checks a courier tracking format. The format is
+// literal text with exactly one {seq} or {seq:N} placeholder, so every
+// number it renders is distinct. Empty keeps the default TRK-<uuid> format.
+func ValidateTrackingFormat(format string) error {
+	if format == "" {
+		return nil
+	}
+	matches := trackingSeqPattern.FindAllStringSubmatch(format, -1)
+	if len(matches) != 1 {
+		return fmt.Errorf("tracking format %q must contain exactly one {seq} or {seq:N} placeholder", format)
+	}
+	if width := matches[0][1]; width != "" {
+		n, err := strconv.Atoi(width)
+		if err != nil || n < 1 || n > 20 {
+			return fmt.Errorf("tracking format %q: sequence width must be between 1 and 20", format)
+		}
+	}
+	if len(renderTrackingNo(format, 0)) > 80 {
+		return fmt.Errorf("tracking format %q is too long", format)
+	}
+	return nil
+}
+
+// renderTrackingNo fills the sequence placeholder of format with seq
+func renderTrackingNo(format string, seq int64) string {
+	return trackingSeqPattern.ReplaceAllStringFunc(format, func(placeholder string) string {
+		width := trackingSeqPattern.FindStringSubmatch(placeholder)[1]
+		if width == "" {
+			return strconv.FormatInt(seq, 10)
+		}
+		n, _ := strconv.Atoi(width)
+		return fmt.Sprintf("%0*d", n, seq)
+	})
+}
+
+// nextTrackingNo generates the tracking number for a label of courier within
+// dbTx. A courier with a tracking format advances its sequence, which locks
+// its row until dbTx ends; numbers already used by another label are skipped.
+func nextTrackingNo(dbTx *gorm.DB, courier *models.Courier) (string, *RepositoryError) {
+	if courier.TrackingFormat == "" {
+		return fmt.Sprintf("TRK-%s", uuid.New().String()[:12]), nil
+	}
+	if err := ValidateTrackingFormat(courier.TrackingFormat); err != nil {
+		return "", &RepositoryError{
+			Code:    "INVALID_TRACKING_FORMAT",
+			Message: "Courier has an invalid tracking format",
+			Detail:  err.Error(),
+		}
+	}
+
+	for attempt := 0; attempt < maxTrackingAttempts; attempt++ {
+		var seq int64
+		err := dbTx.Model(&models.Courier{}).
+			Where("courier_id = ?", courier.ID).
+			Update("tracking_seq", gorm.Expr("tracking_seq + 1")).Error
+		if err == nil {
+			err = dbTx.Model(&models.Courier{}).
+				Where("courier_id = ?", courier.ID).
+				Pluck("tracking_seq", &seq).Error
+		}
+		if err != nil {
+			return "", &RepositoryError{
+				Code:    "UPDATE_FAILED",
+				Message: "Failed to advance tracking sequence",
+				Detail:  err.Error(),
+			}
+		}
+
+		trackingNo := renderTrackingNo(courier.TrackingFormat, seq)
+		var taken int64
+		if err := dbTx.Model(&models.Label{}).Where("tracking_no = ?", trackingNo).Count(&taken).Error; err != nil {
+			return "", &RepositoryError{
+				Code:    "DATABASE_ERROR",
+				Message: "Database error",
+				Detail:  err.Error(),
+			}
+		}
+		if taken == 0 {
+			courier.TrackingSeq = seq
+			return trackingNo, nil
+		}
+	}
+
+	return "", &RepositoryError{
+		Code:    "CONFLICT",
+		Message: "No free tracking number",
+		Detail:  fmt.Sprintf("Courier %s produced %d tracking numbers that are already in use", courier.ID, maxTrackingAttempts),
+	}
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/ahmadzakiakmal/thesis-extension/layer-2/config"
+	"github.com/ahmadzakiakmal/thesis-extension/layer-2/l1client"
+	"github.com/ahmadzakiakmal/thesis-extension/layer-2/repository"
+	"github.com/ahmadzakiakmal/thesis-extension/layer-2/repository/models"
+)
+
+// selfTestRepository is the part of the repository the self-test drives
+type selfTestRepository interface {
+	PrepareSelfTest() (*repository.SelfTestFixture, *repository.RepositoryError)
+	CleanupSelfTest(fixture *repository.SelfTestFixture, sessionID string) *repository.RepositoryError
+	CreateSession(operatorID string) (*models.Session, *repository.RepositoryError)
+	ScanPackage(sessionID, packageID string) (*models.Package, *repository.RepositoryError)
+	ValidatePackage(signature, packageID, sessionID string) (*models.Package, *repository.RepositoryError)
+	QualityCheck(sessionID string, passed bool, issues []string) (*models.Package, *models.QCRecord, *repository.RepositoryError)
+	LabelPackage(sessionID, courierID string) (*models.Label, *repository.RepositoryError)
+	GetSession(sessionID string) (*models.Session, *repository.RepositoryError)
+}
+
+// selfTestL1 is the part of the L1 client the self-test checks
+type selfTestL1 interface {
+	HealthCheck() error
+	GetShardByClientGroup(clientGroup string) (l1client.ShardInfo, bool)
+	DryRunCommit(session *models.Session, clientGroup string) error
+}
+
+// selfTestStep is one stage of the startup self-test
+type selfTestStep struct {
+	name string
+	run  func() error
+}
+
+// runStartupSelfTest checks L1 and the shard registry, then runs a synthetic
+// session through scan, validate, QC and label and builds its L1 commit
+// without sending it. The session and its package are removed afterwards.
+// It returns the name of the first failing step.
+func runStartupSelfTest(l1Client selfTestL1, repo selfTestRepository, shardID, clientGroup string) (string, error) {
+	var (
+		fixture   *repository.SelfTestFixture
+		sessionID string
+	)
+	defer func() {
+		if fixture == nil {
+			return
+		}
+		if repoErr := repo.CleanupSelfTest(fixture, sessionID); repoErr != nil {
+			log.Printf("⚠️  Warning: Failed to clean up self-test data: %v", repoErr)
+		}
+	}()
+
+	steps := []selfTestStep{
+		{"l1-health", l1Client.HealthCheck},
+		{"shard-registry", func() error {
+			shard, ok := l1Client.GetShardByClientGroup(clientGroup)
+			if !ok {
+				return fmt.Errorf("client group %s is not in the shard registry", clientGroup)
+			}
+			if shard.ShardID != shardID {
+				return fmt.Errorf("client group %s is registered to shard %s, not %s", clientGroup, shard.ShardID, shardID)
+			}
+			return nil
+		}},
+		{"prepare", func() error {
+			var repoErr *repository.RepositoryError
+			fixture, repoErr = repo.PrepareSelfTest()
+			return asError(repoErr)
+		}},
+		{"create-session", func() error {
+			session, repoErr := repo.CreateSession(fixture.OperatorID)
+			if repoErr != nil {
+				return repoErr
+			}
+			sessionID = session.ID
+			return nil
+		}},
+		{"scan", func() error {
+			_, repoErr := repo.ScanPackage(sessionID, fixture.PackageID)
+			return asError(repoErr)
+		}},
+		{"validate", func() error {
+			_, repoErr := repo.ValidatePackage("selftest", fixture.PackageID, sessionID)
+			return asError(repoErr)
+		}},
+		{"quality-check", func() error {
+			_, _, repoErr := repo.QualityCheck(sessionID, true, nil)
+			return asError(repoErr)
+		}},
+		{"label", func() error {
+			_, repoErr := repo.LabelPackage(sessionID, fixture.CourierID)
+			return asError(repoErr)
+		}},
+		{"build-commit", func() error {
+			session, repoErr := repo.GetSession(sessionID)
+			if repoErr != nil {
+				return repoErr
+			}
+			if session.Status != "completed" {
+				return fmt.Errorf("session is %s after labeling, want completed", session.Status)
+			}
+			return l1Client.DryRunCommit(session, clientGroup)
+		}},
+	}
+
+	for _, step := range steps {
+		if err := step.run(); err != nil {
+			return step.name, err
+		}
+	}
+	return "", nil
+}
+
+// asError converts a repository error to an error, keeping nil as nil
+func asError(repoErr *repository.RepositoryError) error {
+	if repoErr == nil {
+		return nil
+	}
+	return repoErr
+}
+
+// startupSelfTest runs the self-test in the configured mode. A failure is
+// returned in strict mode, to stop the node, and only logged otherwise.
+func startupSelfTest(cfg *config.Config, l1Client selfTestL1, repo selfTestRepository) error {
+	if cfg.StartupSelfTest == config.SelfTestOff {
+		return nil
+	}
+
+	log.Println("\n🧪 Running startup self-test...")
+	step, err := runStartupSelfTest(l1Client, repo, cfg.ShardID, cfg.ClientGroup)
+	if err == nil {
+		log.Println("✓ Startup self-test passed")
+		return nil
+	}
+	if cfg.StartupSelfTest == config.SelfTestStrict {
+		return fmt.Errorf("startup self-test failed at step %s: %w", step, err)
+	}
+	log.Printf("⚠️  Warning: Startup self-test failed at step %s: %v", step, err)
+	return nil
+}
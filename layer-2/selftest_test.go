@@ -0,0 +1,137 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ahmadzakiakmal/thesis-extension/layer-2/config"
+	"github.com/ahmadzakiakmal/thesis-extension/layer-2/l1client"
+	"github.com/ahmadzakiakmal/thesis-extension/layer-2/repository"
+	"github.com/ahmadzakiakmal/thesis-extension/layer-2/repository/models"
+)
+
+// fakeSelfTestRepository runs every self-test step successfully except
+// failAt, which returns a database error
+type fakeSelfTestRepository struct {
+	failAt    string
+	status    string // session status after labeling
+	cleanedUp bool
+}
+
+func (f *fakeSelfTestRepository) fail(step string) *repository.RepositoryError {
+	if step != f.failAt {
+		return nil
+	}
+	return &repository.RepositoryError{Code: "DATABASE_ERROR", Message: "injected failure", Detail: step}
+}
+
+func (f *fakeSelfTestRepository) PrepareSelfTest() (*repository.SelfTestFixture, *repository.RepositoryError) {
+	if repoErr := f.fail("prepare"); repoErr != nil {
+		return nil, repoErr
+	}
+	return &repository.SelfTestFixture{OperatorID: "OPR-001", PackageID: "PKG-SELFTEST", CourierID: "CUR-001"}, nil
+}
+
+func (f *fakeSelfTestRepository) CleanupSelfTest(*repository.SelfTestFixture, string) *repository.RepositoryError {
+	f.cleanedUp = true
+	return nil
+}
+
+func (f *fakeSelfTestRepository) CreateSession(operatorID string) (*models.Session, *repository.RepositoryError) {
+	if repoErr := f.fail("create-session"); repoErr != nil {
+		return nil, repoErr
+	}
+	return &models.Session{ID: "SES-SELFTEST", OperatorID: operatorID}, nil
+}
+
+func (f *fakeSelfTestRepository) ScanPackage(string, string) (*models.Package, *repository.RepositoryError) {
+	return &models.Package{}, f.fail("scan")
+}
+
+func (f *fakeSelfTestRepository) ValidatePackage(string, string, string) (*models.Package, *repository.RepositoryError) {
+	return &models.Package{}, f.fail("validate")
+}
+
+func (f *fakeSelfTestRepository) QualityCheck(string, bool, []string) (*models.Package, *models.QCRecord, *repository.RepositoryError) {
+	return &models.Package{}, &models.QCRecord{}, f.fail("quality-check")
+}
+
+func (f *fakeSelfTestRepository) LabelPackage(string, string) (*models.Label, *repository.RepositoryError) {
+	return &models.Label{}, f.fail("label")
+}
+
+func (f *fakeSelfTestRepository) GetSession(sessionID string) (*models.Session, *repository.RepositoryError) {
+	status := f.status
+	if status == "" {
+		status = "completed"
+	}
+	return &models.Session{ID: sessionID, Status: status}, nil
+}
+
+// healthyL1 passes every L1 check of the self-test for shard-a
+type healthyL1 struct{}
+
+func (healthyL1) HealthCheck() error { return nil }
+
+func (healthyL1) GetShardByClientGroup(string) (l1client.ShardInfo, bool) {
+	return l1client.ShardInfo{ShardID: "shard-a"}, true
+}
+
+func (healthyL1) DryRunCommit(*models.Session, string) error { return nil }
+
+func selfTestConfig(mode string) *config.Config {
+	return &config.Config{StartupSelfTest: mode, ShardID: "shard-a", ClientGroup: "group-a"}
+}
+
+func TestStartupSelfTestPasses(t *testing.T) {
+	repo := &fakeSelfTestRepository{}
+	if err := startupSelfTest(selfTestConfig(config.SelfTestStrict), healthyL1{}, repo); err != nil {
+		t.Fatalf("startupSelfTest: %v", err)
+	}
+	if !repo.cleanedUp {
+		t.Error("self-test data was not cleaned up")
+	}
+}
+
+func TestStartupSelfTestStrictAbortsOnFailingStep(t *testing.T) {
+	for _, step := range []string{"prepare", "create-session", "scan", "validate", "quality-check", "label"} {
+		t.Run(step, func(t *testing.T) {
+			repo := &fakeSelfTestRepository{failAt: step}
+			err := startupSelfTest(selfTestConfig(config.SelfTestStrict), healthyL1{}, repo)
+			if err == nil {
+				t.Fatal("startup continued after a failing step")
+			}
+			if !strings.Contains(err.Error(), "at step "+step+":") {
+				t.Errorf("err = %v, want it to name step %s", err, step)
+			}
+			if step != "prepare" && !repo.cleanedUp {
+				t.Error("self-test data was not cleaned up")
+			}
+		})
+	}
+}
+
+func TestStartupSelfTestStrictAbortsOnIncompleteSession(t *testing.T) {
+	repo := &fakeSelfTestRepository{status: "labeling"}
+	err := startupSelfTest(selfTestConfig(config.SelfTestStrict), healthyL1{}, repo)
+	if err == nil || !strings.Contains(err.Error(), "at step build-commit:") {
+		t.Fatalf("err = %v, want a build-commit failure", err)
+	}
+}
+
+func TestStartupSelfTestWarnContinues(t *testing.T) {
+	repo := &fakeSelfTestRepository{failAt: "scan"}
+	if err := startupSelfTest(selfTestConfig(config.SelfTestWarn), healthyL1{}, repo); err != nil {
+		t.Fatalf("startupSelfTest in warn mode: %v", err)
+	}
+}
+
+func TestStartupSelfTestOff(t *testing.T) {
+	repo := &fakeSelfTestRepository{failAt: "prepare"}
+	if err := startupSelfTest(selfTestConfig(config.SelfTestOff), healthyL1{}, repo); err != nil {
+		t.Fatalf("startupSelfTest when off: %v", err)
+	}
+	if repo.cleanedUp {
+		t.Error("self-test ran while off")
+	}
+}
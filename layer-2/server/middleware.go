@@ -0,0 +1,251 @@
+package server
+
+import (
+	"compress/gzip"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/ahmadzakiakmal/thesis-extension/layer-2/l1client"
+	"github.com/ahmadzakiakmal/thesis-extension/layer-2/tracing"
+)
+
+// accessLogger writes bare JSON lines, one per request
+var accessLogger = log.New(os.Stdout, "", 0)
+
+// accessLogEntry is the structured access log line for one request
+type accessLogEntry struct {
+	Time       time.Time `json:"time"`
+	Level      string    `json:"level"`
+	Msg        string    `json:"msg"`
+	Method     string    `json:"method"`
+	Path       string    `json:"path"`
+	Status     int       `json:"status"`
+	DurationMs float64   `json:"duration_ms"`
+	Bytes      int       `json:"bytes"`
+	RequestID  string    `json:"request_id"`
+	RemoteAddr string    `json:"remote_addr"`
+}
+
+// statusRecorder captures the status code and size written by a handler
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func (rec *statusRecorder) WriteHeader(status int) {
+	if rec.status == 0 {
+		rec.status = status
+	}
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *statusRecorder) Write(b []byte) (int, error) {
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+	n, err := rec.ResponseWriter.Write(b)
+	rec.bytes += n
+	return n, err
+}
+
+// validRequestID accepts incoming request IDs that are safe to log and forward
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// withAccessLog writes one JSON log line per request with method, path,
+// status, duration and request ID. An incoming X-Request-ID is reused, or a
+// new one generated, and echoed in the response and forwarded to L1.
+func withAccessLog(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+
+		requestID := r.Header.Get(l1client.RequestIDHeader)
+		if !validRequestID.MatchString(requestID) {
+			requestID = newRequestID()
+			r.Header.Set(l1client.RequestIDHeader, requestID)
+		}
+		w.Header().Set(l1client.RequestIDHeader, requestID)
+		r = r.WithContext(l1client.WithRequestID(r.Context(), requestID))
+
+		rec := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(rec, r)
+
+		if rec.status == 0 {
+			rec.status = http.StatusOK
+		}
+
+		entry := accessLogEntry{
+			Time:       start,
+			Level:      "info",
+			Msg:        "access",
+			Method:     r.Method,
+			Path:       r.URL.Path,
+			Status:     rec.status,
+			DurationMs: float64(time.Since(start).Microseconds()) / 1000,
+			Bytes:      rec.bytes,
+			RequestID:  requestID,
+			RemoteAddr: r.RemoteAddr,
+		}
+		line, err := json.Marshal(entry)
+		if err != nil {
+			log.Printf("Failed to encode access log entry: %v", err)
+			return
+		}
+		accessLogger.Println(string(line))
+	})
+}
+
+// newRequestID generates a random request identifier
+func newRequestID() string {
+	bytes := make([]byte, 16)
+	if _, err := rand.Read(bytes); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(bytes)
+}
+
+// gzipMinSize is the smallest response body worth compressing
+const gzipMinSize = 1024
+
+// gzipResponseWriter buffers the start of a response and only switches to
+// gzip once the body grows past gzipMinSize
+type gzipResponseWriter struct {
+	http.ResponseWriter
+	status      int
+	buf         []byte
+	gz          *gzip.Writer
+	passthrough bool
+}
+
+func (g *gzipResponseWriter) WriteHeader(status int) {
+	if g.status == 0 {
+		g.status = status
+	}
+}
+
+func (g *gzipResponseWriter) Write(b []byte) (int, error) {
+	if g.gz != nil {
+		return g.gz.Write(b)
+	}
+	if g.passthrough {
+		return g.ResponseWriter.Write(b)
+	}
+
+	g.buf = append(g.buf, b...)
+	if len(g.buf) < gzipMinSize {
+		return len(b), nil
+	}
+
+	// Large enough: commit to compression unless the handler already encoded the body
+	header := g.ResponseWriter.Header()
+	if header.Get("Content-Encoding") != "" {
+		g.passthrough = true
+	} else {
+		header.Set("Content-Encoding", "gzip")
+		header.Del("Content-Length")
+		g.gz = gzip.NewWriter(g.ResponseWriter)
+	}
+	g.ResponseWriter.WriteHeader(g.statusCode())
+
+	buffered := g.buf
+	g.buf = nil
+	if g.gz != nil {
+		if _, err := g.gz.Write(buffered); err != nil {
+			return 0, err
+		}
+	} else if _, err := g.ResponseWriter.Write(buffered); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// Flush writes out whatever is buffered so far
+func (g *gzipResponseWriter) Flush() {
+	if g.gz != nil {
+		g.gz.Flush()
+	} else if !g.passthrough {
+		g.flushPlain()
+	}
+	if flusher, ok := g.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// close finishes the response, writing small bodies uncompressed
+func (g *gzipResponseWriter) close() {
+	if g.gz != nil {
+		g.gz.Close()
+		return
+	}
+	if !g.passthrough {
+		g.flushPlain()
+	}
+}
+
+func (g *gzipResponseWriter) flushPlain() {
+	g.passthrough = true
+	g.ResponseWriter.WriteHeader(g.statusCode())
+	if len(g.buf) > 0 {
+		g.ResponseWriter.Write(g.buf)
+		g.buf = nil
+	}
+}
+
+func (g *gzipResponseWriter) statusCode() int {
+	if g.status == 0 {
+		return http.StatusOK
+	}
+	return g.status
+}
+
+// withTracing continues the trace of an incoming traceparent header so spans
+// created while handling the request, and the calls to L1, join it
+func withTracing(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(tracing.Extract(r.Context(), r.Header)))
+	})
+}
+
+// withGzip compresses responses for clients that accept gzip once the body
+// exceeds gzipMinSize. WebSocket upgrades and HEAD requests are left alone.
+func withGzip(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead || r.Header.Get("Upgrade") != "" ||
+			!strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Add("Vary", "Accept-Encoding")
+		gw := &gzipResponseWriter{ResponseWriter: w}
+		defer gw.close()
+		next.ServeHTTP(gw, r)
+	})
+}
+
+// withInflightLimit answers 503 once max requests are being served, so an
+// overload degrades into fast rejections instead of piling up goroutines and
+// database connections. reject writes the 503. max <= 0 disables the limit.
+func withInflightLimit(max int, reject func(http.ResponseWriter), next http.Handler) http.Handler {
+	if max <= 0 {
+		return next
+	}
+	slots := make(chan struct{}, max)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case slots <- struct{}{}:
+			defer func() { <-slots }()
+			next.ServeHTTP(w, r)
+		default:
+			w.Header().Set("Retry-After", "1")
+			reject(w)
+		}
+	})
+}
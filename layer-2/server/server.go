@@ -3,12 +3,15 @@ package server
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
+	"net"
 	"net/http"
 	"time"
 
+	"github.com/ahmadzakiakmal/thesis-extension/layer-2/api"
 	"github.com/ahmadzakiakmal/thesis-extension/layer-2/srvreg"
 )
 
@@ -20,28 +23,92 @@ type WebServer struct {
 	startTime       time.Time
 	shardID         string
 	clientGroup     string
+	config          *ServerConfig
 }
 
+// ServerConfig contains configuration for the L2 web server
+type ServerConfig struct {
+	// MaxBodyBytes caps the size of request bodies. Defaults to DefaultMaxBodyBytes.
+	MaxBodyBytes int64
+
+	// MaxImportBytes caps a session import, which is streamed instead of
+	// read into memory. Defaults to DefaultMaxImportBytes.
+	MaxImportBytes int64
+
+	// BindAddress is the interface the server listens on. Defaults to
+	// DefaultBindAddress, which binds all interfaces.
+	BindAddress string
+
+	// MaxInflight caps the requests served at once; further requests get 503.
+	// Zero is unlimited.
+	MaxInflight int
+
+	// Envelope wraps JSON responses in an api.L2Response carrying the
+	// shard ID and a timestamp
+	Envelope bool
+}
+
+// DefaultMaxBodyBytes is the request body limit used when none is configured
+const DefaultMaxBodyBytes int64 = 1 << 20
+
+// DefaultMaxImportBytes is the session import limit used when none is configured
+const DefaultMaxImportBytes int64 = 256 << 20
+
+// DefaultBindAddress is the listen interface used when none is configured
+const DefaultBindAddress = "0.0.0.0"
+
 // NewWebServer creates a new L2 web server
-func NewWebServer(httpPort string, serviceRegistry *srvreg.ServiceRegistry, shardID, clientGroup string) *WebServer {
+func NewWebServer(httpPort string, serviceRegistry *srvreg.ServiceRegistry, shardID, clientGroup string, config *ServerConfig) *WebServer {
+	if config == nil {
+		config = &ServerConfig{}
+	}
+	if config.MaxBodyBytes <= 0 {
+		config.MaxBodyBytes = DefaultMaxBodyBytes
+	}
+	if config.MaxImportBytes <= 0 {
+		config.MaxImportBytes = DefaultMaxImportBytes
+	}
+	if config.BindAddress == "" {
+		config.BindAddress = DefaultBindAddress
+	}
+	httpAddr := net.JoinHostPort(config.BindAddress, httpPort)
+
 	mux := http.NewServeMux()
 
 	ws := &WebServer{
-		httpAddr: ":" + httpPort,
+		httpAddr: httpAddr,
 		server: &http.Server{
-			Addr:    ":" + httpPort,
+			Addr:    httpAddr,
 			Handler: mux,
 		},
 		serviceRegistry: serviceRegistry,
 		startTime:       time.Now(),
 		shardID:         shardID,
 		clientGroup:     clientGroup,
+		config:          config,
 	}
 
 	// Register routes
 	mux.HandleFunc("/", ws.handleRoot)
-	mux.HandleFunc("/info", ws.handleInfo)
+	mux.HandleFunc("/info", ws.handleRead)
+	mux.HandleFunc("/packages", ws.handleRead)
+	mux.HandleFunc("/couriers", ws.handleRead)
+	mux.HandleFunc("/suppliers", ws.handleRead)
+	mux.HandleFunc("/labels/", ws.handleRead)
+	mux.HandleFunc("/healthz", ws.handleHealth)
+	mux.HandleFunc("/readyz", ws.handleHealth)
+	mux.HandleFunc("/debug", ws.handleDebug)
 	mux.HandleFunc("/session/", ws.handleSession)
+	mux.HandleFunc("/sessions/", ws.handleSession)
+	mux.HandleFunc("/sessions/export", ws.handleBackup)
+	mux.HandleFunc("/sessions/import", ws.handleBackup)
+	mux.HandleFunc("/openapi.json", ws.handleOpenAPI)
+	mux.Handle("/metrics", srvreg.MetricsHandler())
+
+	overloaded := func(w http.ResponseWriter) {
+		ws.jsonError(w, srvreg.CodeOverloaded, "Server overloaded")
+	}
+	ws.server.Handler = withAccessLog(withInflightLimit(config.MaxInflight, overloaded, withTracing(withGzip(mux))))
 
 	return ws
 }
@@ -119,12 +186,27 @@ func (ws *WebServer) handleRoot(w http.ResponseWriter, r *http.Request) {
         <div class="endpoints">
             <h3>Available Endpoints:</h3>
             <div class="endpoint"><span class="method">GET</span>/info - Shard information</div>
+            <div class="endpoint"><span class="method">GET</span>/packages - Package catalog</div>
+            <div class="endpoint"><span class="method">GET</span>/couriers - Courier catalog</div>
+            <div class="endpoint"><span class="method">GET</span>/suppliers - Supplier catalog</div>
+            <div class="endpoint"><span class="method">GET</span>/labels/:tracking_no - Look up a label by tracking number</div>
+            <div class="endpoint"><span class="method">GET</span>/healthz - Liveness probe</div>
+            <div class="endpoint"><span class="method">GET</span>/readyz - Readiness probe (database + L1)</div>
+            <div class="endpoint"><span class="method">GET</span>/debug - Shard diagnostics</div>
             <div class="endpoint"><span class="method">POST</span>/session/start - Create new session</div>
+            <div class="endpoint"><span class="method">POST</span>/session/start/batch - Create sessions in bulk</div>
             <div class="endpoint"><span class="method">GET</span>/session/:id/scan - Scan package</div>
             <div class="endpoint"><span class="method">POST</span>/session/:id/validate - Validate package</div>
             <div class="endpoint"><span class="method">POST</span>/session/:id/qc - Quality check</div>
-            <div class="endpoint"><span class="method">POST</span>/session/:id/label - Create shipping label</div>
+            <div class="endpoint"><span class="method">POST</span>/session/:id/label - Create shipping label (courier auto-selected when omitted and COURIER_STRATEGY is set)</div>
+            <div class="endpoint"><span class="method">PUT</span>/session/:id/label - Change courier before commit</div>
             <div class="endpoint"><span class="method">POST</span>/session/:id/commit - Commit to L1</div>
+            <div class="endpoint"><span class="method">POST</span>/session/:id/recommit - Retry a failed L1 commit</div>
+            <div class="endpoint"><span class="method">DELETE</span>/session/:id - Delete uncommitted session</div>
+            <div class="endpoint"><span class="method">GET</span>/sessions/export - Export sessions as NDJSON (X-Admin-Key)</div>
+            <div class="endpoint"><span class="method">POST</span>/sessions/import - Import an NDJSON session export (X-Admin-Key)</div>
+            <div class="endpoint"><span class="method">GET</span>/openapi.json - OpenAPI 3 document</div>
+            <div class="endpoint"><span class="method">GET</span>/metrics - Prometheus metrics</div>
         </div>
     </div>
 </body>
@@ -134,10 +216,11 @@ func (ws *WebServer) handleRoot(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte(html))
 }
 
-// handleInfo returns shard information as JSON
-func (ws *WebServer) handleInfo(w http.ResponseWriter, r *http.Request) {
+// handleRead serves the read-only GET endpoints (shard info and catalogs)
+// through the service registry
+func (ws *WebServer) handleRead(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		jsonError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		ws.jsonError(w, srvreg.CodeMethodNotAllowed, "Method not allowed")
 		return
 	}
 
@@ -146,24 +229,127 @@ func (ws *WebServer) handleInfo(w http.ResponseWriter, r *http.Request) {
 		Path:    r.URL.Path,
 		Body:    "",
 		Headers: convertHeaders(r.Header),
+		Context: r.Context(),
 	}
 
 	response, err := req.GenerateResponse(ws.serviceRegistry)
 	if err != nil {
 		log.Printf("Error generating response: %v", err)
-		jsonError(w, "Internal server error", http.StatusInternalServerError)
+		ws.jsonError(w, srvreg.CodeInternal, "Internal server error")
+		return
+	}
+
+	ws.writeResponse(w, response)
+}
+
+// handleOpenAPI serves the OpenAPI document generated from registered routes
+func (ws *WebServer) handleOpenAPI(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		ws.jsonError(w, srvreg.CodeMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(ws.serviceRegistry.OpenAPISpec()); err != nil {
+		log.Printf("Failed to encode OpenAPI document: %v", err)
+	}
+}
+
+// handleHealth serves the liveness and readiness probes. Probes are always
+// answered by this shard and never forwarded based on X-Client-Group.
+func (ws *WebServer) handleHealth(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		ws.jsonError(w, srvreg.CodeMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	handler, _, found := ws.serviceRegistry.GetHandlerForPath(r.Method, r.URL.Path)
+	if !found {
+		http.NotFound(w, r)
+		return
+	}
+
+	response, err := handler(&srvreg.Request{
+		Method:  r.Method,
+		Path:    r.URL.Path,
+		Headers: convertHeaders(r.Header),
+		Context: r.Context(),
+	})
+	if err != nil {
+		log.Printf("Error generating response: %v", err)
+		ws.jsonError(w, srvreg.CodeInternal, "Internal server error")
+		return
+	}
+
+	ws.writeResponse(w, response)
+}
+
+// handleDebug provides L2 debugging information. Like the health probes it is
+// always answered by this shard.
+func (ws *WebServer) handleDebug(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		ws.jsonError(w, srvreg.CodeMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	debugInfo := ws.serviceRegistry.DebugInfo()
+	debugInfo["layer"] = "L2"
+	debugInfo["type"] = "L2 Shard Node"
+	debugInfo["address"] = ws.httpAddr
+	debugInfo["uptime"] = time.Since(ws.startTime).String()
+
+	w.Header().Set("Content-Type", "application/json")
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(debugInfo); err != nil {
+		log.Printf("Failed to encode debug info: %v", err)
+	}
+}
+
+// handleBackup serves the session export and import. Like the health probes
+// they are always answered by this shard. The import body is streamed to the
+// handler under MaxImportBytes instead of being read into memory.
+func (ws *WebServer) handleBackup(w http.ResponseWriter, r *http.Request) {
+	handler, params, found := ws.serviceRegistry.GetHandlerForPath(r.Method, r.URL.Path)
+	if !found {
+		ws.jsonError(w, srvreg.CodeMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, ws.config.MaxImportBytes)
+	defer r.Body.Close()
+
+	response, err := handler(&srvreg.Request{
+		Method:     r.Method,
+		Path:       r.URL.Path,
+		Headers:    convertHeaders(r.Header),
+		Params:     params,
+		BodyReader: r.Body,
+		Context:    r.Context(),
+	})
+	if err != nil {
+		log.Printf("Error generating response: %v", err)
+		ws.jsonError(w, srvreg.CodeInternal, "Internal server error")
 		return
 	}
 
-	writeResponse(w, response)
+	ws.writeResponse(w, response)
 }
 
 // handleSession handles all session-related endpoints
 func (ws *WebServer) handleSession(w http.ResponseWriter, r *http.Request) {
 	// Read request body
+	r.Body = http.MaxBytesReader(w, r.Body, ws.config.MaxBodyBytes)
 	bodyBytes, err := io.ReadAll(r.Body)
 	if err != nil {
-		jsonError(w, "Failed to read request body", http.StatusBadRequest)
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			ws.jsonError(w, srvreg.CodePayloadTooLarge, fmt.Sprintf("Request body exceeds %d bytes", maxBytesErr.Limit))
+			return
+		}
+		ws.jsonError(w, srvreg.CodeInvalidRequest, "Failed to read request body")
 		return
 	}
 	defer r.Body.Close()
@@ -174,21 +360,27 @@ func (ws *WebServer) handleSession(w http.ResponseWriter, r *http.Request) {
 		Path:    r.URL.Path,
 		Body:    string(bodyBytes),
 		Headers: convertHeaders(r.Header),
+		Context: r.Context(),
 	}
 
 	// Generate response through service registry
 	response, err := req.GenerateResponse(ws.serviceRegistry)
 	if err != nil {
 		log.Printf("Error generating response: %v", err)
-		jsonError(w, "Internal server error", http.StatusInternalServerError)
+		ws.jsonError(w, srvreg.CodeInternal, "Internal server error")
 		return
 	}
 
-	writeResponse(w, response)
+	ws.writeResponse(w, response)
 }
 
-// writeResponse writes a Response to http.ResponseWriter
-func writeResponse(w http.ResponseWriter, resp *srvreg.Response) {
+// writeResponse writes a Response to http.ResponseWriter, wrapped in an
+// api.L2Response when the envelope is enabled
+func (ws *WebServer) writeResponse(w http.ResponseWriter, resp *srvreg.Response) {
+	if ws.config.Envelope {
+		resp = srvreg.Envelope(resp, ws.shardID, time.Now())
+	}
+
 	// Set headers
 	for key, value := range resp.Headers {
 		w.Header().Set(key, value)
@@ -198,18 +390,26 @@ func writeResponse(w http.ResponseWriter, resp *srvreg.Response) {
 	w.WriteHeader(resp.StatusCode)
 
 	// Write body
+	if resp.Stream != nil {
+		if err := resp.Stream(w); err != nil {
+			// The status is already sent; drop the connection so the client
+			// sees a truncated body instead of a complete-looking one
+			log.Printf("Error streaming response: %v", err)
+			panic(http.ErrAbortHandler)
+		}
+		return
+	}
 	w.Write([]byte(resp.Body))
 }
 
-// jsonError writes a JSON error response
-func jsonError(w http.ResponseWriter, message string, statusCode int) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(statusCode)
-
-	errorResp := map[string]string{
-		"error": message,
-	}
-	json.NewEncoder(w).Encode(errorResp)
+// jsonError writes a JSON error response with the status for code
+func (ws *WebServer) jsonError(w http.ResponseWriter, code, message string) {
+	body, _ := json.Marshal(api.ErrorResponse{Error: message, Code: code})
+	ws.writeResponse(w, &srvreg.Response{
+		StatusCode: srvreg.ErrorStatus(code),
+		Headers:    map[string]string{"Content-Type": "application/json"},
+		Body:       string(body),
+	})
 }
 
 // convertHeaders converts http.Header to map[string]string
@@ -0,0 +1,64 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ahmadzakiakmal/thesis-extension/layer-2/api"
+	"github.com/ahmadzakiakmal/thesis-extension/layer-2/repository"
+	"github.com/ahmadzakiakmal/thesis-extension/layer-2/srvreg"
+)
+
+// limitedServer serves a shard without a database under config's limits
+func limitedServer(t *testing.T, config *ServerConfig) *httptest.Server {
+	t.Helper()
+	registry := srvreg.NewServiceRegistry(repository.NewRepository(), nil, "shard-a", "group-a")
+	registry.SetAdminKey("secret")
+	registry.RegisterDefaultServices()
+
+	server := httptest.NewServer(NewWebServer("0", registry, "shard-a", "group-a", config).server.Handler)
+	t.Cleanup(server.Close)
+	return server
+}
+
+// requirePayloadTooLarge fails the test unless resp is a 413 PAYLOAD_TOO_LARGE
+func requirePayloadTooLarge(t *testing.T, resp *http.Response) {
+	t.Helper()
+	defer resp.Body.Close()
+	var body api.ErrorResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatalf("decoding response: %v", err)
+	}
+	if resp.StatusCode != http.StatusRequestEntityTooLarge || body.Code != srvreg.CodePayloadTooLarge {
+		t.Fatalf("got %d %+v, want 413 %s", resp.StatusCode, body, srvreg.CodePayloadTooLarge)
+	}
+}
+
+func TestSessionBodyTooLarge(t *testing.T) {
+	server := limitedServer(t, &ServerConfig{MaxBodyBytes: 16})
+
+	body := `{"operator_id": "` + strings.Repeat("x", 64) + `"}`
+	resp, err := http.Post(server.URL+"/session/start", "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("POST: %v", err)
+	}
+	requirePayloadTooLarge(t, resp)
+}
+
+func TestImportTooLarge(t *testing.T) {
+	server := limitedServer(t, &ServerConfig{MaxImportBytes: 16})
+
+	req, err := http.NewRequest(http.MethodPost, server.URL+"/sessions/import", strings.NewReader(strings.Repeat("x", 64)))
+	if err != nil {
+		t.Fatalf("NewRequest: %v", err)
+	}
+	req.Header.Set(srvreg.AdminKeyHeader, "secret")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("POST: %v", err)
+	}
+	requirePayloadTooLarge(t, resp)
+}
@@ -0,0 +1,87 @@
+package srvreg
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/ahmadzakiakmal/thesis-extension/layer-2/api"
+	"github.com/ahmadzakiakmal/thesis-extension/layer-2/repository/models"
+)
+
+// Operator access levels, lowest to highest
+const (
+	AccessBasic    = "Basic"
+	AccessStandard = "Standard"
+	AccessAdmin    = "Admin"
+)
+
+var accessLevelRank = map[string]int{
+	AccessBasic:    1,
+	AccessStandard: 2,
+	AccessAdmin:    3,
+}
+
+// actionAccessLevels is the minimum access level required for each session action
+var actionAccessLevels = map[string]string{
+	"start":    AccessBasic,
+	"scan":     AccessBasic,
+	"validate": AccessBasic,
+	"qc":       AccessStandard,
+	"label":    AccessBasic,
+	"commit":   AccessStandard,
+	"delete":   AccessStandard,
+	"cancel":   AccessAdmin, // retire a session already committed to L1
+}
+
+// hasAccess reports whether an access level meets the required one.
+// Unknown levels never do.
+func hasAccess(level, required string) bool {
+	rank, ok := accessLevelRank[level]
+	return ok && rank >= accessLevelRank[required]
+}
+
+// authorizeOperator checks that an operator may perform an action, returning
+// the error response to send when it may not
+func (sr *ServiceRegistry) authorizeOperator(operatorID, action string) *Response {
+	operator, dbErr := sr.repository.GetOperator(operatorID)
+	if dbErr != nil {
+		// An unknown operator is a permission problem, not a missing resource
+		if dbErr.Code == CodeNotFound {
+			return codedError(CodeForbidden, "Unknown operator "+operatorID)
+		}
+		return codedError(dbErr.Code, "Failed to load operator: "+dbErr.Message)
+	}
+	return checkAccess(operator, action)
+}
+
+// checkAccess returns the 403 response to send when operator's access level
+// is below the one action requires
+func checkAccess(operator *models.Operator, action string) *Response {
+	required := actionAccessLevels[action]
+	if !hasAccess(operator.AccessLevel, required) {
+		return codedError(CodeForbidden,
+			fmt.Sprintf("Operator %s (%s) is not allowed to %s; requires %s", operator.ID, operator.AccessLevel, action, required))
+	}
+	return nil
+}
+
+// actingOperator returns the operator named in api.OperatorHeader, or the 401
+// response to send when there is none
+func actingOperator(req *Request) (string, *Response) {
+	operatorID := strings.TrimSpace(req.Headers[http.CanonicalHeaderKey(api.OperatorHeader)])
+	if operatorID == "" {
+		return "", codedError(CodeUnauthorized, api.OperatorHeader+" header is required")
+	}
+	return operatorID, nil
+}
+
+// authorizeRequest checks that the operator performing req may perform an
+// action, returning the error response to send when it may not
+func (sr *ServiceRegistry) authorizeRequest(req *Request, action string) *Response {
+	operatorID, denied := actingOperator(req)
+	if denied != nil {
+		return denied
+	}
+	return sr.authorizeOperator(operatorID, action)
+}
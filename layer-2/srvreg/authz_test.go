@@ -0,0 +1,226 @@
+package srvreg
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ahmadzakiakmal/thesis-extension/layer-2/api"
+	"github.com/ahmadzakiakmal/thesis-extension/layer-2/repository"
+	"github.com/ahmadzakiakmal/thesis-extension/layer-2/repository/models"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// Operators mirrored by testRegistry, one per access level
+const (
+	testBasic    = "OPR-TEST-BASIC"
+	testStandard = "OPR-TEST-STANDARD"
+	testAdmin    = "OPR-TEST-ADMIN"
+)
+
+// testSeed is the seed file testRegistry mirrors its operators from
+const testSeed = `{"operators": [
+	{"ID": "OPR-TEST-BASIC", "Name": "Test Basic", "AccessLevel": "Basic"},
+	{"ID": "OPR-TEST-STANDARD", "Name": "Test Standard", "AccessLevel": "Standard"},
+	{"ID": "OPR-TEST-ADMIN", "Name": "Test Admin", "AccessLevel": "Admin"}
+]}`
+
+// testRegistry returns a registry backed by the PostgreSQL database named by
+// L2_TEST_DSN, with the test operators mirrored and no L1 client, skipping
+// the test when the variable is unset. The returned handle is for setting up
+// and cleaning up rows the repository has no method for.
+func testRegistry(t *testing.T) (*ServiceRegistry, *gorm.DB) {
+	t.Helper()
+	dsn := os.Getenv("L2_TEST_DSN")
+	if dsn == "" {
+		t.Skip("L2_TEST_DSN is not set")
+	}
+
+	seedFile := filepath.Join(t.TempDir(), "seed.json")
+	if err := os.WriteFile(seedFile, []byte(testSeed), 0o600); err != nil {
+		t.Fatalf("writing seed file: %v", err)
+	}
+	repo := repository.NewRepository()
+	repo.SetSeedConfig(repository.SeedConfig{File: seedFile})
+	if err := repo.ConnectDB(dsn); err != nil {
+		t.Fatalf("connecting to %s: %v", dsn, err)
+	}
+
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("connecting to %s: %v", dsn, err)
+	}
+	return NewServiceRegistry(repo, nil, "shard-test", "group-test"), db
+}
+
+// testSession creates a session deleted when the test ends, committed to L1
+// when committed is set
+func testSession(t *testing.T, sr *ServiceRegistry, db *gorm.DB, committed bool) *models.Session {
+	t.Helper()
+	session, dbErr := sr.repository.CreateSession(testBasic)
+	if dbErr != nil {
+		t.Fatalf("CreateSession: %v", dbErr)
+	}
+	t.Cleanup(func() { db.Delete(&models.Session{}, "session_id = ?", session.ID) })
+
+	if committed {
+		version, dbErr := sr.repository.BeginCommit(session.ID, session.Version)
+		if dbErr != nil {
+			t.Fatalf("BeginCommit: %v", dbErr)
+		}
+		if dbErr := sr.repository.MarkSessionCommitted(session.ID, version, "AB12", 42); dbErr != nil {
+			t.Fatalf("MarkSessionCommitted: %v", dbErr)
+		}
+	}
+	return session
+}
+
+// asOperator returns a request for session acting as operatorID, with no
+// operator header when operatorID is empty
+func asOperator(session *models.Session, operatorID, body string) *Request {
+	req := &Request{
+		Body:    body,
+		Headers: map[string]string{},
+		Params:  map[string]string{"id": session.ID},
+	}
+	if operatorID != "" {
+		req.Headers[http.CanonicalHeaderKey(api.OperatorHeader)] = operatorID
+	}
+	return req
+}
+
+func TestCheckAccess(t *testing.T) {
+	tests := []struct {
+		level   string
+		action  string
+		allowed bool
+	}{
+		{AccessBasic, "scan", true},
+		{AccessBasic, "label", true},
+		{AccessBasic, "qc", false},
+		{AccessBasic, "commit", false},
+		{AccessBasic, "delete", false},
+		{AccessStandard, "qc", true},
+		{AccessStandard, "commit", true},
+		{AccessStandard, "delete", true},
+		{AccessStandard, "cancel", false},
+		{AccessAdmin, "cancel", true},
+		{AccessAdmin, "delete", true},
+		{"Unknown", "scan", false},
+	}
+
+	for _, tt := range tests {
+		operator := &models.Operator{ID: "OPR-TEST", AccessLevel: tt.level}
+		resp := checkAccess(operator, tt.action)
+		if tt.allowed && resp != nil {
+			t.Errorf("%s %s: denied with %d, want allowed", tt.level, tt.action, resp.StatusCode)
+		}
+		if !tt.allowed && (resp == nil || resp.StatusCode != http.StatusForbidden) {
+			t.Errorf("%s %s: got %v, want 403", tt.level, tt.action, resp)
+		}
+	}
+}
+
+func TestActingOperator(t *testing.T) {
+	req := &Request{Headers: map[string]string{"X-Operator-Id": " OPR-002 "}}
+	operatorID, resp := actingOperator(req)
+	if resp != nil || operatorID != "OPR-002" {
+		t.Fatalf("actingOperator = %q, %v; want OPR-002", operatorID, resp)
+	}
+}
+
+func TestActingOperatorMissing(t *testing.T) {
+	for _, headers := range []map[string]string{
+		{},
+		{"X-Operator-Id": "  "},
+	} {
+		_, resp := actingOperator(&Request{Headers: headers})
+		if resp == nil || resp.StatusCode != http.StatusUnauthorized {
+			t.Errorf("headers %v: got %v, want 401", headers, resp)
+		}
+	}
+}
+
+func TestSessionStepAuthorization(t *testing.T) {
+	sr, db := testRegistry(t)
+	session := testSession(t, sr, db, false)
+
+	tests := []struct {
+		name     string
+		handler  HandlerFunc
+		operator string
+		denied   int // 0 when the operator gets past authorization
+	}{
+		{"scan without operator", sr.ScanPackageHandler, "", http.StatusUnauthorized},
+		{"scan by unknown operator", sr.ScanPackageHandler, "OPR-TEST-NOBODY", http.StatusForbidden},
+		{"scan by Basic", sr.ScanPackageHandler, testBasic, 0},
+		{"validate by Basic", sr.ValidatePackageHandler, testBasic, 0},
+		{"qc by Basic", sr.QualityCheckHandler, testBasic, http.StatusForbidden},
+		{"qc by Standard", sr.QualityCheckHandler, testStandard, 0},
+		{"label by Basic", sr.LabelPackageHandler, testBasic, 0},
+		{"commit by Basic", sr.CommitSessionHandler, testBasic, http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		resp, err := tt.handler(asOperator(session, tt.operator, "{}"))
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		switch {
+		case tt.denied != 0 && resp.StatusCode != tt.denied:
+			t.Errorf("%s: got %d, want %d", tt.name, resp.StatusCode, tt.denied)
+		case tt.denied == 0 && (resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden):
+			t.Errorf("%s: denied with %d, want allowed", tt.name, resp.StatusCode)
+		}
+	}
+}
+
+func TestDeleteSessionAuthorization(t *testing.T) {
+	sr, db := testRegistry(t)
+	session := testSession(t, sr, db, false)
+
+	resp, _ := sr.DeleteSessionHandler(asOperator(session, testBasic, ""))
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("delete by Basic: got %d, want 403", resp.StatusCode)
+	}
+	if _, dbErr := sr.repository.GetSession(session.ID); dbErr != nil {
+		t.Fatalf("session gone after a denied delete: %v", dbErr)
+	}
+
+	resp, _ = sr.DeleteSessionHandler(asOperator(session, testStandard, ""))
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("delete by Standard: got %d, want 200", resp.StatusCode)
+	}
+	if _, dbErr := sr.repository.GetSession(session.ID); dbErr == nil || dbErr.Code != CodeNotFound {
+		t.Fatalf("GetSession after delete = %v, want NOT_FOUND", dbErr)
+	}
+}
+
+func TestCancelSessionAuthorization(t *testing.T) {
+	sr, db := testRegistry(t)
+	session := testSession(t, sr, db, true)
+
+	resp, _ := sr.DeleteSessionHandler(asOperator(session, "", ""))
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("cancel without operator: got %d, want 401", resp.StatusCode)
+	}
+	resp, _ = sr.DeleteSessionHandler(asOperator(session, testStandard, ""))
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("cancel by Standard: got %d, want 403", resp.StatusCode)
+	}
+
+	resp, _ = sr.DeleteSessionHandler(asOperator(session, testAdmin, ""))
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("cancel by Admin: got %d, want 200", resp.StatusCode)
+	}
+	cancelled, dbErr := sr.repository.GetSession(session.ID)
+	if dbErr != nil {
+		t.Fatalf("GetSession: %v", dbErr)
+	}
+	if cancelled.Status != repository.SessionCancelled || !cancelled.IsCommitted {
+		t.Fatalf("session = %s (committed %v), want cancelled with its commit kept", cancelled.Status, cancelled.IsCommitted)
+	}
+}
@@ -0,0 +1,106 @@
+package srvreg
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/ahmadzakiakmal/thesis-extension/layer-2/api"
+)
+
+// AdminKeyHeader carries the key that unlocks the backup endpoints
+const AdminKeyHeader = "X-Admin-Key"
+
+// SetAdminKey enables the backup endpoints for requests sending key in
+// AdminKeyHeader. Without a key they answer 404, since an export holds every
+// session on the shard and an import writes to all of them.
+func (sr *ServiceRegistry) SetAdminKey(key string) {
+	sr.adminKey = key
+}
+
+// authorizeAdmin returns the error response to send when req does not carry
+// the admin key
+func (sr *ServiceRegistry) authorizeAdmin(req *Request) *Response {
+	if sr.adminKey == "" {
+		return codedError(CodeNotFound, "Backup endpoints are disabled on this shard")
+	}
+	key := req.Headers[http.CanonicalHeaderKey(AdminKeyHeader)]
+	if subtle.ConstantTimeCompare([]byte(key), []byte(sr.adminKey)) != 1 {
+		return codedError(CodeUnauthorized, "A valid "+AdminKeyHeader+" header is required")
+	}
+	return nil
+}
+
+// ExportSessionsHandler streams every session with its relations as NDJSON,
+// for restoring the shard with POST /sessions/import
+func (sr *ServiceRegistry) ExportSessionsHandler(req *Request) (*Response, error) {
+	if denied := sr.authorizeAdmin(req); denied != nil {
+		return denied, nil
+	}
+
+	return &Response{
+		StatusCode: http.StatusOK,
+		Headers: map[string]string{
+			"Content-Type":        "application/x-ndjson",
+			"Content-Disposition": fmt.Sprintf("attachment; filename=%q", sr.shardID+"-sessions.ndjson"),
+		},
+		Stream: func(w io.Writer) error {
+			count, dbErr := sr.repository.ExportSessions(w)
+			if dbErr != nil {
+				return fmt.Errorf("%s after %d sessions: %s", dbErr.Message, count, dbErr.Detail)
+			}
+			return nil
+		},
+	}, nil
+}
+
+// ImportSessionsHandler recreates sessions from an NDJSON export, skipping
+// sessions that already exist
+func (sr *ServiceRegistry) ImportSessionsHandler(req *Request) (*Response, error) {
+	if denied := sr.authorizeAdmin(req); denied != nil {
+		return denied, nil
+	}
+
+	body := &bodyReader{r: req.BodyReader}
+	if body.r == nil {
+		body.r = strings.NewReader(req.Body)
+	}
+
+	result, dbErr := sr.repository.ImportSessions(body)
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(body.err, &maxBytesErr) {
+		return codedError(CodePayloadTooLarge, fmt.Sprintf("Import exceeds %d bytes (%d sessions imported before the limit)",
+			maxBytesErr.Limit, result.Imported)), nil
+	}
+	if dbErr != nil {
+		return codedError(dbErr.Code, fmt.Sprintf("%s: %s (%d sessions imported before the error)",
+			dbErr.Message, dbErr.Detail, result.Imported)), nil
+	}
+	if result.Imported+result.Skipped == 0 {
+		return codedError(CodeInvalidRequest, "Request body must contain NDJSON sessions"), nil
+	}
+
+	return jsonResponse(http.StatusOK, api.ImportSessionsResponse{
+		Message:  "Sessions imported",
+		Imported: result.Imported,
+		Skipped:  result.Skipped,
+	}), nil
+}
+
+// bodyReader remembers the first error reading a request body, so a body
+// cut off by its size limit can be told apart from a malformed one
+type bodyReader struct {
+	r   io.Reader
+	err error
+}
+
+func (b *bodyReader) Read(p []byte) (int, error) {
+	n, err := b.r.Read(p)
+	if err != nil && err != io.EOF && b.err == nil {
+		b.err = err
+	}
+	return n, err
+}
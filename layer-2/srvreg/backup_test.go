@@ -0,0 +1,63 @@
+package srvreg
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAuthorizeAdmin(t *testing.T) {
+	tests := []struct {
+		name     string
+		adminKey string
+		header   string
+		status   int // 0 when allowed
+	}{
+		{"disabled", "", "secret", http.StatusNotFound},
+		{"missing key", "secret", "", http.StatusUnauthorized},
+		{"wrong key", "secret", "guess", http.StatusUnauthorized},
+		{"valid key", "secret", "secret", 0},
+	}
+
+	for _, tt := range tests {
+		sr := &ServiceRegistry{adminKey: tt.adminKey}
+		req := &Request{Headers: map[string]string{}}
+		if tt.header != "" {
+			req.Headers["X-Admin-Key"] = tt.header
+		}
+
+		resp := sr.authorizeAdmin(req)
+		switch {
+		case tt.status == 0 && resp != nil:
+			t.Errorf("%s: denied with %d, want allowed", tt.name, resp.StatusCode)
+		case tt.status != 0 && (resp == nil || resp.StatusCode != tt.status):
+			t.Errorf("%s: got %v, want %d", tt.name, resp, tt.status)
+		}
+	}
+}
+
+func TestBodyReaderKeepsLimitError(t *testing.T) {
+	limited := http.MaxBytesReader(httptest.NewRecorder(), io.NopCloser(strings.NewReader("0123456789")), 4)
+	body := &bodyReader{r: limited}
+
+	if _, err := io.ReadAll(body); err == nil {
+		t.Fatal("reading past the limit succeeded")
+	}
+	var maxBytesErr *http.MaxBytesError
+	if !errors.As(body.err, &maxBytesErr) || maxBytesErr.Limit != 4 {
+		t.Fatalf("err = %v, want a MaxBytesError with limit 4", body.err)
+	}
+}
+
+func TestBodyReaderIgnoresEOF(t *testing.T) {
+	body := &bodyReader{r: strings.NewReader("{}\n")}
+	if _, err := io.ReadAll(body); err != nil {
+		t.Fatalf("ReadAll: %v", err)
+	}
+	if body.err != nil {
+		t.Fatalf("err = %v after a clean read, want nil", body.err)
+	}
+}
@@ -0,0 +1,88 @@
+package srvreg
+
+import (
+	"net/http"
+
+	"github.com/ahmadzakiakmal/thesis-extension/layer-2/api"
+)
+
+// ListPackagesHandler returns the packages known to this shard
+func (sr *ServiceRegistry) ListPackagesHandler(req *Request) (*Response, error) {
+	packages, dbErr := sr.repository.ListPackages()
+	if dbErr != nil {
+		return repositoryError(dbErr), nil
+	}
+
+	catalog := make([]api.CatalogPackage, 0, len(packages))
+	for _, pkg := range packages {
+		items := []api.PackageItem{}
+		for _, item := range pkg.Items {
+			items = append(items, api.PackageItem{
+				ItemID:      item.ID,
+				Description: item.Description,
+				Quantity:    item.Quantity,
+			})
+		}
+
+		supplierName := "Unknown"
+		if pkg.Supplier != nil {
+			supplierName = pkg.Supplier.Name
+		}
+
+		catalog = append(catalog, api.CatalogPackage{
+			PackageID:  pkg.ID,
+			SupplierID: pkg.SupplierID,
+			Supplier:   supplierName,
+			Status:     pkg.Status,
+			IsTrusted:  pkg.IsTrusted,
+			Items:      items,
+		})
+	}
+
+	return jsonResponse(http.StatusOK, api.PackagesResponse{Packages: catalog, Count: len(catalog)}), nil
+}
+
+// ListCouriersHandler returns the couriers known to this shard
+func (sr *ServiceRegistry) ListCouriersHandler(req *Request) (*Response, error) {
+	couriers, dbErr := sr.repository.ListCouriers()
+	if dbErr != nil {
+		return repositoryError(dbErr), nil
+	}
+
+	assignments := sr.repository.CourierAssignments()
+	catalog := make([]api.CatalogCourier, 0, len(couriers))
+	for _, courier := range couriers {
+		catalog = append(catalog, api.CatalogCourier{
+			CourierID:   courier.ID,
+			Name:        courier.Name,
+			Assignments: assignments[courier.ID],
+
+			TrackingFormat: courier.TrackingFormat,
+		})
+	}
+
+	return jsonResponse(http.StatusOK, api.CouriersResponse{
+		Couriers: catalog,
+		Count:    len(catalog),
+		Strategy: sr.repository.CourierStrategy(),
+	}), nil
+}
+
+// ListSuppliersHandler returns the suppliers known to this shard
+func (sr *ServiceRegistry) ListSuppliersHandler(req *Request) (*Response, error) {
+	suppliers, dbErr := sr.repository.ListSuppliers()
+	if dbErr != nil {
+		return repositoryError(dbErr), nil
+	}
+
+	catalog := make([]api.CatalogSupplier, 0, len(suppliers))
+	for _, supplier := range suppliers {
+		catalog = append(catalog, api.CatalogSupplier{
+			SupplierID: supplier.ID,
+			Name:       supplier.Name,
+			Country:    supplier.Country,
+		})
+	}
+
+	return jsonResponse(http.StatusOK, api.SuppliersResponse{Suppliers: catalog, Count: len(catalog)}), nil
+}
@@ -0,0 +1,220 @@
+package srvreg
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ahmadzakiakmal/thesis-extension/layer-2/api"
+	"github.com/ahmadzakiakmal/thesis-extension/layer-2/l1client"
+	"github.com/ahmadzakiakmal/thesis-extension/layer-2/repository"
+	"github.com/ahmadzakiakmal/thesis-extension/layer-2/repository/models"
+	"github.com/ahmadzakiakmal/thesis-extension/layer-2/webhook"
+	"gorm.io/gorm"
+)
+
+// testCallbackURL is the callback_url commit tests ask to be notified at.
+// Callbacks are only queued, never delivered.
+const testCallbackURL = "http://integrator.test/commits"
+
+// fakeL1 answers commits with commitStatus. A 409 is backed by a stored
+// session committed by heldBy, which L2 then looks up.
+func fakeL1(t *testing.T, commitStatus int, heldBy string) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/l1/commit", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(commitStatus)
+		if commitStatus != http.StatusOK {
+			fmt.Fprint(w, `{"data":{"error":"commit rejected"}}`)
+			return
+		}
+		fmt.Fprint(w, `{"data":{"tx_hash":"AB12"},"meta":{"tx_id":"tx-1","status":"confirmed","block_height":42,"votes":3}}`)
+	})
+	mux.HandleFunc("/l1/sessions/", func(w http.ResponseWriter, r *http.Request) {
+		sessionID := strings.TrimPrefix(r.URL.Path, "/l1/sessions/")
+		fmt.Fprintf(w, `{"data":{"ID":%q,"ShardID":%q,"ClientGroup":"group-test","Transaction":{
+			"TxHash":"CD34","TxID":"tx-2","BlockHeight":41,"Status":"confirmed"}}}`, sessionID, heldBy)
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+// commitRegistry returns a testRegistry committing to l1 and queuing commit
+// callbacks
+func commitRegistry(t *testing.T, l1 *httptest.Server) (*ServiceRegistry, *gorm.DB) {
+	t.Helper()
+	sr, db := testRegistry(t)
+	sr.l1Client = l1client.NewL1Client(l1.URL, "shard-test", "node-test")
+	sr.SetCallbackDispatcher(webhook.NewDispatcher(sr.repository, "secret", 1))
+	return sr, db
+}
+
+// completedSession creates a session that has been through every step and
+// is ready to commit
+func completedSession(t *testing.T, sr *ServiceRegistry, db *gorm.DB) *models.Session {
+	t.Helper()
+	session := testSession(t, sr, db, false)
+	t.Cleanup(func() { db.Delete(&models.CommitCallback{}, "session_id = ?", session.ID) })
+	if err := db.Model(&models.Session{}).Where("session_id = ?", session.ID).Update("status", "completed").Error; err != nil {
+		t.Fatalf("completing session: %v", err)
+	}
+	return session
+}
+
+// queuedCallbacks returns the callbacks queued for sessionID, oldest first
+func queuedCallbacks(t *testing.T, db *gorm.DB, sessionID string) []webhook.CommitStatus {
+	t.Helper()
+	var rows []models.CommitCallback
+	if err := db.Where("session_id = ?", sessionID).Order("callback_id").Find(&rows).Error; err != nil {
+		t.Fatalf("loading callbacks: %v", err)
+	}
+	statuses := make([]webhook.CommitStatus, len(rows))
+	for i, row := range rows {
+		if row.URL != testCallbackURL {
+			t.Errorf("callback URL = %q, want %q", row.URL, testCallbackURL)
+		}
+		if err := json.Unmarshal([]byte(row.Payload), &statuses[i]); err != nil {
+			t.Fatalf("decoding callback payload: %v", err)
+		}
+	}
+	return statuses
+}
+
+// requireErrorCode fails the test unless resp is an error body with code
+func requireErrorCode(t *testing.T, resp *Response, code string) {
+	t.Helper()
+	var body api.ErrorResponse
+	if err := json.Unmarshal([]byte(resp.Body), &body); err != nil {
+		t.Fatalf("decoding error body %q: %v", resp.Body, err)
+	}
+	if body.Code != code {
+		t.Errorf("error code = %s (%s), want %s", body.Code, body.Error, code)
+	}
+}
+
+func TestCommitCallbackOnSuccess(t *testing.T) {
+	sr, db := commitRegistry(t, fakeL1(t, http.StatusOK, ""))
+	session := completedSession(t, sr, db)
+
+	resp, _ := sr.CommitSessionHandler(asOperator(session, testStandard, `{"callback_url":"`+testCallbackURL+`"}`))
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("commit = %d %s, want 200", resp.StatusCode, resp.Body)
+	}
+
+	callbacks := queuedCallbacks(t, db, session.ID)
+	if len(callbacks) != 1 {
+		t.Fatalf("queued %d callbacks, want 1", len(callbacks))
+	}
+	got := callbacks[0]
+	if got.Status != "committed" || got.TxHash != "AB12" || got.BlockHeight != 42 || got.ShardID != "shard-test" {
+		t.Errorf("callback = %+v, want committed AB12 at 42", got)
+	}
+}
+
+func TestCommitCallbackOnFailure(t *testing.T) {
+	tests := []struct {
+		name       string
+		l1Status   int
+		heldBy     string
+		wantStatus int
+		wantCode   string
+	}{
+		{"L1 error", http.StatusInternalServerError, "", http.StatusBadGateway, CodeL1CommitFailed},
+		{"committed by another shard", http.StatusConflict, "shard-other", http.StatusConflict, CodeConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sr, db := commitRegistry(t, fakeL1(t, tt.l1Status, tt.heldBy))
+			session := completedSession(t, sr, db)
+
+			resp, _ := sr.CommitSessionHandler(asOperator(session, testStandard, `{"callback_url":"`+testCallbackURL+`"}`))
+			if resp.StatusCode != tt.wantStatus {
+				t.Fatalf("commit = %d %s, want %d", resp.StatusCode, resp.Body, tt.wantStatus)
+			}
+			requireErrorCode(t, resp, tt.wantCode)
+
+			callbacks := queuedCallbacks(t, db, session.ID)
+			if len(callbacks) != 1 {
+				t.Fatalf("queued %d callbacks, want 1", len(callbacks))
+			}
+			if got := callbacks[0]; got.Status != "failed" || got.Error == "" || got.TxHash != "" {
+				t.Errorf("callback = %+v, want failed with an error", got)
+			}
+
+			stored, dbErr := sr.repository.GetSession(session.ID)
+			if dbErr != nil {
+				t.Fatalf("GetSession: %v", dbErr)
+			}
+			if stored.Status != "completed" || stored.IsCommitted {
+				t.Errorf("session status = %s committed=%t, want completed and uncommitted", stored.Status, stored.IsCommitted)
+			}
+		})
+	}
+}
+
+func TestRecommitSession(t *testing.T) {
+	for _, status := range []string{"completed", repository.SessionCommitting} {
+		t.Run(status, func(t *testing.T) {
+			sr, db := commitRegistry(t, fakeL1(t, http.StatusOK, ""))
+			session := completedSession(t, sr, db)
+			if err := db.Model(&models.Session{}).Where("session_id = ?", session.ID).Update("status", status).Error; err != nil {
+				t.Fatalf("setting session status: %v", err)
+			}
+
+			resp, _ := sr.RecommitSessionHandler(asOperator(session, testStandard, ""))
+			if resp.StatusCode != http.StatusOK {
+				t.Fatalf("recommit = %d %s, want 200", resp.StatusCode, resp.Body)
+			}
+			var body api.CommitSessionResponse
+			if err := json.Unmarshal([]byte(resp.Body), &body); err != nil {
+				t.Fatalf("decoding response: %v", err)
+			}
+			if body.TxHash != "AB12" || body.BlockHeight != 42 || body.Status != "committed" {
+				t.Errorf("response = %+v, want committed AB12 at 42", body)
+			}
+
+			stored, dbErr := sr.repository.GetSession(session.ID)
+			if dbErr != nil {
+				t.Fatalf("GetSession: %v", dbErr)
+			}
+			if !stored.IsCommitted || stored.L1TxHash == nil || *stored.L1TxHash != "AB12" {
+				t.Errorf("session = %+v, want committed with AB12", stored)
+			}
+		})
+	}
+}
+
+func TestRecommitSessionAlreadyCommitted(t *testing.T) {
+	sr, db := commitRegistry(t, fakeL1(t, http.StatusOK, ""))
+	session := testSession(t, sr, db, true)
+
+	resp, _ := sr.RecommitSessionHandler(asOperator(session, testStandard, ""))
+	if resp.StatusCode != http.StatusConflict {
+		t.Fatalf("recommit = %d %s, want 409", resp.StatusCode, resp.Body)
+	}
+	requireErrorCode(t, resp, CodeAlreadyCommitted)
+}
+
+func TestRecommitSessionNotCompleted(t *testing.T) {
+	sr, db := commitRegistry(t, fakeL1(t, http.StatusOK, ""))
+	session := testSession(t, sr, db, false)
+
+	resp, _ := sr.RecommitSessionHandler(asOperator(session, testStandard, ""))
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("recommit = %d %s, want 400", resp.StatusCode, resp.Body)
+	}
+	requireErrorCode(t, resp, CodeInvalidState)
+
+	stored, dbErr := sr.repository.GetSession(session.ID)
+	if dbErr != nil {
+		t.Fatalf("GetSession: %v", dbErr)
+	}
+	if stored.IsCommitted || stored.Status != session.Status {
+		t.Errorf("session status = %s committed=%t, want it untouched", stored.Status, stored.IsCommitted)
+	}
+}
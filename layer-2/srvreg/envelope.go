@@ -0,0 +1,44 @@
+package srvreg
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/ahmadzakiakmal/thesis-extension/layer-2/api"
+)
+
+// Envelope wraps a JSON response in an api.L2Response answered by shardID.
+// Non-JSON responses such as the NDJSON export, and responses that are
+// already wrapped, e.g. forwarded from another shard, are returned as is.
+func Envelope(resp *Response, shardID string, now time.Time) *Response {
+	if resp.Headers[api.EnvelopeHeader] != "" || resp.Headers["Content-Type"] != "application/json" {
+		return resp
+	}
+	body := json.RawMessage(resp.Body)
+	if !json.Valid(body) {
+		return resp
+	}
+
+	envelope := api.L2Response{ShardID: shardID, Timestamp: now.UTC()}
+	var errResp api.ErrorResponse
+	if resp.StatusCode >= 400 && json.Unmarshal(body, &errResp) == nil && errResp.Error != "" {
+		envelope.Error = &errResp
+	} else {
+		envelope.Data = body
+	}
+
+	wrapped, err := json.Marshal(envelope)
+	if err != nil {
+		return resp
+	}
+	headers := make(map[string]string, len(resp.Headers)+1)
+	for key, value := range resp.Headers {
+		headers[key] = value
+	}
+	headers[api.EnvelopeHeader] = "1"
+	return &Response{
+		StatusCode: resp.StatusCode,
+		Headers:    headers,
+		Body:       string(wrapped),
+	}
+}
@@ -0,0 +1,61 @@
+package srvreg
+
+import (
+	"net/http"
+
+	"github.com/ahmadzakiakmal/thesis-extension/layer-2/api"
+	"github.com/ahmadzakiakmal/thesis-extension/layer-2/repository"
+)
+
+// Error codes produced by the HTTP layer. Repository errors carry their own
+// codes (NOT_FOUND, CONFLICT, DATABASE_ERROR, ...) which are passed through.
+const (
+	CodeInvalidRequest   = "INVALID_REQUEST"
+	CodeUnauthorized     = "UNAUTHORIZED"
+	CodeForbidden        = "FORBIDDEN"
+	CodeNotFound         = "NOT_FOUND"
+	CodeMethodNotAllowed = "METHOD_NOT_ALLOWED"
+	CodePayloadTooLarge  = "PAYLOAD_TOO_LARGE"
+	CodeConflict         = "CONFLICT"
+	CodeAlreadyCommitted = "ALREADY_COMMITTED"
+	CodeInvalidState     = "INVALID_STATE"
+	CodeL1CommitFailed   = "L1_COMMIT_FAILED"
+	CodeNoCourier        = "NO_COURIER_AVAILABLE"
+	CodeOverloaded       = "OVERLOADED"
+	CodeInternal         = "INTERNAL_ERROR"
+)
+
+// errorStatuses maps error codes to HTTP statuses. Codes missing here, such
+// as DATABASE_ERROR or UPDATE_FAILED, are server errors.
+var errorStatuses = map[string]int{
+	CodeInvalidRequest:   http.StatusBadRequest,
+	CodeUnauthorized:     http.StatusUnauthorized,
+	CodeForbidden:        http.StatusForbidden,
+	CodeNotFound:         http.StatusNotFound,
+	CodeMethodNotAllowed: http.StatusMethodNotAllowed,
+	CodePayloadTooLarge:  http.StatusRequestEntityTooLarge,
+	CodeConflict:         http.StatusConflict,
+	CodeAlreadyCommitted: http.StatusConflict,
+	CodeInvalidState:     http.StatusBadRequest,
+	CodeL1CommitFailed:   http.StatusBadGateway,
+	CodeNoCourier:        http.StatusServiceUnavailable,
+	CodeOverloaded:       http.StatusServiceUnavailable,
+}
+
+// ErrorStatus returns the HTTP status for an error code
+func ErrorStatus(code string) int {
+	if status, ok := errorStatuses[code]; ok {
+		return status
+	}
+	return http.StatusInternalServerError
+}
+
+// codedError builds a JSON error response whose status follows from code
+func codedError(code, message string) *Response {
+	return jsonResponse(ErrorStatus(code), api.ErrorResponse{Error: message, Code: code})
+}
+
+// repositoryError builds the error response for a repository error
+func repositoryError(dbErr *repository.RepositoryError) *Response {
+	return codedError(dbErr.Code, dbErr.Message)
+}
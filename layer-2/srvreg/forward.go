@@ -0,0 +1,70 @@
+package srvreg
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// ForwardTimeouts bounds requests forwarded to another shard by what they
+// do: reads should answer quickly, while a commit waits for L1 consensus
+type ForwardTimeouts struct {
+	Read   time.Duration // GET and HEAD
+	Write  time.Duration // other methods
+	Commit time.Duration // routes in consensusRoutes
+}
+
+// DefaultForwardTimeouts keeps the previous 30s bound for commits only
+var DefaultForwardTimeouts = ForwardTimeouts{
+	Read:   5 * time.Second,
+	Write:  15 * time.Second,
+	Commit: 30 * time.Second,
+}
+
+// consensusRoutes are the forwarded routes that wait for L1 consensus
+var consensusRoutes = []struct {
+	method  string
+	pattern pathPattern
+}{
+	{"POST", compilePattern("/session/:id/commit")},
+	{"POST", compilePattern("/session/:id/recommit")},
+}
+
+// forwardClient is shared by all forwards so connections to the other
+// shards are reused; each forward sets its own deadline
+var forwardClient = &http.Client{}
+
+// Validate rejects non-positive timeouts
+func (t ForwardTimeouts) Validate() error {
+	if t.Read <= 0 || t.Write <= 0 || t.Commit <= 0 {
+		return fmt.Errorf("forward timeouts must be positive")
+	}
+	return nil
+}
+
+// For returns the timeout for forwarding method and path
+func (t ForwardTimeouts) For(method, path string) time.Duration {
+	segments := splitPath(normalizePath(path))
+	for _, route := range consensusRoutes {
+		if route.method != method {
+			continue
+		}
+		if _, ok := route.pattern.match(segments); ok {
+			return t.Commit
+		}
+	}
+	if method == http.MethodGet || method == http.MethodHead {
+		return t.Read
+	}
+	return t.Write
+}
+
+// SetForwardTimeouts sets the timeouts applied when forwarding requests to
+// the shard owning their client group
+func (sr *ServiceRegistry) SetForwardTimeouts(timeouts ForwardTimeouts) error {
+	if err := timeouts.Validate(); err != nil {
+		return err
+	}
+	sr.forwardTimeouts = timeouts
+	return nil
+}
@@ -2,125 +2,189 @@ package srvreg
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
 	"strings"
+	"time"
+
+	"github.com/ahmadzakiakmal/thesis-extension/layer-2/api"
+	"github.com/ahmadzakiakmal/thesis-extension/layer-2/l1client"
+	"github.com/ahmadzakiakmal/thesis-extension/layer-2/repository"
+	"github.com/ahmadzakiakmal/thesis-extension/layer-2/tracing"
+	"github.com/ahmadzakiakmal/thesis-extension/layer-2/webhook"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // InfoHandler returns shard information
 func (sr *ServiceRegistry) InfoHandler(req *Request) (*Response, error) {
-	info := map[string]interface{}{
-		"shard_id":     sr.shardID,
-		"client_group": sr.clientGroup,
-		"type":         "L2 Shard Node",
-		"status":       "active",
+	return jsonResponse(http.StatusOK, api.InfoResponse{
+		ShardID:     sr.shardID,
+		ClientGroup: sr.clientGroup,
+		Type:        "L2 Shard Node",
+		Status:      "active",
+	}), nil
+}
+
+// HealthzHandler reports that the process is alive
+func (sr *ServiceRegistry) HealthzHandler(req *Request) (*Response, error) {
+	return jsonResponse(http.StatusOK, api.HealthResponse{Status: "ok"}), nil
+}
+
+// ReadyzHandler reports whether the shard can serve traffic, which requires
+// both its database and L1 to be reachable
+func (sr *ServiceRegistry) ReadyzHandler(req *Request) (*Response, error) {
+	checks := map[string]string{
+		"database": "ok",
+		"l1":       "ok",
 	}
+	ready := true
 
-	body, _ := json.Marshal(info)
+	if err := sr.repository.Ping(); err != nil {
+		checks["database"] = err.Error()
+		ready = false
+	}
+	if err := sr.l1Client.HealthCheck(); err != nil {
+		checks["l1"] = err.Error()
+		ready = false
+	}
 
-	return &Response{
-		StatusCode: http.StatusOK,
-		Headers:    defaultHeaders,
-		Body:       string(body),
-	}, nil
+	if !ready {
+		return jsonResponse(http.StatusServiceUnavailable, api.HealthResponse{Status: "not_ready", Checks: checks}), nil
+	}
+	return jsonResponse(http.StatusOK, api.HealthResponse{Status: "ready", Checks: checks}), nil
 }
 
 // CreateSessionHandler creates a new session
 func (sr *ServiceRegistry) CreateSessionHandler(req *Request) (*Response, error) {
-	var body struct {
-		OperatorID string `json:"operator_id"`
-	}
+	var body api.CreateSessionRequest
 
 	if err := json.Unmarshal([]byte(req.Body), &body); err != nil {
-		return &Response{
-			StatusCode: http.StatusBadRequest,
-			Headers:    defaultHeaders,
-			Body:       fmt.Sprintf(`{"error":"Invalid request body: %s"}`, err.Error()),
-		}, nil
+		return codedError(CodeInvalidRequest, "Invalid request body: "+err.Error()), nil
 	}
 
 	if body.OperatorID == "" {
-		return &Response{
-			StatusCode: http.StatusBadRequest,
-			Headers:    defaultHeaders,
-			Body:       `{"error":"operator_id is required"}`,
-		}, nil
+		return codedError(CodeInvalidRequest, "operator_id is required"), nil
+	}
+
+	if denied := sr.authorizeOperator(body.OperatorID, "start"); denied != nil {
+		return denied, nil
 	}
 
 	session, dbErr := sr.repository.CreateSession(body.OperatorID)
 	if dbErr != nil {
-		return &Response{
-			StatusCode: http.StatusInternalServerError,
-			Headers:    defaultHeaders,
-			Body:       fmt.Sprintf(`{"error":"Failed to create session: %s"}`, dbErr.Message),
-		}, nil
-	}
-
-	return &Response{
-		StatusCode: http.StatusCreated,
-		Headers:    defaultHeaders,
-		Body: fmt.Sprintf(`{
-			"message":"Session created successfully",
-			"session_id":"%s",
-			"operator_id":"%s",
-			"status":"%s",
-			"shard_id":"%s"
-		}`, session.ID, session.OperatorID, session.Status, sr.shardID),
-	}, nil
+		return codedError(dbErr.Code, "Failed to create session: "+dbErr.Message), nil
+	}
+	sessionsCreated.Inc()
+
+	return jsonResponse(http.StatusCreated, api.CreateSessionResponse{
+		Message:    "Session created successfully",
+		SessionID:  session.ID,
+		OperatorID: session.OperatorID,
+		Status:     session.Status,
+		ShardID:    sr.shardID,
+	}), nil
+}
+
+// maxBatchSessions caps how many sessions one batch start may create
+const maxBatchSessions = 100
+
+// CreateSessionsHandler creates several sessions in one request
+func (sr *ServiceRegistry) CreateSessionsHandler(req *Request) (*Response, error) {
+	var body api.CreateSessionsRequest
+
+	if err := json.Unmarshal([]byte(req.Body), &body); err != nil {
+		return codedError(CodeInvalidRequest, "Invalid request body: "+err.Error()), nil
+	}
+
+	operatorIDs := body.OperatorIDs
+	switch {
+	case len(operatorIDs) > 0 && (body.OperatorID != "" || body.Count != 0):
+		return codedError(CodeInvalidRequest, "Use either operator_id with count or operator_ids, not both"), nil
+	case len(operatorIDs) == 0:
+		if body.OperatorID == "" || body.Count <= 0 {
+			return codedError(CodeInvalidRequest, "operator_id and a positive count, or operator_ids, are required"), nil
+		}
+		if body.Count > maxBatchSessions {
+			return codedError(CodeInvalidRequest, fmt.Sprintf("count must not exceed %d", maxBatchSessions)), nil
+		}
+		operatorIDs = make([]string, body.Count)
+		for i := range operatorIDs {
+			operatorIDs[i] = body.OperatorID
+		}
+	}
+
+	if len(operatorIDs) > maxBatchSessions {
+		return codedError(CodeInvalidRequest, fmt.Sprintf("operator_ids must not exceed %d entries", maxBatchSessions)), nil
+	}
+
+	// Authorize each distinct operator once
+	checked := make(map[string]bool)
+	for _, operatorID := range operatorIDs {
+		if operatorID == "" {
+			return codedError(CodeInvalidRequest, "operator_ids must not contain empty entries"), nil
+		}
+		if checked[operatorID] {
+			continue
+		}
+		if denied := sr.authorizeOperator(operatorID, "start"); denied != nil {
+			return denied, nil
+		}
+		checked[operatorID] = true
+	}
+
+	sessions, dbErr := sr.repository.CreateSessions(operatorIDs)
+	if dbErr != nil {
+		return codedError(dbErr.Code, "Failed to create sessions: "+dbErr.Message), nil
+	}
+	sessionsCreated.Add(float64(len(sessions)))
+
+	sessionIDs := make([]string, 0, len(sessions))
+	for _, session := range sessions {
+		sessionIDs = append(sessionIDs, session.ID)
+	}
+
+	return jsonResponse(http.StatusCreated, api.CreateSessionsResponse{
+		Message:    "Sessions created successfully",
+		SessionIDs: sessionIDs,
+		Count:      len(sessionIDs),
+		ShardID:    sr.shardID,
+	}), nil
 }
 
 // ScanPackageHandler scans a package
 func (sr *ServiceRegistry) ScanPackageHandler(req *Request) (*Response, error) {
-	pathParts := strings.Split(req.Path, "/")
-	if len(pathParts) != 4 {
-		return &Response{
-			StatusCode: http.StatusBadRequest,
-			Headers:    defaultHeaders,
-			Body:       `{"error":"Invalid path format"}`,
-		}, nil
-	}
-	sessionID := pathParts[2]
+	sessionID := req.Params["id"]
 
-	var body struct {
-		PackageID string `json:"package_id"`
+	if denied := sr.authorizeRequest(req, "scan"); denied != nil {
+		return denied, nil
 	}
 
+	var body api.ScanPackageRequest
+
 	if err := json.Unmarshal([]byte(req.Body), &body); err != nil {
-		return &Response{
-			StatusCode: http.StatusBadRequest,
-			Headers:    defaultHeaders,
-			Body:       fmt.Sprintf(`{"error":"Invalid request body: %s"}`, err.Error()),
-		}, nil
+		return codedError(CodeInvalidRequest, "Invalid request body: "+err.Error()), nil
 	}
 
 	if body.PackageID == "" {
-		return &Response{
-			StatusCode: http.StatusBadRequest,
-			Headers:    defaultHeaders,
-			Body:       `{"error":"package_id is required"}`,
-		}, nil
+		return codedError(CodeInvalidRequest, "package_id is required"), nil
 	}
 
 	pkg, dbErr := sr.repository.ScanPackage(sessionID, body.PackageID)
 	if dbErr != nil {
-		statusCode := http.StatusInternalServerError
-		if dbErr.Code == "NOT_FOUND" {
-			statusCode = http.StatusNotFound
-		}
-		return &Response{
-			StatusCode: statusCode,
-			Headers:    defaultHeaders,
-			Body:       fmt.Sprintf(`{"error":"%s"}`, dbErr.Message),
-		}, nil
+		return repositoryError(dbErr), nil
 	}
 
 	// Format items
-	items := []map[string]interface{}{}
+	items := []api.PackageItem{}
 	for _, item := range pkg.Items {
-		items = append(items, map[string]interface{}{
-			"item_id":     item.ID,
-			"description": item.Description,
-			"quantity":    item.Quantity,
+		items = append(items, api.PackageItem{
+			ItemID:      item.ID,
+			Description: item.Description,
+			Quantity:    item.Quantity,
 		})
 	}
 
@@ -129,69 +193,38 @@ func (sr *ServiceRegistry) ScanPackageHandler(req *Request) (*Response, error) {
 		supplierName = pkg.Supplier.Name
 	}
 
-	response := map[string]interface{}{
-		"message":            "Package scanned successfully",
-		"package_id":         pkg.ID,
-		"supplier":           supplierName,
-		"expected_contents":  items,
-		"supplier_signature": pkg.Signature,
-		"status":             pkg.Status,
-		"next_step":          "validate",
-	}
-
-	body_bytes, _ := json.Marshal(response)
-
-	return &Response{
-		StatusCode: http.StatusOK,
-		Headers:    defaultHeaders,
-		Body:       string(body_bytes),
-	}, nil
+	return jsonResponse(http.StatusOK, api.ScanPackageResponse{
+		Message:           "Package scanned successfully",
+		PackageID:         pkg.ID,
+		Supplier:          supplierName,
+		ExpectedContents:  items,
+		SupplierSignature: pkg.Signature,
+		Status:            pkg.Status,
+		NextStep:          "validate",
+	}), nil
 }
 
 // ValidatePackageHandler validates package signature
 func (sr *ServiceRegistry) ValidatePackageHandler(req *Request) (*Response, error) {
-	pathParts := strings.Split(req.Path, "/")
-	if len(pathParts) != 4 {
-		return &Response{
-			StatusCode: http.StatusBadRequest,
-			Headers:    defaultHeaders,
-			Body:       `{"error":"Invalid path format"}`,
-		}, nil
-	}
-	sessionID := pathParts[2]
+	sessionID := req.Params["id"]
 
-	var body struct {
-		Signature string `json:"signature"`
-		PackageID string `json:"package_id"`
+	if denied := sr.authorizeRequest(req, "validate"); denied != nil {
+		return denied, nil
 	}
 
+	var body api.ValidatePackageRequest
+
 	if err := json.Unmarshal([]byte(req.Body), &body); err != nil {
-		return &Response{
-			StatusCode: http.StatusBadRequest,
-			Headers:    defaultHeaders,
-			Body:       fmt.Sprintf(`{"error":"Invalid request body: %s"}`, err.Error()),
-		}, nil
+		return codedError(CodeInvalidRequest, "Invalid request body: "+err.Error()), nil
 	}
 
 	if body.Signature == "" || body.PackageID == "" {
-		return &Response{
-			StatusCode: http.StatusBadRequest,
-			Headers:    defaultHeaders,
-			Body:       `{"error":"signature and package_id are required"}`,
-		}, nil
+		return codedError(CodeInvalidRequest, "signature and package_id are required"), nil
 	}
 
 	pkg, dbErr := sr.repository.ValidatePackage(body.Signature, body.PackageID, sessionID)
 	if dbErr != nil {
-		statusCode := http.StatusInternalServerError
-		if dbErr.Code == "NOT_FOUND" {
-			statusCode = http.StatusNotFound
-		}
-		return &Response{
-			StatusCode: statusCode,
-			Headers:    defaultHeaders,
-			Body:       fmt.Sprintf(`{"error":"%s"}`, dbErr.Message),
-		}, nil
+		return repositoryError(dbErr), nil
 	}
 
 	supplierName := "Unknown"
@@ -199,115 +232,114 @@ func (sr *ServiceRegistry) ValidatePackageHandler(req *Request) (*Response, erro
 		supplierName = pkg.Supplier.Name
 	}
 
-	return &Response{
-		StatusCode: http.StatusOK,
-		Headers:    defaultHeaders,
-		Body: fmt.Sprintf(`{
-			"message":"Package validated successfully",
-			"package_id":"%s",
-			"supplier":"%s",
-			"is_trusted":%t,
-			"status":"%s",
-			"next_step":"qc"
-		}`, pkg.ID, supplierName, pkg.IsTrusted, pkg.Status),
-	}, nil
+	return jsonResponse(http.StatusOK, api.ValidatePackageResponse{
+		Message:   "Package validated successfully",
+		PackageID: pkg.ID,
+		Supplier:  supplierName,
+		IsTrusted: pkg.IsTrusted,
+		Status:    pkg.Status,
+		NextStep:  "qc",
+	}), nil
 }
 
 // QualityCheckHandler performs quality check
 func (sr *ServiceRegistry) QualityCheckHandler(req *Request) (*Response, error) {
-	pathParts := strings.Split(req.Path, "/")
-	if len(pathParts) != 4 {
-		return &Response{
-			StatusCode: http.StatusBadRequest,
-			Headers:    defaultHeaders,
-			Body:       `{"error":"Invalid path format"}`,
-		}, nil
-	}
-	sessionID := pathParts[2]
+	sessionID := req.Params["id"]
 
-	var body struct {
-		Passed bool     `json:"passed"`
-		Issues []string `json:"issues"`
+	if denied := sr.authorizeRequest(req, "qc"); denied != nil {
+		return denied, nil
 	}
 
+	var body api.QualityCheckRequest
+
 	if err := json.Unmarshal([]byte(req.Body), &body); err != nil {
-		return &Response{
-			StatusCode: http.StatusBadRequest,
-			Headers:    defaultHeaders,
-			Body:       fmt.Sprintf(`{"error":"Invalid request body: %s"}`, err.Error()),
-		}, nil
+		return codedError(CodeInvalidRequest, "Invalid request body: "+err.Error()), nil
 	}
 
 	pkg, qcRecord, dbErr := sr.repository.QualityCheck(sessionID, body.Passed, body.Issues)
 	if dbErr != nil {
-		statusCode := http.StatusInternalServerError
-		if dbErr.Code == "NOT_FOUND" {
-			statusCode = http.StatusNotFound
-		}
-		return &Response{
-			StatusCode: statusCode,
-			Headers:    defaultHeaders,
-			Body:       fmt.Sprintf(`{"error":"%s"}`, dbErr.Message),
-		}, nil
-	}
-
-	return &Response{
-		StatusCode: http.StatusOK,
-		Headers:    defaultHeaders,
-		Body: fmt.Sprintf(`{
-			"message":"Quality check completed",
-			"qc_id":"%s",
-			"passed":%t,
-			"package_id":"%s",
-			"status":"%s",
-			"next_step":"label"
-		}`, qcRecord.ID, qcRecord.Passed, pkg.ID, pkg.Status),
-	}, nil
+		return repositoryError(dbErr), nil
+	}
+
+	return jsonResponse(http.StatusOK, api.QualityCheckResponse{
+		Message:   "Quality check completed",
+		QCID:      qcRecord.ID,
+		Passed:    qcRecord.Passed,
+		PackageID: pkg.ID,
+		Status:    pkg.Status,
+		NextStep:  "label",
+	}), nil
 }
 
 // LabelPackageHandler creates shipping label
 func (sr *ServiceRegistry) LabelPackageHandler(req *Request) (*Response, error) {
-	pathParts := strings.Split(req.Path, "/")
-	if len(pathParts) != 4 {
-		return &Response{
-			StatusCode: http.StatusBadRequest,
-			Headers:    defaultHeaders,
-			Body:       `{"error":"Invalid path format"}`,
-		}, nil
+	sessionID := req.Params["id"]
+
+	if denied := sr.authorizeRequest(req, "label"); denied != nil {
+		return denied, nil
+	}
+
+	var body api.LabelPackageRequest
+
+	// The body may be omitted when the shard selects the courier
+	if strings.TrimSpace(req.Body) != "" {
+		if err := json.Unmarshal([]byte(req.Body), &body); err != nil {
+			return codedError(CodeInvalidRequest, "Invalid request body: "+err.Error()), nil
+		}
+	}
+
+	if body.CourierID == "" {
+		if sr.repository.CourierStrategy() == "" {
+			return codedError(CodeInvalidRequest, "courier_id is required"), nil
+		}
+		courierID, dbErr := sr.repository.SelectCourier()
+		if dbErr != nil {
+			return repositoryError(dbErr), nil
+		}
+		body.CourierID = courierID
+	}
+
+	label, dbErr := sr.repository.LabelPackage(sessionID, body.CourierID)
+	if dbErr != nil {
+		return repositoryError(dbErr), nil
+	}
+
+	courierName := "Unknown"
+	if label.Courier != nil {
+		courierName = label.Courier.Name
 	}
-	sessionID := pathParts[2]
 
-	var body struct {
-		CourierID string `json:"courier_id"`
+	return jsonResponse(http.StatusOK, api.LabelPackageResponse{
+		Message:    "Shipping label created",
+		LabelID:    label.ID,
+		TrackingNo: label.TrackingNo,
+		Courier:    courierName,
+		SessionID:  sessionID,
+		NextStep:   "commit",
+	}), nil
+}
+
+// RelabelPackageHandler moves an uncommitted session's label to another courier
+func (sr *ServiceRegistry) RelabelPackageHandler(req *Request) (*Response, error) {
+	sessionID := req.Params["id"]
+
+	if denied := sr.authorizeRequest(req, "label"); denied != nil {
+		return denied, nil
 	}
 
+	var body api.LabelPackageRequest
+
 	if err := json.Unmarshal([]byte(req.Body), &body); err != nil {
-		return &Response{
-			StatusCode: http.StatusBadRequest,
-			Headers:    defaultHeaders,
-			Body:       fmt.Sprintf(`{"error":"Invalid request body: %s"}`, err.Error()),
-		}, nil
+		return codedError(CodeInvalidRequest, "Invalid request body: "+err.Error()), nil
 	}
 
 	if body.CourierID == "" {
-		return &Response{
-			StatusCode: http.StatusBadRequest,
-			Headers:    defaultHeaders,
-			Body:       `{"error":"courier_id is required"}`,
-		}, nil
+		return codedError(CodeInvalidRequest, "courier_id is required"), nil
 	}
 
-	label, dbErr := sr.repository.LabelPackage(sessionID, body.CourierID)
+	label, dbErr := sr.repository.RelabelPackage(sessionID, body.CourierID)
 	if dbErr != nil {
-		statusCode := http.StatusInternalServerError
-		if dbErr.Code == "NOT_FOUND" {
-			statusCode = http.StatusNotFound
-		}
-		return &Response{
-			StatusCode: statusCode,
-			Headers:    defaultHeaders,
-			Body:       fmt.Sprintf(`{"error":"%s"}`, dbErr.Message),
-		}, nil
+		return repositoryError(dbErr), nil
 	}
 
 	courierName := "Unknown"
@@ -315,94 +347,232 @@ func (sr *ServiceRegistry) LabelPackageHandler(req *Request) (*Response, error)
 		courierName = label.Courier.Name
 	}
 
-	return &Response{
-		StatusCode: http.StatusOK,
-		Headers:    defaultHeaders,
-		Body: fmt.Sprintf(`{
-			"message":"Shipping label created",
-			"label_id":"%s",
-			"tracking_no":"%s",
-			"courier":"%s",
-			"session_id":"%s",
-			"next_step":"commit"
-		}`, label.ID, label.TrackingNo, courierName, sessionID),
-	}, nil
+	return jsonResponse(http.StatusOK, api.LabelPackageResponse{
+		Message:    "Shipping label updated",
+		LabelID:    label.ID,
+		TrackingNo: label.TrackingNo,
+		Courier:    courierName,
+		SessionID:  sessionID,
+		NextStep:   "commit",
+	}), nil
+}
+
+// GetLabelHandler looks a label and its session up by tracking number
+func (sr *ServiceRegistry) GetLabelHandler(req *Request) (*Response, error) {
+	trackingNo := req.Params["tracking_no"]
+
+	label, dbErr := sr.repository.GetLabelByTrackingNo(trackingNo)
+	if dbErr != nil {
+		return repositoryError(dbErr), nil
+	}
+
+	response := api.LabelLookupResponse{
+		LabelID:    label.ID,
+		TrackingNo: label.TrackingNo,
+		CourierID:  label.CourierID,
+		Courier:    "Unknown",
+		SessionID:  label.SessionID,
+		CreatedAt:  label.CreatedAt,
+	}
+	if label.Courier != nil {
+		response.Courier = label.Courier.Name
+	}
+	if label.Session != nil {
+		response.SessionStatus = label.Session.Status
+		if label.Session.PackageID != nil {
+			response.PackageID = *label.Session.PackageID
+		}
+		if label.Session.L1TxHash != nil {
+			response.L1TxHash = *label.Session.L1TxHash
+		}
+	}
+
+	return jsonResponse(http.StatusOK, response), nil
 }
 
 // CommitSessionHandler commits session to L1
 func (sr *ServiceRegistry) CommitSessionHandler(req *Request) (*Response, error) {
-	pathParts := strings.Split(req.Path, "/")
-	if len(pathParts) != 4 {
-		return &Response{
-			StatusCode: http.StatusBadRequest,
-			Headers:    defaultHeaders,
-			Body:       `{"error":"Invalid path format"}`,
-		}, nil
+	return sr.commitSession(req, false)
+}
+
+// RecommitSessionHandler re-attempts only the L1 commit of a completed
+// session whose earlier commit failed. Like a commit, it records the commit
+// L1 already holds, e.g. because the earlier response was lost.
+func (sr *ServiceRegistry) RecommitSessionHandler(req *Request) (*Response, error) {
+	return sr.commitSession(req, true)
+}
+
+// commitSession commits a completed session to L1. A session L1 already
+// holds from this shard is recorded with L1's transaction.
+func (sr *ServiceRegistry) commitSession(req *Request, recommit bool) (*Response, error) {
+	sessionID := req.Params["id"]
+
+	if denied := sr.authorizeRequest(req, "commit"); denied != nil {
+		return denied, nil
+	}
+
+	ctx, span := tracing.Tracer().Start(req.Ctx(), "CommitSession", trace.WithAttributes(
+		attribute.String("l2.session_id", sessionID),
+		attribute.Bool("l2.recommit", recommit),
+	))
+	defer span.End()
+	req.Context = ctx
+
+	// The body is optional; an empty one commits without a callback
+	var body api.CommitSessionRequest
+	if strings.TrimSpace(req.Body) != "" {
+		if err := json.Unmarshal([]byte(req.Body), &body); err != nil {
+			return codedError(CodeInvalidRequest, "Invalid request body: "+err.Error()), nil
+		}
+	}
+	if body.CallbackURL != "" {
+		if sr.callbacks == nil {
+			return codedError(CodeInvalidRequest, "callback_url is not enabled on this shard"), nil
+		}
+		parsed, err := url.Parse(body.CallbackURL)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return codedError(CodeInvalidRequest, "callback_url must be an absolute http or https URL"), nil
+		}
 	}
-	sessionID := pathParts[2]
 
 	// Get session with all related data
 	session, dbErr := sr.repository.GetSession(sessionID)
 	if dbErr != nil {
-		statusCode := http.StatusInternalServerError
-		if dbErr.Code == "NOT_FOUND" {
-			statusCode = http.StatusNotFound
-		}
-		return &Response{
-			StatusCode: statusCode,
-			Headers:    defaultHeaders,
-			Body:       fmt.Sprintf(`{"error":"%s"}`, dbErr.Message),
-		}, nil
+		return repositoryError(dbErr), nil
 	}
 
 	// Check if session is already committed
 	if session.IsCommitted {
-		return &Response{
-			StatusCode: http.StatusConflict,
-			Headers:    defaultHeaders,
-			Body:       fmt.Sprintf(`{"error":"Session already committed","tx_hash":"%s"}`, *session.L1TxHash),
-		}, nil
+		txHash := ""
+		if session.L1TxHash != nil {
+			txHash = *session.L1TxHash
+		}
+		return jsonResponse(ErrorStatus(CodeAlreadyCommitted), api.ErrorResponse{
+			Error:  "Session already committed",
+			Code:   CodeAlreadyCommitted,
+			TxHash: txHash,
+		}), nil
+	}
+
+	// Check if session is completed. A recommit also takes a session left
+	// committing by a commit that never finished, e.g. across a restart.
+	if session.Status != "completed" && !(recommit && session.Status == repository.SessionCommitting) {
+		return jsonResponse(ErrorStatus(CodeInvalidState), api.ErrorResponse{
+			Error:         "Session must be completed before committing",
+			Code:          CodeInvalidState,
+			CurrentStatus: session.Status,
+		}), nil
 	}
 
-	// Check if session is completed
-	if session.Status != "completed" {
-		return &Response{
-			StatusCode: http.StatusBadRequest,
-			Headers:    defaultHeaders,
-			Body:       fmt.Sprintf(`{"error":"Session must be completed before committing","current_status":"%s"}`, session.Status),
-		}, nil
+	// Claim the session so it can't be deleted or relabeled while L1 commits it
+	version, dbErr := sr.repository.BeginCommit(sessionID, session.Version)
+	if dbErr != nil {
+		return repositoryError(dbErr), nil
+	}
+
+	// From here on every outcome is final for this request and is reported
+	// to the callback, failures included
+	fail := func(code, message string) *Response {
+		span.SetStatus(codes.Error, message)
+		sr.notifyCommit(body.CallbackURL, webhook.CommitStatus{
+			SessionID: sessionID,
+			Status:    "failed",
+			Error:     message,
+		})
+		return codedError(code, message)
 	}
 
 	// Commit to L1
-	l1Response, err := sr.l1Client.CommitSession(session, sr.clientGroup)
+	l1Response, err := sr.l1Client.CommitSession(req.Ctx(), session, sr.clientGroup)
+	countL1Commit(err)
 	if err != nil {
-		return &Response{
-			StatusCode: http.StatusBadGateway,
-			Headers:    defaultHeaders,
-			Body:       fmt.Sprintf(`{"error":"Failed to commit to L1: %s"}`, err.Error()),
-		}, nil
+		if dbErr := sr.repository.AbortCommit(sessionID, version); dbErr != nil {
+			sr.logger.Warnf("⚠️  Failed to release session %s after a failed commit: %s", sessionID, dbErr.Detail)
+		}
+	}
+	if errors.Is(err, l1client.ErrForeignCommit) {
+		return fail(CodeConflict, err.Error()), nil
+	}
+	if err != nil {
+		return fail(CodeL1CommitFailed, "Failed to commit to L1: "+err.Error()), nil
 	}
 
 	// Update session with L1 commitment info
-	dbErr = sr.repository.MarkSessionCommitted(sessionID, l1Response.Data.TxHash, l1Response.Meta.BlockHeight)
+	dbErr = sr.repository.MarkSessionCommitted(sessionID, version, l1Response.Data.TxHash, l1Response.Meta.BlockHeight)
 	if dbErr != nil {
-		return &Response{
-			StatusCode: http.StatusInternalServerError,
-			Headers:    defaultHeaders,
-			Body:       fmt.Sprintf(`{"error":"Failed to update session: %s"}`, dbErr.Message),
-		}, nil
-	}
-
-	return &Response{
-		StatusCode: http.StatusOK,
-		Headers:    defaultHeaders,
-		Body: fmt.Sprintf(`{
-			"message":"Session committed to L1 successfully",
-			"session_id":"%s",
-			"tx_hash":"%s",
-			"block_height":%d,
-			"shard_id":"%s",
-			"status":"committed"
-		}`, sessionID, l1Response.Data.TxHash, l1Response.Meta.BlockHeight, sr.shardID),
-	}, nil
+		return fail(dbErr.Code, "Failed to update session: "+dbErr.Message), nil
+	}
+
+	sr.notifyCommit(body.CallbackURL, webhook.CommitStatus{
+		SessionID:   sessionID,
+		Status:      "committed",
+		TxHash:      l1Response.Data.TxHash,
+		BlockHeight: l1Response.Meta.BlockHeight,
+	})
+
+	message := "Session committed to L1 successfully"
+	if l1Response.Existing {
+		message = "Session was already on L1; recorded its commit"
+	}
+	return jsonResponse(http.StatusOK, api.CommitSessionResponse{
+		Message:     message,
+		SessionID:   sessionID,
+		TxHash:      l1Response.Data.TxHash,
+		BlockHeight: l1Response.Meta.BlockHeight,
+		Votes:       l1Response.Meta.Votes,
+		ShardID:     sr.shardID,
+		Status:      "committed",
+	}), nil
+}
+
+// notifyCommit queues a commit callback when the request asked for one. The
+// commit outcome stands even if the callback cannot be queued.
+func (sr *ServiceRegistry) notifyCommit(callbackURL string, status webhook.CommitStatus) {
+	if callbackURL == "" || sr.callbacks == nil {
+		return
+	}
+	status.ShardID = sr.shardID
+	status.Timestamp = time.Now()
+	if err := sr.callbacks.Enqueue(callbackURL, status); err != nil {
+		sr.logger.Warnf("⚠️  Failed to queue commit callback for session %s: %v", status.SessionID, err)
+	}
+}
+
+// DeleteSessionHandler removes an uncommitted session. A committed session
+// can't be removed since L1 holds it; an Admin may cancel it instead.
+func (sr *ServiceRegistry) DeleteSessionHandler(req *Request) (*Response, error) {
+	sessionID := req.Params["id"]
+
+	if _, denied := actingOperator(req); denied != nil {
+		return denied, nil
+	}
+	session, dbErr := sr.repository.GetSession(sessionID)
+	if dbErr != nil {
+		return repositoryError(dbErr), nil
+	}
+
+	if session.IsCommitted {
+		if denied := sr.authorizeRequest(req, "cancel"); denied != nil {
+			return denied, nil
+		}
+		if dbErr := sr.repository.CancelSession(sessionID); dbErr != nil {
+			return repositoryError(dbErr), nil
+		}
+		return jsonResponse(http.StatusOK, api.DeleteSessionResponse{
+			Message:   "Session cancelled; its L1 commit is kept",
+			SessionID: sessionID,
+		}), nil
+	}
+
+	if denied := sr.authorizeRequest(req, "delete"); denied != nil {
+		return denied, nil
+	}
+	if dbErr := sr.repository.DeleteSession(sessionID); dbErr != nil {
+		return repositoryError(dbErr), nil
+	}
+
+	return jsonResponse(http.StatusOK, api.DeleteSessionResponse{
+		Message:   "Session deleted",
+		SessionID: sessionID,
+	}), nil
 }
@@ -0,0 +1,62 @@
+package srvreg
+
+import (
+	"fmt"
+	"log"
+	"strings"
+)
+
+// LogLevel gates service registry logging. Higher levels are more verbose.
+type LogLevel int
+
+const (
+	LogLevelError LogLevel = iota
+	LogLevelWarn
+	LogLevelInfo
+	LogLevelDebug
+)
+
+var logLevelNames = map[string]LogLevel{
+	"error": LogLevelError,
+	"warn":  LogLevelWarn,
+	"info":  LogLevelInfo,
+	"debug": LogLevelDebug,
+}
+
+// ParseLogLevel parses error, warn, info or debug, ignoring case
+func ParseLogLevel(name string) (LogLevel, error) {
+	level, ok := logLevelNames[strings.ToLower(strings.TrimSpace(name))]
+	if !ok {
+		return LogLevelDebug, fmt.Errorf("unknown log level %q (expected error, warn, info or debug)", name)
+	}
+	return level, nil
+}
+
+// levelLogger drops messages above its level
+type levelLogger struct {
+	*log.Logger
+	level LogLevel
+}
+
+func (l *levelLogger) logf(level LogLevel, format string, args ...interface{}) {
+	if level > l.level {
+		return
+	}
+	l.Printf(format, args...)
+}
+
+func (l *levelLogger) Errorf(format string, args ...interface{}) {
+	l.logf(LogLevelError, format, args...)
+}
+
+func (l *levelLogger) Warnf(format string, args ...interface{}) {
+	l.logf(LogLevelWarn, format, args...)
+}
+
+func (l *levelLogger) Infof(format string, args ...interface{}) {
+	l.logf(LogLevelInfo, format, args...)
+}
+
+func (l *levelLogger) Debugf(format string, args ...interface{}) {
+	l.logf(LogLevelDebug, format, args...)
+}
@@ -0,0 +1,95 @@
+package srvreg
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Outcome label values
+const (
+	outcomeSuccess = "success"
+	outcomeFailure = "failure"
+)
+
+// metricsRegistry holds the shard metrics served on /metrics, next to the Go
+// runtime and process collectors
+var metricsRegistry = prometheus.NewRegistry()
+
+var (
+	sessionsCreated = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "l2_sessions_created_total",
+		Help: "Sessions created, single and batch.",
+	})
+	workflowSteps = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "l2_workflow_steps_total",
+		Help: "Workflow step requests handled by this shard, by step and outcome.",
+	}, []string{"step", "outcome"})
+	l1Commits = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "l2_l1_commits_total",
+		Help: "Session commits sent to L1, by outcome.",
+	}, []string{"outcome"})
+	forwards = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "l2_forwards_total",
+		Help: "Requests forwarded to the shard owning their client group, by outcome.",
+	}, []string{"outcome"})
+	forwardDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "l2_forward_duration_seconds",
+		Help:    "Round trip of requests forwarded to another shard.",
+		Buckets: prometheus.DefBuckets,
+	})
+)
+
+func init() {
+	metricsRegistry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		sessionsCreated,
+		workflowSteps,
+		l1Commits,
+		forwards,
+		forwardDuration,
+	)
+}
+
+// MetricsHandler serves the shard metrics in the Prometheus text format
+func MetricsHandler() http.Handler {
+	return promhttp.HandlerFor(metricsRegistry, promhttp.HandlerOpts{})
+}
+
+// countStep wraps a workflow step handler, counting each request as a
+// success or failure by its response status
+func countStep(step string, handler HandlerFunc) HandlerFunc {
+	return func(req *Request) (*Response, error) {
+		response, err := handler(req)
+		outcome := outcomeSuccess
+		if err != nil || response == nil || response.StatusCode >= http.StatusBadRequest {
+			outcome = outcomeFailure
+		}
+		workflowSteps.WithLabelValues(step, outcome).Inc()
+		return response, err
+	}
+}
+
+// observeForward records a forwarded request. Transport errors and 5xx
+// answers from the target shard count as failures.
+func observeForward(start time.Time, statusCode int, err error) {
+	forwardDuration.Observe(time.Since(start).Seconds())
+	if err != nil || statusCode >= http.StatusInternalServerError {
+		forwards.WithLabelValues(outcomeFailure).Inc()
+		return
+	}
+	forwards.WithLabelValues(outcomeSuccess).Inc()
+}
+
+// countL1Commit records the outcome of a commit sent to L1
+func countL1Commit(err error) {
+	if err != nil {
+		l1Commits.WithLabelValues(outcomeFailure).Inc()
+		return
+	}
+	l1Commits.WithLabelValues(outcomeSuccess).Inc()
+}
@@ -0,0 +1,210 @@
+package srvreg
+
+import (
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ahmadzakiakmal/thesis-extension/layer-2/api"
+)
+
+// RouteDoc describes a registered route in the generated OpenAPI document.
+// Request and Response are sample values whose types describe the bodies.
+type RouteDoc struct {
+	Summary  string
+	Status   int
+	Request  interface{}
+	Response interface{}
+}
+
+// routeKey identifies a documented route
+type routeKey struct {
+	Method string
+	Path   string
+}
+
+// DocumentRoute attaches documentation to a registered route
+func (sr *ServiceRegistry) DocumentRoute(method, path string, doc RouteDoc) {
+	sr.docs[routeKey{Method: method, Path: normalizePath(path)}] = doc
+}
+
+// OpenAPISpec builds an OpenAPI 3 document from the registered routes
+func (sr *ServiceRegistry) OpenAPISpec() map[string]interface{} {
+	routes := []routeKey{}
+	for method, methodHandlers := range sr.handlers {
+		for path := range methodHandlers {
+			routes = append(routes, routeKey{Method: method, Path: path})
+		}
+	}
+	sort.Slice(routes, func(i, j int) bool {
+		if routes[i].Path != routes[j].Path {
+			return routes[i].Path < routes[j].Path
+		}
+		return routes[i].Method < routes[j].Method
+	})
+
+	gen := &schemaGenerator{components: map[string]interface{}{}}
+	paths := make(map[string]interface{})
+
+	for _, route := range routes {
+		oasPath, params := openAPIPath(route.Path)
+		doc := sr.docs[route]
+
+		status := doc.Status
+		if status == 0 {
+			status = http.StatusOK
+		}
+
+		var dataSchema map[string]interface{}
+		if doc.Response != nil {
+			dataSchema = gen.schemaFor(reflect.TypeOf(doc.Response))
+		} else {
+			dataSchema = map[string]interface{}{}
+		}
+
+		operation := map[string]interface{}{
+			"summary": doc.Summary,
+			"responses": map[string]interface{}{
+				strconv.Itoa(status): map[string]interface{}{
+					"description": http.StatusText(status),
+					"content":     jsonContent(dataSchema),
+				},
+				"default": map[string]interface{}{
+					"description": "Error",
+					"content":     jsonContent(gen.schemaFor(reflect.TypeOf(api.ErrorResponse{}))),
+				},
+			},
+		}
+		if len(params) > 0 {
+			operation["parameters"] = params
+		}
+		if doc.Request != nil {
+			operation["requestBody"] = map[string]interface{}{
+				"required": true,
+				"content":  jsonContent(gen.schemaFor(reflect.TypeOf(doc.Request))),
+			}
+		}
+
+		pathItem, ok := paths[oasPath].(map[string]interface{})
+		if !ok {
+			pathItem = make(map[string]interface{})
+			paths[oasPath] = pathItem
+		}
+		pathItem[strings.ToLower(route.Method)] = operation
+	}
+
+	return map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]interface{}{
+			"title":       "Layer 2 Shard API",
+			"description": "Supply chain session workflow served by an L2 shard",
+			"version":     "1.0.0",
+		},
+		"paths": paths,
+		"components": map[string]interface{}{
+			"schemas": gen.components,
+		},
+	}
+}
+
+func jsonContent(schema map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"application/json": map[string]interface{}{"schema": schema},
+	}
+}
+
+// openAPIPath converts "/session/:id/scan" into "/session/{id}/scan"
+// along with its path parameters
+func openAPIPath(path string) (string, []interface{}) {
+	parts := strings.Split(path, "/")
+	params := []interface{}{}
+	for i, part := range parts {
+		if strings.HasPrefix(part, ":") {
+			name := part[1:]
+			parts[i] = "{" + name + "}"
+			params = append(params, map[string]interface{}{
+				"name":     name,
+				"in":       "path",
+				"required": true,
+				"schema":   map[string]interface{}{"type": "string"},
+			})
+		}
+	}
+	return strings.Join(parts, "/"), params
+}
+
+// schemaGenerator derives JSON schemas from Go types, collecting named structs
+// as reusable components so recursive models terminate
+type schemaGenerator struct {
+	components map[string]interface{}
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+func (g *schemaGenerator) schemaFor(t reflect.Type) map[string]interface{} {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == timeType {
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Int64, reflect.Uint64:
+		return map[string]interface{}{"type": "integer", "format": "int64"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]interface{}{"type": "string", "format": "byte"}
+		}
+		return map[string]interface{}{"type": "array", "items": g.schemaFor(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": g.schemaFor(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.structSchema(t)
+		}
+		if _, ok := g.components[t.Name()]; !ok {
+			// Reserve the name before recursing to break reference cycles
+			g.components[t.Name()] = map[string]interface{}{}
+			g.components[t.Name()] = g.structSchema(t)
+		}
+		return map[string]interface{}{"$ref": "#/components/schemas/" + t.Name()}
+	default:
+		return map[string]interface{}{}
+	}
+}
+
+func (g *schemaGenerator) structSchema(t reflect.Type) map[string]interface{} {
+	properties := make(map[string]interface{})
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name := field.Name
+		if tag := field.Tag.Get("json"); tag != "" {
+			tagName := strings.Split(tag, ",")[0]
+			if tagName == "-" {
+				continue
+			}
+			if tagName != "" {
+				name = tagName
+			}
+		}
+		properties[name] = g.schemaFor(field.Type)
+	}
+	return map[string]interface{}{"type": "object", "properties": properties}
+}
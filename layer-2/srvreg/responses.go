@@ -0,0 +1,24 @@
+package srvreg
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// jsonResponse marshals body into a JSON response with the given status code
+func jsonResponse(statusCode int, body interface{}) *Response {
+	bodyBytes, err := json.Marshal(body)
+	if err != nil {
+		return &Response{
+			StatusCode: http.StatusInternalServerError,
+			Headers:    defaultHeaders,
+			Body:       `{"error":"Failed to serialize response"}`,
+		}
+	}
+
+	return &Response{
+		StatusCode: statusCode,
+		Headers:    defaultHeaders,
+		Body:       string(bodyBytes),
+	}
+}
@@ -2,16 +2,21 @@ package srvreg
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
 	"os"
+	"regexp"
+	"sort"
 	"strings"
 	"time"
 
+	"github.com/ahmadzakiakmal/thesis-extension/layer-2/api"
 	"github.com/ahmadzakiakmal/thesis-extension/layer-2/l1client"
 	"github.com/ahmadzakiakmal/thesis-extension/layer-2/repository"
+	"github.com/ahmadzakiakmal/thesis-extension/layer-2/webhook"
 )
 
 // Request represents an incoming HTTP request
@@ -20,6 +25,25 @@ type Request struct {
 	Path    string
 	Body    string
 	Headers map[string]string
+
+	// BodyReader streams the body in place of Body for uploads too large to
+	// hold in memory, such as a session import. Nil means Body holds it.
+	BodyReader io.Reader
+
+	// Params holds the named path parameters of the matched route, e.g.
+	// Params["id"] for "/session/:id/scan"
+	Params map[string]string
+
+	// Context is canceled when the client disconnects
+	Context context.Context
+}
+
+// Ctx returns the request context, falling back to context.Background
+func (r *Request) Ctx() context.Context {
+	if r.Context == nil {
+		return context.Background()
+	}
+	return r.Context
 }
 
 // Response represents an HTTP response
@@ -27,6 +51,10 @@ type Response struct {
 	StatusCode int
 	Headers    map[string]string
 	Body       string
+
+	// Stream writes the body after the headers are sent, for bodies too
+	// large to build in memory. Body is ignored when it is set.
+	Stream func(w io.Writer) error
 }
 
 // HandlerFunc is a function that handles a request
@@ -35,81 +63,149 @@ type HandlerFunc func(*Request) (*Response, error)
 // ServiceRegistry manages all service handlers
 type ServiceRegistry struct {
 	handlers    map[string]map[string]HandlerFunc
+	patterns    map[string]pathPattern
+	docs        map[routeKey]RouteDoc
 	repository  *repository.Repository
 	l1Client    *l1client.L1Client
 	shardID     string
 	clientGroup string
-	logger      log.Logger
+	callbacks   *webhook.Dispatcher // nil when commit callbacks are disabled
+	logger      *levelLogger
+
+	forwardTimeouts ForwardTimeouts
+
+	// adminKey enables the backup endpoints, empty leaves them disabled
+	adminKey string
 }
 
 var defaultHeaders = map[string]string{
 	"Content-Type": "application/json",
 }
 
+// validClientGroup matches well-formed X-Client-Group values
+var validClientGroup = regexp.MustCompile(`^[A-Za-z0-9._-]{1,100}$`)
+
 // NewServiceRegistry creates a new service registry
 func NewServiceRegistry(repo *repository.Repository, l1Client *l1client.L1Client, shardID, clientGroup string) *ServiceRegistry {
 	return &ServiceRegistry{
 		handlers:    make(map[string]map[string]HandlerFunc),
+		patterns:    make(map[string]pathPattern),
+		docs:        make(map[routeKey]RouteDoc),
 		repository:  repo,
 		l1Client:    l1Client,
 		shardID:     shardID,
 		clientGroup: clientGroup,
-		logger:      *log.New(os.Stdout, "[ServiceRegistry] ", log.LstdFlags),
+
+		forwardTimeouts: DefaultForwardTimeouts,
+		logger: &levelLogger{
+			Logger: log.New(os.Stdout, "[ServiceRegistry] ", log.LstdFlags),
+			level:  LogLevelDebug,
+		},
+	}
+}
+
+// SetLogLevel sets how verbose the registry's logging is. Per-request
+// redirect and forwarding logs are debug messages.
+func (sr *ServiceRegistry) SetLogLevel(level LogLevel) {
+	sr.logger.level = level
+}
+
+// SetLogOutput redirects the registry's logging, e.g. to capture it
+func (sr *ServiceRegistry) SetLogOutput(w io.Writer) {
+	sr.logger.SetOutput(w)
+}
+
+// SetCallbackDispatcher enables callback_url on commits, delivered through d
+func (sr *ServiceRegistry) SetCallbackDispatcher(d *webhook.Dispatcher) {
+	sr.callbacks = d
+}
+
+// DebugInfo collects the shard diagnostics served on /debug: identity,
+// database and L1 reachability, and the loaded shard registry
+func (sr *ServiceRegistry) DebugInfo() map[string]interface{} {
+	info := map[string]interface{}{
+		"shard_id":          sr.shardID,
+		"client_group":      sr.clientGroup,
+		"database_status":   "connected",
+		"l1_endpoint":       sr.l1Client.Endpoint(),
+		"l1_status":         "reachable",
+		"shards_loaded":     len(sr.l1Client.Shards()),
+		"callbacks_enabled": sr.callbacks != nil,
 	}
+	if err := sr.repository.Ping(); err != nil {
+		info["database_status"] = "disconnected"
+		info["database_error"] = err.Error()
+	}
+	if err := sr.l1Client.HealthCheck(); err != nil {
+		info["l1_status"] = "unreachable"
+		info["l1_error"] = err.Error()
+	}
+	return info
 }
 
 // RegisterHandler registers a handler for a specific method and path
 func (sr *ServiceRegistry) RegisterHandler(method, path string, handler HandlerFunc) {
+	path = normalizePath(path)
 	if sr.handlers[method] == nil {
 		sr.handlers[method] = make(map[string]HandlerFunc)
 	}
 	sr.handlers[method][path] = handler
+	sr.patterns[path] = compilePattern(path)
 	log.Printf("✓ Registered handler: %s %s", method, path)
 }
 
-// GetHandlerForPath finds the handler for a given method and path
-func (sr *ServiceRegistry) GetHandlerForPath(method, path string) (HandlerFunc, bool) {
+// GetHandlerForPath finds the handler for a given method and path, along
+// with the named path parameters of its route
+func (sr *ServiceRegistry) GetHandlerForPath(method, path string) (HandlerFunc, map[string]string, bool) {
 	methodHandlers, exists := sr.handlers[method]
 	if !exists {
-		return nil, false
+		return nil, nil, false
 	}
 
+	path = normalizePath(path)
+
 	// Try exact match first
 	if handler, exists := methodHandlers[path]; exists {
-		return handler, true
+		return handler, nil, true
 	}
 
 	// Try pattern matching for paths with parameters
+	segments := splitPath(path)
 	for pattern, handler := range methodHandlers {
-		if matchPath(pattern, path) {
-			return handler, true
+		if params, ok := sr.patterns[pattern].match(segments); ok {
+			return handler, params, true
 		}
 	}
 
-	return nil, false
+	return nil, nil, false
 }
 
-// matchPath checks if a path matches a pattern with parameters
-// It supports patterns like "/session/:id" matching "/session/123"
-func matchPath(pattern, path string) bool {
-	patternParts := strings.Split(pattern, "/")
-	pathParts := strings.Split(path, "/")
-
-	if len(patternParts) != len(pathParts) {
-		return false
-	}
-
-	for i := 0; i < len(patternParts); i++ {
-		if strings.HasPrefix(patternParts[i], ":") {
-			// This is a parameter, it matches anything
-			continue
-		}
-		if patternParts[i] != pathParts[i] {
-			return false
+// AllowedMethods returns the sorted methods registered for a path, regardless
+// of the method used in the request
+func (sr *ServiceRegistry) AllowedMethods(path string) []string {
+	path = normalizePath(path)
+	segments := splitPath(path)
+
+	methods := []string{}
+	for method, methodHandlers := range sr.handlers {
+		for pattern := range methodHandlers {
+			if _, ok := sr.patterns[pattern].match(segments); pattern == path || ok {
+				methods = append(methods, method)
+				break
+			}
 		}
 	}
+	sort.Strings(methods)
+	return methods
+}
 
-	return true
+// normalizePath trims a single trailing slash so "/session/start/" and
+// "/session/start" resolve to the same route. The root path is left untouched.
+func normalizePath(path string) string {
+	if len(path) > 1 && strings.HasSuffix(path, "/") {
+		return path[:len(path)-1]
+	}
+	return path
 }
 
 // RegisterDefaultServices sets up all default endpoints
@@ -118,23 +214,135 @@ func (sr *ServiceRegistry) RegisterDefaultServices() {
 
 	// Session endpoints
 	sr.RegisterHandler("POST", "/session/start", sr.CreateSessionHandler)
-	sr.RegisterHandler("GET", "/session/:id/scan", sr.ScanPackageHandler)
-	sr.RegisterHandler("POST", "/session/:id/validate", sr.ValidatePackageHandler)
-	sr.RegisterHandler("POST", "/session/:id/qc", sr.QualityCheckHandler)
-	sr.RegisterHandler("POST", "/session/:id/label", sr.LabelPackageHandler)
-	sr.RegisterHandler("POST", "/session/:id/commit", sr.CommitSessionHandler)
+	sr.DocumentRoute("POST", "/session/start", RouteDoc{
+		Summary:  "Start a new session for an operator",
+		Status:   http.StatusCreated,
+		Request:  api.CreateSessionRequest{},
+		Response: api.CreateSessionResponse{},
+	})
+	sr.RegisterHandler("POST", "/session/start/batch", sr.CreateSessionsHandler)
+	sr.DocumentRoute("POST", "/session/start/batch", RouteDoc{
+		Summary:  "Start several sessions in one transaction",
+		Status:   http.StatusCreated,
+		Request:  api.CreateSessionsRequest{},
+		Response: api.CreateSessionsResponse{},
+	})
+	sr.RegisterHandler("GET", "/session/:id/scan", countStep("scan", sr.ScanPackageHandler))
+	sr.DocumentRoute("GET", "/session/:id/scan", RouteDoc{
+		Summary:  "Scan a package into the session",
+		Request:  api.ScanPackageRequest{},
+		Response: api.ScanPackageResponse{},
+	})
+	sr.RegisterHandler("POST", "/session/:id/validate", countStep("validate", sr.ValidatePackageHandler))
+	sr.DocumentRoute("POST", "/session/:id/validate", RouteDoc{
+		Summary:  "Validate the supplier signature of the scanned package",
+		Request:  api.ValidatePackageRequest{},
+		Response: api.ValidatePackageResponse{},
+	})
+	sr.RegisterHandler("POST", "/session/:id/qc", countStep("qc", sr.QualityCheckHandler))
+	sr.DocumentRoute("POST", "/session/:id/qc", RouteDoc{
+		Summary:  "Record the quality check result",
+		Request:  api.QualityCheckRequest{},
+		Response: api.QualityCheckResponse{},
+	})
+	sr.RegisterHandler("POST", "/session/:id/label", countStep("label", sr.LabelPackageHandler))
+	sr.DocumentRoute("POST", "/session/:id/label", RouteDoc{
+		Summary:  "Create a shipping label (courier_id optional when COURIER_STRATEGY is set)",
+		Request:  api.LabelPackageRequest{},
+		Response: api.LabelPackageResponse{},
+	})
+	sr.RegisterHandler("PUT", "/session/:id/label", countStep("relabel", sr.RelabelPackageHandler))
+	sr.DocumentRoute("PUT", "/session/:id/label", RouteDoc{
+		Summary:  "Move the label to another courier before commit",
+		Request:  api.LabelPackageRequest{},
+		Response: api.LabelPackageResponse{},
+	})
+	sr.RegisterHandler("POST", "/session/:id/commit", countStep("commit", sr.CommitSessionHandler))
+	sr.DocumentRoute("POST", "/session/:id/commit", RouteDoc{
+		Summary:  "Commit the completed session to L1",
+		Request:  api.CommitSessionRequest{},
+		Response: api.CommitSessionResponse{},
+	})
+	sr.RegisterHandler("POST", "/session/:id/recommit", countStep("recommit", sr.RecommitSessionHandler))
+	sr.DocumentRoute("POST", "/session/:id/recommit", RouteDoc{
+		Summary:  "Retry only the L1 commit of a completed session after a failed commit",
+		Request:  api.CommitSessionRequest{},
+		Response: api.CommitSessionResponse{},
+	})
+	sr.RegisterHandler("DELETE", "/session/:id", sr.DeleteSessionHandler)
+	sr.DocumentRoute("DELETE", "/session/:id", RouteDoc{
+		Summary:  "Delete an uncommitted session",
+		Response: api.DeleteSessionResponse{},
+	})
+
+	// Backup endpoints
+	sr.RegisterHandler("GET", "/sessions/export", sr.ExportSessionsHandler)
+	sr.DocumentRoute("GET", "/sessions/export", RouteDoc{
+		Summary: "Stream all sessions with their relations as NDJSON (requires X-Admin-Key)",
+	})
+	sr.RegisterHandler("POST", "/sessions/import", sr.ImportSessionsHandler)
+	sr.DocumentRoute("POST", "/sessions/import", RouteDoc{
+		Summary:  "Import an NDJSON session export, skipping existing sessions and packages (requires X-Admin-Key)",
+		Response: api.ImportSessionsResponse{},
+	})
 
 	// Info endpoints
 	sr.RegisterHandler("GET", "/info", sr.InfoHandler)
+	sr.DocumentRoute("GET", "/info", RouteDoc{
+		Summary:  "Shard information",
+		Response: api.InfoResponse{},
+	})
+
+	// Catalog endpoints
+	sr.RegisterHandler("GET", "/packages", sr.ListPackagesHandler)
+	sr.DocumentRoute("GET", "/packages", RouteDoc{
+		Summary:  "List packages seeded on this shard",
+		Response: api.PackagesResponse{},
+	})
+	sr.RegisterHandler("GET", "/couriers", sr.ListCouriersHandler)
+	sr.DocumentRoute("GET", "/couriers", RouteDoc{
+		Summary:  "List couriers available for labels",
+		Response: api.CouriersResponse{},
+	})
+	sr.RegisterHandler("GET", "/suppliers", sr.ListSuppliersHandler)
+	sr.DocumentRoute("GET", "/suppliers", RouteDoc{
+		Summary:  "List package suppliers",
+		Response: api.SuppliersResponse{},
+	})
+
+	sr.RegisterHandler("GET", "/labels/:tracking_no", sr.GetLabelHandler)
+	sr.DocumentRoute("GET", "/labels/:tracking_no", RouteDoc{
+		Summary:  "Look up a label and its session by tracking number",
+		Response: api.LabelLookupResponse{},
+	})
+
+	// Health endpoints
+	sr.RegisterHandler("GET", "/healthz", sr.HealthzHandler)
+	sr.DocumentRoute("GET", "/healthz", RouteDoc{
+		Summary:  "Liveness probe",
+		Response: api.HealthResponse{},
+	})
+	sr.RegisterHandler("GET", "/readyz", sr.ReadyzHandler)
+	sr.DocumentRoute("GET", "/readyz", RouteDoc{
+		Summary:  "Readiness probe covering the database and L1",
+		Response: api.HealthResponse{},
+	})
 
 	log.Println("✓ All services registered")
 }
 
 // GenerateResponse executes the request and generates a response
 func (req *Request) GenerateResponse(services *ServiceRegistry) (*Response, error) {
-	// Check client group header and redirect if needed
-	clientGroup := req.Headers["X-Client-Group"]
-	if clientGroup != "" {
+	// Check client group header and redirect if needed. A header that is
+	// present but malformed is rejected rather than silently handled here.
+	if rawGroup, present := req.Headers["X-Client-Group"]; present {
+		clientGroup := strings.TrimSpace(rawGroup)
+		if !validClientGroup.MatchString(clientGroup) {
+			return codedError(CodeInvalidRequest,
+				"Invalid X-Client-Group header: expected 1-100 letters, digits, '.', '_' or '-'"), nil
+		}
+		req.Headers["X-Client-Group"] = clientGroup
+
 		shouldHandle, redirectURL := services.CheckShardAndRedirect(clientGroup)
 		if !shouldHandle {
 			// Forward to correct shard instead of returning redirect
@@ -143,22 +351,26 @@ func (req *Request) GenerateResponse(services *ServiceRegistry) (*Response, erro
 	}
 
 	// Continue with normal handler routing
-	handler, found := services.GetHandlerForPath(req.Method, req.Path)
+	req.Path = normalizePath(req.Path)
+	handler, params, found := services.GetHandlerForPath(req.Method, req.Path)
 
 	if !found {
-		return &Response{
-			StatusCode: 404,
-			Headers:    defaultHeaders,
-			Body:       fmt.Sprintf(`{"error":"Service not found for %s %s"}`, req.Method, req.Path),
-		}, nil
+		if allowed := services.AllowedMethods(req.Path); len(allowed) > 0 {
+			response := codedError(CodeMethodNotAllowed, fmt.Sprintf("Method %s not allowed for %s", req.Method, req.Path))
+			response.Headers = map[string]string{
+				"Content-Type": "application/json",
+				"Allow":        strings.Join(allowed, ", "),
+			}
+			return response, nil
+		}
+		return codedError(CodeNotFound, fmt.Sprintf("Service not found for %s %s", req.Method, req.Path)), nil
 	}
+	req.Params = params
 
 	response, err := handler(req)
 	return response, err
 }
 
-// CheckShardAndRedirect checks if the client group belongs to this shard
-// Returns (shouldHandle, redirectURL)
 // CheckShardAndRedirect checks if the client group belongs to this shard
 // Returns (shouldHandle, redirectURL)
 func (sr *ServiceRegistry) CheckShardAndRedirect(clientGroup string) (bool, string) {
@@ -171,12 +383,12 @@ func (sr *ServiceRegistry) CheckShardAndRedirect(clientGroup string) (bool, stri
 	shard, found := sr.l1Client.GetShardByClientGroup(clientGroup)
 	if !found {
 		// Unknown client group - let this shard handle it (will likely fail later)
-		sr.logger.Printf("⚠️  Unknown client group: %s", clientGroup)
+		sr.logger.Warnf("⚠️  Unknown client group: %s", clientGroup)
 		return true, ""
 	}
 
 	// Return redirect URL
-	sr.logger.Printf("↪️  Redirecting client_group=%s to shard=%s at %s", clientGroup, shard.ShardID, shard.L2Endpoint)
+	sr.logger.Debugf("↪️  Redirecting client_group=%s to shard=%s at %s", clientGroup, shard.ShardID, shard.L2Endpoint)
 
 	return false, shard.L2Endpoint
 }
@@ -188,29 +400,38 @@ func (sr *ServiceRegistry) ForwardToCorrectShard(req *Request, targetURL string)
 	// Construct the full URL
 	fullURL := fmt.Sprintf("%s%s", targetURL, req.Path)
 
-	sr.logger.Printf("🔄 Forwarding request to correct shard: %s %s", req.Method, fullURL)
+	timeout := sr.forwardTimeouts.For(req.Method, req.Path)
+	sr.logger.Debugf("🔄 Forwarding request to correct shard: %s %s (timeout %s)", req.Method, fullURL, timeout)
+
+	ctx, cancel := context.WithTimeout(req.Ctx(), timeout)
+	defer cancel()
 
 	// Create HTTP request
-	httpReq, err := http.NewRequest(req.Method, fullURL, bytes.NewBufferString(req.Body))
+	httpReq, err := http.NewRequestWithContext(ctx, req.Method, fullURL, bytes.NewBufferString(req.Body))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create forward request: %w", err)
 	}
 
-	// Copy headers from original request
+	// Copy headers from original request. Accept-Encoding is left to the
+	// transport so the forwarded body comes back decompressed.
 	for key, value := range req.Headers {
+		if http.CanonicalHeaderKey(key) == "Accept-Encoding" {
+			continue
+		}
 		httpReq.Header.Set(key, value)
 	}
 
 	// Make the request
-	client := &http.Client{Timeout: 30 * time.Second}
-	httpResp, err := client.Do(httpReq)
+	httpResp, err := forwardClient.Do(httpReq)
 	if err != nil {
+		observeForward(startTime, 0, err)
 		return nil, fmt.Errorf("failed to forward request: %w", err)
 	}
 	defer httpResp.Body.Close()
 
 	// Read response body
 	bodyBytes, err := io.ReadAll(httpResp.Body)
+	observeForward(startTime, httpResp.StatusCode, err)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read forward response: %w", err)
 	}
@@ -218,12 +439,20 @@ func (sr *ServiceRegistry) ForwardToCorrectShard(req *Request, targetURL string)
 	// Measure time
 	forwardLatency := time.Since(startTime).Milliseconds()
 
-	sr.logger.Printf("✅ Cross-shard request completed in %d ms", forwardLatency)
+	sr.logger.Debugf("✅ Cross-shard request completed in %d ms", forwardLatency)
 
-	// Return the response from the correct shard
+	// Return the response from the correct shard, keeping its envelope
+	// marker so the body isn't wrapped a second time here
+	headers := defaultHeaders
+	if marker := httpResp.Header.Get(api.EnvelopeHeader); marker != "" {
+		headers = map[string]string{
+			"Content-Type":     "application/json",
+			api.EnvelopeHeader: marker,
+		}
+	}
 	return &Response{
 		StatusCode: httpResp.StatusCode,
-		Headers:    defaultHeaders,
+		Headers:    headers,
 		Body:       string(bodyBytes),
 	}, nil
 }
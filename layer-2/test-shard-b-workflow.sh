@@ -2,6 +2,7 @@
 
 BASE_URL="http://localhost:7001"
 L1_URL="http://localhost:5000"
+OPERATOR_ID="OPR-001"
 
 echo "🧪 Testing Shard B Complete Workflow"
 echo "====================================="
@@ -26,6 +27,7 @@ echo ""
 # 2. Scan Package
 echo "📦 Step 2: Scanning package..."
 SCAN_RESPONSE=$(curl -s -X GET "$BASE_URL/session/$SESSION_ID/scan" \
+  -H "X-Operator-ID: $OPERATOR_ID" \
   -H "Content-Type: application/json" \
   -d '{"package_id":"PKG-002"}')
 
@@ -42,6 +44,7 @@ echo ""
 # 3. Validate Package
 echo "🔍 Step 3: Validating package..."
 VALIDATE_RESPONSE=$(curl -s -X POST "$BASE_URL/session/$SESSION_ID/validate" \
+  -H "X-Operator-ID: $OPERATOR_ID" \
   -H "Content-Type: application/json" \
   -d '{"signature":"sig_global_tech_002","package_id":"PKG-002"}')
 
@@ -58,6 +61,7 @@ echo ""
 # 4. Quality Check
 echo "✔️  Step 4: Quality check..."
 QC_RESPONSE=$(curl -s -X POST "$BASE_URL/session/$SESSION_ID/qc" \
+  -H "X-Operator-ID: $OPERATOR_ID" \
   -H "Content-Type: application/json" \
   -d '{"passed":true,"issues":[]}')
 
@@ -74,6 +78,7 @@ echo ""
 # 5. Label Package
 echo "🏷️  Step 5: Creating label..."
 LABEL_RESPONSE=$(curl -s -X POST "$BASE_URL/session/$SESSION_ID/label" \
+  -H "X-Operator-ID: $OPERATOR_ID" \
   -H "Content-Type: application/json" \
   -d '{"courier_id":"CUR-002"}')
 
@@ -90,6 +95,7 @@ echo ""
 # 6. Commit to L1
 echo "🔗 Step 6: Committing to L1..."
 COMMIT_RESPONSE=$(curl -s -X POST "$BASE_URL/session/$SESSION_ID/commit" \
+  -H "X-Operator-ID: $OPERATOR_ID" \
   -H "Content-Type: application/json")
 
 TX_HASH=$(echo "$COMMIT_RESPONSE" | jq -r '.tx_hash')
@@ -107,7 +113,7 @@ if [ "$TX_HASH" != "null" ] && [ ! -z "$TX_HASH" ]; then
     # Check session in L1 by shard
     echo "   Checking L1 sessions for shard-b..."
     L1_SESSIONS=$(curl -s "$L1_URL/l1/sessions/shard/shard-b")
-    SESSION_COUNT=$(echo "$L1_SESSIONS" | jq '.data | length')
+    SESSION_COUNT=$(echo "$L1_SESSIONS" | jq '.data.total')
     echo "   ✅ Found $SESSION_COUNT sessions for shard-b in L1"
     
     # Check session in L1 by group
@@ -0,0 +1,231 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/ahmadzakiakmal/thesis-extension/layer-2/repository"
+	"github.com/ahmadzakiakmal/thesis-extension/layer-2/repository/models"
+)
+
+// SignatureHeader carries "sha256=<hex>", the HMAC-SHA256 of the request body
+// keyed with the shared callback secret
+const SignatureHeader = "X-L2-Signature"
+
+const (
+	// pollInterval is how often pending callbacks are checked when idle
+	pollInterval = time.Second
+	// batchSize bounds how many callbacks are attempted per poll
+	batchSize = 20
+	// maxBackoff caps the delay between attempts for one callback
+	maxBackoff = 5 * time.Minute
+	// deliveryTimeout bounds a single POST to an integrator
+	deliveryTimeout = 10 * time.Second
+)
+
+// CommitStatus is the body POSTed to a callback URL once a commit finishes
+type CommitStatus struct {
+	SessionID   string    `json:"session_id"`
+	ShardID     string    `json:"shard_id"`
+	Status      string    `json:"status"` // committed, failed
+	TxHash      string    `json:"tx_hash,omitempty"`
+	BlockHeight int64     `json:"block_height,omitempty"`
+	Error       string    `json:"error,omitempty"`
+	Timestamp   time.Time `json:"timestamp"`
+}
+
+// Dispatcher delivers commit callbacks persisted in the repository, retrying
+// failed deliveries with exponential backoff
+type Dispatcher struct {
+	repository  *repository.Repository
+	secret      []byte
+	maxAttempts int
+	httpClient  *http.Client
+	wake        chan struct{}
+	stopped     chan struct{} // closed when Run returns
+}
+
+// NewDispatcher creates a dispatcher signing callbacks with secret and giving
+// up on a callback after maxAttempts failed deliveries
+func NewDispatcher(repo *repository.Repository, secret string, maxAttempts int) *Dispatcher {
+	return &Dispatcher{
+		repository:  repo,
+		secret:      []byte(secret),
+		maxAttempts: maxAttempts,
+		httpClient:  &http.Client{Timeout: deliveryTimeout},
+		wake:        make(chan struct{}, 1),
+		stopped:     make(chan struct{}),
+	}
+}
+
+// Sign returns the SignatureHeader value for body
+func Sign(secret, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Enqueue persists a callback for status and wakes the delivery loop
+func (d *Dispatcher) Enqueue(url string, status CommitStatus) error {
+	payload, err := json.Marshal(status)
+	if err != nil {
+		return fmt.Errorf("failed to encode callback payload: %w", err)
+	}
+	if _, dbErr := d.repository.CreateCommitCallback(status.SessionID, url, string(payload)); dbErr != nil {
+		return dbErr
+	}
+
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Run delivers due callbacks until ctx is canceled. Callbacks left pending by
+// a previous run are picked up on the first poll.
+func (d *Dispatcher) Run(ctx context.Context) {
+	defer close(d.stopped)
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		d.deliverDue(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-d.wake:
+		}
+	}
+}
+
+// Drain delivers the callbacks that are due once Run has stopped, until none
+// are left or ctx ends. Callbacks it doesn't deliver stay pending in the
+// database for the next run. It returns how many were delivered and how many
+// are still pending.
+func (d *Dispatcher) Drain(ctx context.Context) (delivered, deferred int) {
+	select {
+	case <-d.stopped:
+	case <-ctx.Done():
+	}
+
+	for ctx.Err() == nil {
+		callbacks, dbErr := d.repository.DueCommitCallbacks(time.Now(), batchSize)
+		if dbErr != nil {
+			log.Printf("⚠️  Warning: Failed to load commit callbacks: %v", dbErr)
+			break
+		}
+		if len(callbacks) == 0 {
+			break
+		}
+		for _, callback := range callbacks {
+			if ctx.Err() != nil {
+				break
+			}
+			if d.attempt(ctx, callback) {
+				delivered++
+			}
+		}
+	}
+
+	pending, dbErr := d.repository.CountPendingCommitCallbacks()
+	if dbErr != nil {
+		log.Printf("⚠️  Warning: Failed to count pending commit callbacks: %v", dbErr)
+	}
+	return delivered, int(pending)
+}
+
+// deliverDue attempts every callback whose next attempt is due
+func (d *Dispatcher) deliverDue(ctx context.Context) {
+	callbacks, dbErr := d.repository.DueCommitCallbacks(time.Now(), batchSize)
+	if dbErr != nil {
+		log.Printf("⚠️  Warning: Failed to load commit callbacks: %v", dbErr)
+		return
+	}
+
+	for _, callback := range callbacks {
+		if ctx.Err() != nil {
+			return
+		}
+		d.attempt(ctx, callback)
+	}
+}
+
+// attempt POSTs one callback and records the outcome, reporting whether it
+// was delivered. An attempt cut short by ctx is not counted.
+func (d *Dispatcher) attempt(ctx context.Context, callback models.CommitCallback) bool {
+	attempts := callback.Attempts + 1
+	err := d.post(ctx, callback.URL, []byte(callback.Payload))
+	if err != nil && ctx.Err() != nil {
+		return false
+	}
+
+	fields := map[string]interface{}{"attempts": attempts}
+	switch {
+	case err == nil:
+		fields["status"] = repository.CallbackDelivered
+		fields["last_error"] = ""
+		log.Printf("📨 Commit callback delivered: session=%s, url=%s", callback.SessionID, callback.URL)
+	case attempts >= d.maxAttempts:
+		fields["status"] = repository.CallbackFailed
+		fields["last_error"] = err.Error()
+		log.Printf("❌ Commit callback failed after %d attempts: session=%s, url=%s: %v",
+			attempts, callback.SessionID, callback.URL, err)
+	default:
+		fields["next_attempt_at"] = time.Now().Add(backoff(attempts))
+		fields["last_error"] = err.Error()
+		log.Printf("⚠️  Commit callback attempt %d failed: session=%s, url=%s: %v",
+			attempts, callback.SessionID, callback.URL, err)
+	}
+
+	if dbErr := d.repository.UpdateCommitCallback(callback.ID, fields); dbErr != nil {
+		log.Printf("⚠️  Warning: Failed to record commit callback attempt: %v", dbErr)
+	}
+	return err == nil
+}
+
+// post sends a signed callback body, treating any non-2xx status as a failure
+func (d *Dispatcher) post(ctx context.Context, url string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(SignatureHeader, Sign(d.secret, body))
+
+	resp, err := d.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("callback returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// backoff returns the delay before the next attempt, doubling from one second
+func backoff(attempts int) time.Duration {
+	delay := time.Second
+	for i := 1; i < attempts && delay < maxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > maxBackoff {
+		return maxBackoff
+	}
+	return delay
+}